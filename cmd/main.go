@@ -4,60 +4,140 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/ainesh01/anomaly_detection/internal/config"
 	"github.com/ainesh01/anomaly_detection/internal/handlers"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/ainesh01/anomaly_detection/internal/requestid"
 	"github.com/ainesh01/anomaly_detection/internal/services"
+	"github.com/ainesh01/anomaly_detection/internal/tracing"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// ingestBatchSize is the number of parsed jobs saved to the database per transaction
+const ingestBatchSize = 1000
+
+// stdinFilePath is the -file value that reads the list of files to parse from stdin
+const stdinFilePath = "-"
+
 func main() {
+	// Set up structured logging first so configuration warnings go through it too
+	logger := config.NewLogConfig().NewLogger()
+	slog.SetDefault(logger)
+
 	// Load configuration
 	servercfg, err := config.LoadServerConfig()
 	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+		fatal(logger, "error loading config", "err", err)
+	}
+	if len(servercfg.APIKeys) == 0 {
+		logger.Warn("API_KEYS is not set, the API is running without authentication")
 	}
 	dbcfg := config.NewDBConfig()
+	detectioncfg := config.NewDetectionConfig()
+	ingestcfg := config.NewIngestConfig()
+	alertcfg := config.NewAlertConfig()
+	schedulercfg := config.NewSchedulerConfig()
+	tracingcfg := config.NewTracingConfig()
+
+	ctx := context.Background()
 
 	// Initialize database service
-	dbService, err := services.InitializeDatabaseService(dbcfg)
+	dbService, err := services.InitializeDatabaseService(ctx, dbcfg, logger)
 	if err != nil {
-		log.Fatalf("Error initializing database service: %v", err)
+		fatal(logger, "error initializing database service", "err", err)
 	}
 	defer dbService.Close()
 
 	// Initialize services
-	jobDataService := services.NewJobDataService(dbService)
-	anomalyRuleService := services.NewAnomalyRuleService(dbService)
-	anomalyService := services.NewAnomalyService(dbService, anomalyRuleService)
+	jobDataService := services.NewJobDataService(dbService, logger)
+	jobDataService.SetRequiredFields(detectioncfg.RequiredFields)
+	anomalyRuleService := services.NewAnomalyRuleService(dbService, logger)
+	var notifier services.AlertNotifier
+	if alertcfg.WebhookURL != "" {
+		notifier = services.NewWebhookNotifier(alertcfg.WebhookURL)
+	}
+	anomalyService := services.NewAnomalyService(dbService, anomalyRuleService, detectioncfg, notifier, logger)
+	anomalyService.SetNotifyChannel(alertcfg.NotifyChannel)
+
+	filePath, migrateDownSteps, force, upsert := parseCommandLineArgs()
+	upsertMode, err := services.ParseUpsertMode(upsert)
+	if err != nil {
+		fatal(logger, "invalid -upsert", "err", err)
+	}
+
+	// Revert migrations and exit when requested
+	if migrateDownSteps > 0 {
+		if err := services.MigrateDown(ctx, dbService, migrateDownSteps, logger); err != nil {
+			fatal(logger, "error reverting migrations", "err", err)
+		}
+		logger.Info("reverted migrations", "steps", migrateDownSteps)
+		return
+	}
 
 	// Check if a file was provided
-	filePath := parseCommandLineArgs()
 	if filePath != "" {
-		// Parse the file and detect anomalies
-		rows, err := services.ParseJSONLFile(filePath)
-		if err != nil {
-			log.Fatalf("Error parsing file: %v", err)
+		// Stream the file, saving jobs to the database in batches as they are parsed
+		services.MaxLineSize = ingestcfg.MaxLineSize
+		ingester := services.NewIngester(ctx, jobDataService, upsertMode, ingestBatchSize, logger)
+		// Files whose content was ingested before are skipped unless -force is given.
+		// A file is only recorded as ingested once all of its jobs were saved.
+		guard := services.NewIngestGuard(jobDataService, force, logger)
+		guard.BeforeRecord = ingester.Flush
+		// A directory, or a list of paths piped in on stdin, is parsed file by file;
+		// a bad file is logged without stopping the others
+		parse := func(path string, fn func(models.JobData) error) error {
+			return guard.ParseFile(ctx, path, fn)
 		}
-
-		// Save each job to the database
-		for _, job := range rows {
-			if err := jobDataService.CreateJobData(&job); err != nil {
-				log.Printf("Error saving job %s: %v", job.JobID, err)
-				continue
+		info, err := os.Stat(filePath)
+		multiFile := true
+		switch {
+		case filePath == stdinFilePath:
+			parse = func(_ string, fn func(models.JobData) error) error {
+				return guard.ParsePathList(ctx, os.Stdin, fn)
 			}
+		case err == nil && info.IsDir():
+			parse = func(dir string, fn func(models.JobData) error) error {
+				return guard.ParseDir(ctx, dir, fn)
+			}
+		default:
+			multiFile = false
+		}
+		err = parse(filePath, ingester.Add)
+		if err != nil && multiFile {
+			logger.Error("error parsing files", "path", filePath, "err", err)
+		} else if err != nil {
+			fatal(logger, "error parsing file", "file", filePath, "err", err)
+		}
+		ingester.ParseError(err)
+		ingester.Flush()
+
+		result := ingester.Result()
+		logger.Info("ingestion finished", "file", filePath, "lines", result.Lines, "parsed", result.Parsed,
+			"saved", result.Saved, "skipped", result.Skipped, "errors", result.Errors)
+		if ratio := result.FailureRatio(); ratio > ingestcfg.MaxFailureRatio {
+			fatal(logger, "too many lines failed to ingest", "file", filePath,
+				"failure_ratio", ratio, "max_failure_ratio", ingestcfg.MaxFailureRatio)
 		}
-		log.Printf("Successfully parsed and saved %d rows from %s", len(rows), filePath)
 	} else {
-		log.Fatal("No file provided. Please provide a file to parse.")
+		fatal(logger, "no file provided, please provide a file to parse")
+	}
+
+	// Export spans for detection and its database calls when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(ctx, tracingcfg)
+	if err != nil {
+		fatal(logger, "error setting up tracing", "err", err)
+	}
+	if tracingcfg.Enabled() {
+		logger.Info("exporting traces", "endpoint", tracingcfg.Endpoint, "service", tracingcfg.ServiceName)
 	}
 
 	// Initialize HTTP server
@@ -66,32 +146,61 @@ func main() {
 	// Start server in a goroutine
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Error starting server: %v", err)
+			fatal(logger, "error starting server", "err", err)
 		}
 	}()
 
+	// Run detection in the background when a schedule is enabled
+	var scheduler *services.DetectionScheduler
+	if schedulercfg.Enabled {
+		scheduler = services.NewDetectionScheduler(anomalyService, schedulercfg.Interval, logger)
+		scheduler.Start(ctx)
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Graceful shutdown, cancelling any scheduled detection run in progress
+	if scheduler != nil {
+		scheduler.Stop()
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), servercfg.ShutdownTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		fatal(logger, "server forced to shutdown", "err", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Warn("error flushing traces", "err", err)
 	}
 
-	log.Println("Server exiting")
+	logger.Info("server exiting")
+}
+
+// fatal logs msg at error level and exits the process
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
 }
 
 // parseCommandLineArgs parses and validates command line arguments
-// Returns the file path to parse or empty string if not provided
-func parseCommandLineArgs() string {
-	filePath := flag.String("file", "", "Path to the JSONL.gz file to parse")
+// Returns the file or directory path to parse (stdinFilePath when the paths are read from
+// stdin) or empty string if not provided, the number of migrations to revert (zero
+// unless -migrate-down is given), whether files that were already ingested are
+// parsed again, and the name of the upsert mode for jobs that already exist
+func parseCommandLineArgs() (string, int, bool, string) {
+	filePath := flag.String("file", "", "Path to the JSONL.gz file, or a directory of .jsonl/.jsonl.gz shards, to parse; - reads newline-separated paths from stdin")
+	readStdin := flag.Bool("stdin", false, "Read newline-separated paths of files to parse from stdin, same as -file -")
+	migrateDown := flag.Int("migrate-down", 0, "Revert this many of the most recent schema migrations and exit")
+	force := flag.Bool("force", false, "Ingest files even if a file with identical content was ingested before")
+	upsert := flag.String("upsert", string(services.UpsertReplace), "How ingested jobs update existing jobs with the same ID: replace overwrites every field, fill-missing keeps existing values of fields the new record leaves empty")
 	flag.Parse()
-	return *filePath
+	if *readStdin {
+		*filePath = stdinFilePath
+	}
+	return *filePath, *migrateDown, *force, *upsert
 }
 
 func setupServer(
@@ -102,13 +211,17 @@ func setupServer(
 ) *http.Server {
 	router := gin.Default()
 
+	// Tag every request with an ID for tracing it through logs and error responses
+	router.Use(handlers.RequestID())
+
 	// Configure CORS
 	config := cors.DefaultConfig()
 	// Allow requests from the frontend development server
 	config.AllowOrigins = []string{"http://localhost:3000"}
 	// Allow common methods and headers
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Content-Encoding", "Accept", "Accept-Encoding", "Authorization", handlers.APIKeyHeader, handlers.IdempotencyKeyHeader, requestid.Header}
+	config.ExposeHeaders = []string{requestid.Header}
 	router.Use(cors.New(config))
 
 	// Health check endpoint
@@ -116,35 +229,87 @@ func setupServer(
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Prometheus metrics endpoint
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Build information, for checking which build a deployment is running
+	router.GET("/version", handlers.GetVersion)
+
 	// Initialize handlers
 	jobDataHandler := handlers.NewJobDataHandler(jobDataService)
-	anomalyHandler := handlers.NewAnomalyHandler(anomalyService)
+	anomalyHandler := handlers.NewAnomalyHandler(anomalyService, jobDataService)
 	anomalyRuleHandler := handlers.NewAnomalyRuleHandler(anomalyRuleService)
 
 	// Define API endpoints
 	api := router.Group("/api")
+	// Require an API key when any are configured; health, metrics and version above stay public
+	api.Use(handlers.RequireAPIKey(servercfg.APIKeys))
+	// Cap request bodies as sent, then accept gzip-compressed bodies on every API endpoint
+	api.Use(handlers.LimitRequestBody(servercfg.MaxBodySize))
+	api.Use(handlers.DecompressGzip(servercfg.MaxDecompressedBodySize))
+	// Compress large responses, such as long lists, for clients that accept gzip
+	if servercfg.CompressResponses {
+		api.Use(handlers.CompressGzip(servercfg.CompressMinSize))
+	}
 	{
 		// Job data endpoints
 		api.POST("/job-data", jobDataHandler.CreateJobData)
+		api.GET("/job-data/summary", jobDataHandler.GetJobDataSummary)
+		api.GET("/job-data/schema", jobDataHandler.GetJobDataSchema)
+		api.GET("/job-data/search", jobDataHandler.SearchJobData)
+		api.GET("/job-data/sample", jobDataHandler.SampleJobData)
+		api.GET("/job-data/range", jobDataHandler.GetJobDataRange)
+		api.GET("/job-data/risk", anomalyHandler.GetJobsByRiskScore)
 		api.GET("/job-data/:job_id", jobDataHandler.GetJobData)
+		api.PATCH("/job-data/:job_id", jobDataHandler.PatchJobData)
+		api.DELETE("/job-data/:job_id", jobDataHandler.DeleteJobData)
+		api.GET("/job-data/:job_id/risk", anomalyHandler.GetJobRiskScore)
 		api.GET("/job-data", jobDataHandler.GetAllJobData)
 
 		// Anomaly endpoints
+		api.GET("/anomalies/stats", anomalyHandler.GetAnomalyStats)
+		api.GET("/anomalies/types", anomalyHandler.GetAnomalyTypes)
+		api.GET("/anomalies/by-company", anomalyHandler.GetAnomaliesByCompany)
+		api.GET("/anomalies/detailed", anomalyHandler.GetDetailedAnomalies)
+		api.GET("/anomalies/runs", anomalyHandler.GetDetectionRuns)
+		api.GET("/anomalies/id/:id", anomalyHandler.GetAnomalyByID)
+		api.PATCH("/anomalies/id/:id/resolve", anomalyHandler.ResolveAnomaly)
+		api.PATCH("/anomalies/id/:id/acknowledge", anomalyHandler.AcknowledgeAnomaly)
+		api.POST("/anomalies/id/:id/notes", anomalyHandler.AddAnomalyNote)
+		api.GET("/anomalies/id/:id/notes", anomalyHandler.GetAnomalyNotes)
 		api.GET("/anomalies/:job_id", anomalyHandler.GetAnomaliesByJobID)
 		api.GET("/anomalies", anomalyHandler.GetAllAnomalies)
+		api.DELETE("/anomalies", anomalyHandler.DeleteAnomalies)
 		api.POST("/anomalies/detect-all", anomalyHandler.DetectAnomaliesForAllJobs)
+		api.POST("/anomalies/detect-since", anomalyHandler.DetectAnomaliesSince)
+		api.POST("/anomalies/detect-duplicates", anomalyHandler.DetectDuplicates)
+		api.POST("/anomalies/detect/:job_id", anomalyHandler.DetectAnomaliesForJob)
+
+		// Configuration endpoints
+		api.GET("/config/detection", anomalyHandler.GetDetectionConfig)
 
 		// Anomaly rule endpoints
 		api.GET("/anomaly-rules", anomalyRuleHandler.GetAnomalyRules)
+		api.GET("/anomaly-rules/export", anomalyRuleHandler.ExportAnomalyRules)
+		api.POST("/anomaly-rules/import", anomalyRuleHandler.ImportAnomalyRules)
 		api.GET("/anomaly-rules/:id", anomalyRuleHandler.GetAnomalyRule)
 		api.POST("/anomaly-rules", anomalyRuleHandler.CreateAnomalyRule)
 		api.PUT("/anomaly-rules/:id", anomalyRuleHandler.UpdateAnomalyRule)
 		api.DELETE("/anomaly-rules/:id", anomalyRuleHandler.DeleteAnomalyRule)
+		api.PATCH("/anomaly-rules/toggle-bulk", anomalyRuleHandler.ToggleRulesBulk)
 		api.PATCH("/anomaly-rules/:id/toggle", anomalyRuleHandler.ToggleAnomalyRule)
+		api.POST("/anomaly-rules/:id/test", anomalyHandler.EvaluateAnomalyRule)
+		api.GET("/anomaly-rules/:id/history", anomalyRuleHandler.GetRuleHistory)
 	}
 
+	// Bound how long a client can hold a connection, so slow clients can't tie up the server
 	return &http.Server{
-		Addr:    fmt.Sprintf(":%d", servercfg.Port),
-		Handler: router,
+		Addr:              fmt.Sprintf(":%d", servercfg.Port),
+		Handler:           router,
+		ReadHeaderTimeout: servercfg.ReadHeaderTimeout,
+		ReadTimeout:       servercfg.ReadTimeout,
+		WriteTimeout:      servercfg.WriteTimeout,
+		IdleTimeout:       servercfg.IdleTimeout,
+		MaxHeaderBytes:    servercfg.MaxHeaderBytes,
 	}
 }
@@ -44,7 +44,7 @@ export default function AnomaliesPage() {
          try {
            const errorBody = await response.json(); // Try to parse backend error message
            if (errorBody && errorBody.error) {
-             errorMsg += `: ${errorBody.error}`;
+             errorMsg += `: ${errorBody.error.message}`;
            }
          } catch (parseError) {
            // Ignore if body isn't JSON or empty
@@ -58,8 +58,8 @@ export default function AnomaliesPage() {
       if (!anomaliesResponse.ok) {
          throw new Error(`HTTP error fetching anomalies! status: ${anomaliesResponse.status}`);
       }
-      const data = await anomaliesResponse.json();
-      setAnomaliesData(data);
+      const page = await anomaliesResponse.json();
+      setAnomaliesData(page.data);
 
     } catch (e) {
        if (e instanceof Error) {
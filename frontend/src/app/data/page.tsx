@@ -20,8 +20,8 @@ export default function DataPage() {
         if (!response.ok) {
           throw new Error(`HTTP error! status: ${response.status}`);
         }
-        const data: JobData[] = await response.json();
-        setJobs(data);
+        const page: { data: JobData[] } = await response.json();
+        setJobs(page.data);
       } catch (e: any) {
         setError(e.message);
       } finally {
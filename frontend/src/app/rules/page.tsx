@@ -62,9 +62,9 @@ export default function RulesPage() { // Changed to non-async as fetching is mov
       if (!response.ok) {
         throw new Error(`HTTP error! status: ${response.status}`);
       }
-      const data = await response.json();
+      const page = await response.json();
       // Ensure ID is a string and isActive is boolean
-      const formattedData = data.map((rule: any) => ({
+      const formattedData = page.data.map((rule: any) => ({
         ...rule,
         id: String(rule.id), // Convert ID to string if necessary
         is_active: !!rule.is_active, // Ensure boolean type
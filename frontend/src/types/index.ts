@@ -1,7 +1,7 @@
 export type JobData = {
   // Company Information
   companyName: string;
-  companyRating: number;
+  companyRating?: number;
   companyAddress: string;
   companyWebsite: string;
 
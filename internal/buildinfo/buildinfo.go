@@ -0,0 +1,32 @@
+// Package buildinfo describes the running build. Commit and BuildTime are set
+// at link time, for example:
+//
+//	go build -ldflags "-X github.com/ainesh01/anomaly_detection/internal/buildinfo.Commit=$(git rev-parse HEAD)
+//	  -X github.com/ainesh01/anomaly_detection/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/main.go
+//
+// Builds without those flags, such as go run and go test, report "unknown".
+package buildinfo
+
+import "runtime"
+
+// Variables rather than constants, so -ldflags -X can override them
+var (
+	Commit    = "unknown" // Git commit the binary was built from
+	BuildTime = "unknown" // When the binary was built, in RFC 3339
+)
+
+// Info is the build information reported by GET /version
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the information about the running build
+func Get() Info {
+	return Info{
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import "log/slog"
+
+// DefaultNotifyChannel is the Postgres channel new anomalies are announced on by default
+const DefaultNotifyChannel = "anomaly_detected"
+
+// AlertConfig holds alert delivery configuration
+type AlertConfig struct {
+	WebhookURL    string
+	NotifyChannel string // Postgres NOTIFY channel announcing new anomalies; empty disables it
+}
+
+// NewAlertConfig loads alert configuration from environment variables.
+// Alerting is disabled when no webhook URL is set. Setting ANOMALY_NOTIFY_CHANNEL
+// to an empty value turns off the NOTIFY sent for each new anomaly.
+func NewAlertConfig() *AlertConfig {
+	// Channels follow the same lowercase identifier rules as schemas, so
+	// listeners can LISTEN on them without quoting
+	channel := getEnv("ANOMALY_NOTIFY_CHANNEL", DefaultNotifyChannel)
+	if channel != "" && !schemaNamePattern.MatchString(channel) {
+		slog.Warn("invalid ANOMALY_NOTIFY_CHANNEL, using default", "value", channel, "default", DefaultNotifyChannel)
+		channel = DefaultNotifyChannel
+	}
+
+	return &AlertConfig{
+		WebhookURL:    getEnv("ALERT_WEBHOOK_URL", ""),
+		NotifyChannel: channel,
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAlertConfigNotifyChannel(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *string
+		want  string
+	}{
+		{"default", nil, DefaultNotifyChannel},
+		{"custom", strPtr("tenant_anomalies"), "tenant_anomalies"},
+		{"disabled", strPtr(""), ""},
+		{"invalid", strPtr("Anomaly-Detected"), DefaultNotifyChannel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value != nil {
+				t.Setenv("ANOMALY_NOTIFY_CHANNEL", *tt.value)
+			}
+			assert.Equal(t, tt.want, NewAlertConfig().NotifyChannel)
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -2,10 +2,25 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"regexp"
 	"strconv"
+	"time"
 )
 
+const (
+	// DefaultRetryAttempts is how many times a database call is tried before a transient error is returned
+	DefaultRetryAttempts = 3
+	// DefaultRetryBackoff is the wait before the first retry; it doubles on each later retry
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// DefaultSchema is the Postgres schema holding the application's tables by default
+	DefaultSchema = "public"
+)
+
+// schemaNamePattern accepts lowercase Postgres identifiers, which need no quoting
+// in the search_path and can't collide with a differently cased schema
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
 // Config holds all configuration for the application
 type Config struct {
 	DB     *DBConfig
@@ -19,6 +34,11 @@ type DBConfig struct {
 	User     string
 	Password string
 	DBName   string
+	Reset    bool   // Drop and recreate all tables on startup, destroying existing data
+	Schema   string // Postgres schema holding the tables, so tenants can share a database
+
+	RetryAttempts int           // Tries per call on transient connection errors; 1 disables retries
+	RetryBackoff  time.Duration // Wait before the first retry, doubled on each later retry
 }
 
 func NewDBConfig() *DBConfig {
@@ -27,23 +47,61 @@ func NewDBConfig() *DBConfig {
 		port = 5432 // Use default if parsing fails
 	}
 
+	rawReset := getEnv("DB_RESET", "false")
+	reset, err := strconv.ParseBool(rawReset)
+	if err != nil {
+		slog.Warn("invalid DB_RESET, database will not be reset", "value", rawReset)
+		reset = false
+	}
+
+	rawRetryAttempts := getEnv("DB_RETRY_ATTEMPTS", strconv.Itoa(DefaultRetryAttempts))
+	retryAttempts, err := strconv.Atoi(rawRetryAttempts)
+	if err != nil || retryAttempts < 1 {
+		slog.Warn("invalid DB_RETRY_ATTEMPTS, using default", "value", rawRetryAttempts, "default", DefaultRetryAttempts)
+		retryAttempts = DefaultRetryAttempts
+	}
+
+	rawRetryBackoff := getEnv("DB_RETRY_BACKOFF", DefaultRetryBackoff.String())
+	retryBackoff, err := time.ParseDuration(rawRetryBackoff)
+	if err != nil || retryBackoff < 0 {
+		slog.Warn("invalid DB_RETRY_BACKOFF, using default", "value", rawRetryBackoff, "default", DefaultRetryBackoff)
+		retryBackoff = DefaultRetryBackoff
+	}
+
+	schema := getEnv("DB_SCHEMA", DefaultSchema)
+	if !schemaNamePattern.MatchString(schema) {
+		slog.Warn("invalid DB_SCHEMA, using default", "value", schema, "default", DefaultSchema)
+		schema = DefaultSchema
+	}
+
 	config := &DBConfig{
 		Host:     getEnv("DB_HOST", "localhost"),
 		Port:     port,
 		User:     getEnv("DB_USER", "postgres"),
 		Password: getEnv("DB_PASSWORD", ""),
 		DBName:   getEnv("DB_NAME", "anomaly_detection"),
+		Reset:    reset,
+		Schema:   schema,
+
+		RetryAttempts: retryAttempts,
+		RetryBackoff:  retryBackoff,
 	}
 
-	log.Printf("Database config: host=%s port=%d user=%s dbname=%s",
-		config.Host, config.Port, config.User, config.DBName)
+	slog.Info("database config",
+		"host", config.Host, "port", config.Port, "user", config.User, "dbname", config.DBName, "schema", config.Schema,
+		"reset", config.Reset, "retry_attempts", config.RetryAttempts, "retry_backoff", config.RetryBackoff)
 
 	return config
 }
 
+// GetDSN returns the connection string for the database. When a schema is set,
+// it becomes the search_path of every connection, so unqualified table names in
+// queries and migrations resolve to that schema.
 func (c *DBConfig) GetDSN() string {
 	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		c.Host, c.Port, c.User, c.Password, c.DBName)
-	log.Printf("Using DSN: %s", dsn)
+	if c.Schema != "" {
+		dsn += " search_path=" + c.Schema
+	}
 	return dsn
 }
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDBConfigSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		unset    bool
+		expected string
+	}{
+		{name: "unset uses public", unset: true, expected: DefaultSchema},
+		{name: "tenant schema", env: "tenant_a", expected: "tenant_a"},
+		{name: "uppercase falls back to default", env: "Tenant", expected: DefaultSchema},
+		{name: "unsafe name falls back to default", env: "tenant; DROP TABLE jobs", expected: DefaultSchema},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setenv restores the variable after the test, even when it is then unset
+			t.Setenv("DB_SCHEMA", tt.env)
+			if tt.unset {
+				os.Unsetenv("DB_SCHEMA")
+			}
+
+			assert.Equal(t, tt.expected, NewDBConfig().Schema)
+		})
+	}
+}
+
+func TestGetDSNSearchPath(t *testing.T) {
+	cfg := &DBConfig{Host: "localhost", Port: 5432, User: "postgres", DBName: "anomaly_detection", Schema: "tenant_a"}
+	assert.Contains(t, cfg.GetDSN(), " search_path=tenant_a")
+
+	// Without a schema the server's default search_path applies
+	cfg.Schema = ""
+	assert.NotContains(t, cfg.GetDSN(), "search_path")
+}
+
+func TestDBConfigDoesNotLogPassword(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	t.Setenv("DB_PASSWORD", "s3cret")
+	t.Setenv("DB_RETRY_ATTEMPTS", "zero")
+
+	cfg := NewDBConfig()
+	assert.Contains(t, cfg.GetDSN(), "password=s3cret")
+
+	assert.Contains(t, buf.String(), `"msg":"invalid DB_RETRY_ATTEMPTS, using default"`)
+	assert.Contains(t, buf.String(), `"level":"WARN"`)
+	assert.NotContains(t, buf.String(), "s3cret")
+}
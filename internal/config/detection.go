@@ -0,0 +1,471 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+const (
+	// DefaultStdDevThreshold is the z-score magnitude above which a value is considered anomalous
+	DefaultStdDevThreshold = 3.0
+	// DefaultAlertMinSeverity is the lowest severity that triggers an alert
+	DefaultAlertMinSeverity = models.SeverityHigh
+	// DefaultMinGroupSamples is the fewest jobs a group needs before its own statistics are used
+	DefaultMinGroupSamples = 5
+	// DefaultTrendWindow is how far back the rolling salary statistics look
+	DefaultTrendWindow = 30 * 24 * time.Hour
+	// DefaultMADCutoff is the modified z-score magnitude above which a salary is a robust outlier
+	DefaultMADCutoff = 3.5
+	// DefaultMultivariateThreshold is the Mahalanobis distance of a job's salary and
+	// company rating from their joint mean above which the pair is an outlier
+	DefaultMultivariateThreshold = 3.5
+	// DefaultStaleAge is how long before it was collected a job may have been
+	// posted before the listing is stale
+	DefaultStaleAge = 90 * 24 * time.Hour
+
+	// Dimensions statistics can be grouped by; StatsGroupByNone uses global statistics only
+	StatsGroupByNone     = ""
+	StatsGroupByRoleType = "role_type"
+	StatsGroupByCity     = "city"
+)
+
+// DefaultRequiredFields are the job columns the null value check flags when empty
+var DefaultRequiredFields = []string{
+	"company_name",
+	"job_title",
+	"job_description",
+	"city",
+	"company_address",
+	"company_website",
+	"job_link",
+}
+
+// DefaultSeverityMap is the severity assigned to each anomaly type when nothing is
+// overridden. Types missing from the map are assigned models.SeverityMedium.
+var DefaultSeverityMap = map[models.AnomalyType]string{
+	models.AnomalyTypeNullValues:   models.SeverityMedium,
+	models.AnomalyTypeSalaryRange:  models.SeverityMedium,
+	models.AnomalyTypeDeviation:    models.SeverityMedium,
+	models.AnomalyTypeIQR:          models.SeverityMedium,
+	models.AnomalyTypeMAD:          models.SeverityMedium,
+	models.AnomalyTypeTrend:        models.SeverityMedium,
+	models.AnomalyTypeGeoOutlier:   models.SeverityMedium,
+	models.AnomalyTypeDuplicate:    models.SeverityMedium,
+	models.AnomalyTypeMultivariate: models.SeverityMedium,
+	models.AnomalyTypeStale:        models.SeverityMedium,
+}
+
+// DefaultSalaryMultipliers convert a salary quoted per hour, day, week, or month
+// into an annual one, assuming full-time work of 40 hours a week, 52 weeks a year.
+// Salaries of other granularities are compared as stored.
+var DefaultSalaryMultipliers = map[string]float64{
+	"hourly":  2080,
+	"daily":   260,
+	"weekly":  52,
+	"monthly": 12,
+	"yearly":  1,
+}
+
+// DefaultSeverityWeights is how much one anomaly of each severity adds to a
+// job's risk score when nothing is overridden. Unknown severities add nothing.
+var DefaultSeverityWeights = map[string]float64{
+	models.SeverityLow:      1,
+	models.SeverityMedium:   2,
+	models.SeverityHigh:     5,
+	models.SeverityCritical: 10,
+}
+
+// DetectionConfig holds anomaly detection configuration
+type DetectionConfig struct {
+	StdDevThreshold       float64                       `json:"stddev_threshold"`
+	AlertMinSeverity      string                        `json:"alert_min_severity"`
+	StatsGroupBy          string                        `json:"stats_group_by"`         // Job column statistics are grouped by, or empty for global statistics
+	MinGroupSamples       int                           `json:"min_group_samples"`      // Groups or trend windows with fewer jobs are not used for comparison
+	TrendWindow           time.Duration                 `json:"-"`                      // Rolling window for the salary trend check, or zero to disable it
+	MADCutoff             float64                       `json:"mad_cutoff"`             // Modified z-score magnitude flagged by the median absolute deviation check
+	MultivariateThreshold float64                       `json:"multivariate_threshold"` // Mahalanobis distance flagged by the joint salary and rating check
+	StaleAge              time.Duration                 `json:"-"`                      // Age at collection above which a posting is stale
+	RequiredFields        []string                      `json:"required_fields"`        // Job columns the null value check flags when empty
+	SalaryMultipliers     map[string]float64            `json:"salary_multipliers"`     // Factor turning a salary of each granularity into an annual one
+	SeverityMap           map[models.AnomalyType]string `json:"severity_map"`           // Default severity per anomaly type
+	SeverityWeights       map[string]float64            `json:"severity_weights"`       // Risk score added per anomaly of each severity
+	Workers               int                           `json:"workers"`                // Jobs checked concurrently by batch detection
+
+	// Toggles for the built-in detectors; rule checks always run
+	EnableNullCheck    bool `json:"enable_null_check"`
+	EnableSalaryRange  bool `json:"enable_salary_range"`
+	EnableDeviation    bool `json:"enable_deviation"` // Salary and company rating z-scores
+	EnableIQR          bool `json:"enable_iqr"`       // Salary and company rating interquartile fences
+	EnableMAD          bool `json:"enable_mad"`
+	EnableTrend        bool `json:"enable_trend"`
+	EnableGeoOutlier   bool `json:"enable_geo_outlier"`
+	EnableMultivariate bool `json:"enable_multivariate"` // Salary and company rating considered together
+	EnableStale        bool `json:"enable_stale"`
+}
+
+// DetectorEnabled reports whether the built-in detector producing anomalies of
+// the given type is switched on. Types without a toggle, such as rule types, are
+// always enabled.
+func (c *DetectionConfig) DetectorEnabled(anomalyType models.AnomalyType) bool {
+	if toggle := c.detectorToggle(anomalyType); toggle != nil {
+		return *toggle
+	}
+	return true
+}
+
+// detectorToggle returns the field switching the detector for anomalyType on and
+// off, or nil when the type has no built-in detector
+func (c *DetectionConfig) detectorToggle(anomalyType models.AnomalyType) *bool {
+	switch anomalyType {
+	case models.AnomalyTypeNullValues:
+		return &c.EnableNullCheck
+	case models.AnomalyTypeSalaryRange:
+		return &c.EnableSalaryRange
+	case models.AnomalyTypeDeviation:
+		return &c.EnableDeviation
+	case models.AnomalyTypeIQR:
+		return &c.EnableIQR
+	case models.AnomalyTypeMAD:
+		return &c.EnableMAD
+	case models.AnomalyTypeTrend:
+		return &c.EnableTrend
+	case models.AnomalyTypeGeoOutlier:
+		return &c.EnableGeoOutlier
+	case models.AnomalyTypeMultivariate:
+		return &c.EnableMultivariate
+	case models.AnomalyTypeStale:
+		return &c.EnableStale
+	}
+	return nil
+}
+
+// MarshalJSON encodes the config with TrendWindow and StaleAge written as
+// duration strings such as "720h0m0s"
+func (c DetectionConfig) MarshalJSON() ([]byte, error) {
+	type plain DetectionConfig
+	return json.Marshal(struct {
+		plain
+		TrendWindow string `json:"trend_window"`
+		StaleAge    string `json:"stale_age"`
+	}{plain(c), c.TrendWindow.String(), c.StaleAge.String()})
+}
+
+// Clone returns a copy of the config that shares no slices or maps with the original
+func (c *DetectionConfig) Clone() *DetectionConfig {
+	clone := *c
+	clone.RequiredFields = append([]string(nil), c.RequiredFields...)
+	clone.SeverityMap = maps.Clone(c.SeverityMap)
+	clone.SeverityWeights = maps.Clone(c.SeverityWeights)
+	clone.SalaryMultipliers = maps.Clone(c.SalaryMultipliers)
+	return &clone
+}
+
+// DefaultDetectionConfig returns the detection configuration used when nothing is overridden
+func DefaultDetectionConfig() *DetectionConfig {
+	return &DetectionConfig{
+		StdDevThreshold:       DefaultStdDevThreshold,
+		AlertMinSeverity:      DefaultAlertMinSeverity,
+		StatsGroupBy:          StatsGroupByNone,
+		MinGroupSamples:       DefaultMinGroupSamples,
+		TrendWindow:           DefaultTrendWindow,
+		MADCutoff:             DefaultMADCutoff,
+		MultivariateThreshold: DefaultMultivariateThreshold,
+		StaleAge:              DefaultStaleAge,
+		RequiredFields:        append([]string(nil), DefaultRequiredFields...),
+		SalaryMultipliers:     maps.Clone(DefaultSalaryMultipliers),
+		SeverityMap:           maps.Clone(DefaultSeverityMap),
+		SeverityWeights:       maps.Clone(DefaultSeverityWeights),
+		Workers:               runtime.NumCPU(),
+
+		EnableNullCheck:    true,
+		EnableSalaryRange:  true,
+		EnableDeviation:    true,
+		EnableIQR:          true,
+		EnableMAD:          true,
+		EnableTrend:        true,
+		EnableGeoOutlier:   true,
+		EnableMultivariate: true,
+		EnableStale:        true,
+	}
+}
+
+// SeverityFor returns the default severity for anomalies of the given type,
+// falling back to models.SeverityMedium for types that are not mapped
+func (c *DetectionConfig) SeverityFor(anomalyType models.AnomalyType) string {
+	if severity, ok := c.SeverityMap[anomalyType]; ok {
+		return severity
+	}
+	return models.SeverityMedium
+}
+
+// NewDetectionConfig loads detection configuration from environment variables,
+// falling back to defaults for missing or invalid values
+func NewDetectionConfig() *DetectionConfig {
+	config := DefaultDetectionConfig()
+
+	if raw, ok := lookupEnv("STDDEV_THRESHOLD"); ok {
+		threshold, err := strconv.ParseFloat(raw, 64)
+		if err != nil || threshold <= 0 {
+			slog.Warn("invalid STDDEV_THRESHOLD, using default", "value", raw, "default", DefaultStdDevThreshold)
+		} else {
+			config.StdDevThreshold = threshold
+		}
+	}
+
+	if raw, ok := lookupEnv("ALERT_MIN_SEVERITY"); ok {
+		if !models.IsValidSeverity(raw) {
+			slog.Warn("invalid ALERT_MIN_SEVERITY, using default", "value", raw, "default", DefaultAlertMinSeverity)
+		} else {
+			config.AlertMinSeverity = raw
+		}
+	}
+
+	if raw, ok := lookupEnv("STATS_GROUP_BY"); ok {
+		switch raw {
+		case StatsGroupByNone, StatsGroupByRoleType, StatsGroupByCity:
+			config.StatsGroupBy = raw
+		default:
+			slog.Warn("invalid STATS_GROUP_BY, using global statistics", "value", raw)
+		}
+	}
+
+	if raw, ok := lookupEnv("STATS_MIN_GROUP_SAMPLES"); ok {
+		samples, err := strconv.Atoi(raw)
+		if err != nil || samples < 1 {
+			slog.Warn("invalid STATS_MIN_GROUP_SAMPLES, using default", "value", raw, "default", DefaultMinGroupSamples)
+		} else {
+			config.MinGroupSamples = samples
+		}
+	}
+
+	if raw, ok := lookupEnv("TREND_WINDOW"); ok {
+		window, err := time.ParseDuration(raw)
+		if err != nil || window < 0 {
+			slog.Warn("invalid TREND_WINDOW, using default", "value", raw, "default", DefaultTrendWindow)
+		} else {
+			config.TrendWindow = window
+		}
+	}
+
+	if raw, ok := lookupEnv("MAD_CUTOFF"); ok {
+		cutoff, err := strconv.ParseFloat(raw, 64)
+		if err != nil || cutoff <= 0 {
+			slog.Warn("invalid MAD_CUTOFF, using default", "value", raw, "default", DefaultMADCutoff)
+		} else {
+			config.MADCutoff = cutoff
+		}
+	}
+
+	if raw, ok := lookupEnv("MULTIVARIATE_THRESHOLD"); ok {
+		threshold, err := strconv.ParseFloat(raw, 64)
+		if err != nil || threshold <= 0 {
+			slog.Warn("invalid MULTIVARIATE_THRESHOLD, using default", "value", raw, "default", DefaultMultivariateThreshold)
+		} else {
+			config.MultivariateThreshold = threshold
+		}
+	}
+
+	if raw, ok := lookupEnv("STALE_POSTING_AGE"); ok {
+		age, err := time.ParseDuration(raw)
+		if err != nil || age <= 0 {
+			slog.Warn("invalid STALE_POSTING_AGE, using default", "value", raw, "default", DefaultStaleAge)
+		} else {
+			config.StaleAge = age
+		}
+	}
+
+	if raw, ok := lookupEnv("REQUIRED_FIELDS"); ok {
+		fields, invalid := parseRequiredFields(raw)
+		if len(invalid) > 0 || len(fields) == 0 {
+			slog.Warn("invalid REQUIRED_FIELDS, using default", "value", raw, "unknown_fields", invalid, "default", DefaultRequiredFields)
+		} else {
+			config.RequiredFields = fields
+		}
+	}
+
+	if raw, ok := lookupEnv("SEVERITY_MAP"); ok {
+		overrides, err := parseSeverityMap(raw)
+		if err != nil {
+			slog.Warn("invalid SEVERITY_MAP, using default severities", "value", raw, "err", err)
+		} else {
+			for anomalyType, severity := range overrides {
+				config.SeverityMap[anomalyType] = severity
+			}
+		}
+	}
+
+	if raw, ok := lookupEnv("SEVERITY_WEIGHTS"); ok {
+		overrides, err := parseSeverityWeights(raw)
+		if err != nil {
+			slog.Warn("invalid SEVERITY_WEIGHTS, using default weights", "value", raw, "err", err)
+		} else {
+			for severity, weight := range overrides {
+				config.SeverityWeights[severity] = weight
+			}
+		}
+	}
+
+	if raw, ok := lookupEnv("SALARY_MULTIPLIERS"); ok {
+		overrides, err := parseSalaryMultipliers(raw)
+		if err != nil {
+			slog.Warn("invalid SALARY_MULTIPLIERS, using default multipliers", "value", raw, "err", err)
+		} else {
+			for granularity, multiplier := range overrides {
+				config.SalaryMultipliers[granularity] = multiplier
+			}
+		}
+	}
+
+	if raw, ok := lookupEnv("DETECTION_WORKERS"); ok {
+		workers, err := strconv.Atoi(raw)
+		if err != nil || workers < 1 {
+			slog.Warn("invalid DETECTION_WORKERS, using default", "value", raw, "default", config.Workers)
+		} else {
+			config.Workers = workers
+		}
+	}
+
+	if raw, ok := lookupEnv("DISABLED_DETECTORS"); ok {
+		if invalid := config.disableDetectors(raw); len(invalid) > 0 {
+			slog.Warn("invalid DISABLED_DETECTORS, keeping every detector enabled", "value", raw, "unknown_detectors", invalid)
+		}
+	}
+
+	slog.Info("detection config",
+		"stddev_threshold", config.StdDevThreshold, "alert_min_severity", config.AlertMinSeverity,
+		"stats_group_by", config.StatsGroupBy, "min_group_samples", config.MinGroupSamples,
+		"trend_window", config.TrendWindow, "mad_cutoff", config.MADCutoff,
+		"multivariate_threshold", config.MultivariateThreshold, "stale_age", config.StaleAge,
+		"required_fields", config.RequiredFields, "salary_multipliers", config.SalaryMultipliers,
+		"severity_map", config.SeverityMap, "severity_weights", config.SeverityWeights,
+		"workers", config.Workers, "disabled_detectors", config.disabledDetectors())
+
+	return config
+}
+
+// disableDetectors switches off the detectors named in a comma-separated list of
+// anomaly types. Nothing is changed when any name is unknown; the unknown names
+// are returned instead.
+func (c *DetectionConfig) disableDetectors(raw string) (invalid []string) {
+	var toggles []*bool
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		toggle := c.detectorToggle(models.AnomalyType(name))
+		if toggle == nil {
+			invalid = append(invalid, name)
+			continue
+		}
+		toggles = append(toggles, toggle)
+	}
+	if len(invalid) > 0 {
+		return invalid
+	}
+	for _, toggle := range toggles {
+		*toggle = false
+	}
+	return nil
+}
+
+// disabledDetectors lists the anomaly types whose detectors are switched off
+func (c *DetectionConfig) disabledDetectors() []models.AnomalyType {
+	var disabled []models.AnomalyType
+	for _, anomalyType := range []models.AnomalyType{
+		models.AnomalyTypeNullValues,
+		models.AnomalyTypeSalaryRange,
+		models.AnomalyTypeDeviation,
+		models.AnomalyTypeIQR,
+		models.AnomalyTypeMAD,
+		models.AnomalyTypeTrend,
+		models.AnomalyTypeGeoOutlier,
+		models.AnomalyTypeMultivariate,
+		models.AnomalyTypeStale,
+	} {
+		if !c.DetectorEnabled(anomalyType) {
+			disabled = append(disabled, anomalyType)
+		}
+	}
+	return disabled
+}
+
+// parseRequiredFields splits a comma-separated list of job columns, returning
+// the valid fields and any names that are not known required fields
+func parseRequiredFields(raw string) (fields []string, invalid []string) {
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !models.IsValidRequiredField(field) {
+			invalid = append(invalid, field)
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields, invalid
+}
+
+// parseSeverityMap decodes a JSON object mapping anomaly types to severities,
+// such as {"null_values": "low", "salary_range": "critical"}
+func parseSeverityMap(raw string) (map[models.AnomalyType]string, error) {
+	var severities map[models.AnomalyType]string
+	if err := json.Unmarshal([]byte(raw), &severities); err != nil {
+		return nil, err
+	}
+	for anomalyType, severity := range severities {
+		if !models.IsValidSeverity(severity) {
+			return nil, fmt.Errorf("unknown severity %q for %s", severity, anomalyType)
+		}
+	}
+	return severities, nil
+}
+
+// parseSalaryMultipliers decodes a JSON object mapping salary granularities to
+// the factor that makes a salary of that granularity annual, such as
+// {"hourly": 2000}. Granularities are lowercased, and multipliers must be positive.
+func parseSalaryMultipliers(raw string) (map[string]float64, error) {
+	var decoded map[string]float64
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+		return nil, err
+	}
+	multipliers := make(map[string]float64, len(decoded))
+	for granularity, multiplier := range decoded {
+		granularity = strings.ToLower(strings.TrimSpace(granularity))
+		if granularity == "" {
+			return nil, fmt.Errorf("empty granularity")
+		}
+		if multiplier <= 0 {
+			return nil, fmt.Errorf("multiplier %v for %s is not positive", multiplier, granularity)
+		}
+		multipliers[granularity] = multiplier
+	}
+	return multipliers, nil
+}
+
+// parseSeverityWeights decodes a JSON object mapping severities to their risk
+// score weights, such as {"high": 8}. Weights must not be negative.
+func parseSeverityWeights(raw string) (map[string]float64, error) {
+	var weights map[string]float64
+	if err := json.Unmarshal([]byte(raw), &weights); err != nil {
+		return nil, err
+	}
+	for severity, weight := range weights {
+		if !models.IsValidSeverity(severity) {
+			return nil, fmt.Errorf("unknown severity %q", severity)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("negative weight %v for %s", weight, severity)
+		}
+	}
+	return weights, nil
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"encoding/json"
+	"maps"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDetectionConfigRequiredFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected []string
+	}{
+		{
+			name:     "unset uses defaults",
+			env:      "",
+			expected: DefaultRequiredFields,
+		},
+		{
+			name:     "custom fields",
+			env:      "company_name, max_salary,state",
+			expected: []string{"company_name", "max_salary", "state"},
+		},
+		{
+			name:     "unknown field falls back to defaults",
+			env:      "company_name,favourite_colour",
+			expected: DefaultRequiredFields,
+		},
+		{
+			name:     "only separators falls back to defaults",
+			env:      " , ",
+			expected: DefaultRequiredFields,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REQUIRED_FIELDS", tt.env)
+
+			assert.Equal(t, tt.expected, NewDetectionConfig().RequiredFields)
+		})
+	}
+}
+
+func TestNewDetectionConfigSeverityMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected map[models.AnomalyType]string
+	}{
+		{
+			name:     "unset uses defaults",
+			env:      "",
+			expected: DefaultSeverityMap,
+		},
+		{
+			name: "overrides are merged over defaults",
+			env:  `{"null_values": "low", "salary_range": "critical"}`,
+			expected: func() map[models.AnomalyType]string {
+				expected := maps.Clone(DefaultSeverityMap)
+				expected[models.AnomalyTypeNullValues] = models.SeverityLow
+				expected[models.AnomalyTypeSalaryRange] = models.SeverityCritical
+				return expected
+			}(),
+		},
+		{
+			name:     "unknown severity falls back to defaults",
+			env:      `{"null_values": "urgent"}`,
+			expected: DefaultSeverityMap,
+		},
+		{
+			name:     "malformed JSON falls back to defaults",
+			env:      `null_values=low`,
+			expected: DefaultSeverityMap,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SEVERITY_MAP", tt.env)
+
+			assert.Equal(t, tt.expected, NewDetectionConfig().SeverityMap)
+		})
+	}
+}
+
+func TestNewDetectionConfigSeverityWeights(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected map[string]float64
+	}{
+		{
+			name:     "unset uses defaults",
+			env:      "",
+			expected: DefaultSeverityWeights,
+		},
+		{
+			name: "overrides are merged over defaults",
+			env:  `{"high": 8, "low": 0}`,
+			expected: func() map[string]float64 {
+				expected := maps.Clone(DefaultSeverityWeights)
+				expected[models.SeverityHigh] = 8
+				expected[models.SeverityLow] = 0
+				return expected
+			}(),
+		},
+		{
+			name:     "unknown severity falls back to defaults",
+			env:      `{"urgent": 20}`,
+			expected: DefaultSeverityWeights,
+		},
+		{
+			name:     "negative weight falls back to defaults",
+			env:      `{"low": -1}`,
+			expected: DefaultSeverityWeights,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SEVERITY_WEIGHTS", tt.env)
+
+			assert.Equal(t, tt.expected, NewDetectionConfig().SeverityWeights)
+		})
+	}
+}
+
+func TestNewDetectionConfigWorkers(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected int
+	}{
+		{"unset uses one worker per CPU", "", runtime.NumCPU()},
+		{"custom count", "3", 3},
+		{"zero falls back to default", "0", runtime.NumCPU()},
+		{"not a number falls back to default", "many", runtime.NumCPU()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DETECTION_WORKERS", tt.env)
+
+			assert.Equal(t, tt.expected, NewDetectionConfig().Workers)
+		})
+	}
+}
+
+func TestNewDetectionConfigMultivariateThreshold(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected float64
+	}{
+		{"unset uses default", "", DefaultMultivariateThreshold},
+		{"custom threshold", "4.5", 4.5},
+		{"negative falls back to default", "-1", DefaultMultivariateThreshold},
+		{"not a number falls back to default", "far", DefaultMultivariateThreshold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MULTIVARIATE_THRESHOLD", tt.env)
+
+			assert.Equal(t, tt.expected, NewDetectionConfig().MultivariateThreshold)
+		})
+	}
+}
+
+func TestNewDetectionConfigStaleAge(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected time.Duration
+	}{
+		{"unset uses default", "", DefaultStaleAge},
+		{"custom age", "720h", 30 * 24 * time.Hour},
+		{"zero falls back to default", "0s", DefaultStaleAge},
+		{"days are not a Go duration", "90d", DefaultStaleAge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STALE_POSTING_AGE", tt.env)
+
+			assert.Equal(t, tt.expected, NewDetectionConfig().StaleAge)
+		})
+	}
+}
+
+func TestNewDetectionConfigSalaryMultipliers(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected map[string]float64
+	}{
+		{"unset uses defaults", "", DefaultSalaryMultipliers},
+		{
+			name:     "overrides are merged over the defaults",
+			env:      `{"Hourly": 2000, "biweekly": 26}`,
+			expected: map[string]float64{"hourly": 2000, "daily": 260, "weekly": 52, "biweekly": 26, "monthly": 12, "yearly": 1},
+		},
+		{"zero multiplier falls back to defaults", `{"hourly": 0}`, DefaultSalaryMultipliers},
+		{"malformed JSON falls back to defaults", `hourly=2000`, DefaultSalaryMultipliers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SALARY_MULTIPLIERS", tt.env)
+
+			assert.Equal(t, tt.expected, NewDetectionConfig().SalaryMultipliers)
+		})
+	}
+	assert.Equal(t, 2080.0, DefaultSalaryMultipliers["hourly"], "a config must not change the defaults")
+}
+
+func TestSeverityFor(t *testing.T) {
+	cfg := DefaultDetectionConfig()
+	cfg.SeverityMap[models.AnomalyTypeNullValues] = models.SeverityLow
+
+	assert.Equal(t, models.SeverityLow, cfg.SeverityFor(models.AnomalyTypeNullValues))
+	assert.Equal(t, models.SeverityMedium, cfg.SeverityFor(models.AnomalyTypeIQR))
+	assert.Equal(t, models.SeverityMedium, cfg.SeverityFor(models.AnomalyType("unmapped")))
+	assert.Equal(t, models.SeverityMedium, DefaultSeverityMap[models.AnomalyTypeNullValues], "changing a config must not change the defaults")
+
+	// A config built without a map still falls back to medium
+	assert.Equal(t, models.SeverityMedium, (&DetectionConfig{}).SeverityFor(models.AnomalyTypeNullValues))
+}
+
+func TestDetectionConfigJSON(t *testing.T) {
+	cfg := DefaultDetectionConfig()
+	cfg.RequiredFields = []string{"company_name"}
+	cfg.Workers = 4
+
+	body, err := json.Marshal(cfg)
+	assert.NoError(t, err)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, "720h0m0s", decoded["trend_window"])
+	assert.Equal(t, "2160h0m0s", decoded["stale_age"])
+	assert.Equal(t, DefaultStdDevThreshold, decoded["stddev_threshold"])
+	assert.Equal(t, []interface{}{"company_name"}, decoded["required_fields"])
+	assert.Equal(t, float64(4), decoded["workers"])
+	assert.NotContains(t, decoded, "TrendWindow")
+	assert.NotContains(t, decoded, "StaleAge")
+}
+
+func TestDetectionConfigClone(t *testing.T) {
+	cfg := DefaultDetectionConfig()
+	clone := cfg.Clone()
+	clone.RequiredFields[0] = "state"
+	clone.SeverityMap[models.AnomalyTypeNullValues] = models.SeverityCritical
+	clone.SalaryMultipliers["hourly"] = 2000
+
+	assert.Equal(t, DefaultRequiredFields, cfg.RequiredFields)
+	assert.Equal(t, 2080.0, cfg.SalaryMultipliers["hourly"])
+	assert.Equal(t, models.SeverityMedium, cfg.SeverityMap[models.AnomalyTypeNullValues])
+}
+
+func TestNewDetectionConfigDisabledDetectors(t *testing.T) {
+	t.Run("unset keeps every detector enabled", func(t *testing.T) {
+		t.Setenv("DISABLED_DETECTORS", "")
+
+		cfg := NewDetectionConfig()
+		for anomalyType := range DefaultSeverityMap {
+			assert.True(t, cfg.DetectorEnabled(anomalyType), anomalyType)
+		}
+	})
+
+	t.Run("listed detectors are disabled", func(t *testing.T) {
+		t.Setenv("DISABLED_DETECTORS", "null_values, standard_deviation")
+
+		cfg := NewDetectionConfig()
+		assert.False(t, cfg.EnableNullCheck)
+		assert.False(t, cfg.EnableDeviation)
+		assert.True(t, cfg.EnableSalaryRange)
+		assert.True(t, cfg.EnableIQR)
+		assert.Equal(t, []models.AnomalyType{models.AnomalyTypeNullValues, models.AnomalyTypeDeviation}, cfg.disabledDetectors())
+	})
+
+	t.Run("unknown detector leaves every detector enabled", func(t *testing.T) {
+		t.Setenv("DISABLED_DETECTORS", "null_values,max_salary")
+
+		cfg := NewDetectionConfig()
+		assert.True(t, cfg.EnableNullCheck)
+		assert.Empty(t, cfg.disabledDetectors())
+	})
+}
+
+func TestDetectorEnabledWithoutToggle(t *testing.T) {
+	cfg := &DetectionConfig{}
+
+	assert.False(t, cfg.DetectorEnabled(models.AnomalyTypeNullValues))
+	assert.True(t, cfg.DetectorEnabled(models.AnomalyTypeMaxSalary), "rule types are always enabled")
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+// DefaultMaxLineSize is the default largest JSONL line, in bytes, accepted during ingestion
+const DefaultMaxLineSize = 4 * 1024 * 1024
+
+// DefaultMaxFailureRatio is the default largest share of ingested lines that may
+// be skipped because of errors before startup is aborted
+const DefaultMaxFailureRatio = 0.1
+
+// IngestConfig holds file ingestion configuration
+type IngestConfig struct {
+	MaxLineSize     int
+	MaxFailureRatio float64
+}
+
+// NewIngestConfig loads ingestion configuration from environment variables,
+// falling back to defaults for missing or invalid values
+func NewIngestConfig() *IngestConfig {
+	config := &IngestConfig{
+		MaxLineSize:     DefaultMaxLineSize,
+		MaxFailureRatio: DefaultMaxFailureRatio,
+	}
+
+	if raw, ok := lookupEnv("INGEST_MAX_LINE_SIZE"); ok {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size <= 0 {
+			slog.Warn("invalid INGEST_MAX_LINE_SIZE, using default", "value", raw, "default", DefaultMaxLineSize)
+		} else {
+			config.MaxLineSize = size
+		}
+	}
+
+	if raw, ok := lookupEnv("INGEST_MAX_FAILURE_RATIO"); ok {
+		ratio, err := strconv.ParseFloat(raw, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			slog.Warn("invalid INGEST_MAX_FAILURE_RATIO, using default", "value", raw, "default", DefaultMaxFailureRatio)
+		} else {
+			config.MaxFailureRatio = ratio
+		}
+	}
+
+	return config
+}
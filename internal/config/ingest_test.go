@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIngestConfigMaxFailureRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected float64
+	}{
+		{name: "valid ratio", env: "0.25", expected: 0.25},
+		{name: "zero tolerates no failures", env: "0", expected: 0},
+		{name: "one never aborts", env: "1", expected: 1},
+		{name: "above one falls back to default", env: "1.5", expected: DefaultMaxFailureRatio},
+		{name: "negative falls back to default", env: "-0.1", expected: DefaultMaxFailureRatio},
+		{name: "not a number falls back to default", env: "ten percent", expected: DefaultMaxFailureRatio},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INGEST_MAX_FAILURE_RATIO", tt.env)
+
+			assert.Equal(t, tt.expected, NewIngestConfig().MaxFailureRatio)
+		})
+	}
+}
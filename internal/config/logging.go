@@ -0,0 +1,42 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ainesh01/anomaly_detection/internal/requestid"
+)
+
+// DefaultLogLevel is the lowest level logged when LOG_LEVEL is not set
+const DefaultLogLevel = slog.LevelInfo
+
+// LogConfig holds logging configuration
+type LogConfig struct {
+	Level slog.Level
+}
+
+// NewLogConfig loads logging configuration from environment variables.
+// LOG_LEVEL accepts debug, info, warn, or error.
+func NewLogConfig() *LogConfig {
+	config := &LogConfig{
+		Level: DefaultLogLevel,
+	}
+
+	if raw, ok := lookupEnv("LOG_LEVEL"); ok {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(raw))); err != nil {
+			slog.Warn("invalid LOG_LEVEL, using default", "value", raw, "default", DefaultLogLevel)
+		} else {
+			config.Level = level
+		}
+	}
+
+	return config
+}
+
+// NewLogger creates a structured JSON logger writing to stderr at the configured level.
+// Records logged with a request context include that request's ID.
+func (c *LogConfig) NewLogger() *slog.Logger {
+	return slog.New(requestid.NewLogHandler(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: c.Level})))
+}
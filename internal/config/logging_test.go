@@ -0,0 +1,30 @@
+package config
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLogConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected slog.Level
+	}{
+		{name: "unset uses default", env: "", expected: DefaultLogLevel},
+		{name: "debug", env: "debug", expected: slog.LevelDebug},
+		{name: "case insensitive", env: "WARN", expected: slog.LevelWarn},
+		{name: "error", env: "error", expected: slog.LevelError},
+		{name: "invalid falls back to default", env: "chatty", expected: DefaultLogLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOG_LEVEL", tt.env)
+
+			assert.Equal(t, tt.expected, NewLogConfig().Level)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// DefaultDetectionInterval is how often scheduled detection runs when enabled
+const DefaultDetectionInterval = time.Hour
+
+// SchedulerConfig holds the configuration of the background detection scheduler
+type SchedulerConfig struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
+// NewSchedulerConfig loads scheduler configuration from environment variables,
+// falling back to defaults for missing or invalid values. Scheduled detection
+// is disabled unless DETECTION_SCHEDULE_ENABLED is set to true.
+func NewSchedulerConfig() *SchedulerConfig {
+	config := &SchedulerConfig{
+		Interval: DefaultDetectionInterval,
+	}
+
+	if raw, ok := lookupEnv("DETECTION_SCHEDULE_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			slog.Warn("invalid DETECTION_SCHEDULE_ENABLED, scheduled detection is disabled", "value", raw)
+		} else {
+			config.Enabled = enabled
+		}
+	}
+
+	if raw, ok := lookupEnv("DETECTION_SCHEDULE_INTERVAL"); ok {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval <= 0 {
+			slog.Warn("invalid DETECTION_SCHEDULE_INTERVAL, using default", "value", raw, "default", DefaultDetectionInterval)
+		} else {
+			config.Interval = interval
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSchedulerConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  string
+		interval string
+		expected SchedulerConfig
+	}{
+		{
+			name:     "unset is disabled with the default interval",
+			expected: SchedulerConfig{Enabled: false, Interval: DefaultDetectionInterval},
+		},
+		{
+			name:     "enabled with custom interval",
+			enabled:  "true",
+			interval: "15m",
+			expected: SchedulerConfig{Enabled: true, Interval: 15 * time.Minute},
+		},
+		{
+			name:     "invalid values fall back to defaults",
+			enabled:  "sometimes",
+			interval: "-1h",
+			expected: SchedulerConfig{Enabled: false, Interval: DefaultDetectionInterval},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DETECTION_SCHEDULE_ENABLED", tt.enabled)
+			t.Setenv("DETECTION_SCHEDULE_INTERVAL", tt.interval)
+
+			assert.Equal(t, tt.expected, *NewSchedulerConfig())
+		})
+	}
+}
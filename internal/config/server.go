@@ -3,11 +3,44 @@ package config
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCompressMinSize is the smallest response, in bytes, that is gzip-compressed by default
+const DefaultCompressMinSize = 1 << 10 // 1 KiB
+
+// DefaultMaxDecompressedBodySize is the largest gzip request body, after decompression, accepted by default
+const DefaultMaxDecompressedBodySize = 32 << 20 // 32 MiB
+
+// Defaults for the HTTP server limits
+const (
+	DefaultMaxBodySize       = 10 << 20 // 10 MiB
+	DefaultMaxHeaderBytes    = 64 << 10 // 64 KiB
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 5 * time.Minute // Detection over every job runs within a single request
+	DefaultIdleTimeout       = 2 * time.Minute
+	DefaultShutdownTimeout   = 5 * time.Second
 )
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Port int
+	Port                    int
+	MaxDecompressedBodySize int64 // Largest gzip request body accepted once decompressed, in bytes
+	MaxBodySize             int64 // Largest request body accepted as sent, in bytes
+	MaxHeaderBytes          int   // Largest request header block accepted, in bytes
+
+	ReadHeaderTimeout time.Duration // Time allowed to read request headers
+	ReadTimeout       time.Duration // Time allowed to read a whole request, including the body
+	WriteTimeout      time.Duration // Time allowed from the end of the request headers to the end of the response
+	IdleTimeout       time.Duration // How long keep-alive connections wait for the next request
+	ShutdownTimeout   time.Duration // How long in-flight requests get to finish on shutdown
+
+	APIKeys []string // Keys accepted on /api requests; empty disables authentication
+
+	CompressResponses bool // Whether /api responses are gzip-compressed for clients that accept it
+	CompressMinSize   int  // Smallest response compressed, in bytes
 }
 
 // LoadServerConfig loads configuration from environment variables
@@ -17,9 +50,85 @@ func LoadServerConfig() (*ServerConfig, error) {
 		return nil, fmt.Errorf("invalid SERVER_PORT: %v", err)
 	}
 
+	maxDecompressedBodySize, err := positiveInt64Env("MAX_DECOMPRESSED_BODY_SIZE", DefaultMaxDecompressedBodySize)
+	if err != nil {
+		return nil, err
+	}
+	maxBodySize, err := positiveInt64Env("MAX_BODY_SIZE", DefaultMaxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	maxHeaderBytes, err := positiveInt64Env("SERVER_MAX_HEADER_BYTES", DefaultMaxHeaderBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	compressResponses, err := strconv.ParseBool(getEnv("RESPONSE_COMPRESSION", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RESPONSE_COMPRESSION: must be true or false")
+	}
+	compressMinSize, err := positiveInt64Env("RESPONSE_COMPRESSION_MIN_SIZE", DefaultCompressMinSize)
+	if err != nil {
+		return nil, err
+	}
+
 	serverConfig := &ServerConfig{
-		Port: serverPort,
+		Port:                    serverPort,
+		MaxDecompressedBodySize: maxDecompressedBodySize,
+		MaxBodySize:             maxBodySize,
+		MaxHeaderBytes:          int(maxHeaderBytes),
+		APIKeys:                 parseAPIKeys(getEnv("API_KEYS", "")),
+		CompressResponses:       compressResponses,
+		CompressMinSize:         int(compressMinSize),
+	}
+
+	timeouts := []struct {
+		key    string
+		target *time.Duration
+		value  time.Duration
+	}{
+		{"SERVER_READ_HEADER_TIMEOUT", &serverConfig.ReadHeaderTimeout, DefaultReadHeaderTimeout},
+		{"SERVER_READ_TIMEOUT", &serverConfig.ReadTimeout, DefaultReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", &serverConfig.WriteTimeout, DefaultWriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", &serverConfig.IdleTimeout, DefaultIdleTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", &serverConfig.ShutdownTimeout, DefaultShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		if *timeout.target, err = positiveDurationEnv(timeout.key, timeout.value); err != nil {
+			return nil, err
+		}
 	}
 
 	return serverConfig, nil
 }
+
+// parseAPIKeys splits a comma-separated list of API keys, dropping blank entries
+func parseAPIKeys(raw string) []string {
+	var keys []string
+	for _, key := range strings.Split(raw, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// positiveInt64Env reads a positive integer from the environment variable key,
+// returning defaultValue when it is not set
+func positiveInt64Env(key string, defaultValue int64) (int64, error) {
+	value, err := strconv.ParseInt(getEnv(key, strconv.FormatInt(defaultValue, 10)), 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be a positive number of bytes", key)
+	}
+	return value, nil
+}
+
+// positiveDurationEnv reads a positive duration such as "30s" from the environment
+// variable key, returning defaultValue when it is not set
+func positiveDurationEnv(key string, defaultValue time.Duration) (time.Duration, error) {
+	value, err := time.ParseDuration(getEnv(key, defaultValue.String()))
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be a positive duration such as 30s", key)
+	}
+	return value, nil
+}
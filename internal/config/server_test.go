@@ -0,0 +1,87 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadServerConfigLimits(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := LoadServerConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(DefaultMaxBodySize), cfg.MaxBodySize)
+		assert.Equal(t, DefaultMaxHeaderBytes, cfg.MaxHeaderBytes)
+		assert.Equal(t, DefaultReadHeaderTimeout, cfg.ReadHeaderTimeout)
+		assert.Equal(t, DefaultReadTimeout, cfg.ReadTimeout)
+		assert.Equal(t, DefaultWriteTimeout, cfg.WriteTimeout)
+		assert.Equal(t, DefaultIdleTimeout, cfg.IdleTimeout)
+		assert.Equal(t, DefaultShutdownTimeout, cfg.ShutdownTimeout)
+		assert.True(t, cfg.CompressResponses)
+		assert.Equal(t, DefaultCompressMinSize, cfg.CompressMinSize)
+	})
+
+	t.Run("overridden from env", func(t *testing.T) {
+		t.Setenv("MAX_BODY_SIZE", "2048")
+		t.Setenv("SERVER_MAX_HEADER_BYTES", "4096")
+		t.Setenv("SERVER_READ_TIMEOUT", "5s")
+		t.Setenv("SERVER_WRITE_TIMEOUT", "1m")
+		t.Setenv("SERVER_SHUTDOWN_TIMEOUT", "20s")
+		t.Setenv("RESPONSE_COMPRESSION", "false")
+		t.Setenv("RESPONSE_COMPRESSION_MIN_SIZE", "512")
+
+		cfg, err := LoadServerConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2048), cfg.MaxBodySize)
+		assert.Equal(t, 4096, cfg.MaxHeaderBytes)
+		assert.Equal(t, 5*time.Second, cfg.ReadTimeout)
+		assert.Equal(t, time.Minute, cfg.WriteTimeout)
+		assert.Equal(t, 20*time.Second, cfg.ShutdownTimeout)
+		assert.False(t, cfg.CompressResponses)
+		assert.Equal(t, 512, cfg.CompressMinSize)
+	})
+
+	invalid := []struct {
+		key   string
+		value string
+	}{
+		{"MAX_BODY_SIZE", "0"},
+		{"MAX_BODY_SIZE", "ten"},
+		{"SERVER_MAX_HEADER_BYTES", "-1"},
+		{"SERVER_READ_TIMEOUT", "30"},
+		{"SERVER_IDLE_TIMEOUT", "-5s"},
+		{"RESPONSE_COMPRESSION", "sometimes"},
+		{"RESPONSE_COMPRESSION_MIN_SIZE", "0"},
+	}
+	for _, tt := range invalid {
+		t.Run("invalid "+tt.key+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+
+			_, err := LoadServerConfig()
+			assert.ErrorContains(t, err, tt.key)
+		})
+	}
+}
+
+func TestLoadServerConfigAPIKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected []string
+	}{
+		{name: "unset disables auth", env: "", expected: nil},
+		{name: "single key", env: "secret", expected: []string{"secret"}},
+		{name: "blank entries dropped", env: " one, ,two ,", expected: []string{"one", "two"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("API_KEYS", tt.env)
+
+			cfg, err := LoadServerConfig()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg.APIKeys)
+		})
+	}
+}
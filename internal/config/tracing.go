@@ -0,0 +1,36 @@
+package config
+
+// DefaultServiceName is the service name spans are reported under by default
+const DefaultServiceName = "anomaly_detection"
+
+// TracingConfig holds OpenTelemetry trace export configuration
+type TracingConfig struct {
+	Endpoint    string // OTLP/HTTP endpoint spans are exported to; empty disables tracing
+	ServiceName string
+}
+
+// NewTracingConfig loads tracing configuration from the standard OpenTelemetry
+// environment variables. Tracing is disabled unless
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT is set; the
+// exporter reads those and the other OTEL_EXPORTER_OTLP_* variables itself.
+func NewTracingConfig() *TracingConfig {
+	endpoint, ok := lookupEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if !ok {
+		endpoint = getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	}
+
+	serviceName, ok := lookupEnv("OTEL_SERVICE_NAME")
+	if !ok {
+		serviceName = DefaultServiceName
+	}
+
+	return &TracingConfig{
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+	}
+}
+
+// Enabled reports whether spans should be exported
+func (c *TracingConfig) Enabled() bool {
+	return c.Endpoint != ""
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTracingConfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             map[string]string
+		wantEndpoint    string
+		wantServiceName string
+	}{
+		{"unset disables tracing", nil, "", DefaultServiceName},
+		{
+			name:            "shared endpoint",
+			env:             map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"},
+			wantEndpoint:    "http://collector:4318",
+			wantServiceName: DefaultServiceName,
+		},
+		{
+			name: "traces endpoint takes precedence",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://collector:4318",
+				"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://traces:4318/v1/traces",
+				"OTEL_SERVICE_NAME":                  "anomaly-api",
+			},
+			wantEndpoint:    "http://traces:4318/v1/traces",
+			wantServiceName: "anomaly-api",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_SERVICE_NAME"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			cfg := NewTracingConfig()
+			assert.Equal(t, tt.wantEndpoint, cfg.Endpoint)
+			assert.Equal(t, tt.wantServiceName, cfg.ServiceName)
+			assert.Equal(t, tt.wantEndpoint != "", cfg.Enabled())
+		})
+	}
+}
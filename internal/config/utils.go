@@ -9,3 +9,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// lookupEnv returns the value of an environment variable and whether it was set to a non-empty value
+func lookupEnv(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return "", false
+	}
+	return value, true
+}
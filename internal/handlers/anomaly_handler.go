@@ -1,72 +1,567 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ainesh01/anomaly_detection/internal/models"
 	"github.com/ainesh01/anomaly_detection/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// DefaultStatsDays is how many days of daily anomaly counts are returned by default
+	DefaultStatsDays = 30
+	// MaxStatsDays is the longest daily anomaly count series that can be requested
+	MaxStatsDays = 365
+	// MaxRuleSamples is the most sample jobs a single rule evaluation request may carry
+	MaxRuleSamples = 1000
+)
+
 // AnomalyHandler handles HTTP requests for anomalies
 type AnomalyHandler struct {
 	anomalyService services.AnomalyServiceInterface
+	jobDataService services.JobDataServiceInterface
 }
 
 // NewAnomalyHandler creates a new AnomalyHandler
-func NewAnomalyHandler(anomalyService services.AnomalyServiceInterface) *AnomalyHandler {
+func NewAnomalyHandler(anomalyService services.AnomalyServiceInterface, jobDataService services.JobDataServiceInterface) *AnomalyHandler {
 	return &AnomalyHandler{
 		anomalyService: anomalyService,
+		jobDataService: jobDataService,
 	}
 }
 
-// GetAnomaliesByJobID handles GET requests for anomalies by job ID
+// GetAnomaliesByJobID handles GET requests for anomalies by job ID.
+// Resolved anomalies are only returned with ?include_resolved=true.
 func (h *AnomalyHandler) GetAnomaliesByJobID(c *gin.Context) {
 	jobID := c.Param("job_id")
-	anomalies, err := h.anomalyService.GetAnomaliesByJobID(jobID)
+	includeResolved, err := parseIncludeResolved(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	anomalies, err := h.anomalyService.GetAnomaliesByJobID(c.Request.Context(), jobID, includeResolved)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, anomalies)
 }
 
-// GetAllAnomalies handles GET requests for all anomalies
+// GetAnomalyByID handles GET requests for a single anomaly by its ID
+func (h *AnomalyHandler) GetAnomalyByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondBadRequest(c, "invalid anomaly ID")
+		return
+	}
+
+	anomaly, err := h.anomalyService.GetAnomalyByID(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, anomaly)
+}
+
+// ResolveAnomaly handles PATCH requests that dismiss an anomaly, with an optional
+// {"note": "..."} body explaining why. The resolved anomaly is returned.
+func (h *AnomalyHandler) ResolveAnomaly(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondBadRequest(c, "invalid anomaly ID")
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.anomalyService.ResolveAnomaly(c.Request.Context(), id, req.Note); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	anomaly, err := h.anomalyService.GetAnomalyByID(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, anomaly)
+}
+
+// AcknowledgeAnomaly handles PATCH requests to assign an anomaly to someone and
+// mark it acknowledged. The assignee is taken from the body, or from the X-User
+// header when the body leaves it out.
+func (h *AnomalyHandler) AcknowledgeAnomaly(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondBadRequest(c, "invalid anomaly ID")
+		return
+	}
+
+	var req struct {
+		Assignee string `json:"assignee"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondBadRequest(c, err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Assignee) == "" {
+		req.Assignee = requestUser(c)
+	}
+
+	if err := h.anomalyService.AcknowledgeAnomaly(c.Request.Context(), id, req.Assignee); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	anomaly, err := h.anomalyService.GetAnomalyByID(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, anomaly)
+}
+
+// AddAnomalyNote handles POST requests that attach a {"body": "..."} note to an
+// anomaly. The author is taken from the body, or from the X-User header when the
+// body leaves it out. The created note is returned.
+func (h *AnomalyHandler) AddAnomalyNote(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondBadRequest(c, "invalid anomaly ID")
+		return
+	}
+
+	var req struct {
+		Author string `json:"author"`
+		Body   string `json:"body"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Author) == "" {
+		req.Author = requestUser(c)
+	}
+
+	note, err := h.anomalyService.AddAnomalyNote(c.Request.Context(), id, req.Author, req.Body)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, note)
+}
+
+// GetAnomalyNotes handles GET requests for the notes on an anomaly, oldest first
+func (h *AnomalyHandler) GetAnomalyNotes(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondBadRequest(c, "invalid anomaly ID")
+		return
+	}
+
+	notes, err := h.anomalyService.GetAnomalyNotes(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	// Notes are not paginated, so the page is always the whole list
+	respondList(c, notes, len(notes), len(notes), 0)
+}
+
+// GetAllAnomalies handles GET requests for a page of anomalies.
+// Resolved anomalies are only returned with ?include_resolved=true, and
+// ?assignee= and ?acknowledged=true|false narrow the list further.
 func (h *AnomalyHandler) GetAllAnomalies(c *gin.Context) {
-	anomalies, err := h.anomalyService.GetAllAnomalies()
+	limit, offset, err := parsePagination(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondBadRequest(c, err.Error())
 		return
 	}
-	if anomalies == nil {
-		anomalies = []models.Anomaly{} // Ensure we return an empty array instead of null
+	sort, err := parseSort(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
 	}
-	c.JSON(http.StatusOK, anomalies)
+
+	filter, err := parseAnomalyFilter(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	anomalies, total, err := h.anomalyService.GetAllAnomaliesPaged(c.Request.Context(), limit, offset, sort, filter)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	respondList(c, anomalies, total, limit, offset)
+}
+
+// GetDetailedAnomalies handles GET requests for a page of anomalies, each with the
+// company name, title, city, and maximum salary of its job. It accepts the same
+// pagination, sort, and filter parameters as GetAllAnomalies.
+func (h *AnomalyHandler) GetDetailedAnomalies(c *gin.Context) {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+	sort, err := parseSort(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+	filter, err := parseAnomalyFilter(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	anomalies, total, err := h.anomalyService.GetDetailedAnomaliesPaged(c.Request.Context(), limit, offset, sort, filter)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	respondList(c, anomalies, total, limit, offset)
+}
+
+// parseAnomalyFilter reads the include_resolved, assignee, and acknowledged
+// query parameters of an anomaly listing
+func parseAnomalyFilter(c *gin.Context) (services.AnomalyFilter, error) {
+	includeResolved, err := parseIncludeResolved(c)
+	if err != nil {
+		return services.AnomalyFilter{}, err
+	}
+
+	filter := services.AnomalyFilter{IncludeResolved: includeResolved, Assignee: c.Query("assignee")}
+	if raw, ok := c.GetQuery("acknowledged"); ok {
+		acknowledged, err := strconv.ParseBool(raw)
+		if err != nil {
+			return services.AnomalyFilter{}, fmt.Errorf("invalid acknowledged %q: must be true or false", raw)
+		}
+		filter.Acknowledged = &acknowledged
+	}
+	return filter, nil
+}
+
+// DeleteAnomalies handles DELETE requests that remove every anomaly matching the
+// type, job_id, created_before, assignee, and acknowledged filters in the body,
+// returning how many were deleted. At least one of them is required. As when
+// listing, resolved anomalies are kept, even when they match, unless
+// include_resolved is true.
+func (h *AnomalyHandler) DeleteAnomalies(c *gin.Context) {
+	var request struct {
+		Type            models.AnomalyType `json:"type"`
+		JobID           string             `json:"job_id"`
+		CreatedBefore   *time.Time         `json:"created_before"`
+		Assignee        string             `json:"assignee"`
+		Acknowledged    *bool              `json:"acknowledged"`
+		IncludeResolved bool               `json:"include_resolved"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	filter := services.AnomalyFilter{
+		Type:            request.Type,
+		JobID:           request.JobID,
+		Assignee:        request.Assignee,
+		Acknowledged:    request.Acknowledged,
+		IncludeResolved: request.IncludeResolved,
+	}
+	if request.CreatedBefore != nil {
+		filter.CreatedBefore = *request.CreatedBefore
+	}
+	deleted, err := h.anomalyService.DeleteAnomalies(c.Request.Context(), filter)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+// GetAnomaliesByCompany handles GET requests for a page of per-company anomaly
+// counts, ordered by count (highest first) unless ?sort= says otherwise.
+// Resolved anomalies are only counted with ?include_resolved=true.
+func (h *AnomalyHandler) GetAnomaliesByCompany(c *gin.Context) {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+	sort, err := parseSort(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+	includeResolved, err := parseIncludeResolved(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	companies, total, err := h.anomalyService.GetAnomaliesByCompany(c.Request.Context(), limit, offset, sort, includeResolved)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	respondList(c, companies, total, limit, offset)
+}
+
+// GetJobRiskScore handles GET requests for the severity-weighted risk score of a job
+func (h *AnomalyHandler) GetJobRiskScore(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	score, err := h.anomalyService.GetJobRiskScore(c.Request.Context(), jobID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job_id": jobID, "risk_score": score})
+}
+
+// GetJobsByRiskScore handles GET requests for a page of jobs with open
+// anomalies, ordered by risk score, highest first
+func (h *AnomalyHandler) GetJobsByRiskScore(c *gin.Context) {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	jobs, total, err := h.anomalyService.GetJobsByRiskScore(c.Request.Context(), limit, offset)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	respondList(c, jobs, total, limit, offset)
+}
+
+// GetDetectionRuns handles GET requests for a page of recorded detection runs, newest first
+func (h *AnomalyHandler) GetDetectionRuns(c *gin.Context) {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	runs, total, err := h.anomalyService.GetDetectionRuns(c.Request.Context(), limit, offset)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	respondList(c, runs, total, limit, offset)
+}
+
+// GetDetectionConfig handles GET requests for the detection settings in effect
+func (h *AnomalyHandler) GetDetectionConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.anomalyService.DetectionConfig())
+}
+
+// GetAnomalyStats handles GET requests for aggregate anomaly counts.
+// The days query parameter sets how many days the daily series covers.
+func (h *AnomalyHandler) GetAnomalyStats(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(DefaultStatsDays)))
+	if err != nil || days < 1 || days > MaxStatsDays {
+		respondBadRequest(c, fmt.Sprintf("days must be an integer between 1 and %d", MaxStatsDays))
+		return
+	}
+
+	stats, err := h.anomalyService.GetAnomalyStats(c.Request.Context(), time.Duration(days)*24*time.Hour)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetAnomalyTypes handles GET requests for the anomaly types present in the
+// data, with the number of anomalies of each
+func (h *AnomalyHandler) GetAnomalyTypes(c *gin.Context) {
+	counts, err := h.anomalyService.GetAnomalyTypeCounts(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, counts)
 }
 
 // DetectAnomalies handles POST request to detect anomalies for a job
 func (h *AnomalyHandler) DetectAnomalies(c *gin.Context) {
 	var jobData models.JobData
 	if err := c.ShouldBindJSON(&jobData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err.Error())
 		return
 	}
 
-	anomalies, err := h.anomalyService.DetectAnomalies(&jobData)
+	anomalies, err := h.anomalyService.DetectAnomalies(c.Request.Context(), &jobData)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, anomalies)
 }
 
-// DetectAnomaliesForAllJobs handles POST request to detect anomalies for all jobs
+// DetectAnomaliesForJob handles POST requests to re-run detection for a single stored job
+func (h *AnomalyHandler) DetectAnomaliesForJob(c *gin.Context) {
+	jobID := c.Param("job_id")
+	job, err := h.jobDataService.GetJobData(c.Request.Context(), jobID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	anomalies, err := h.anomalyService.DetectAnomalies(c.Request.Context(), job)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	if anomalies == nil {
+		anomalies = []models.Anomaly{} // Ensure we return an empty array instead of null
+	}
+
+	c.JSON(http.StatusOK, anomalies)
+}
+
+// EvaluateAnomalyRule handles POST requests that check sample jobs against a rule
+// without storing anything. The body is a single job or an array of jobs.
+func (h *AnomalyHandler) EvaluateAnomalyRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondBadRequest(c, "invalid rule ID")
+		return
+	}
+
+	jobs, err := bindSampleJobs(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	evaluations, err := h.anomalyService.EvaluateRule(c.Request.Context(), id, jobs)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, evaluations)
+}
+
+// bindSampleJobs reads a request body holding either one job or an array of jobs
+func bindSampleJobs(c *gin.Context) ([]models.JobData, error) {
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %w", err)
+	}
+
+	var jobs []models.JobData
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &jobs)
+	} else {
+		var job models.JobData
+		err = json.Unmarshal(trimmed, &job)
+		jobs = append(jobs, job)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid sample jobs: %w", err)
+	}
+
+	if len(jobs) == 0 {
+		return nil, errors.New("at least one sample job is required")
+	}
+	if len(jobs) > MaxRuleSamples {
+		return nil, fmt.Errorf("at most %d sample jobs can be evaluated at once", MaxRuleSamples)
+	}
+	return jobs, nil
+}
+
+// DetectAnomaliesForAllJobs handles POST request to detect anomalies for all jobs.
+// With ?dry_run=true nothing is stored and the would-be anomalies are returned.
 func (h *AnomalyHandler) DetectAnomaliesForAllJobs(c *gin.Context) {
-	if err := h.anomalyService.DetectAnomaliesForAllJobs(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		respondBadRequest(c, "dry_run must be true or false")
+		return
+	}
+
+	anomalies, err := h.anomalyService.DetectAnomaliesForAllJobs(c.Request.Context(), dryRun)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	if dryRun {
+		if anomalies == nil {
+			anomalies = []models.Anomaly{} // Ensure we return an empty array instead of null
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Dry run completed for all jobs, no anomalies were stored",
+			"dry_run":   true,
+			"anomalies": anomalies,
+		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Anomaly detection completed for all jobs"})
 }
+
+// DetectAnomaliesSince handles POST request to detect anomalies only for jobs
+// ingested after the RFC 3339 timestamp given in ?since=
+func (h *AnomalyHandler) DetectAnomaliesSince(c *gin.Context) {
+	raw := c.Query("since")
+	if raw == "" {
+		respondBadRequest(c, "since is required")
+		return
+	}
+	since, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		respondBadRequest(c, "since must be an RFC 3339 timestamp")
+		return
+	}
+
+	processed, err := h.anomalyService.DetectAnomaliesSince(c.Request.Context(), since)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Anomaly detection completed for jobs ingested since " + since.Format(time.RFC3339),
+		"jobs_processed": processed,
+	})
+}
+
+// DetectDuplicates handles POST request to flag jobs that are listed more than
+// once with the same company, title, and description
+func (h *AnomalyHandler) DetectDuplicates(c *gin.Context) {
+	anomalies, err := h.anomalyService.DetectDuplicates(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	if anomalies == nil {
+		anomalies = []models.Anomaly{} // Ensure we return an empty array instead of null
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Duplicate detection completed for all jobs",
+		"anomalies": anomalies,
+	})
+}
@@ -0,0 +1,1228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/ainesh01/anomaly_detection/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectAnomaliesForAllJobsDryRun(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name: "persisting run",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DetectAnomaliesForAllJobs", false).Return([]models.Anomaly(nil), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"message":"Anomaly detection completed for all jobs"`},
+		},
+		{
+			name:  "dry run returns would-be anomalies",
+			query: "?dry_run=true",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DetectAnomaliesForAllJobs", true).Return([]models.Anomaly{
+					{JobID: "job1", Type: models.AnomalyTypeNullValues},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"dry_run":true`, `"job_id":"job1"`},
+		},
+		{
+			name:  "dry run with nothing flagged",
+			query: "?dry_run=true",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DetectAnomaliesForAllJobs", true).Return([]models.Anomaly(nil), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"anomalies":[]`},
+		},
+		{
+			name:           "invalid dry_run value",
+			query:          "?dry_run=maybe",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []string{`"code":"` + ErrCodeInvalidRequest + `"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.POST("/detect-all", NewAnomalyHandler(mockService, nil).DetectAnomaliesForAllJobs)
+
+			w := performRequest(router, http.MethodPost, "/detect-all"+tt.query, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDetectAnomaliesSince(t *testing.T) {
+	since := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name:  "valid timestamp",
+			query: "?since=2025-04-01T00:00:00Z",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DetectAnomaliesSince", since).Return(12, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"jobs_processed":12`},
+		},
+		{
+			name:           "missing timestamp",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []string{`"code":"` + ErrCodeInvalidRequest + `"`},
+		},
+		{
+			name:           "invalid timestamp",
+			query:          "?since=yesterday",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []string{"RFC 3339"},
+		},
+		{
+			name:  "service error",
+			query: "?since=2025-04-01T00:00:00Z",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DetectAnomaliesSince", since).Return(0, assert.AnError)
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.POST("/detect-since", NewAnomalyHandler(mockService, nil).DetectAnomaliesSince)
+
+			w := performRequest(router, http.MethodPost, "/detect-since"+tt.query, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDetectDuplicates(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name: "duplicates found",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DetectDuplicates").Return([]models.Anomaly{
+					{JobID: "job-1", Type: models.AnomalyTypeDuplicate, Value: 2},
+					{JobID: "job-2", Type: models.AnomalyTypeDuplicate, Value: 2},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"job_id":"job-1"`, `"job_id":"job-2"`, `"type":"duplicate_listing"`},
+		},
+		{
+			name: "no duplicates",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DetectDuplicates").Return([]models.Anomaly(nil), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"anomalies":[]`},
+		},
+		{
+			name: "service error",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DetectDuplicates").Return([]models.Anomaly(nil), assert.AnError)
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.POST("/detect-duplicates", NewAnomalyHandler(mockService, nil).DetectDuplicates)
+
+			w := performRequest(router, http.MethodPost, "/detect-duplicates", "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetDetectionConfig(t *testing.T) {
+	cfg := config.DefaultDetectionConfig()
+	cfg.StdDevThreshold = 2.5
+	cfg.MinGroupSamples = 10
+	cfg.StatsGroupBy = config.StatsGroupByCity
+	cfg.TrendWindow = 7 * 24 * time.Hour
+	cfg.RequiredFields = []string{"company_name", "job_title"}
+	cfg.SeverityMap[models.AnomalyTypeNullValues] = models.SeverityLow
+	cfg.Workers = 2
+	cfg.EnableMAD = false
+
+	anomalyService := services.NewAnomalyService(nil, nil, cfg, nil, nil)
+	router := gin.New()
+	router.GET("/config/detection", NewAnomalyHandler(anomalyService, nil).GetDetectionConfig)
+
+	w := performRequest(router, http.MethodGet, "/config/detection", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"stddev_threshold": 2.5,
+		"alert_min_severity": "high",
+		"stats_group_by": "city",
+		"min_group_samples": 10,
+		"trend_window": "168h0m0s",
+		"mad_cutoff": 3.5,
+		"multivariate_threshold": 3.5,
+		"stale_age": "2160h0m0s",
+		"required_fields": ["company_name", "job_title"],
+		"salary_multipliers": {"hourly": 2080, "daily": 260, "weekly": 52, "monthly": 12, "yearly": 1},
+		"severity_map": {
+			"null_values": "low",
+			"salary_range": "medium",
+			"standard_deviation": "medium",
+			"iqr_outlier": "medium",
+			"mad_outlier": "medium",
+			"salary_trend": "medium",
+			"geo_outlier": "medium",
+			"duplicate_listing": "medium",
+			"multivariate_outlier": "medium",
+			"stale_posting": "medium"
+		},
+		"severity_weights": {"low": 1, "medium": 2, "high": 5, "critical": 10},
+		"workers": 2,
+		"enable_null_check": true,
+		"enable_salary_range": true,
+		"enable_deviation": true,
+		"enable_iqr": true,
+		"enable_mad": false,
+		"enable_trend": true,
+		"enable_geo_outlier": true,
+		"enable_multivariate": true,
+		"enable_stale": true
+	}`, w.Body.String())
+}
+
+func TestGetAnomalyStats(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+	}{
+		{
+			name: "default window",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyStats", 30*24*time.Hour).Return(&models.AnomalyStats{Total: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "custom window",
+			query: "?days=7",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyStats", 7*24*time.Hour).Return(&models.AnomalyStats{Total: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "window too long",
+			query:          "?days=1000",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "non-numeric window",
+			query:          "?days=week",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			handler := NewAnomalyHandler(mockService, nil)
+			router.GET("/anomalies/stats", handler.GetAnomalyStats)
+			router.GET("/anomalies/:job_id", handler.GetAnomaliesByJobID)
+
+			w := performRequest(router, http.MethodGet, "/anomalies/stats"+tt.query, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAnomalyTypes(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "types with counts",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyTypeCounts").Return(map[string]int{"null_values": 4, "iqr_outlier": 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"iqr_outlier": 2, "null_values": 4}`,
+		},
+		{
+			name: "no anomalies",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyTypeCounts").Return(map[string]int{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{}`,
+		},
+		{
+			name: "service error",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyTypeCounts").Return(nil, assert.AnError)
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			handler := NewAnomalyHandler(mockService, nil)
+			router.GET("/anomalies/types", handler.GetAnomalyTypes)
+			router.GET("/anomalies/:job_id", handler.GetAnomaliesByJobID)
+
+			w := performRequest(router, http.MethodGet, "/anomalies/types", "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAnomalyByID(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+	}{
+		{
+			name: "found",
+			path: "/anomalies/id/42",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyByID", int64(42)).Return(&models.Anomaly{ID: "42", JobID: "job1"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "not found",
+			path: "/anomalies/id/7",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyByID", int64(7)).Return(nil, fmt.Errorf("anomaly with ID 7 %w", services.ErrNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid ID",
+			path:           "/anomalies/id/abc",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "job ID route still matches",
+			path: "/anomalies/job1",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomaliesByJobID", "job1", false).Return([]models.Anomaly{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			handler := NewAnomalyHandler(mockService, nil)
+			router.GET("/anomalies/id/:id", handler.GetAnomalyByID)
+			router.GET("/anomalies/:job_id", handler.GetAnomaliesByJobID)
+
+			w := performRequest(router, http.MethodGet, tt.path, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestResolveAnomaly(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "resolve with note",
+			path: "/anomalies/id/42/resolve",
+			body: `{"note":"Listing fixed upstream"}`,
+			setupMock: func(m *MockAnomalyService) {
+				m.On("ResolveAnomaly", int64(42), "Listing fixed upstream").Return(nil)
+				m.On("GetAnomalyByID", int64(42)).Return(&models.Anomaly{ID: "42", Status: models.AnomalyStatusResolved}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"resolved"`,
+		},
+		{
+			name: "resolve without body",
+			path: "/anomalies/id/42/resolve",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("ResolveAnomaly", int64(42), "").Return(nil)
+				m.On("GetAnomalyByID", int64(42)).Return(&models.Anomaly{ID: "42", Status: models.AnomalyStatusResolved}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"resolved"`,
+		},
+		{
+			name: "missing anomaly",
+			path: "/anomalies/id/7/resolve",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("ResolveAnomaly", int64(7), "").Return(fmt.Errorf("anomaly with ID 7 %w", services.ErrNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"` + ErrCodeNotFound + `"`,
+		},
+		{
+			name:           "invalid ID",
+			path:           "/anomalies/id/abc/resolve",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"` + ErrCodeInvalidRequest + `"`,
+		},
+		{
+			name:           "malformed body",
+			path:           "/anomalies/id/42/resolve",
+			body:           `{"note":`,
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"` + ErrCodeInvalidRequest + `"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.PATCH("/anomalies/id/:id/resolve", NewAnomalyHandler(mockService, nil).ResolveAnomaly)
+
+			w := performRequest(router, http.MethodPatch, tt.path, tt.body)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAcknowledgeAnomaly(t *testing.T) {
+	acknowledgedAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	acknowledged := &models.Anomaly{ID: "42", Assignee: "alice", AcknowledgedAt: &acknowledgedAt}
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		user           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "acknowledge with assignee",
+			path: "/anomalies/id/42/acknowledge",
+			body: `{"assignee":"alice"}`,
+			user: "bob",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("AcknowledgeAnomaly", int64(42), "alice").Return(nil)
+				m.On("GetAnomalyByID", int64(42)).Return(acknowledged, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"acknowledged_at":"2025-04-02T09:00:00Z"`,
+		},
+		{
+			name: "assignee defaults to the requesting user",
+			path: "/anomalies/id/42/acknowledge",
+			user: "alice",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("AcknowledgeAnomaly", int64(42), "alice").Return(nil)
+				m.On("GetAnomalyByID", int64(42)).Return(acknowledged, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"assignee":"alice"`,
+		},
+		{
+			name: "missing assignee",
+			path: "/anomalies/id/42/acknowledge",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("AcknowledgeAnomaly", int64(42), "").Return(fmt.Errorf("%w: assignee is required", services.ErrValidation))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "assignee is required",
+		},
+		{
+			name: "missing anomaly",
+			path: "/anomalies/id/7/acknowledge",
+			body: `{"assignee":"alice"}`,
+			setupMock: func(m *MockAnomalyService) {
+				m.On("AcknowledgeAnomaly", int64(7), "alice").Return(fmt.Errorf("anomaly with ID 7 %w", services.ErrNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"` + ErrCodeNotFound + `"`,
+		},
+		{
+			name:           "invalid ID",
+			path:           "/anomalies/id/abc/acknowledge",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"` + ErrCodeInvalidRequest + `"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.PATCH("/anomalies/id/:id/acknowledge", NewAnomalyHandler(mockService, nil).AcknowledgeAnomaly)
+
+			req := httptest.NewRequest(http.MethodPatch, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.user != "" {
+				req.Header.Set(UserHeader, tt.user)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAddAnomalyNote(t *testing.T) {
+	createdAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	note := &models.AnomalyNote{ID: 3, AnomalyID: 42, Author: "alice", Body: "Salary looks like a typo", CreatedAt: createdAt}
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		user           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "note with author",
+			path: "/anomalies/id/42/notes",
+			body: `{"author":"alice","body":"Salary looks like a typo"}`,
+			user: "bob",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("AddAnomalyNote", int64(42), "alice", "Salary looks like a typo").Return(note, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"id":3,"anomaly_id":42,"author":"alice","body":"Salary looks like a typo","created_at":"2025-04-02T09:00:00Z"`,
+		},
+		{
+			name: "author defaults to the requesting user",
+			path: "/anomalies/id/42/notes",
+			body: `{"body":"Salary looks like a typo"}`,
+			user: "alice",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("AddAnomalyNote", int64(42), "alice", "Salary looks like a typo").Return(note, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"author":"alice"`,
+		},
+		{
+			name: "missing body",
+			path: "/anomalies/id/42/notes",
+			body: `{"author":"alice"}`,
+			setupMock: func(m *MockAnomalyService) {
+				m.On("AddAnomalyNote", int64(42), "alice", "").Return(nil, fmt.Errorf("%w: note body is required", services.ErrValidation))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "note body is required",
+		},
+		{
+			name: "missing anomaly",
+			path: "/anomalies/id/7/notes",
+			body: `{"body":"note"}`,
+			setupMock: func(m *MockAnomalyService) {
+				m.On("AddAnomalyNote", int64(7), "", "note").Return(nil, fmt.Errorf("anomaly with ID 7 %w", services.ErrNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"` + ErrCodeNotFound + `"`,
+		},
+		{
+			name:           "malformed JSON",
+			path:           "/anomalies/id/42/notes",
+			body:           `{"body":`,
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"` + ErrCodeInvalidRequest + `"`,
+		},
+		{
+			name:           "invalid ID",
+			path:           "/anomalies/id/abc/notes",
+			body:           `{"body":"note"}`,
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"` + ErrCodeInvalidRequest + `"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.POST("/anomalies/id/:id/notes", NewAnomalyHandler(mockService, nil).AddAnomalyNote)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.user != "" {
+				req.Header.Set(UserHeader, tt.user)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAnomalyNotes(t *testing.T) {
+	createdAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		path           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name: "anomaly with notes",
+			path: "/anomalies/id/42/notes",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyNotes", int64(42)).Return([]models.AnomalyNote{
+					{ID: 1, AnomalyID: 42, Author: "alice", Body: "Looking into it", CreatedAt: createdAt},
+					{ID: 2, AnomalyID: 42, Author: "bob", Body: "Confirmed with the recruiter", CreatedAt: createdAt},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: []string{
+				`"author":"alice","body":"Looking into it"`,
+				`"author":"bob","body":"Confirmed with the recruiter"`,
+				`"meta":{"total":2,"limit":2,"offset":0}`,
+			},
+		},
+		{
+			name: "anomaly without notes",
+			path: "/anomalies/id/42/notes",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyNotes", int64(42)).Return([]models.AnomalyNote{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"data":[]`},
+		},
+		{
+			name: "missing anomaly",
+			path: "/anomalies/id/7/notes",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomalyNotes", int64(7)).Return([]models.AnomalyNote(nil), fmt.Errorf("anomaly with ID 7 %w", services.ErrNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   []string{`"code":"` + ErrCodeNotFound + `"`},
+		},
+		{
+			name:           "invalid ID",
+			path:           "/anomalies/id/abc/notes",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.GET("/anomalies/id/:id/notes", NewAnomalyHandler(mockService, nil).GetAnomalyNotes)
+
+			w := performRequest(router, http.MethodGet, tt.path, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteAnomalies(t *testing.T) {
+	cutoff := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	acknowledged := true
+
+	tests := []struct {
+		name           string
+		body           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "filtered delete",
+			body: `{"type":"salary_trend","job_id":"job1","created_before":"2025-01-01T00:00:00Z","include_resolved":true}`,
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DeleteAnomalies", services.AnomalyFilter{
+					Type:            models.AnomalyTypeTrend,
+					JobID:           "job1",
+					CreatedBefore:   cutoff,
+					IncludeResolved: true,
+				}).Return(4, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"deleted":4}`,
+		},
+		{
+			name: "assignee and acknowledged",
+			body: `{"assignee":"alice","acknowledged":true}`,
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DeleteAnomalies", services.AnomalyFilter{Assignee: "alice", Acknowledged: &acknowledged}).Return(2, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"deleted":2}`,
+		},
+		{
+			name: "empty filter",
+			body: `{}`,
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DeleteAnomalies", services.AnomalyFilter{}).
+					Return(0, fmt.Errorf("%w: at least one of type, job_id, created_before, assignee or acknowledged is required", services.ErrValidation))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "at least one of type, job_id, created_before, assignee or acknowledged is required",
+		},
+		{
+			name: "no body",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("DeleteAnomalies", services.AnomalyFilter{}).
+					Return(0, fmt.Errorf("%w: at least one of type, job_id, created_before, assignee or acknowledged is required", services.ErrValidation))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"` + ErrCodeValidation + `"`,
+		},
+		{
+			name:           "unknown type",
+			body:           `{"type":"bogus"}`,
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `unknown anomaly type`,
+		},
+		{
+			name:           "invalid timestamp",
+			body:           `{"created_before":"yesterday"}`,
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"` + ErrCodeInvalidRequest + `"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.DELETE("/anomalies", NewAnomalyHandler(mockService, nil).DeleteAnomalies)
+
+			req := httptest.NewRequest(http.MethodDelete, "/anomalies", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAllAnomaliesAcknowledgementFilter(t *testing.T) {
+	acknowledged, unacknowledged := true, false
+	tests := []struct {
+		name           string
+		path           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+	}{
+		{
+			name: "by assignee",
+			path: "/anomalies?assignee=alice",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAllAnomaliesPaged", DefaultPageLimit, 0, services.SortOptions{}, services.AnomalyFilter{Assignee: "alice"}).
+					Return([]models.Anomaly{{ID: "1", Assignee: "alice"}}, 1, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "acknowledged only",
+			path: "/anomalies?acknowledged=true",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAllAnomaliesPaged", DefaultPageLimit, 0, services.SortOptions{}, services.AnomalyFilter{Acknowledged: &acknowledged}).
+					Return([]models.Anomaly{}, 0, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "unacknowledged for an assignee, including resolved",
+			path: "/anomalies?acknowledged=false&assignee=bob&include_resolved=true",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAllAnomaliesPaged", DefaultPageLimit, 0, services.SortOptions{}, services.AnomalyFilter{IncludeResolved: true, Assignee: "bob", Acknowledged: &unacknowledged}).
+					Return([]models.Anomaly{}, 0, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid acknowledged",
+			path:           "/anomalies?acknowledged=maybe",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.GET("/anomalies", NewAnomalyHandler(mockService, nil).GetAllAnomalies)
+
+			w := performRequest(router, http.MethodGet, tt.path, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetDetailedAnomalies(t *testing.T) {
+	acknowledged := true
+	mockService := new(MockAnomalyService)
+	mockService.On("GetDetailedAnomaliesPaged", 10, 0, services.SortOptions{Column: "severity", Order: services.SortDescending}, services.AnomalyFilter{Acknowledged: &acknowledged}).
+		Return([]models.DetailedAnomaly{{
+			Anomaly: models.Anomaly{ID: "7", JobID: "job1", Type: models.AnomalyTypeMaxSalary},
+			Job:     models.AnomalyJob{CompanyName: "Tech Corp", JobTitle: "Software Engineer", City: "Austin", MaxSalary: services.Float64Ptr(900000)},
+		}}, 1, nil)
+
+	router := gin.New()
+	router.GET("/anomalies/detailed", NewAnomalyHandler(mockService, nil).GetDetailedAnomalies)
+
+	w := performRequest(router, http.MethodGet, "/anomalies/detailed?limit=10&sort=severity&order=desc&acknowledged=true", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"7"`)
+	assert.Contains(t, w.Body.String(), `"job":{"company_name":"Tech Corp","job_title":"Software Engineer","city":"Austin","max_salary":900000}`)
+	assert.Contains(t, w.Body.String(), `"meta":{"total":1,"limit":10,"offset":0}`)
+	mockService.AssertExpectations(t)
+
+	w = performRequest(router, http.MethodGet, "/anomalies/detailed?acknowledged=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetAllAnomaliesEnvelope(t *testing.T) {
+	mockService := new(MockAnomalyService)
+	mockService.On("GetAllAnomaliesPaged", 2, 4, services.SortOptions{}, services.AnomalyFilter{}).
+		Return([]models.Anomaly{{ID: "7", JobID: "job1", Type: models.AnomalyTypeNullValues}}, 5, nil)
+
+	router := gin.New()
+	router.GET("/anomalies", NewAnomalyHandler(mockService, nil).GetAllAnomalies)
+
+	w := performRequest(router, http.MethodGet, "/anomalies?limit=2&offset=4", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data []models.Anomaly `json:"data"`
+		Meta ListMeta         `json:"meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Data, 1)
+	assert.Equal(t, "7", body.Data[0].ID)
+	assert.Equal(t, ListMeta{Total: 5, Limit: 2, Offset: 4}, body.Meta)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetAnomaliesIncludeResolved(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+	}{
+		{
+			name: "list hides resolved by default",
+			path: "/anomalies",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAllAnomaliesPaged", DefaultPageLimit, 0, services.SortOptions{}, services.AnomalyFilter{}).Return([]models.Anomaly{}, 0, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "list includes resolved on request",
+			path: "/anomalies?include_resolved=true",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAllAnomaliesPaged", DefaultPageLimit, 0, services.SortOptions{}, services.AnomalyFilter{IncludeResolved: true}).Return([]models.Anomaly{}, 0, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "job anomalies include resolved on request",
+			path: "/anomalies/job1?include_resolved=true",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomaliesByJobID", "job1", true).Return([]models.Anomaly{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid include_resolved value",
+			path:           "/anomalies?include_resolved=sometimes",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			handler := NewAnomalyHandler(mockService, nil)
+			router.GET("/anomalies", handler.GetAllAnomalies)
+			router.GET("/anomalies/:job_id", handler.GetAnomaliesByJobID)
+
+			w := performRequest(router, http.MethodGet, tt.path, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEvaluateAnomalyRule(t *testing.T) {
+	// An inactive rule, so the endpoint is shown to work before a rule is switched on
+	highSalary := &models.AnomalyRule{
+		ID:       3,
+		Name:     "High Salary",
+		Type:     models.AnomalyTypeMaxSalary,
+		Operator: models.GreaterThan,
+		Value:    500000,
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		setupMock      func(m *MockAnomalyRuleService)
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name: "matching sample",
+			path: "/anomaly-rules/3/test",
+			body: `{"jobID":"job1","maxSalary":750000}`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetAnomalyRule", int64(3)).Return(highSalary, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`"job_id":"job1","triggered":true,"value":750000,"threshold":500000`},
+		},
+		{
+			name: "matching and non-matching samples",
+			path: "/anomaly-rules/3/test",
+			body: `[{"jobID":"job1","maxSalary":750000},{"jobID":"job2","maxSalary":120000}]`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetAnomalyRule", int64(3)).Return(highSalary, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: []string{
+				`"job_id":"job1","triggered":true`,
+				`"job_id":"job2","triggered":false,"value":120000,"threshold":500000,"violations":[]`,
+			},
+		},
+		{
+			name: "missing rule",
+			path: "/anomaly-rules/9/test",
+			body: `{"jobID":"job1","maxSalary":750000}`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetAnomalyRule", int64(9)).Return(nil, fmt.Errorf("anomaly rule with ID 9 %w", services.ErrNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "empty sample list",
+			path:           "/anomaly-rules/3/test",
+			body:           `[]`,
+			setupMock:      func(m *MockAnomalyRuleService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed body",
+			path:           "/anomaly-rules/3/test",
+			body:           `{"maxSalary":`,
+			setupMock:      func(m *MockAnomalyRuleService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleService := new(MockAnomalyRuleService)
+			tt.setupMock(ruleService)
+
+			// Absolute rules are evaluated without touching the database
+			anomalyService := services.NewAnomalyService(nil, ruleService, nil, nil, nil)
+
+			router := gin.New()
+			router.POST("/anomaly-rules/:id/test", NewAnomalyHandler(anomalyService, nil).EvaluateAnomalyRule)
+
+			w := performRequest(router, http.MethodPost, tt.path, tt.body)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			ruleService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAnomaliesByCompany(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "default ordering and page",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomaliesByCompany", DefaultPageLimit, 0, services.SortOptions{}, false).Return([]models.CompanyAnomalies{
+					{CompanyName: "Tech Corp", AnomalyCount: 120, AnomalyTypes: []models.AnomalyType{models.AnomalyTypeNullValues}},
+				}, 1, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"company_name":"Tech Corp","anomaly_count":120,"anomaly_types":["null_values"]`,
+		},
+		{
+			name:  "sorted and paged",
+			query: "?sort=company_name&order=asc&limit=10&offset=20",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetAnomaliesByCompany", 10, 20, services.SortOptions{Column: "company_name", Order: services.SortAscending}, false).
+					Return([]models.CompanyAnomalies{}, 25, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"data":[],"meta":{"total":25,"limit":10,"offset":20}`,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=-1",
+			setupMock:      func(m *MockAnomalyService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			handler := NewAnomalyHandler(mockService, nil)
+			router.GET("/anomalies/by-company", handler.GetAnomaliesByCompany)
+			router.GET("/anomalies/:job_id", handler.GetAnomaliesByJobID)
+
+			w := performRequest(router, http.MethodGet, "/anomalies/by-company"+tt.query, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetJobRiskScore(t *testing.T) {
+	tests := []struct {
+		name           string
+		jobID          string
+		setupMock      func(m *MockAnomalyService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "scored job",
+			jobID: "job1",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetJobRiskScore", "job1").Return(12.0, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"job_id":"job1","risk_score":12}`,
+		},
+		{
+			name:  "unknown job",
+			jobID: "missing",
+			setupMock: func(m *MockAnomalyService) {
+				m.On("GetJobRiskScore", "missing").Return(0.0, fmt.Errorf("job missing %w", services.ErrNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"not_found"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.GET("/job-data/:job_id/risk", NewAnomalyHandler(mockService, nil).GetJobRiskScore)
+
+			w := performRequest(router, http.MethodGet, "/job-data/"+tt.jobID+"/risk", "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetJobsByRiskScore(t *testing.T) {
+	mockService := new(MockAnomalyService)
+	mockService.On("GetJobsByRiskScore", 10, 0).Return([]models.JobRiskScore{
+		{JobID: "job2", CompanyName: "Acme", JobTitle: "Analyst", AnomalyCount: 1, RiskScore: 10},
+		{JobID: "job1", CompanyName: "Tech Corp", JobTitle: "Engineer", AnomalyCount: 3, RiskScore: 3},
+	}, 2, nil)
+
+	router := gin.New()
+	handler := NewAnomalyHandler(mockService, nil)
+	router.GET("/job-data/risk", handler.GetJobsByRiskScore)
+	router.GET("/job-data/:job_id/risk", handler.GetJobRiskScore)
+
+	w := performRequest(router, http.MethodGet, "/job-data/risk?limit=10", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"data": [
+			{"job_id": "job2", "company_name": "Acme", "job_title": "Analyst", "anomaly_count": 1, "risk_score": 10},
+			{"job_id": "job1", "company_name": "Tech Corp", "job_title": "Engineer", "anomaly_count": 3, "risk_score": 3}
+		],
+		"meta": {"total": 2, "limit": 10, "offset": 0}
+	}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetDetectionRuns(t *testing.T) {
+	started := time.Date(2025, 4, 1, 2, 0, 0, 0, time.UTC)
+	mockService := new(MockAnomalyService)
+	mockService.On("GetDetectionRuns", 10, 0).Return([]models.DetectionRun{
+		{ID: 2, Status: models.DetectionRunRunning, StartedAt: started},
+	}, 2, nil)
+
+	router := gin.New()
+	handler := NewAnomalyHandler(mockService, nil)
+	router.GET("/anomalies/runs", handler.GetDetectionRuns)
+	router.GET("/anomalies/:job_id", handler.GetAnomaliesByJobID)
+
+	w := performRequest(router, http.MethodGet, "/anomalies/runs?limit=10", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"data": [{"id": 2, "status": "running", "dry_run": false, "started_at": "2025-04-01T02:00:00Z", "jobs_processed": 0, "anomalies_found": 0}],
+		"meta": {"total": 2, "limit": 10, "offset": 0}
+	}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
@@ -3,12 +3,17 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/ainesh01/anomaly_detection/internal/models"
 	"github.com/ainesh01/anomaly_detection/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
+// UserHeader names the user making a rule change. It is trusted as sent until
+// requests are authenticated.
+const UserHeader = "X-User"
+
 // AnomalyRuleHandler handles HTTP requests for anomaly rules
 type AnomalyRuleHandler struct {
 	ruleService services.AnomalyRuleServiceInterface
@@ -21,89 +26,126 @@ func NewAnomalyRuleHandler(ruleService services.AnomalyRuleServiceInterface) *An
 	}
 }
 
-// GetAnomalyRules handles GET requests for all anomaly rules
+// GetAnomalyRules handles GET requests for all anomaly rules, optionally
+// limited to those carrying the tag query parameter
 func (h *AnomalyRuleHandler) GetAnomalyRules(c *gin.Context) {
-	rules, err := h.ruleService.GetAnomalyRules()
+	sort, err := parseSort(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	rules, err := h.ruleService.GetAnomalyRules(c.Request.Context(), sort, c.Query("tag"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, rules)
+	// Rules are not paginated, so the page is always the whole list
+	respondList(c, rules, len(rules), len(rules), 0)
 }
 
 // GetAnomalyRule handles GET requests for a specific anomaly rule
 func (h *AnomalyRuleHandler) GetAnomalyRule(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule ID"})
+		respondBadRequest(c, "invalid rule ID")
 		return
 	}
 
-	rule, err := h.ruleService.GetAnomalyRule(id)
+	rule, err := h.ruleService.GetAnomalyRule(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, rule)
 }
 
-// CreateAnomalyRule handles POST requests to create a new anomaly rule
+// CreateAnomalyRule handles POST requests to create a new anomaly rule,
+// recording the X-User header as its creator
 func (h *AnomalyRuleHandler) CreateAnomalyRule(c *gin.Context) {
 	var rule models.AnomalyRule
 	if err := c.ShouldBindJSON(&rule); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err.Error())
 		return
 	}
+	rule.CreatedBy = requestUser(c)
 
-	if err := h.ruleService.CreateAnomalyRule(&rule); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.ruleService.CreateAnomalyRule(c.Request.Context(), &rule); err != nil {
+		respondServiceError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, rule)
 }
 
-// UpdateAnomalyRule handles PUT requests to update an existing anomaly rule
+// UpdateAnomalyRule handles PUT requests to update an existing anomaly rule,
+// recording the X-User header as its last editor
 func (h *AnomalyRuleHandler) UpdateAnomalyRule(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule ID"})
+		respondBadRequest(c, "invalid rule ID")
 		return
 	}
 
 	var rule models.AnomalyRule
 	if err := c.ShouldBindJSON(&rule); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err.Error())
 		return
 	}
 
 	rule.ID = id
-	if err := h.ruleService.UpdateAnomalyRule(&rule); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	rule.UpdatedBy = requestUser(c)
+	if err := h.ruleService.UpdateAnomalyRule(c.Request.Context(), &rule); err != nil {
+		respondServiceError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, rule)
 }
 
-// DeleteAnomalyRule handles DELETE requests to remove an anomaly rule
+// DeleteAnomalyRule handles DELETE requests to remove an anomaly rule,
+// recording the X-User header in its history
 func (h *AnomalyRuleHandler) DeleteAnomalyRule(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule ID"})
+		respondBadRequest(c, "invalid rule ID")
 		return
 	}
 
-	if err := h.ruleService.DeleteAnomalyRule(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.ruleService.DeleteAnomalyRule(c.Request.Context(), id, requestUser(c)); err != nil {
+		respondServiceError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
-// ToggleAnomalyRule handles PATCH requests to toggle the active state of an anomaly rule
+// ToggleRulesBulk handles PATCH requests to set the active state of every rule
+// matching a tag, type, or list of IDs, such as disabling a class of rules during an incident
+func (h *AnomalyRuleHandler) ToggleRulesBulk(c *gin.Context) {
+	var request struct {
+		Tag      string             `json:"tag"`
+		Type     models.AnomalyType `json:"type"`
+		IDs      []int64            `json:"ids"`
+		IsActive *bool              `json:"is_active" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	filter := services.RuleFilter{Tag: request.Tag, Type: request.Type, IDs: request.IDs}
+	updated, err := h.ruleService.ToggleRulesBulk(c.Request.Context(), filter, *request.IsActive, requestUser(c))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// ToggleAnomalyRule handles PATCH requests to toggle the active state of an anomaly rule,
+// recording the X-User header as its last editor
 func (h *AnomalyRuleHandler) ToggleAnomalyRule(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule ID"})
+		respondBadRequest(c, "invalid rule ID")
 		return
 	}
 
@@ -111,13 +153,64 @@ func (h *AnomalyRuleHandler) ToggleAnomalyRule(c *gin.Context) {
 		IsActive bool `json:"is_active"`
 	}
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err.Error())
 		return
 	}
 
-	if err := h.ruleService.ToggleAnomalyRule(id, request.IsActive); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.ruleService.ToggleAnomalyRule(c.Request.Context(), id, request.IsActive, requestUser(c)); err != nil {
+		respondServiceError(c, err)
 		return
 	}
 	c.Status(http.StatusOK)
 }
+
+// GetRuleHistory handles GET requests for the recorded changes to an anomaly rule, oldest first
+func (h *AnomalyRuleHandler) GetRuleHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondBadRequest(c, "invalid rule ID")
+		return
+	}
+
+	entries, err := h.ruleService.GetRuleHistory(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	// History is not paginated, so the page is always the whole list
+	respondList(c, entries, len(entries), len(entries), 0)
+}
+
+// ExportAnomalyRules handles GET requests for every rule as a portable JSON
+// document, which ImportAnomalyRules accepts unchanged
+func (h *AnomalyRuleHandler) ExportAnomalyRules(c *gin.Context) {
+	export, err := h.ruleService.ExportAnomalyRules(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, export)
+}
+
+// ImportAnomalyRules handles POST requests to create or update rules from an
+// exported document, matching rules by name and recording the X-User header
+// as the author of each change
+func (h *AnomalyRuleHandler) ImportAnomalyRules(c *gin.Context) {
+	var export models.RuleExport
+	if err := c.ShouldBindJSON(&export); err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.ruleService.ImportAnomalyRules(c.Request.Context(), &export, requestUser(c))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// requestUser returns the user named by the UserHeader, or "" when it is not sent
+func requestUser(c *gin.Context) string {
+	return strings.TrimSpace(c.GetHeader(UserHeader))
+}
@@ -0,0 +1,563 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/ainesh01/anomaly_detection/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRuleRouter(ruleService services.AnomalyRuleServiceInterface) *gin.Engine {
+	handler := NewAnomalyRuleHandler(ruleService)
+	router := gin.New()
+	router.POST("/rules", handler.CreateAnomalyRule)
+	router.GET("/rules/:id", handler.GetAnomalyRule)
+	router.PUT("/rules/:id", handler.UpdateAnomalyRule)
+	router.DELETE("/rules/:id", handler.DeleteAnomalyRule)
+	router.PATCH("/rules/:id/toggle", handler.ToggleAnomalyRule)
+	return router
+}
+
+func TestAnomalyRuleHandlerStatusCodes(t *testing.T) {
+	notFound := fmt.Errorf("anomaly rule with ID 42 %w", services.ErrNotFound)
+	ruleBody := `{"name":"High salary","type":"max_salary","operator":">","value":500000}`
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		setupMock      func(m *MockAnomalyRuleService)
+		expectedStatus int
+	}{
+		{
+			name:   "get existing rule",
+			method: http.MethodGet,
+			path:   "/rules/1",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetAnomalyRule", int64(1)).Return(&models.AnomalyRule{ID: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "get missing rule",
+			method: http.MethodGet,
+			path:   "/rules/42",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetAnomalyRule", int64(42)).Return(nil, notFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "get with invalid ID",
+			method:         http.MethodGet,
+			path:           "/rules/abc",
+			setupMock:      func(m *MockAnomalyRuleService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "update missing rule",
+			method: http.MethodPut,
+			path:   "/rules/42",
+			body:   ruleBody,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("UpdateAnomalyRule", mock.AnythingOfType("*models.AnomalyRule")).Return(notFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "update with inverted range",
+			method: http.MethodPut,
+			path:   "/rules/1",
+			body:   `{"name":"Bad","type":"max_salary","operator":"BETWEEN","value":10,"value_high":1}`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("UpdateAnomalyRule", mock.AnythingOfType("*models.AnomalyRule")).
+					Return(fmt.Errorf("%w: BETWEEN upper bound 1 is below lower bound 10", services.ErrValidation))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "update with invalid operator",
+			method:         http.MethodPut,
+			path:           "/rules/1",
+			body:           `{"name":"Bad","type":"max_salary","operator":"!!","value":1}`,
+			setupMock:      func(m *MockAnomalyRuleService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "create rule with duplicate name",
+			method: http.MethodPost,
+			path:   "/rules",
+			body:   ruleBody,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("CreateAnomalyRule", mock.AnythingOfType("*models.AnomalyRule")).
+					Return(fmt.Errorf("%w: %q", services.ErrDuplicateRuleName, "High salary"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "delete existing rule",
+			method: http.MethodDelete,
+			path:   "/rules/1",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("DeleteAnomalyRule", int64(1), "").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "delete missing rule",
+			method: http.MethodDelete,
+			path:   "/rules/42",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("DeleteAnomalyRule", int64(42), "").Return(notFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "toggle missing rule",
+			method: http.MethodPatch,
+			path:   "/rules/42/toggle",
+			body:   `{"is_active":false}`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("ToggleAnomalyRule", int64(42), false, "").Return(notFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyRuleService)
+			tt.setupMock(mockService)
+
+			w := performRequest(newRuleRouter(mockService), tt.method, tt.path, tt.body)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusNotFound {
+				assert.Contains(t, w.Body.String(), `"code":"`+ErrCodeNotFound+`"`)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAnomalyRulesSorting(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(m *MockAnomalyRuleService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "default ordering",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetAnomalyRules", services.SortOptions{}, "").Return([]models.AnomalyRule(nil), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data":[],"meta":{"total":0,"limit":0,"offset":0}}`,
+		},
+		{
+			name:  "sort and order are passed to the service",
+			query: "?sort=name&order=DESC",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetAnomalyRules", services.SortOptions{Column: "name", Order: services.SortDescending}, "").
+					Return([]models.AnomalyRule{{ID: 2, Name: "b"}, {ID: 1, Name: "a"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"meta":{"total":2,"limit":2,"offset":0}`,
+		},
+		{
+			name:  "unknown sort column",
+			query: "?sort=description",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetAnomalyRules", services.SortOptions{Column: "description"}, "").
+					Return([]models.AnomalyRule(nil), fmt.Errorf("%w: cannot sort by %q", services.ErrValidation, "description"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "tag is passed to the service",
+			query: "?tag=fraud",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetAnomalyRules", services.SortOptions{}, "fraud").
+					Return([]models.AnomalyRule{{ID: 3, Name: "c", Tags: models.StringSlice{"fraud"}}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"tags":["fraud"]`,
+		},
+		{
+			name:           "invalid order",
+			query:          "?sort=name&order=sideways",
+			setupMock:      func(m *MockAnomalyRuleService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyRuleService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.GET("/rules", NewAnomalyRuleHandler(mockService).GetAnomalyRules)
+
+			w := performRequest(router, http.MethodGet, "/rules"+tt.query, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestToggleRulesBulk(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		setupMock      func(m *MockAnomalyRuleService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "filter by tag and type",
+			body: `{"tag":"fraud","type":"max_salary","is_active":false}`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("ToggleRulesBulk", services.RuleFilter{Tag: "fraud", Type: models.AnomalyTypeMaxSalary}, false, "").Return(3, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"updated":3}`,
+		},
+		{
+			name: "filter by IDs",
+			body: `{"ids":[1,2],"is_active":true}`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("ToggleRulesBulk", services.RuleFilter{IDs: []int64{1, 2}}, true, "").Return(2, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"updated":2}`,
+		},
+		{
+			name: "empty filter",
+			body: `{"is_active":false}`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("ToggleRulesBulk", services.RuleFilter{}, false, "").
+					Return(0, fmt.Errorf("%w: at least one of tag, type or ids is required", services.ErrValidation))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing is_active",
+			body:           `{"tag":"fraud"}`,
+			setupMock:      func(m *MockAnomalyRuleService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyRuleService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.PATCH("/rules/toggle-bulk", NewAnomalyRuleHandler(mockService).ToggleRulesBulk)
+
+			w := performRequest(router, http.MethodPatch, "/rules/toggle-bulk", tt.body)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAnomalyRuleHandlerRecordsUser(t *testing.T) {
+	ruleBody := `{"name":"High salary","type":"max_salary","operator":">","value":500000,"created_by":"mallory"}`
+
+	t.Run("create records the creator", func(t *testing.T) {
+		mockService := new(MockAnomalyRuleService)
+		mockService.On("CreateAnomalyRule", mock.MatchedBy(func(rule *models.AnomalyRule) bool {
+			return rule.CreatedBy == "alice"
+		})).Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/rules", strings.NewReader(ruleBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(UserHeader, " alice ")
+		w := httptest.NewRecorder()
+		newRuleRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"created_by":"alice"`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("update records the editor", func(t *testing.T) {
+		mockService := new(MockAnomalyRuleService)
+		mockService.On("UpdateAnomalyRule", mock.MatchedBy(func(rule *models.AnomalyRule) bool {
+			return rule.ID == 1 && rule.UpdatedBy == "bob"
+		})).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(0).(*models.AnomalyRule).CreatedBy = "alice" // As read back from the database
+		})
+
+		req := httptest.NewRequest(http.MethodPut, "/rules/1", strings.NewReader(ruleBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(UserHeader, "bob")
+		w := httptest.NewRecorder()
+		newRuleRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"created_by":"alice"`)
+		assert.Contains(t, w.Body.String(), `"updated_by":"bob"`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing header leaves the creator empty", func(t *testing.T) {
+		mockService := new(MockAnomalyRuleService)
+		mockService.On("CreateAnomalyRule", mock.MatchedBy(func(rule *models.AnomalyRule) bool {
+			return rule.CreatedBy == ""
+		})).Return(nil)
+
+		w := performRequest(newRuleRouter(mockService), http.MethodPost, "/rules", ruleBody)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestCreateAnomalyRuleWarnings(t *testing.T) {
+	mockService := new(MockAnomalyRuleService)
+	mockService.On("CreateAnomalyRule", mock.AnythingOfType("*models.AnomalyRule")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.AnomalyRule).Warnings = []string{"max_salary < 0 can never match a valid job"}
+	})
+
+	w := performRequest(newRuleRouter(mockService), http.MethodPost, "/rules", `{"name":"Negative salary","type":"max_salary","operator":"<","value":0}`)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"warnings":["max_salary \u003c 0 can never match a valid job"]`)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateAnomalyRuleEnumBinding(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantError string // Empty when the rule binds and reaches the service
+	}{
+		{
+			name: "known type and operator",
+			body: `{"name":"High salary","type":"max_salary","operator":">=","value":500000}`,
+		},
+		{
+			name: "compound rule without an operator",
+			body: `{"name":"Odd pay","type":"compound","logic":"and","conditions":[{"type":"min_salary","operator":"<","value":1},{"type":"company_rating","operator":"BETWEEN","value":0,"value_high":1}]}`,
+		},
+		{
+			name: "text operator",
+			body: `{"name":"MLM","type":"text_match","field":"job_description","operator":"contains","text_value":"mlm"}`,
+		},
+		{
+			name:      "misspelled type",
+			body:      `{"name":"High salary","type":"salery","operator":">","value":500000}`,
+			wantError: `unknown anomaly type \"salery\"`,
+		},
+		{
+			name:      "unknown operator",
+			body:      `{"name":"High salary","type":"max_salary","operator":"=>","value":500000}`,
+			wantError: `unknown comparison operator \"=\u003e\"`,
+		},
+		{
+			name:      "unknown condition type",
+			body:      `{"name":"Odd pay","type":"compound","logic":"and","conditions":[{"type":"bonus","operator":">","value":1}]}`,
+			wantError: `unknown anomaly type \"bonus\"`,
+		},
+		{
+			name:      "type that is not a string",
+			body:      `{"name":"High salary","type":3,"operator":">","value":500000}`,
+			wantError: "anomaly type must be a string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyRuleService)
+			if tt.wantError == "" {
+				mockService.On("CreateAnomalyRule", mock.AnythingOfType("*models.AnomalyRule")).Return(nil)
+			}
+
+			w := performRequest(newRuleRouter(mockService), http.MethodPost, "/rules", tt.body)
+
+			if tt.wantError == "" {
+				assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			} else {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+				assert.Contains(t, w.Body.String(), tt.wantError)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetRuleHistory(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		setupMock      func(m *MockAnomalyRuleService)
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			name: "rule with history",
+			path: "/rules/7/history",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetRuleHistory", int64(7)).Return([]models.RuleAuditEntry{
+					{ID: 1, RuleID: 7, Action: models.RuleAuditCreate, NewValue: []byte(`{"id":7}`), Actor: "alice"},
+					{ID: 2, RuleID: 7, Action: models.RuleAuditDelete, OldValue: []byte(`{"id":7}`), Actor: "bob"},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: []string{
+				`"action":"create","old_value":null,"new_value":{"id":7},"actor":"alice"`,
+				`"action":"delete","old_value":{"id":7},"new_value":null,"actor":"bob"`,
+				`"meta":{"total":2,"limit":2,"offset":0}`,
+			},
+		},
+		{
+			name:           "invalid ID",
+			path:           "/rules/abc/history",
+			setupMock:      func(m *MockAnomalyRuleService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service error",
+			path: "/rules/7/history",
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("GetRuleHistory", int64(7)).Return([]models.RuleAuditEntry(nil), assert.AnError)
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyRuleService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.GET("/rules/:id/history", NewAnomalyRuleHandler(mockService).GetRuleHistory)
+
+			w := performRequest(router, http.MethodGet, tt.path, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExportAnomalyRules(t *testing.T) {
+	mockService := new(MockAnomalyRuleService)
+	mockService.On("ExportAnomalyRules").Return(&models.RuleExport{
+		Version: models.RuleExportVersion,
+		Rules: []models.RuleDefinition{{
+			Name:      "High salary",
+			Type:      models.AnomalyTypeMaxSalary,
+			Operator:  models.GreaterThan,
+			Value:     300000,
+			ValueMode: models.ValueModeAbsolute,
+			IsActive:  true,
+			Severity:  models.SeverityHigh,
+			Logic:     models.RuleLogicAnd,
+			Tags:      models.StringSlice{"salary"},
+		}},
+	}, nil)
+
+	router := gin.New()
+	router.GET("/rules/export", NewAnomalyRuleHandler(mockService).ExportAnomalyRules)
+
+	w := performRequest(router, http.MethodGet, "/rules/export", "")
+
+	// Server-specific fields such as IDs and timestamps are not part of the document
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"version": 1,
+		"exported_at": "0001-01-01T00:00:00Z",
+		"rules": [{
+			"name": "High salary", "description": "", "type": "max_salary", "operator": ">",
+			"value": 300000, "value_high": 0, "value_mode": "absolute", "field": "", "text_value": "",
+			"is_active": true, "severity": "high", "logic": "and", "tags": ["salary"]
+		}]
+	}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestImportAnomalyRules(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		setupMock      func(m *MockAnomalyRuleService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "rules are upserted",
+			body: `{"version":1,"rules":[{"name":"High salary","type":"max_salary","operator":">","value":300000}]}`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("ImportAnomalyRules", mock.MatchedBy(func(export *models.RuleExport) bool {
+					return len(export.Rules) == 1 && export.Rules[0].Name == "High salary" && export.Rules[0].Value == 300000
+				}), "").Return(&models.RuleImportResult{Created: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"created":1,"updated":0,"unchanged":0}`,
+		},
+		{
+			name: "invalid rule",
+			body: `{"rules":[{"name":"Bad","type":"max_salary","operator":"BETWEEN","value":10,"value_high":1}]}`,
+			setupMock: func(m *MockAnomalyRuleService) {
+				m.On("ImportAnomalyRules", mock.Anything, "").
+					Return(nil, fmt.Errorf("rule 1 (%q): %w: BETWEEN upper bound 1 is below lower bound 10", "Bad", services.ErrValidation))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown rule type",
+			body:           `{"rules":[{"name":"Bad","type":"unknown"}]}`,
+			setupMock:      func(m *MockAnomalyRuleService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed document",
+			body:           `{"rules":`,
+			setupMock:      func(m *MockAnomalyRuleService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAnomalyRuleService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.POST("/rules/import", NewAnomalyRuleHandler(mockService).ImportAnomalyRules)
+
+			w := performRequest(router, http.MethodPost, "/rules/import", tt.body)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
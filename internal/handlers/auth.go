@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader is the header clients may send an API key in, as an alternative
+// to an "Authorization: Bearer <key>" header
+const APIKeyHeader = "X-API-Key"
+
+// RequireAPIKey returns middleware that rejects requests with 401 unless they
+// carry one of keys, either as a bearer token or in the X-API-Key header.
+// With no keys configured it lets every request through, which keeps local
+// development working without credentials.
+func RequireAPIKey(keys []string) gin.HandlerFunc {
+	// Keys are compared by hash, so every comparison takes the same time
+	// whatever the length of the key presented
+	hashes := make([][sha256.Size]byte, 0, len(keys))
+	for _, key := range keys {
+		hashes = append(hashes, sha256.Sum256([]byte(key)))
+	}
+
+	return func(c *gin.Context) {
+		if len(hashes) == 0 {
+			c.Next()
+			return
+		}
+
+		key, ok := requestAPIKey(c)
+		if !ok {
+			respondUnauthorized(c, "missing API key")
+			return
+		}
+		presented := sha256.Sum256([]byte(key))
+		valid := 0
+		for _, hash := range hashes {
+			valid |= subtle.ConstantTimeCompare(presented[:], hash[:])
+		}
+		if valid == 0 {
+			respondUnauthorized(c, "invalid API key")
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestAPIKey returns the key from the Authorization bearer token, falling
+// back to the X-API-Key header, and whether either carried one
+func requestAPIKey(c *gin.Context) (string, bool) {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		scheme, token, found := strings.Cut(auth, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return "", false
+		}
+		token = strings.TrimSpace(token)
+		return token, token != ""
+	}
+	key := strings.TrimSpace(c.GetHeader(APIKeyHeader))
+	return key, key != ""
+}
+
+// respondUnauthorized writes a 401 error envelope and stops the request
+func respondUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, message)
+	c.Abort()
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	keys := []string{"key-one", "key-two"}
+
+	tests := []struct {
+		name           string
+		keys           []string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{
+			name:           "bearer token",
+			keys:           keys,
+			headers:        map[string]string{"Authorization": "Bearer key-one"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "any configured key is accepted",
+			keys:           keys,
+			headers:        map[string]string{"Authorization": "bearer key-two"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "API key header",
+			keys:           keys,
+			headers:        map[string]string{APIKeyHeader: "key-two"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing credentials",
+			keys:           keys,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown bearer token",
+			keys:           keys,
+			headers:        map[string]string{"Authorization": "Bearer key-three"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown API key",
+			keys:           keys,
+			headers:        map[string]string{APIKeyHeader: "key-on"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "non-bearer scheme",
+			keys:           keys,
+			headers:        map[string]string{"Authorization": "Basic key-one"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "empty bearer token",
+			keys:           keys,
+			headers:        map[string]string{"Authorization": "Bearer "},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "disabled without keys",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(RequireAPIKey(tt.keys))
+			router.GET("/jobs", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Contains(t, w.Body.String(), `"code":"`+ErrCodeUnauthorized+`"`)
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LimitRequestBody returns middleware that rejects request bodies larger than
+// maxSize bytes with 413, so a huge payload can't tie up the server. Bodies that
+// declare an oversized Content-Length are rejected before any of them is read.
+// The limit applies to the body as sent, so it runs before DecompressGzip.
+func LimitRequestBody(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxSize {
+			respondBodyTooLarge(c, maxSize)
+			return
+		}
+
+		// MaxBytesReader also closes the connection once the limit is hit,
+		// so the rest of an oversized body is never read
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondBodyTooLarge(c, maxSize)
+			return
+		}
+		if err != nil {
+			respondBadRequest(c, fmt.Sprintf("error reading request body: %v", err))
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// respondBodyTooLarge writes a 413 error envelope and stops the request
+func respondBodyTooLarge(c *gin.Context, maxSize int64) {
+	respondError(c, http.StatusRequestEntityTooLarge, ErrCodeTooLarge,
+		fmt.Sprintf("request body exceeds %d bytes", maxSize))
+	c.Abort()
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLimitRequestBody(t *testing.T) {
+	validJob := `{"jobID":"job1","companyName":"Tech Corp","companyRating":4.5}`
+	oversizedJob := `{"jobID":"job1","jobDescription":"` + strings.Repeat("a", 4096) + `"}`
+
+	tests := []struct {
+		name           string
+		body           string
+		unknownLength  bool
+		maxSize        int64
+		expectCreate   bool
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "body within the limit",
+			body:           validJob,
+			maxSize:        1024,
+			expectCreate:   true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "body exactly at the limit",
+			body:           validJob,
+			maxSize:        int64(len(validJob)),
+			expectCreate:   true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "declared length over the limit",
+			body:           oversizedJob,
+			maxSize:        1024,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   ErrCodeTooLarge,
+		},
+		{
+			name:           "unknown length over the limit",
+			body:           oversizedJob,
+			unknownLength:  true,
+			maxSize:        1024,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   ErrCodeTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobDataService)
+			if tt.expectCreate {
+				mockService.On("CreateJobData", mock.MatchedBy(func(job *models.JobData) bool {
+					return job.JobID == "job1" && job.CompanyName == "Tech Corp"
+				})).Return(nil)
+			}
+
+			router := gin.New()
+			router.Use(LimitRequestBody(tt.maxSize))
+			router.POST("/jobs", NewJobDataHandler(mockService).CreateJobData)
+
+			req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.unknownLength {
+				// As with a chunked upload, the size is only found out by reading
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedCode != "" {
+				assert.Contains(t, w.Body.String(), `"code":"`+tt.expectedCode+`"`)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
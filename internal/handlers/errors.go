@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/ainesh01/anomaly_detection/internal/requestid"
+	"github.com/ainesh01/anomaly_detection/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes returned in the error envelope
+const (
+	ErrCodeInvalidRequest = "invalid_request"
+	ErrCodeUnauthorized   = "unauthorized"
+	ErrCodeNotFound       = "not_found"
+	ErrCodeValidation     = "validation_failed"
+	ErrCodeConflict       = "conflict"
+	ErrCodeTooLarge       = "payload_too_large"
+	ErrCodeInternal       = "internal_error"
+)
+
+// ErrorBody is the machine-readable error returned to clients
+type ErrorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"` // Matches the X-Request-ID response header
+}
+
+// ErrorResponse is the envelope wrapping every error response
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// respondError writes an error envelope with the given status, code, and message,
+// tagged with the request ID when the RequestID middleware has set one
+func respondError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, ErrorResponse{Error: ErrorBody{
+		Code:      code,
+		Message:   message,
+		RequestID: requestid.FromContext(c.Request.Context()),
+	}})
+}
+
+// respondBadRequest writes a 400 error envelope for malformed client input
+func respondBadRequest(c *gin.Context, message string) {
+	respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, message)
+}
+
+// respondServiceError maps a services error onto an HTTP status and error code.
+// Typed service errors keep their human-readable message; anything else is
+// logged and replaced with a generic message so driver details are not leaked.
+// A bare sql.ErrNoRows that escapes a service is still treated as a 404.
+func respondServiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, sql.ErrNoRows):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "resource not found")
+	case errors.Is(err, services.ErrValidation):
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error())
+	case errors.Is(err, services.ErrConflict):
+		respondError(c, http.StatusConflict, ErrCodeConflict, err.Error())
+	default:
+		slog.ErrorContext(c.Request.Context(), "internal error handling request", "method", c.Request.Method, "path", c.Request.URL.Path, "err", err)
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "an internal error occurred")
+	}
+}
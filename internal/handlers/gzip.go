@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DecompressGzip returns middleware that transparently decompresses request
+// bodies sent with Content-Encoding: gzip, so handlers can bind them as usual.
+// Bodies that decompress to more than maxSize bytes are rejected with 413 to
+// guard against decompression bombs, and malformed gzip is rejected with 400.
+func DecompressGzip(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.EqualFold(c.GetHeader("Content-Encoding"), "gzip") || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		reader, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			respondBadRequest(c, fmt.Sprintf("invalid gzip body: %v", err))
+			c.Abort()
+			return
+		}
+		defer reader.Close()
+
+		// Read one byte past the limit to tell an exact fit from an oversized body
+		body, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+		if err != nil {
+			respondBadRequest(c, fmt.Sprintf("invalid gzip body: %v", err))
+			c.Abort()
+			return
+		}
+		if int64(len(body)) > maxSize {
+			respondError(c, http.StatusRequestEntityTooLarge, ErrCodeTooLarge,
+				fmt.Sprintf("decompressed body exceeds %d bytes", maxSize))
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Next()
+	}
+}
+
+// CompressGzip returns middleware that gzip-compresses responses for clients
+// sending Accept-Encoding: gzip. Responses shorter than minSize bytes are sent
+// as is, since compressing them costs more than it saves, and so are responses
+// a handler has already given a Content-Encoding, so nothing is compressed twice.
+func CompressGzip(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		writer := &gzipResponseWriter{ResponseWriter: c.Writer, minSize: minSize}
+		c.Writer = writer
+		defer writer.finish()
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows a gzip response
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// gzip;q=0 explicitly refuses it
+		name, value, ok := strings.Cut(params, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it is known to
+// reach the minimum size, then either compresses the whole response or, for a
+// short one, writes it out unchanged when the handler is done
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	minSize int
+
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer // Set once the response is being compressed
+}
+
+// Write buffers data until the response is known to be worth compressing
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteString writes s through Write so it is buffered and compressed too
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what has been written so far. A streaming response is compressed
+// from the first flush on, whatever its size so far.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide fixes whether the response is compressed and writes out the buffered
+// start of it. Responses the handler has already encoded are never compressed.
+func (w *gzipResponseWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if compress && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+		_, err := w.gz.Write(w.buf)
+		w.buf = nil
+		return err
+	}
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// finish writes out a response that stayed below the minimum size and closes
+// the gzip stream of a compressed one
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		w.decide(false)
+		return
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// gzipBody compresses body for use as a gzip-encoded request body
+func gzipBody(t *testing.T, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(body))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestDecompressGzip(t *testing.T) {
+	validJob := `{"jobID":"job1","companyName":"Tech Corp","companyRating":4.5}`
+
+	tests := []struct {
+		name           string
+		body           []byte
+		gzipped        bool
+		maxSize        int64
+		expectCreate   bool
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "gzipped body is decompressed",
+			body:           gzipBody(t, validJob),
+			gzipped:        true,
+			maxSize:        1 << 20,
+			expectCreate:   true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "plain body is passed through",
+			body:           []byte(validJob),
+			maxSize:        1 << 20,
+			expectCreate:   true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "malformed gzip",
+			body:           []byte("definitely not gzip"),
+			gzipped:        true,
+			maxSize:        1 << 20,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrCodeInvalidRequest,
+		},
+		{
+			name:           "truncated gzip",
+			body:           gzipBody(t, validJob)[:20],
+			gzipped:        true,
+			maxSize:        1 << 20,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrCodeInvalidRequest,
+		},
+		{
+			name:           "decompressed body over the limit",
+			body:           gzipBody(t, `{"jobDescription":"`+strings.Repeat("a", 4096)+`"}`),
+			gzipped:        true,
+			maxSize:        1024,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   ErrCodeTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobDataService)
+			if tt.expectCreate {
+				mockService.On("CreateJobData", mock.MatchedBy(func(job *models.JobData) bool {
+					return job.JobID == "job1" && job.CompanyName == "Tech Corp"
+				})).Return(nil)
+			}
+
+			router := gin.New()
+			router.Use(DecompressGzip(tt.maxSize))
+			router.POST("/jobs", NewJobDataHandler(mockService).CreateJobData)
+
+			req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.gzipped {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedCode != "" {
+				assert.Contains(t, w.Body.String(), `"code":"`+tt.expectedCode+`"`)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCompressGzip(t *testing.T) {
+	large := strings.Repeat(`{"job_id":"job1","company_name":"Tech Corp"},`, 100)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		handler        gin.HandlerFunc
+		expectGzip     bool
+		expectedBody   string
+	}{
+		{
+			name:           "large response is compressed",
+			acceptEncoding: "gzip, deflate, br",
+			handler:        func(c *gin.Context) { c.String(http.StatusOK, large) },
+			expectGzip:     true,
+			expectedBody:   large,
+		},
+		{
+			name:           "small response is sent as is",
+			acceptEncoding: "gzip",
+			handler:        func(c *gin.Context) { c.String(http.StatusOK, "ok") },
+			expectedBody:   "ok",
+		},
+		{
+			name:         "client without gzip gets plain response",
+			handler:      func(c *gin.Context) { c.String(http.StatusOK, large) },
+			expectedBody: large,
+		},
+		{
+			name:           "gzip refused with q=0",
+			acceptEncoding: "gzip;q=0, identity",
+			handler:        func(c *gin.Context) { c.String(http.StatusOK, large) },
+			expectedBody:   large,
+		},
+		{
+			name:           "already encoded response is not compressed again",
+			acceptEncoding: "gzip",
+			handler: func(c *gin.Context) {
+				c.Header("Content-Encoding", "gzip")
+				c.Data(http.StatusOK, "text/csv", gzipBody(t, large))
+			},
+			expectGzip:   true,
+			expectedBody: large,
+		},
+		{
+			name:           "streamed response is compressed from the first flush",
+			acceptEncoding: "gzip",
+			handler: func(c *gin.Context) {
+				c.Header("Content-Type", "text/csv")
+				c.Writer.WriteString("job_id\n")
+				c.Writer.Flush()
+				c.Writer.WriteString("job1\n")
+			},
+			expectGzip:   true,
+			expectedBody: "job_id\njob1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(CompressGzip(1024))
+			router.GET("/anomalies", tt.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/anomalies", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			if !tt.expectGzip {
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+				return
+			}
+
+			assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+			reader, err := gzip.NewReader(w.Body)
+			assert.NoError(t, err)
+			body, err := io.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, string(body), "the body must be compressed exactly once")
+		})
+	}
+}
+
+func TestCompressGzipListResponse(t *testing.T) {
+	jobs := make([]models.JobData, 200)
+	for i := range jobs {
+		jobs[i] = models.JobData{JobID: fmt.Sprintf("job%d", i), CompanyName: "Tech Corp"}
+	}
+	mockService := new(MockJobDataService)
+	mockService.On("GetAllJobDataPaged", DefaultPageLimit, 0).Return(jobs[:DefaultPageLimit], len(jobs), nil)
+
+	router := gin.New()
+	router.Use(CompressGzip(1024))
+	router.GET("/job-data", NewJobDataHandler(mockService).GetAllJobData)
+
+	req := httptest.NewRequest(http.MethodGet, "/job-data", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+
+	reader, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	var response ListResponse[models.JobData]
+	assert.NoError(t, json.NewDecoder(reader).Decode(&response))
+	assert.Len(t, response.Data, DefaultPageLimit)
+	assert.Equal(t, len(jobs), response.Meta.Total)
+}
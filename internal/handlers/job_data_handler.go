@@ -1,13 +1,27 @@
 package handlers
 
 import (
+	"fmt"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
 
 	"github.com/ainesh01/anomaly_detection/internal/models"
 	"github.com/ainesh01/anomaly_detection/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// IdempotencyKeyHeader is the request header that makes a job write safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// MaxIdempotencyKeyLength is the longest idempotency key that is accepted
+	MaxIdempotencyKeyLength = 255
+	// DefaultSampleSize is how many jobs a random sample holds when n is not supplied
+	DefaultSampleSize = 50
+	// MaxSampleSize is the largest random sample a client may request
+	MaxSampleSize = 500
+)
+
 // JobDataHandler handles HTTP requests for job data
 type JobDataHandler struct {
 	jobDataService services.JobDataServiceInterface
@@ -20,38 +34,207 @@ func NewJobDataHandler(jobDataService services.JobDataServiceInterface) *JobData
 	}
 }
 
-// CreateJobData handles POST requests to create a new job data entry
+// CreateJobData handles POST requests to create a new job data entry.
+// With an Idempotency-Key header, a retried request returns the job stored by
+// the first request with 200 instead of writing it again.
 func (h *JobDataHandler) CreateJobData(c *gin.Context) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if len(key) > MaxIdempotencyKeyLength {
+		respondBadRequest(c, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, MaxIdempotencyKeyLength))
+		return
+	}
+
 	var job models.JobData
 	if err := c.ShouldBindJSON(&job); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	if err := services.ValidateJobData(&job); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	if key == "" {
+		if err := h.jobDataService.CreateJobData(c.Request.Context(), &job); err != nil {
+			respondServiceError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, job)
 		return
 	}
 
-	if err := h.jobDataService.CreateJobData(&job); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	stored, replayed, err := h.jobDataService.CreateJobDataIdempotent(c.Request.Context(), key, &job)
+	if err != nil {
+		respondServiceError(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, job)
+	if replayed {
+		c.JSON(http.StatusOK, stored)
+		return
+	}
+	c.JSON(http.StatusCreated, stored)
 }
 
 // GetJobData handles GET requests for a specific job data entry
 func (h *JobDataHandler) GetJobData(c *gin.Context) {
 	jobID := c.Param("job_id")
-	job, err := h.jobDataService.GetJobData(jobID)
+	job, err := h.jobDataService.GetJobData(c.Request.Context(), jobID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, job)
 }
 
-// GetAllJobData handles GET requests for all job data entries
+// PatchJobData handles PATCH requests that update only the provided fields of a job
+func (h *JobDataHandler) PatchJobData(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var fields map[string]interface{}
+	if err := c.ShouldBindJSON(&fields); err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.jobDataService.PatchJobData(c.Request.Context(), jobID, fields); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	job, err := h.jobDataService.GetJobData(c.Request.Context(), jobID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// DeleteJobData handles DELETE requests for a job and its anomalies
+func (h *JobDataHandler) DeleteJobData(c *gin.Context) {
+	jobID := c.Param("job_id")
+	if err := h.jobDataService.DeleteJobData(c.Request.Context(), jobID); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetAllJobData handles GET requests for a page of job data entries
 func (h *JobDataHandler) GetAllJobData(c *gin.Context) {
-	jobs, err := h.jobDataService.GetAllJobData()
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	jobs, total, err := h.jobDataService.GetAllJobDataPaged(c.Request.Context(), limit, offset)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	respondList(c, jobs, total, limit, offset)
+}
+
+// SearchJobData handles GET requests for a page of jobs matching the company,
+// title and city query parameters
+func (h *JobDataHandler) SearchJobData(c *gin.Context) {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondBadRequest(c, err.Error())
+		return
+	}
+
+	criteria := services.SearchCriteria{
+		Company: c.Query("company"),
+		Title:   c.Query("title"),
+		City:    c.Query("city"),
+	}
+
+	jobs, total, err := h.jobDataService.SearchJobs(c.Request.Context(), criteria, limit, offset)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	respondList(c, jobs, total, limit, offset)
+}
+
+// SampleJobData handles GET requests for a random sample of jobs to review.
+// n sets the sample size, capped at MaxSampleSize, and seed makes the sample
+// reproducible. A random seed is chosen when none is given, and the seed used
+// is always returned so the sample can be fetched again.
+func (h *JobDataHandler) SampleJobData(c *gin.Context) {
+	n := DefaultSampleSize
+	if raw := c.Query("n"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			respondBadRequest(c, fmt.Sprintf("invalid n %q: must be a positive integer", raw))
+			return
+		}
+		n = min(parsed, MaxSampleSize)
+	}
+
+	seed := rand.Int64()
+	if raw := c.Query("seed"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			respondBadRequest(c, fmt.Sprintf("invalid seed %q: must be an integer", raw))
+			return
+		}
+		seed = parsed
+	}
+
+	jobs, err := h.jobDataService.SampleJobs(c.Request.Context(), n, seed)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": jobs, "seed": seed})
+}
+
+// GetJobDataRange handles GET requests for the jobs at row indexes start through
+// end, inclusive, counting from zero in order of ingestion. The range may cover
+// at most services.MaxRowRange jobs.
+func (h *JobDataHandler) GetJobDataRange(c *gin.Context) {
+	start, err := strconv.ParseInt(c.Query("start"), 10, 64)
+	if err != nil || start < 0 {
+		respondBadRequest(c, fmt.Sprintf("invalid start %q: must be a non-negative integer", c.Query("start")))
+		return
+	}
+	end, err := strconv.ParseInt(c.Query("end"), 10, 64)
+	if err != nil || end < 0 {
+		respondBadRequest(c, fmt.Sprintf("invalid end %q: must be a non-negative integer", c.Query("end")))
+		return
+	}
+	if start > end {
+		respondBadRequest(c, fmt.Sprintf("invalid range: start %d is after end %d", start, end))
+		return
+	}
+	if end-start >= services.MaxRowRange {
+		respondBadRequest(c, fmt.Sprintf("invalid range: at most %d rows may be requested at once", services.MaxRowRange))
+		return
+	}
+
+	jobs, err := h.jobDataService.GetJobsByRowIndexRange(c.Request.Context(), start, end)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": jobs, "start": start, "end": end})
+}
+
+// GetJobDataSchema handles GET requests for the fields of a job: their JSON
+// names and types, which are required, and the accepted timestamp formats
+func (h *JobDataHandler) GetJobDataSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"fields": services.JobDataSchema()})
+}
+
+// GetJobDataSummary handles GET requests for aggregate job data numbers
+func (h *JobDataHandler) GetJobDataSummary(c *gin.Context) {
+	summary, err := h.jobDataService.GetSummary(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, jobs)
+	c.JSON(http.StatusOK, summary)
 }
@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/ainesh01/anomaly_detection/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetJobDataStatusCodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		job            *models.JobData
+		serviceErr     error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "existing job",
+			job:            &models.JobData{JobID: "job1"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing job",
+			serviceErr:     fmt.Errorf("job data with ID job1 %w", services.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   ErrCodeNotFound,
+		},
+		{
+			name:           "unwrapped no rows error",
+			serviceErr:     sql.ErrNoRows,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   ErrCodeNotFound,
+		},
+		{
+			name:           "database failure",
+			serviceErr:     errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   ErrCodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobDataService)
+			mockService.On("GetJobData", "job1").Return(tt.job, tt.serviceErr)
+
+			router := gin.New()
+			router.GET("/jobs/:job_id", NewJobDataHandler(mockService).GetJobData)
+
+			w := performRequest(router, http.MethodGet, "/jobs/job1", "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedCode != "" {
+				assert.Contains(t, w.Body.String(), `"code":"`+tt.expectedCode+`"`)
+			}
+			assert.NotContains(t, w.Body.String(), "connection refused")
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateJobDataRejectsInvalidJob(t *testing.T) {
+	mockService := new(MockJobDataService)
+
+	router := gin.New()
+	router.POST("/jobs", NewJobDataHandler(mockService).CreateJobData)
+
+	w := performRequest(router, http.MethodPost, "/jobs", `{"jobID":"job1","companyRating":9}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"`+ErrCodeValidation+`"`)
+	mockService.AssertNotCalled(t, "CreateJobData")
+}
+
+func TestCreateJobDataIdempotencyKey(t *testing.T) {
+	stored := &models.JobData{JobID: "job1", CompanyName: "Tech Corp", CreatedAt: time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)}
+	mockService := new(MockJobDataService)
+	mockService.On("CreateJobDataIdempotent", "retry-1", mock.Anything).Return(stored, false, nil).Once()
+	mockService.On("CreateJobDataIdempotent", "retry-1", mock.Anything).Return(stored, true, nil).Once()
+
+	router := gin.New()
+	router.POST("/jobs", NewJobDataHandler(mockService).CreateJobData)
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"jobID":"job1","companyName":"Tech Corp"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, "retry-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post()
+	second := post()
+
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.JSONEq(t, first.Body.String(), second.Body.String())
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "CreateJobData", mock.Anything)
+}
+
+func TestCreateJobDataIdempotencyKeyErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		key            string
+		setupMock      func(m *MockJobDataService)
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name: "key reused for a different job",
+			key:  "retry-1",
+			setupMock: func(m *MockJobDataService) {
+				m.On("CreateJobDataIdempotent", "retry-1", mock.Anything).
+					Return(nil, false, fmt.Errorf("%w: idempotency key \"retry-1\" was already used for a different request", services.ErrConflict))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   ErrCodeConflict,
+		},
+		{
+			name:           "key too long",
+			key:            strings.Repeat("k", MaxIdempotencyKeyLength+1),
+			setupMock:      func(m *MockJobDataService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrCodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobDataService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.POST("/jobs", NewJobDataHandler(mockService).CreateJobData)
+
+			req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"jobID":"job1"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(IdempotencyKeyHeader, tt.key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.expectedCode+`"`)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAllJobDataEnvelope(t *testing.T) {
+	mockService := new(MockJobDataService)
+	mockService.On("GetAllJobDataPaged", DefaultPageLimit, 0).Return([]models.JobData(nil), 0, nil)
+
+	router := gin.New()
+	router.GET("/job-data", NewJobDataHandler(mockService).GetAllJobData)
+
+	w := performRequest(router, http.MethodGet, "/job-data", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[],"meta":{"total":0,"limit":50,"offset":0}}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetJobDataSummary(t *testing.T) {
+	mockService := new(MockJobDataService)
+	mockService.On("GetSummary").Return(&models.JobSummary{TotalJobs: 3, DistinctCities: 2}, nil)
+
+	router := gin.New()
+	handler := NewJobDataHandler(mockService)
+	router.GET("/job-data/summary", handler.GetJobDataSummary)
+	router.GET("/job-data/:job_id", handler.GetJobData)
+
+	w := performRequest(router, http.MethodGet, "/job-data/summary", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total_jobs":3`)
+	assert.Contains(t, w.Body.String(), `"avg_salary":null`)
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "GetJobData")
+}
+
+func TestGetJobDataSchema(t *testing.T) {
+	mockService := new(MockJobDataService)
+	router := gin.New()
+	handler := NewJobDataHandler(mockService)
+	router.GET("/job-data/schema", handler.GetJobDataSchema)
+	router.GET("/job-data/:job_id", handler.GetJobData)
+
+	w := performRequest(router, http.MethodGet, "/job-data/schema", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Fields []models.JobDataField `json:"fields"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	fields := make(map[string]models.JobDataField, len(response.Fields))
+	for _, field := range response.Fields {
+		fields[field.JSONName] = field
+	}
+
+	assert.Equal(t, models.JobDataField{Name: "JobID", JSONName: "jobID", Type: "string", Required: true}, fields["jobID"])
+	assert.Equal(t, models.JobDataField{Name: "MaxSalary", JSONName: "maxSalary", Type: "number", Nullable: true}, fields["maxSalary"])
+	assert.Equal(t, models.JobDataField{Name: "JobRequirements", JSONName: "jobRequirements", Type: "array", Items: "string", Nullable: true}, fields["jobRequirements"])
+	assert.Equal(t, "integer", fields["locationCount"].Type)
+	assert.Equal(t, "boolean", fields["isNewJob"].Type)
+	assert.False(t, fields["companyName"].Required)
+	assert.Equal(t, "timestamp", fields["jobPostedTime"].Type)
+	assert.Equal(t, models.CustomTimeLayouts(), fields["jobPostedTime"].Formats)
+	assert.Contains(t, fields["dateCollected"].Formats, "2006-01-02 15:04:05.999 MST")
+	mockService.AssertNotCalled(t, "GetJobData")
+}
+
+func TestSearchJobData(t *testing.T) {
+	criteria := services.SearchCriteria{Company: "acme", City: "austin"}
+	mockService := new(MockJobDataService)
+	mockService.On("SearchJobs", criteria, 10, 20).
+		Return([]models.JobData{{JobID: "job-1", CompanyName: "Acme Corp"}}, 21, nil)
+
+	router := gin.New()
+	handler := NewJobDataHandler(mockService)
+	router.GET("/job-data/search", handler.SearchJobData)
+	router.GET("/job-data/:job_id", handler.GetJobData)
+
+	w := performRequest(router, http.MethodGet, "/job-data/search?company=acme&city=austin&limit=10&offset=20", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"jobID":"job-1"`)
+	assert.Contains(t, w.Body.String(), `"meta":{"total":21,"limit":10,"offset":20}`)
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "GetJobData")
+}
+
+func TestSearchJobDataErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"no criteria", "/job-data/search", fmt.Errorf("%w: at least one criterion is required", services.ErrValidation), http.StatusBadRequest},
+		{"invalid limit", "/job-data/search?title=engineer&limit=abc", nil, http.StatusBadRequest},
+		{"service failure", "/job-data/search?title=engineer", assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobDataService)
+			if tt.serviceErr != nil {
+				mockService.On("SearchJobs", mock.Anything, DefaultPageLimit, 0).Return([]models.JobData(nil), 0, tt.serviceErr)
+			}
+
+			router := gin.New()
+			router.GET("/job-data/search", NewJobDataHandler(mockService).SearchJobData)
+
+			w := performRequest(router, http.MethodGet, tt.path, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSampleJobData(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(m *MockJobDataService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "size and seed are passed to the service",
+			query: "?n=2&seed=123",
+			setupMock: func(m *MockJobDataService) {
+				m.On("SampleJobs", 2, int64(123)).Return([]models.JobData{{JobID: "job7"}, {JobID: "job2"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"seed":123`,
+		},
+		{
+			name:  "size is capped",
+			query: "?n=100000&seed=1",
+			setupMock: func(m *MockJobDataService) {
+				m.On("SampleJobs", MaxSampleSize, int64(1)).Return([]models.JobData{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"data":[]`,
+		},
+		{
+			name:  "default size with a generated seed",
+			query: "",
+			setupMock: func(m *MockJobDataService) {
+				m.On("SampleJobs", DefaultSampleSize, mock.AnythingOfType("int64")).Return([]models.JobData{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"seed":`,
+		},
+		{
+			name:           "invalid size",
+			query:          "?n=0",
+			setupMock:      func(m *MockJobDataService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid seed",
+			query:          "?seed=abc",
+			setupMock:      func(m *MockJobDataService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobDataService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			handler := NewJobDataHandler(mockService)
+			router.GET("/job-data/sample", handler.SampleJobData)
+			router.GET("/job-data/:job_id", handler.GetJobData)
+
+			w := performRequest(router, http.MethodGet, "/job-data/sample"+tt.query, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetJobDataRange(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(m *MockJobDataService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "valid range",
+			query: "?start=1&end=3",
+			setupMock: func(m *MockJobDataService) {
+				m.On("GetJobsByRowIndexRange", int64(1), int64(3)).
+					Return([]models.JobData{{JobID: "job1"}, {JobID: "job2"}, {JobID: "job3"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"start":1`,
+		},
+		{
+			name:  "empty range",
+			query: "?start=10&end=20",
+			setupMock: func(m *MockJobDataService) {
+				m.On("GetJobsByRowIndexRange", int64(10), int64(20)).Return([]models.JobData{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"data":[]`,
+		},
+		{
+			name:           "invalid range",
+			query:          "?start=5&end=3",
+			setupMock:      func(m *MockJobDataService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "start 5 is after end 3",
+		},
+		{
+			name:           "range too large",
+			query:          "?start=0&end=500",
+			setupMock:      func(m *MockJobDataService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "at most 500 rows",
+		},
+		{
+			name:           "missing end",
+			query:          "?start=0",
+			setupMock:      func(m *MockJobDataService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative start",
+			query:          "?start=-1&end=3",
+			setupMock:      func(m *MockJobDataService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobDataService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			handler := NewJobDataHandler(mockService)
+			router.GET("/job-data/range", handler.GetJobDataRange)
+			router.GET("/job-data/:job_id", handler.GetJobData)
+
+			w := performRequest(router, http.MethodGet, "/job-data/range"+tt.query, "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPatchJobDataStatusCodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		setupMock      func(m *MockJobDataService)
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name: "valid patch",
+			body: `{"city":"Austin"}`,
+			setupMock: func(m *MockJobDataService) {
+				m.On("PatchJobData", "job1", map[string]interface{}{"city": "Austin"}).Return(nil)
+				m.On("GetJobData", "job1").Return(&models.JobData{JobID: "job1", City: "Austin"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "missing job",
+			body: `{"city":"Austin"}`,
+			setupMock: func(m *MockJobDataService) {
+				m.On("PatchJobData", "job1", map[string]interface{}{"city": "Austin"}).
+					Return(fmt.Errorf("job data with ID job1 %w", services.ErrNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   ErrCodeNotFound,
+		},
+		{
+			name: "patch leaves job invalid",
+			body: `{"companyRating":9}`,
+			setupMock: func(m *MockJobDataService) {
+				m.On("PatchJobData", "job1", map[string]interface{}{"companyRating": 9.0}).
+					Return(fmt.Errorf("%w: company_rating 9 must be between 0 and 5", services.ErrValidation))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrCodeValidation,
+		},
+		{
+			name: "wrong field type",
+			body: `{"city":12}`,
+			setupMock: func(m *MockJobDataService) {
+				m.On("PatchJobData", "job1", map[string]interface{}{"city": 12.0}).
+					Return(fmt.Errorf("%w: json: cannot unmarshal number into Go struct field JobData.city of type string", services.ErrValidation))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrCodeValidation,
+		},
+		{
+			name:           "malformed body",
+			body:           `{"city":`,
+			setupMock:      func(m *MockJobDataService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   ErrCodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobDataService)
+			tt.setupMock(mockService)
+
+			router := gin.New()
+			router.PATCH("/jobs/:job_id", NewJobDataHandler(mockService).PatchJobData)
+
+			w := performRequest(router, http.MethodPatch, "/jobs/job1", tt.body)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedCode != "" {
+				assert.Contains(t, w.Body.String(), `"code":"`+tt.expectedCode+`"`)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteJobDataStatusCodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "deleted", expectedStatus: http.StatusNoContent},
+		{
+			name:           "missing job",
+			serviceErr:     fmt.Errorf("job data with ID job1 %w", services.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobDataService)
+			mockService.On("DeleteJobData", "job1").Return(tt.serviceErr)
+
+			router := gin.New()
+			router.DELETE("/jobs/:job_id", NewJobDataHandler(mockService).DeleteJobData)
+
+			w := performRequest(router, http.MethodDelete, "/jobs/job1", "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/ainesh01/anomaly_detection/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// MockJobDataService is a mock implementation of services.JobDataServiceInterface
+type MockJobDataService struct {
+	mock.Mock
+}
+
+func (m *MockJobDataService) CreateJobData(ctx context.Context, job *models.JobData) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockJobDataService) CreateJobDataIdempotent(ctx context.Context, key string, job *models.JobData) (*models.JobData, bool, error) {
+	args := m.Called(key, job)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*models.JobData), args.Bool(1), args.Error(2)
+}
+
+func (m *MockJobDataService) GetJobData(ctx context.Context, jobID string) (*models.JobData, error) {
+	args := m.Called(jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.JobData), args.Error(1)
+}
+
+func (m *MockJobDataService) GetAllJobData(ctx context.Context) ([]models.JobData, error) {
+	args := m.Called()
+	return args.Get(0).([]models.JobData), args.Error(1)
+}
+
+func (m *MockJobDataService) GetJobsByRowIndexRange(ctx context.Context, start, end int64) ([]models.JobData, error) {
+	args := m.Called(start, end)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.JobData), args.Error(1)
+}
+
+func (m *MockJobDataService) GetAllJobDataPaged(ctx context.Context, limit, offset int) ([]models.JobData, int, error) {
+	args := m.Called(limit, offset)
+	return args.Get(0).([]models.JobData), args.Int(1), args.Error(2)
+}
+
+func (m *MockJobDataService) SampleJobs(ctx context.Context, n int, seed int64) ([]models.JobData, error) {
+	args := m.Called(n, seed)
+	return args.Get(0).([]models.JobData), args.Error(1)
+}
+
+func (m *MockJobDataService) SearchJobs(ctx context.Context, criteria services.SearchCriteria, limit, offset int) ([]models.JobData, int, error) {
+	args := m.Called(criteria, limit, offset)
+	return args.Get(0).([]models.JobData), args.Int(1), args.Error(2)
+}
+
+func (m *MockJobDataService) CreateJobDataBatch(ctx context.Context, jobs []models.JobData, mode services.UpsertMode) error {
+	args := m.Called(jobs, mode)
+	return args.Error(0)
+}
+
+func (m *MockJobDataService) PatchJobData(ctx context.Context, jobID string, fields map[string]interface{}) error {
+	args := m.Called(jobID, fields)
+	return args.Error(0)
+}
+
+func (m *MockJobDataService) DeleteJobData(ctx context.Context, jobID string) error {
+	args := m.Called(jobID)
+	return args.Error(0)
+}
+
+func (m *MockJobDataService) GetSummary(ctx context.Context) (*models.JobSummary, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.JobSummary), args.Error(1)
+}
+
+// MockAnomalyRuleService is a mock implementation of services.AnomalyRuleServiceInterface
+type MockAnomalyRuleService struct {
+	mock.Mock
+}
+
+func (m *MockAnomalyRuleService) GetAnomalyRules(ctx context.Context, sort services.SortOptions, tag string) ([]models.AnomalyRule, error) {
+	args := m.Called(sort, tag)
+	return args.Get(0).([]models.AnomalyRule), args.Error(1)
+}
+
+func (m *MockAnomalyRuleService) GetAnomalyRule(ctx context.Context, id int64) (*models.AnomalyRule, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AnomalyRule), args.Error(1)
+}
+
+func (m *MockAnomalyRuleService) CreateAnomalyRule(ctx context.Context, rule *models.AnomalyRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *MockAnomalyRuleService) UpdateAnomalyRule(ctx context.Context, rule *models.AnomalyRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *MockAnomalyRuleService) DeleteAnomalyRule(ctx context.Context, id int64, actor string) error {
+	args := m.Called(id, actor)
+	return args.Error(0)
+}
+
+func (m *MockAnomalyRuleService) ToggleAnomalyRule(ctx context.Context, id int64, isActive bool, actor string) error {
+	args := m.Called(id, isActive, actor)
+	return args.Error(0)
+}
+
+func (m *MockAnomalyRuleService) ToggleRulesBulk(ctx context.Context, filter services.RuleFilter, isActive bool, actor string) (int, error) {
+	args := m.Called(filter, isActive, actor)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAnomalyRuleService) GetRuleHistory(ctx context.Context, ruleID int64) ([]models.RuleAuditEntry, error) {
+	args := m.Called(ruleID)
+	return args.Get(0).([]models.RuleAuditEntry), args.Error(1)
+}
+
+func (m *MockAnomalyRuleService) ExportAnomalyRules(ctx context.Context) (*models.RuleExport, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RuleExport), args.Error(1)
+}
+
+func (m *MockAnomalyRuleService) ImportAnomalyRules(ctx context.Context, export *models.RuleExport, actor string) (*models.RuleImportResult, error) {
+	args := m.Called(export, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RuleImportResult), args.Error(1)
+}
+
+// MockAnomalyService is a mock implementation of services.AnomalyServiceInterface
+type MockAnomalyService struct {
+	mock.Mock
+}
+
+func (m *MockAnomalyService) DetectAnomalies(ctx context.Context, job *models.JobData) ([]models.Anomaly, error) {
+	args := m.Called(job)
+	return args.Get(0).([]models.Anomaly), args.Error(1)
+}
+
+func (m *MockAnomalyService) DetectionConfig() *config.DetectionConfig {
+	args := m.Called()
+	return args.Get(0).(*config.DetectionConfig)
+}
+
+func (m *MockAnomalyService) GetAnomaliesByJobID(ctx context.Context, jobID string, includeResolved bool) ([]models.Anomaly, error) {
+	args := m.Called(jobID, includeResolved)
+	return args.Get(0).([]models.Anomaly), args.Error(1)
+}
+
+func (m *MockAnomalyService) GetAnomalyByID(ctx context.Context, id int64) (*models.Anomaly, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Anomaly), args.Error(1)
+}
+
+func (m *MockAnomalyService) GetAllAnomalies(ctx context.Context, includeResolved bool) ([]models.Anomaly, error) {
+	args := m.Called(includeResolved)
+	return args.Get(0).([]models.Anomaly), args.Error(1)
+}
+
+func (m *MockAnomalyService) GetAllAnomaliesPaged(ctx context.Context, limit, offset int, sort services.SortOptions, filter services.AnomalyFilter) ([]models.Anomaly, int, error) {
+	args := m.Called(limit, offset, sort, filter)
+	return args.Get(0).([]models.Anomaly), args.Int(1), args.Error(2)
+}
+
+func (m *MockAnomalyService) GetDetailedAnomaliesPaged(ctx context.Context, limit, offset int, sort services.SortOptions, filter services.AnomalyFilter) ([]models.DetailedAnomaly, int, error) {
+	args := m.Called(limit, offset, sort, filter)
+	return args.Get(0).([]models.DetailedAnomaly), args.Int(1), args.Error(2)
+}
+
+func (m *MockAnomalyService) GetAnomaliesByCompany(ctx context.Context, limit, offset int, sort services.SortOptions, includeResolved bool) ([]models.CompanyAnomalies, int, error) {
+	args := m.Called(limit, offset, sort, includeResolved)
+	return args.Get(0).([]models.CompanyAnomalies), args.Int(1), args.Error(2)
+}
+
+func (m *MockAnomalyService) GetJobRiskScore(ctx context.Context, jobID string) (float64, error) {
+	args := m.Called(jobID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockAnomalyService) GetJobsByRiskScore(ctx context.Context, limit, offset int) ([]models.JobRiskScore, int, error) {
+	args := m.Called(limit, offset)
+	return args.Get(0).([]models.JobRiskScore), args.Int(1), args.Error(2)
+}
+
+func (m *MockAnomalyService) GetDetectionRuns(ctx context.Context, limit, offset int) ([]models.DetectionRun, int, error) {
+	args := m.Called(limit, offset)
+	return args.Get(0).([]models.DetectionRun), args.Int(1), args.Error(2)
+}
+
+func (m *MockAnomalyService) EvaluateRule(ctx context.Context, ruleID int64, jobs []models.JobData) ([]models.RuleEvaluation, error) {
+	args := m.Called(ruleID, jobs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RuleEvaluation), args.Error(1)
+}
+
+func (m *MockAnomalyService) ResolveAnomaly(ctx context.Context, id int64, note string) error {
+	args := m.Called(id, note)
+	return args.Error(0)
+}
+
+func (m *MockAnomalyService) AcknowledgeAnomaly(ctx context.Context, id int64, assignee string) error {
+	args := m.Called(id, assignee)
+	return args.Error(0)
+}
+
+func (m *MockAnomalyService) AddAnomalyNote(ctx context.Context, anomalyID int64, author, body string) (*models.AnomalyNote, error) {
+	args := m.Called(anomalyID, author, body)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AnomalyNote), args.Error(1)
+}
+
+func (m *MockAnomalyService) GetAnomalyNotes(ctx context.Context, anomalyID int64) ([]models.AnomalyNote, error) {
+	args := m.Called(anomalyID)
+	return args.Get(0).([]models.AnomalyNote), args.Error(1)
+}
+
+func (m *MockAnomalyService) DeleteAnomalies(ctx context.Context, filter services.AnomalyFilter) (int, error) {
+	args := m.Called(filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAnomalyService) DetectAnomaliesForAllJobs(ctx context.Context, dryRun bool) ([]models.Anomaly, error) {
+	args := m.Called(dryRun)
+	return args.Get(0).([]models.Anomaly), args.Error(1)
+}
+
+func (m *MockAnomalyService) DetectAnomaliesSince(ctx context.Context, since time.Time) (int, error) {
+	args := m.Called(since)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAnomalyService) DetectDuplicates(ctx context.Context) ([]models.Anomaly, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Anomaly), args.Error(1)
+}
+
+func (m *MockAnomalyService) GetAnomalyStats(ctx context.Context, window time.Duration) (*models.AnomalyStats, error) {
+	args := m.Called(window)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AnomalyStats), args.Error(1)
+}
+
+func (m *MockAnomalyService) GetAnomalyTypeCounts(ctx context.Context) (map[string]int, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+// performRequest serves a single request against the router and returns the recorded response
+func performRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
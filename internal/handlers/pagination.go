@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ainesh01/anomaly_detection/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultPageLimit is the page size used when no limit is supplied
+	DefaultPageLimit = 50
+	// MaxPageLimit is the largest page size a client may request
+	MaxPageLimit = 500
+)
+
+// ListMeta describes the page of results carried by a list response
+type ListMeta struct {
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// ListResponse is the envelope every list endpoint responds with
+type ListResponse[T any] struct {
+	Data []T      `json:"data"`
+	Meta ListMeta `json:"meta"`
+}
+
+// respondList writes a page of results in the list envelope. A nil page is
+// sent as an empty array rather than null. The fields and pretty query
+// parameters trim each result to the named fields and indent the output.
+func respondList[T any](c *gin.Context, data []T, total, limit, offset int) {
+	if data == nil {
+		data = []T{}
+	}
+	meta := ListMeta{Total: total, Limit: limit, Offset: offset}
+
+	fields := parseFields(c)
+	if len(fields) == 0 {
+		respondJSON(c, http.StatusOK, ListResponse[T]{Data: data, Meta: meta})
+		return
+	}
+
+	projected, err := projectFields(data, fields)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, ListResponse[map[string]json.RawMessage]{Data: projected, Meta: meta})
+}
+
+// parsePagination reads the limit and offset query parameters, applying defaults
+// and capping the limit at MaxPageLimit. Negative or non-numeric values are rejected.
+func parsePagination(c *gin.Context) (int, int, error) {
+	limit := DefaultPageLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("invalid limit %q: must be a non-negative integer", raw)
+		}
+		limit = parsed
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	offset := 0
+	if raw := c.Query("offset"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q: must be a non-negative integer", raw)
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
+
+// parseSort reads the sort and order query parameters. The sort column is
+// checked against each listing's allow-list by the service; only the order
+// is validated here. Missing parameters keep the listing's default ordering.
+func parseSort(c *gin.Context) (services.SortOptions, error) {
+	opts := services.SortOptions{
+		Column: c.Query("sort"),
+		Order:  services.SortOrder(strings.ToLower(c.Query("order"))),
+	}
+	switch opts.Order {
+	case "", services.SortAscending, services.SortDescending:
+		return opts, nil
+	default:
+		return services.SortOptions{}, fmt.Errorf("invalid order %q: must be %q or %q", c.Query("order"), services.SortAscending, services.SortDescending)
+	}
+}
+
+// parseIncludeResolved reads the include_resolved query parameter, which
+// defaults to false so resolved anomalies stay hidden from listings
+func parseIncludeResolved(c *gin.Context) (bool, error) {
+	raw := c.DefaultQuery("include_resolved", "false")
+	includeResolved, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid include_resolved %q: must be true or false", raw)
+	}
+	return includeResolved, nil
+}
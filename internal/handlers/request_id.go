@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"github.com/ainesh01/anomaly_detection/internal/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRequestIDLength caps client-supplied request IDs so they cannot bloat logs
+const maxRequestIDLength = 128
+
+// RequestID returns middleware that tags every request with an ID, taken from
+// the X-Request-ID header when the client sends a usable one and generated
+// otherwise. The ID is stored on the request context, so context-aware log
+// calls include it, and echoed back in the X-Request-ID response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !isValidRequestID(id) {
+			id = requestid.New()
+		}
+
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+		c.Next()
+	}
+}
+
+// isValidRequestID accepts non-empty IDs of letters, digits, and - _ . : only,
+// which keeps arbitrary client input out of logs and response headers
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
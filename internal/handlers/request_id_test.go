@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		expectedID  string
+		expectFresh bool
+	}{
+		{name: "client ID is echoed", header: "client-req-42", expectedID: "client-req-42"},
+		{name: "missing ID is generated", expectFresh: true},
+		{name: "unsafe ID is replaced", header: "bad id\r\ninjected", expectFresh: true},
+		{name: "oversized ID is replaced", header: strings.Repeat("a", maxRequestIDLength+1), expectFresh: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextID string
+			router := gin.New()
+			router.Use(RequestID())
+			router.GET("/missing", func(c *gin.Context) {
+				contextID = requestid.FromContext(c.Request.Context())
+				respondError(c, http.StatusNotFound, ErrCodeNotFound, "resource not found")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(requestid.Header)
+			if tt.expectFresh {
+				assert.NotEmpty(t, id)
+				assert.NotEqual(t, tt.header, id)
+			} else {
+				assert.Equal(t, tt.expectedID, id)
+			}
+			assert.Equal(t, id, contextID)
+			assert.Contains(t, w.Body.String(), `"request_id":"`+id+`"`)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseFields reads the comma-separated fields query parameter naming the JSON
+// fields a list response should keep. It returns nil when every field is wanted.
+func parseFields(c *gin.Context) []string {
+	var fields []string
+	for _, field := range strings.Split(c.Query("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// prettyRequested reports whether the pretty query parameter asks for indented output
+func prettyRequested(c *gin.Context) bool {
+	pretty, err := strconv.ParseBool(c.Query("pretty"))
+	return err == nil && pretty
+}
+
+// projectFields encodes each item as a JSON object holding only the given top-level
+// fields. Names that do not match a field of the item are ignored.
+func projectFields[T any](items []T, fields []string) ([]map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &objects); err != nil {
+		return nil, err
+	}
+
+	projected := make([]map[string]json.RawMessage, len(objects))
+	for i, object := range objects {
+		projected[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := object[field]; ok {
+				projected[i][field] = value
+			}
+		}
+	}
+	return projected, nil
+}
+
+// respondJSON writes body as JSON, indented when the request asks for pretty output
+func respondJSON(c *gin.Context, status int, body any) {
+	if prettyRequested(c) {
+		c.IndentedJSON(status, body)
+		return
+	}
+	c.JSON(status, body)
+}
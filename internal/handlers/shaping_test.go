@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newShapingRouter(jobs []models.JobData) *gin.Engine {
+	mockService := new(MockJobDataService)
+	mockService.On("GetAllJobDataPaged", DefaultPageLimit, 0).Return(jobs, len(jobs), nil)
+
+	router := gin.New()
+	router.GET("/job-data", NewJobDataHandler(mockService).GetAllJobData)
+	return router
+}
+
+func TestListFieldProjection(t *testing.T) {
+	router := newShapingRouter([]models.JobData{
+		{JobID: "job1", CompanyName: "Tech Corp", City: "Austin"},
+		{JobID: "job2", CompanyName: "Data Inc", City: "Denver"},
+	})
+
+	// Unknown field names are ignored and the meta block is left intact
+	w := performRequest(router, http.MethodGet, "/job-data?fields=jobID,%20companyName,,favouriteColour", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"data": [
+			{"jobID": "job1", "companyName": "Tech Corp"},
+			{"jobID": "job2", "companyName": "Data Inc"}
+		],
+		"meta": {"total": 2, "limit": 50, "offset": 0}
+	}`, w.Body.String())
+}
+
+func TestListFieldProjectionEmptyPage(t *testing.T) {
+	router := newShapingRouter(nil)
+
+	w := performRequest(router, http.MethodGet, "/job-data?fields=jobID", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[],"meta":{"total":0,"limit":50,"offset":0}}`, w.Body.String())
+}
+
+func TestListPrettyOutput(t *testing.T) {
+	router := newShapingRouter([]models.JobData{{JobID: "job1", CompanyName: "Tech Corp"}})
+
+	w := performRequest(router, http.MethodGet, "/job-data?pretty=true&fields=jobID", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{
+    "data": [
+        {
+            "jobID": "job1"
+        }
+    ],
+    "meta": {
+        "total": 1,
+        "limit": 50,
+        "offset": 0
+    }
+}`, w.Body.String())
+
+	// Without pretty, or with a value that is not true, the output stays compact
+	for _, query := range []string{"", "?pretty=false", "?pretty=yes"} {
+		w = performRequest(router, http.MethodGet, "/job-data"+query, "")
+		assert.NotContains(t, w.Body.String(), "\n", query)
+	}
+}
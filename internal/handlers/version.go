@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ainesh01/anomaly_detection/internal/buildinfo"
+	"github.com/gin-gonic/gin"
+)
+
+// GetVersion handles GET requests for the commit, build time, and Go version of
+// the running server, so a deployment can be checked against the expected build
+func GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
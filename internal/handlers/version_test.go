@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetVersion(t *testing.T) {
+	router := gin.New()
+	router.GET("/version", GetVersion)
+
+	w := performRequest(router, http.MethodGet, "/version", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	// Test builds are not linked with -ldflags, so the commit and build time are placeholders
+	assert.Equal(t, map[string]string{
+		"commit":     "unknown",
+		"build_time": "unknown",
+		"go_version": runtime.Version(),
+	}, body)
+}
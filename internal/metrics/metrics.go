@@ -0,0 +1,29 @@
+// Package metrics defines the Prometheus collectors exposed by the anomaly detection service.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// AnomaliesDetected counts newly stored anomalies by type and severity; updates
+	// of anomalies found again by later runs are not counted
+	AnomaliesDetected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "anomalies_detected_total",
+		Help: "Total number of new anomalies detected, by anomaly type and severity.",
+	}, []string{"type", "severity"})
+
+	// DetectionRuns counts full detection runs over all jobs
+	DetectionRuns = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "detection_runs_total",
+		Help: "Total number of detection runs over all jobs.",
+	})
+
+	// DetectionDuration observes how long full detection runs take
+	DetectionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "detection_duration_seconds",
+		Help:    "Duration of detection runs over all jobs in seconds.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+	})
+)
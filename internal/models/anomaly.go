@@ -1,17 +1,31 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 type AnomalyType string
 type ComparisonOperator string
 
 const (
 	// Simple predefined check types
-	AnomalyTypeMaxSalary  AnomalyType = "max_salary"         // For max salary threshold checks
-	AnomalyTypeMinSalary  AnomalyType = "min_salary"         // For min salary threshold checks
-	AnomalyTypeRating     AnomalyType = "company_rating"     // For company rating checks
-	AnomalyTypeNullValues AnomalyType = "null_values"        // For null value checks
-	AnomalyTypeDeviation  AnomalyType = "standard_deviation" // For standard deviation checks
+	AnomalyTypeMaxSalary    AnomalyType = "max_salary"           // For max salary threshold checks
+	AnomalyTypeMinSalary    AnomalyType = "min_salary"           // For min salary threshold checks
+	AnomalyTypeRating       AnomalyType = "company_rating"       // For company rating checks
+	AnomalyTypeNullValues   AnomalyType = "null_values"          // For null value checks
+	AnomalyTypeDeviation    AnomalyType = "standard_deviation"   // For standard deviation checks
+	AnomalyTypeIQR          AnomalyType = "iqr_outlier"          // For interquartile range outlier checks
+	AnomalyTypeCompound     AnomalyType = "compound"             // For rules combining several conditions
+	AnomalyTypeSalaryRange  AnomalyType = "salary_range"         // For listings whose min salary exceeds the max salary
+	AnomalyTypeTrend        AnomalyType = "salary_trend"         // For salaries that deviate from the recent rolling average
+	AnomalyTypeMAD          AnomalyType = "mad_outlier"          // For salaries whose modified z-score exceeds the MAD cutoff
+	AnomalyTypeGeoOutlier   AnomalyType = "geo_outlier"          // For coordinates far outside the usual cluster of job locations
+	AnomalyTypeDuplicate    AnomalyType = "duplicate_listing"    // For jobs reposted under a different job ID
+	AnomalyTypeText         AnomalyType = "text_match"           // For rules matching a text field against a substring or pattern
+	AnomalyTypeMultivariate AnomalyType = "multivariate_outlier" // For salary and rating pairs that are unusual together
+	AnomalyTypeStale        AnomalyType = "stale_posting"        // For jobs collected long after they were posted
 
 	// Operators
 	GreaterThan        ComparisonOperator = ">"
@@ -19,8 +33,48 @@ const (
 	LessThan           ComparisonOperator = "<"
 	LessThanOrEqual    ComparisonOperator = "<="
 	Equal              ComparisonOperator = "="
+	Between            ComparisonOperator = "BETWEEN"     // Value <= field <= ValueHigh
+	NotBetween         ComparisonOperator = "NOT BETWEEN" // Field < Value or field > ValueHigh
+
+	// Text operators, comparing a text field with TextValue
+	Contains    ComparisonOperator = "contains"     // Field contains TextValue, ignoring case
+	NotContains ComparisonOperator = "not_contains" // Field does not contain TextValue, ignoring case
+	Matches     ComparisonOperator = "matches"      // Field matches the regular expression TextValue
+	NotMatches  ComparisonOperator = "not_matches"  // Field does not match the regular expression TextValue
+
+	// Severity levels
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+
+	// Anomaly statuses
+	AnomalyStatusOpen     = "open"
+	AnomalyStatusResolved = "resolved"
 )
 
+// IsValidSeverity checks if the given severity is one of the known severity levels
+func IsValidSeverity(severity string) bool {
+	return SeverityRank(severity) > 0
+}
+
+// SeverityRank orders severity levels from low (1) to critical (4).
+// Unknown severities have a rank of 0.
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	default:
+		return 0
+	}
+}
+
 // Anomaly represents a detected anomaly
 type Anomaly struct {
 	ID          string             `json:"id"`
@@ -32,6 +86,13 @@ type Anomaly struct {
 	Operator    ComparisonOperator `json:"operator"`
 	CreatedAt   time.Time          `json:"created_at"`
 	Violations  []string           `json:"violations"` // List of fields that violated the rule
+	Severity    string             `json:"severity"`   // Severity of the anomaly
+
+	Status         string     `json:"status"`                    // open or resolved
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`     // When the anomaly was dismissed
+	ResolutionNote string     `json:"resolution_note,omitempty"` // Why the anomaly was dismissed
+	Assignee       string     `json:"assignee,omitempty"`        // Who is triaging the anomaly
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"` // When the anomaly was last acknowledged
 }
 
 // AnomalyRule represents a simple predefined check rule
@@ -39,12 +100,90 @@ type AnomalyRule struct {
 	ID          int64              `json:"id" db:"id"`
 	Name        string             `json:"name" db:"name"`
 	Description string             `json:"description" db:"description"`
-	Type        AnomalyType        `json:"type" db:"type"`           // Type of check (salary, rating)
-	Operator    ComparisonOperator `json:"operator" db:"operator"`   // The comparison operator
-	Value       float64            `json:"value" db:"value"`         // The threshold value
-	IsActive    bool               `json:"is_active" db:"is_active"` // Whether the rule is active
+	Type        AnomalyType        `json:"type" db:"type"`             // Type of check (salary, rating)
+	Operator    ComparisonOperator `json:"operator" db:"operator"`     // The comparison operator
+	Value       float64            `json:"value" db:"value"`           // The threshold value, or the lower bound of a range
+	ValueHigh   float64            `json:"value_high" db:"value_high"` // The upper bound for range operators
+	ValueMode   RuleValueMode      `json:"value_mode" db:"value_mode"` // Whether Value is absolute or a percentile
+	Field       string             `json:"field" db:"field"`           // The text field compared by text operators
+	TextValue   string             `json:"text_value" db:"text_value"` // The substring or pattern for text operators
+	IsActive    bool               `json:"is_active" db:"is_active"`   // Whether the rule is active
+	Severity    string             `json:"severity" db:"severity"`     // Severity assigned to anomalies from this rule
+	Logic       RuleLogic          `json:"logic" db:"logic"`           // How Conditions are combined ("and"/"or")
+	Conditions  RuleConditions     `json:"conditions,omitempty" db:"conditions"`
+	Tags        StringSlice        `json:"tags" db:"tags"` // Labels used to group rules, stored lowercase
 	CreatedAt   time.Time          `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" db:"updated_at"`
+	CreatedBy   string             `json:"created_by" db:"created_by"` // Who created the rule, empty when unknown
+	UpdatedBy   string             `json:"updated_by" db:"updated_by"` // Who last changed the rule, empty when unknown
+
+	Warnings []string `json:"warnings,omitempty" db:"-"` // Suspicious settings found when the rule was created, not stored
+}
+
+// IsRange reports whether the operator compares against a Value to ValueHigh range
+func (o ComparisonOperator) IsRange() bool {
+	return o == Between || o == NotBetween
+}
+
+// IsText reports whether the operator compares a text field with a TextValue
+func (o ComparisonOperator) IsText() bool {
+	return o == Contains || o == NotContains || o.IsPattern()
+}
+
+// IsPattern reports whether the operator treats TextValue as a regular expression
+func (o ComparisonOperator) IsPattern() bool {
+	return o == Matches || o == NotMatches
+}
+
+// IsValid reports whether the type is one of the known anomaly types
+func (t AnomalyType) IsValid() bool {
+	switch t {
+	case AnomalyTypeMaxSalary, AnomalyTypeMinSalary, AnomalyTypeRating, AnomalyTypeNullValues,
+		AnomalyTypeDeviation, AnomalyTypeIQR, AnomalyTypeCompound, AnomalyTypeSalaryRange,
+		AnomalyTypeTrend, AnomalyTypeMAD, AnomalyTypeGeoOutlier, AnomalyTypeDuplicate, AnomalyTypeText,
+		AnomalyTypeMultivariate, AnomalyTypeStale:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, rejecting unknown
+// types so a misspelled rule type fails when the request is decoded. An empty
+// string is accepted and left to validation, as rules may omit it.
+func (t *AnomalyType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("anomaly type must be a string, got %s", data)
+	}
+	if s != "" && !AnomalyType(s).IsValid() {
+		return fmt.Errorf("unknown anomaly type %q", s)
+	}
+	*t = AnomalyType(s)
+	return nil
+}
+
+// IsValid reports whether the operator is one of the known comparison or text operators
+func (o ComparisonOperator) IsValid() bool {
+	switch o {
+	case GreaterThan, GreaterThanOrEqual, LessThan, LessThanOrEqual, Equal, Between, NotBetween:
+		return true
+	}
+	return o.IsText()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, rejecting unknown
+// operators so a typo fails when the request is decoded. An empty string is
+// accepted and left to validation, as compound rules have no operator.
+func (o *ComparisonOperator) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("comparison operator must be a string, got %s", data)
+	}
+	if s != "" && !ComparisonOperator(s).IsValid() {
+		return fmt.Errorf("unknown comparison operator %q", s)
+	}
+	*o = ComparisonOperator(s)
+	return nil
 }
 
 // TableName returns the table name for the AnomalyRule model
@@ -52,6 +191,16 @@ func (AnomalyRule) TableName() string {
 	return "anomaly_rules"
 }
 
+// EffectiveConditions returns the conditions the rule should be evaluated against.
+// Rules created before compound conditions existed only carry Type/Operator/Value,
+// so they are treated as a single condition.
+func (r AnomalyRule) EffectiveConditions() []RuleCondition {
+	if len(r.Conditions) > 0 {
+		return r.Conditions
+	}
+	return []RuleCondition{{Type: r.Type, Operator: r.Operator, Value: r.Value, ValueHigh: r.ValueHigh, Field: r.Field, TextValue: r.TextValue}}
+}
+
 // AnomalyRuleRequest represents the data needed to create or update a rule
 type AnomalyRuleRequest struct {
 	Name        string             `json:"name" binding:"required"`
@@ -59,5 +208,12 @@ type AnomalyRuleRequest struct {
 	Type        AnomalyType        `json:"type" binding:"required"`
 	Operator    ComparisonOperator `json:"operator" binding:"required"`
 	Value       float64            `json:"value" binding:"required"`
+	ValueHigh   float64            `json:"value_high"`
+	ValueMode   RuleValueMode      `json:"value_mode"`
+	Field       string             `json:"field"`
+	TextValue   string             `json:"text_value"`
 	IsActive    bool               `json:"is_active"`
+	Severity    string             `json:"severity"`
+	Logic       RuleLogic          `json:"logic"`
+	Conditions  RuleConditions     `json:"conditions"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AnomalyNote is a free-text note left on an anomaly during triage
+type AnomalyNote struct {
+	ID        int64     `json:"id" db:"id"`
+	AnomalyID int64     `json:"anomaly_id" db:"anomaly_id"`
+	Author    string    `json:"author" db:"author"` // Who wrote the note, empty when unknown
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// TableName returns the table name for the AnomalyNote model
+func (AnomalyNote) TableName() string {
+	return "anomaly_notes"
+}
@@ -0,0 +1,10 @@
+package models
+
+// AnomalyNotification is the JSON payload of the Postgres NOTIFY sent when a new
+// anomaly is stored, letting listeners fetch the anomaly or its job
+type AnomalyNotification struct {
+	ID       string      `json:"id"`
+	JobID    string      `json:"job_id"`
+	Type     AnomalyType `json:"type"`
+	Severity string      `json:"severity"`
+}
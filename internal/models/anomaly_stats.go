@@ -0,0 +1,15 @@
+package models
+
+// AnomalyStats holds aggregate anomaly counts for dashboards
+type AnomalyStats struct {
+	Total      int                 `json:"total"`
+	ByType     map[AnomalyType]int `json:"by_type"`
+	BySeverity map[string]int      `json:"by_severity"`
+	Daily      []DailyAnomalyCount `json:"daily"` // Days within the requested window that have anomalies, oldest first
+}
+
+// DailyAnomalyCount is the number of anomalies detected on a single day
+type DailyAnomalyCount struct {
+	Date  string `json:"date"` // YYYY-MM-DD in UTC
+	Count int    `json:"count"`
+}
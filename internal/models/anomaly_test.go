@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnomalyTypeIsValid(t *testing.T) {
+	assert.True(t, AnomalyTypeMaxSalary.IsValid())
+	assert.True(t, AnomalyTypeText.IsValid())
+	assert.False(t, AnomalyType("salery").IsValid())
+	assert.False(t, AnomalyType("").IsValid())
+}
+
+func TestComparisonOperatorIsValid(t *testing.T) {
+	assert.True(t, GreaterThan.IsValid())
+	assert.True(t, NotBetween.IsValid())
+	assert.True(t, NotMatches.IsValid())
+	assert.False(t, ComparisonOperator("=>").IsValid())
+	assert.False(t, ComparisonOperator("").IsValid())
+}
+
+func TestAnomalyRuleEnumsRoundTrip(t *testing.T) {
+	rule := AnomalyRule{
+		Name:       "Odd pay",
+		Type:       AnomalyTypeCompound,
+		Logic:      RuleLogicOr,
+		Conditions: RuleConditions{{Type: AnomalyTypeMinSalary, Operator: Between, Value: 0, ValueHigh: 1}},
+	}
+	encoded, err := json.Marshal(rule)
+	assert.NoError(t, err)
+
+	// The compound rule's empty operator decodes like any other value
+	var decoded AnomalyRule
+	assert.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, rule.Type, decoded.Type)
+	assert.Equal(t, ComparisonOperator(""), decoded.Operator)
+	assert.Equal(t, rule.Conditions, decoded.Conditions)
+}
+
+func TestAnomalyRuleEnumsRejectUnknownValues(t *testing.T) {
+	var rule AnomalyRule
+	err := json.Unmarshal([]byte(`{"type":"salery","operator":">"}`), &rule)
+	assert.ErrorContains(t, err, `unknown anomaly type "salery"`)
+
+	err = json.Unmarshal([]byte(`{"type":"max_salary","operator":"=>"}`), &rule)
+	assert.ErrorContains(t, err, `unknown comparison operator "=>"`)
+}
@@ -0,0 +1,8 @@
+package models
+
+// CompanyAnomalies summarizes the anomalies detected across one company's job listings
+type CompanyAnomalies struct {
+	CompanyName  string        `json:"company_name"`
+	AnomalyCount int           `json:"anomaly_count"`
+	AnomalyTypes []AnomalyType `json:"anomaly_types"` // Distinct anomaly types, alphabetically
+}
@@ -0,0 +1,16 @@
+package models
+
+// DetailedAnomaly is an anomaly together with the key fields of the job it was
+// detected on, so clients can list anomalies without fetching each job
+type DetailedAnomaly struct {
+	Anomaly
+	Job AnomalyJob `json:"job"`
+}
+
+// AnomalyJob holds the job fields embedded in a DetailedAnomaly
+type AnomalyJob struct {
+	CompanyName string   `json:"company_name"`
+	JobTitle    string   `json:"job_title"`
+	City        string   `json:"city"`
+	MaxSalary   *float64 `json:"max_salary"`
+}
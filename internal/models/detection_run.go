@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Detection run statuses
+const (
+	DetectionRunRunning   = "running"
+	DetectionRunSucceeded = "succeeded"
+	DetectionRunFailed    = "failed"
+)
+
+// DetectionRun records one run of detection over all jobs
+type DetectionRun struct {
+	ID             int64      `json:"id" db:"id"`
+	Status         string     `json:"status" db:"status"`
+	DryRun         bool       `json:"dry_run" db:"dry_run"`
+	StartedAt      time.Time  `json:"started_at" db:"started_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty" db:"completed_at"` // Nil while the run is in progress
+	JobsProcessed  int        `json:"jobs_processed" db:"jobs_processed"`
+	AnomaliesFound int        `json:"anomalies_found" db:"anomalies_found"`
+	Error          *string    `json:"error,omitempty" db:"error"`
+}
+
+// TableName returns the table name for the DetectionRun model
+func (DetectionRun) TableName() string {
+	return "anomaly_run_history"
+}
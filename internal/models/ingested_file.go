@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IngestedFile records a file whose jobs were loaded into the database, keyed
+// by a hash of its content so a copy under another name is recognized too
+type IngestedFile struct {
+	ContentHash string    `json:"content_hash" db:"content_hash"` // Hex-encoded SHA-256 of the file's bytes
+	Path        string    `json:"path" db:"path"`                 // Path the file was last ingested from
+	Jobs        int       `json:"jobs" db:"jobs"`                 // Number of jobs read from the file
+	IngestedAt  time.Time `json:"ingested_at" db:"ingested_at"`
+}
+
+// TableName returns the table name for the IngestedFile model
+func (IngestedFile) TableName() string {
+	return "ingested_files"
+}
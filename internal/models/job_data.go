@@ -4,6 +4,9 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,32 +15,66 @@ type CustomTime struct {
 	time.Time
 }
 
-// Value implements the driver.Valuer interface
+// customTimeLayouts are the timestamp formats CustomTime accepts, tried in order
+var customTimeLayouts = []string{
+	time.RFC3339,                  // "2006-01-02T15:04:05Z07:00"
+	"2006-01-02 15:04:05.999 MST", // "2025-03-23 01:43:50.322 UTC"
+	"2006-01-02 15:04:05 MST",     // "2025-03-23 01:43:50 UTC"
+	"2006-01-02 15:04:05.999",     // "2025-03-23 01:43:50.322"
+	"2006-01-02 15:04:05",         // "2025-03-23 01:43:50"
+}
+
+// parseCustomTime parses s with the first of customTimeLayouts that fits.
+// An empty string is the zero time.
+func parseCustomTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	var lastErr error
+	for _, layout := range customTimeLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+		lastErr = err
+	}
+	return time.Time{}, fmt.Errorf("could not parse time %q with any known format: %v", s, lastErr)
+}
+
+// Value implements the driver.Valuer interface. The zero time is stored as NULL.
 func (ct CustomTime) Value() (driver.Value, error) {
+	if ct.Time.IsZero() {
+		return nil, nil
+	}
 	return ct.Time, nil
 }
 
-// Scan implements the sql.Scanner interface
+// Scan implements the sql.Scanner interface. Besides time.Time it accepts the
+// text forms, as string or []byte, in any of the layouts UnmarshalJSON accepts.
 func (ct *CustomTime) Scan(value interface{}) error {
-	if value == nil {
+	var s string
+	switch v := value.(type) {
+	case nil:
 		ct.Time = time.Time{}
 		return nil
-	}
-
-	switch v := value.(type) {
 	case time.Time:
 		ct.Time = v
 		return nil
 	case string:
-		t, err := time.Parse(time.RFC3339, v)
-		if err != nil {
-			return err
-		}
-		ct.Time = t
-		return nil
+		s = v
+	case []byte:
+		s = string(v)
 	default:
 		return fmt.Errorf("cannot scan %T into CustomTime", value)
 	}
+
+	t, err := parseCustomTime(s)
+	if err != nil {
+		return err
+	}
+	ct.Time = t
+	return nil
 }
 
 // MarshalJSON implements the json.Marshaler interface
@@ -54,40 +91,50 @@ func (ct *CustomTime) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
 	}
-	if s == "" {
-		ct.Time = time.Time{}
-		return nil
-	}
 
-	// Try different time formats
-	formats := []string{
-		time.RFC3339,                  // "2006-01-02T15:04:05Z07:00"
-		"2006-01-02 15:04:05.999 MST", // "2025-03-23 01:43:50.322 UTC"
-		"2006-01-02 15:04:05 MST",     // "2025-03-23 01:43:50 UTC"
-		"2006-01-02 15:04:05.999",     // "2025-03-23 01:43:50.322"
-		"2006-01-02 15:04:05",         // "2025-03-23 01:43:50"
+	t, err := parseCustomTime(s)
+	if err != nil {
+		return err
 	}
+	ct.Time = t
+	return nil
+}
 
-	var lastErr error
-	for _, format := range formats {
-		if t, err := time.Parse(format, s); err == nil {
-			ct.Time = t
-			return nil
-		} else {
-			lastErr = err
-		}
+// FlexFloat is a float64 that unmarshals from either a JSON number or a string
+// such as "$85,000" or "85000.00". It is used to decode salaries from feeds
+// that format them as text; jobs still store them as plain float64 values.
+type FlexFloat float64
+
+// flexFloatReplacer strips currency symbols, thousands separators, and spaces
+var flexFloatReplacer = strings.NewReplacer("$", "", "£", "", "€", "", ",", "", " ", "")
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (f *FlexFloat) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*f = FlexFloat(number)
+		return nil
 	}
 
-	return fmt.Errorf("could not parse time %q with any known format: %v", s, lastErr)
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("expected a number or a numeric string, got %s", data)
+	}
+	number, err := strconv.ParseFloat(flexFloatReplacer.Replace(s), 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return fmt.Errorf("could not parse %q as a number", s)
+	}
+	*f = FlexFloat(number)
+	return nil
 }
 
 // JobData represents a job listing with all its associated data
 type JobData struct {
 	// Company Information
-	CompanyName    string  `json:"companyName"`
-	CompanyRating  float64 `json:"companyRating"`
-	CompanyAddress string  `json:"companyAddress"`
-	CompanyWebsite string  `json:"companyWebsite"`
+	CompanyName    string   `json:"companyName"`
+	CompanyRating  *float64 `json:"companyRating,omitempty"`
+	CompanyAddress string   `json:"companyAddress"`
+	CompanyWebsite string   `json:"companyWebsite"`
 
 	// Job Information
 	JobTitle         string     `json:"jobTitle"`
@@ -140,3 +187,119 @@ type JobData struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting salaries
+// given as numbers or as strings like "$85,000". Fields missing from data keep
+// their current values, so a partial document can be applied over a stored job.
+func (job *JobData) UnmarshalJSON(data []byte) error {
+	type plainJobData JobData // Drops this method so decoding does not recurse
+	aux := struct {
+		*plainJobData
+		MinSalary *FlexFloat `json:"minSalary,omitempty"`
+		MaxSalary *FlexFloat `json:"maxSalary,omitempty"`
+	}{
+		plainJobData: (*plainJobData)(job),
+		MinSalary:    copyAsFlexFloat(job.MinSalary),
+		MaxSalary:    copyAsFlexFloat(job.MaxSalary),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	job.MinSalary = (*float64)(aux.MinSalary)
+	job.MaxSalary = (*float64)(aux.MaxSalary)
+	return nil
+}
+
+// copyAsFlexFloat copies an optional float so decoding into it cannot modify the original
+func copyAsFlexFloat(f *float64) *FlexFloat {
+	if f == nil {
+		return nil
+	}
+	copied := FlexFloat(*f)
+	return &copied
+}
+
+// requiredFieldCheckers reports, for each job column that can be configured as
+// required, whether that field is missing from a job
+var requiredFieldCheckers = map[string]func(job *JobData) bool{
+	"company_name":       func(job *JobData) bool { return job.CompanyName == "" },
+	"company_rating":     func(job *JobData) bool { return job.CompanyRating == nil },
+	"company_address":    func(job *JobData) bool { return job.CompanyAddress == "" },
+	"company_website":    func(job *JobData) bool { return job.CompanyWebsite == "" },
+	"job_title":          func(job *JobData) bool { return job.JobTitle == "" },
+	"job_link":           func(job *JobData) bool { return job.JobLink == "" },
+	"job_description":    func(job *JobData) bool { return job.JobDescription == "" },
+	"job_posted_time":    func(job *JobData) bool { return job.JobPostedTime.IsZero() },
+	"role_type":          func(job *JobData) bool { return isEmptyString(job.RoleType) },
+	"min_salary":         func(job *JobData) bool { return job.MinSalary == nil },
+	"max_salary":         func(job *JobData) bool { return job.MaxSalary == nil },
+	"salary_granularity": func(job *JobData) bool { return isEmptyString(job.SalaryGranularity) },
+	"city":               func(job *JobData) bool { return job.City == "" },
+	"state":              func(job *JobData) bool { return isEmptyString(job.State) },
+	"zip":                func(job *JobData) bool { return isEmptyString(job.Zip) },
+	"latitude":           func(job *JobData) bool { return job.Latitude == nil },
+	"longitude":          func(job *JobData) bool { return job.Longitude == nil },
+	"date_collected":     func(job *JobData) bool { return job.DateCollected.IsZero() },
+}
+
+// IsValidRequiredField checks whether field is a job column that can be configured as required
+func IsValidRequiredField(field string) bool {
+	_, ok := requiredFieldCheckers[field]
+	return ok
+}
+
+// MissingFields returns the given required fields that are empty on the job, in the order given.
+// Fields that are not valid required fields are ignored.
+func (job *JobData) MissingFields(fields []string) []string {
+	var missing []string
+	for _, field := range fields {
+		if isMissing, ok := requiredFieldCheckers[field]; ok && isMissing(job) {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// textFieldGetters returns, for each job column that text rules can compare, the
+// field's value and whether it is set
+var textFieldGetters = map[string]func(job *JobData) (string, bool){
+	"company_name":       func(job *JobData) (string, bool) { return job.CompanyName, job.CompanyName != "" },
+	"company_address":    func(job *JobData) (string, bool) { return job.CompanyAddress, job.CompanyAddress != "" },
+	"company_website":    func(job *JobData) (string, bool) { return job.CompanyWebsite, job.CompanyWebsite != "" },
+	"job_title":          func(job *JobData) (string, bool) { return job.JobTitle, job.JobTitle != "" },
+	"job_link":           func(job *JobData) (string, bool) { return job.JobLink, job.JobLink != "" },
+	"job_description":    func(job *JobData) (string, bool) { return job.JobDescription, job.JobDescription != "" },
+	"role_type":          func(job *JobData) (string, bool) { return optionalString(job.RoleType) },
+	"salary_granularity": func(job *JobData) (string, bool) { return optionalString(job.SalaryGranularity) },
+	"city":               func(job *JobData) (string, bool) { return job.City, job.City != "" },
+	"state":              func(job *JobData) (string, bool) { return optionalString(job.State) },
+	"zip":                func(job *JobData) (string, bool) { return optionalString(job.Zip) },
+}
+
+// IsValidTextField checks whether field is a job column that text rules can compare
+func IsValidTextField(field string) bool {
+	_, ok := textFieldGetters[field]
+	return ok
+}
+
+// TextField returns the value of the named text column. The boolean result is
+// false when the field is unknown or empty on the job.
+func (job *JobData) TextField(field string) (string, bool) {
+	if get, ok := textFieldGetters[field]; ok {
+		return get(job)
+	}
+	return "", false
+}
+
+// optionalString returns an optional string's value and whether it is set and non-empty
+func optionalString(s *string) (string, bool) {
+	if isEmptyString(s) {
+		return "", false
+	}
+	return *s, true
+}
+
+// isEmptyString reports whether an optional string is nil or empty
+func isEmptyString(s *string) bool {
+	return s == nil || *s == ""
+}
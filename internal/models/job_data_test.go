@@ -0,0 +1,157 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlexFloatUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    FlexFloat
+		expectError bool
+	}{
+		{name: "number", input: `85000`, expected: 85000},
+		{name: "decimal number", input: `85000.5`, expected: 85000.5},
+		{name: "plain string", input: `"85000.00"`, expected: 85000},
+		{name: "currency and separators", input: `"$85,000"`, expected: 85000},
+		{name: "padded string", input: `" $ 1,250.75 "`, expected: 1250.75},
+		{name: "negative string", input: `"-100"`, expected: -100},
+		{name: "words", input: `"competitive"`, expectError: true},
+		{name: "empty string", input: `""`, expectError: true},
+		{name: "not a number", input: `"NaN"`, expectError: true},
+		{name: "boolean", input: `true`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f FlexFloat
+			err := json.Unmarshal([]byte(tt.input), &f)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, f)
+		})
+	}
+}
+
+func TestCustomTimeScan(t *testing.T) {
+	expected := time.Date(2025, 3, 23, 1, 43, 50, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		value       interface{}
+		expected    time.Time
+		expectError bool
+	}{
+		{name: "nil", value: nil, expected: time.Time{}},
+		{name: "time", value: expected, expected: expected},
+		{name: "RFC 3339 string", value: "2025-03-23T01:43:50Z", expected: expected},
+		{name: "string with zone name", value: "2025-03-23 01:43:50 UTC", expected: expected},
+		{name: "string without zone", value: "2025-03-23 01:43:50", expected: expected},
+		{name: "bytes", value: []byte("2025-03-23 01:43:50.000 UTC"), expected: expected},
+		{name: "empty string", value: "", expected: time.Time{}},
+		{name: "unparseable string", value: "yesterday", expectError: true},
+		{name: "unsupported type", value: int64(1742694230), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct := CustomTime{Time: time.Now()}
+			err := ct.Scan(tt.value)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(ct.Time), "got %s", ct.Time)
+		})
+	}
+}
+
+func TestCustomTimeValue(t *testing.T) {
+	value, err := CustomTime{}.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, value, "the zero time is stored as NULL")
+
+	now := time.Now()
+	value, err = CustomTime{Time: now}.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, now, value)
+}
+
+func TestJobDataUnmarshalSalaries(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectedMin *float64
+		expectedMax *float64
+		expectError bool
+	}{
+		{
+			name:        "numeric salaries",
+			input:       `{"jobID":"job1","minSalary":80000,"maxSalary":95000.5}`,
+			expectedMin: floatPtr(80000),
+			expectedMax: floatPtr(95000.5),
+		},
+		{
+			name:        "string salaries",
+			input:       `{"jobID":"job1","minSalary":"$80,000","maxSalary":"95000.00"}`,
+			expectedMin: floatPtr(80000),
+			expectedMax: floatPtr(95000),
+		},
+		{
+			name:  "null and missing salaries",
+			input: `{"jobID":"job1","minSalary":null}`,
+		},
+		{
+			name:        "malformed salary",
+			input:       `{"jobID":"job1","maxSalary":"DOE"}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var job JobData
+			err := json.Unmarshal([]byte(tt.input), &job)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "job1", job.JobID)
+			assert.Equal(t, tt.expectedMin, job.MinSalary)
+			assert.Equal(t, tt.expectedMax, job.MaxSalary)
+		})
+	}
+}
+
+func TestJobDataUnmarshalKeepsMissingSalaries(t *testing.T) {
+	original := 90000.0
+	job := JobData{JobID: "job1", MinSalary: &original, MaxSalary: floatPtr(120000)}
+
+	// Applying a partial document leaves salaries it does not mention untouched
+	assert.NoError(t, json.Unmarshal([]byte(`{"city":"Austin","maxSalary":"$130,000"}`), &job))
+
+	assert.Equal(t, "Austin", job.City)
+	assert.Equal(t, floatPtr(90000), job.MinSalary)
+	assert.Equal(t, floatPtr(130000), job.MaxSalary)
+
+	// The original value is copied, not decoded into in place
+	assert.NoError(t, json.Unmarshal([]byte(`{"minSalary":1}`), &job))
+	assert.Equal(t, 90000.0, original)
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
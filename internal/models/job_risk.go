@@ -0,0 +1,10 @@
+package models
+
+// JobRiskScore is the severity-weighted sum of the open anomalies on one job
+type JobRiskScore struct {
+	JobID        string  `json:"job_id"`
+	CompanyName  string  `json:"company_name"`
+	JobTitle     string  `json:"job_title"`
+	AnomalyCount int     `json:"anomaly_count"`
+	RiskScore    float64 `json:"risk_score"`
+}
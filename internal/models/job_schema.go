@@ -0,0 +1,88 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JobDataField describes one field of JobData as it is sent and received in JSON
+type JobDataField struct {
+	Name     string   `json:"name"`              // Go field name
+	JSONName string   `json:"json_name"`         // Key used in JSON documents
+	Type     string   `json:"type"`              // string, number, integer, boolean, array, or timestamp
+	Items    string   `json:"items,omitempty"`   // Element type of array fields
+	Nullable bool     `json:"nullable"`          // Whether the field may be null or left out
+	Required bool     `json:"required"`          // Whether validation rejects the job when the field is empty
+	Formats  []string `json:"formats,omitempty"` // Accepted layouts of timestamp fields, as Go reference times
+}
+
+var (
+	customTimeType = reflect.TypeOf(CustomTime{})
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// JobDataFields describes every JSON field of JobData, in declaration order.
+// It is built by reflection so it always matches the struct. Required is left
+// false, since which fields are required is decided by validation.
+func JobDataFields() []JobDataField {
+	jobType := reflect.TypeOf(JobData{})
+	fields := make([]JobDataField, 0, jobType.NumField())
+	for i := 0; i < jobType.NumField(); i++ {
+		structField := jobType.Field(i)
+		jsonName, _, _ := strings.Cut(structField.Tag.Get("json"), ",")
+		if !structField.IsExported() || jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = structField.Name
+		}
+
+		field := JobDataField{Name: structField.Name, JSONName: jsonName}
+		fieldType := structField.Type
+		if fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice {
+			field.Nullable = true
+		}
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		switch {
+		case fieldType == customTimeType:
+			field.Type = "timestamp"
+			field.Nullable = true // The zero time is stored and sent as null
+			field.Formats = CustomTimeLayouts()
+		case fieldType == timeType:
+			field.Type = "timestamp"
+			field.Formats = []string{time.RFC3339}
+		case fieldType.Kind() == reflect.Slice:
+			field.Type = "array"
+			field.Items = jsonType(fieldType.Elem())
+		default:
+			field.Type = jsonType(fieldType)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// jsonType names the JSON type a Go type of kind string, number, or bool is encoded as
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return t.Kind().String()
+	}
+}
+
+// CustomTimeLayouts returns the timestamp layouts CustomTime accepts, in the order they are tried
+func CustomTimeLayouts() []string {
+	return append([]string(nil), customTimeLayouts...)
+}
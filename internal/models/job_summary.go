@@ -0,0 +1,12 @@
+package models
+
+// JobSummary holds headline aggregate numbers across all stored jobs
+type JobSummary struct {
+	TotalJobs                 int      `json:"total_jobs"`
+	JobsMissingRequiredFields int      `json:"jobs_missing_required_fields"`
+	AvgSalary                 *float64 `json:"avg_salary"` // Average of max_salary; null when no job has a salary
+	MinSalary                 *float64 `json:"min_salary"`
+	MaxSalary                 *float64 `json:"max_salary"`
+	DistinctCompanies         int      `json:"distinct_companies"`
+	DistinctCities            int      `json:"distinct_cities"`
+}
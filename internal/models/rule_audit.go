@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RuleAuditAction names the kind of change recorded in a rule's history
+type RuleAuditAction string
+
+const (
+	RuleAuditCreate RuleAuditAction = "create"
+	RuleAuditUpdate RuleAuditAction = "update"
+	RuleAuditDelete RuleAuditAction = "delete"
+	RuleAuditToggle RuleAuditAction = "toggle"
+)
+
+// RuleAuditEntry records one change to an anomaly rule, with snapshots of the
+// rule before and after it
+type RuleAuditEntry struct {
+	ID        int64           `json:"id" db:"id"`
+	RuleID    int64           `json:"rule_id" db:"rule_id"`
+	Action    RuleAuditAction `json:"action" db:"action"`
+	OldValue  json.RawMessage `json:"old_value" db:"old_value"` // The rule before the change, null when it was created
+	NewValue  json.RawMessage `json:"new_value" db:"new_value"` // The rule after the change, null when it was deleted
+	Actor     string          `json:"actor" db:"actor"`         // Who made the change, empty when unknown
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// TableName returns the table name for the RuleAuditEntry model
+func (RuleAuditEntry) TableName() string {
+	return "rule_audit_log"
+}
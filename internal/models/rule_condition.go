@@ -0,0 +1,71 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// RuleLogic determines how the conditions of a compound rule are combined
+type RuleLogic string
+
+const (
+	RuleLogicAnd RuleLogic = "and" // Every condition must match
+	RuleLogicOr  RuleLogic = "or"  // At least one condition must match
+)
+
+// RuleValueMode determines how the values of a rule's conditions are interpreted
+type RuleValueMode string
+
+const (
+	ValueModeAbsolute   RuleValueMode = "absolute"   // Values are compared with the job field directly
+	ValueModePercentile RuleValueMode = "percentile" // Values are percentiles (0-100) of the field across all jobs
+)
+
+// RuleCondition is a single field/operator/value comparison within a compound rule
+type RuleCondition struct {
+	Type      AnomalyType        `json:"type"`
+	Operator  ComparisonOperator `json:"operator"`
+	Value     float64            `json:"value"`
+	ValueHigh float64            `json:"value_high,omitempty"` // Upper bound for range operators
+	Field     string             `json:"field,omitempty"`      // Text field compared by text operators
+	TextValue string             `json:"text_value,omitempty"` // Substring or pattern for text operators
+}
+
+// String describes the condition, such as "max_salary > 500000",
+// "max_salary BETWEEN 0 AND 1000" or `job_description contains "mlm"`
+func (c RuleCondition) String() string {
+	if c.Operator.IsText() {
+		return fmt.Sprintf("%s %s %q", c.Field, c.Operator, c.TextValue)
+	}
+	if c.Operator.IsRange() {
+		return fmt.Sprintf("%s %s %g AND %g", c.Type, c.Operator, c.Value, c.ValueHigh)
+	}
+	return fmt.Sprintf("%s %s %g", c.Type, c.Operator, c.Value)
+}
+
+// RuleConditions is a custom type for storing rule conditions as JSON in the database
+type RuleConditions []RuleCondition
+
+// Value implements the driver.Valuer interface
+func (c RuleConditions) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface
+func (c *RuleConditions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into RuleConditions", value)
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+// RuleEvaluation is the outcome of checking a sample job against a rule without storing anything
+type RuleEvaluation struct {
+	JobID      string   `json:"job_id"`
+	Triggered  bool     `json:"triggered"`
+	Value      float64  `json:"value"`      // Job field value compared by the first matching condition, or by the first condition when none match
+	Threshold  float64  `json:"threshold"`  // Threshold that Value was compared against
+	Violations []string `json:"violations"` // Each condition that matched
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// RuleExportVersion is the version of the rule export document format
+const RuleExportVersion = 1
+
+// RuleExport is a portable document of anomaly rules, for keeping rules in version
+// control and syncing them between servers. Rules are identified by name.
+type RuleExport struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Rules      []RuleDefinition `json:"rules"`
+}
+
+// RuleDefinition is the portable part of an AnomalyRule. It leaves out the
+// database ID, timestamps, and authors, which differ between servers.
+type RuleDefinition struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Type        AnomalyType        `json:"type"`
+	Operator    ComparisonOperator `json:"operator"`
+	Value       float64            `json:"value"`
+	ValueHigh   float64            `json:"value_high"`
+	ValueMode   RuleValueMode      `json:"value_mode"`
+	Field       string             `json:"field"`
+	TextValue   string             `json:"text_value"`
+	IsActive    bool               `json:"is_active"`
+	Severity    string             `json:"severity"`
+	Logic       RuleLogic          `json:"logic"`
+	Conditions  RuleConditions     `json:"conditions,omitempty"`
+	Tags        StringSlice        `json:"tags"`
+}
+
+// RuleImportResult counts what an import did with the rules in the document
+type RuleImportResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Definition returns the portable part of the rule
+func (r AnomalyRule) Definition() RuleDefinition {
+	return RuleDefinition{
+		Name:        r.Name,
+		Description: r.Description,
+		Type:        r.Type,
+		Operator:    r.Operator,
+		Value:       r.Value,
+		ValueHigh:   r.ValueHigh,
+		ValueMode:   r.ValueMode,
+		Field:       r.Field,
+		TextValue:   r.TextValue,
+		IsActive:    r.IsActive,
+		Severity:    r.Severity,
+		Logic:       r.Logic,
+		Conditions:  r.Conditions,
+		Tags:        r.Tags,
+	}
+}
+
+// Rule returns a new rule with the definition's settings
+func (d RuleDefinition) Rule() AnomalyRule {
+	return AnomalyRule{
+		Name:        d.Name,
+		Description: d.Description,
+		Type:        d.Type,
+		Operator:    d.Operator,
+		Value:       d.Value,
+		ValueHigh:   d.ValueHigh,
+		ValueMode:   d.ValueMode,
+		Field:       d.Field,
+		TextValue:   d.TextValue,
+		IsActive:    d.IsActive,
+		Severity:    d.Severity,
+		Logic:       d.Logic,
+		Conditions:  d.Conditions,
+		Tags:        d.Tags,
+	}
+}
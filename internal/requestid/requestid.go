@@ -0,0 +1,64 @@
+// Package requestid carries a per-request identifier through request contexts
+// and adds it to structured log records, so a single request can be traced
+// across handlers, services, and log lines.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+)
+
+// Header is the HTTP header a request ID is read from and echoed in
+const Header = "X-Request-ID"
+
+// LogKey is the attribute name request IDs are logged under
+const LogKey = "request_id"
+
+// contextKey is unexported so only this package can set or read the request ID
+type contextKey struct{}
+
+// New generates a random request ID of 26 base32 characters (128 bits)
+func New() string {
+	return rand.Text()
+}
+
+// NewContext returns a copy of ctx carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or "" when there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// LogHandler wraps a slog.Handler, adding the request ID from the record's
+// context to every record logged through a *Context logging method
+type LogHandler struct {
+	slog.Handler
+}
+
+// NewLogHandler wraps handler so log records include the request ID
+func NewLogHandler(handler slog.Handler) *LogHandler {
+	return &LogHandler{Handler: handler}
+}
+
+// Handle adds the request ID attribute, when ctx has one, before passing the record on
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		record.AddAttrs(slog.String(LogKey, id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs keeps the request ID handling on loggers derived with With
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewLogHandler(h.Handler.WithAttrs(attrs))
+}
+
+// WithGroup keeps the request ID handling on loggers derived with WithGroup
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return NewLogHandler(h.Handler.WithGroup(name))
+}
@@ -0,0 +1,48 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	first, second := New(), New()
+
+	assert.Len(t, first, 26)
+	assert.NotEqual(t, first, second)
+}
+
+func TestContextRoundTrip(t *testing.T) {
+	assert.Empty(t, FromContext(context.Background()))
+	assert.Equal(t, "abc123", FromContext(NewContext(context.Background(), "abc123")))
+}
+
+func TestLogHandlerAddsRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		expectedID interface{}
+	}{
+		{name: "request context", ctx: NewContext(context.Background(), "abc123"), expectedID: "abc123"},
+		{name: "background context", ctx: context.Background(), expectedID: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("service", "test")
+
+			logger.InfoContext(tt.ctx, "hello")
+
+			var record map[string]interface{}
+			assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			assert.Equal(t, tt.expectedID, record[LogKey])
+			assert.Equal(t, "test", record["service"])
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// AlertNotifier defines the interface for delivering anomaly alerts.
+// Batch detection may call Notify from several goroutines at once.
+type AlertNotifier interface {
+	Notify(ctx context.Context, alert models.AnomalyAlert) error
+}
+
+// webhookTimeout bounds how long a webhook delivery may take
+const webhookTimeout = 5 * time.Second
+
+// WebhookNotifier posts alerts as JSON to a Slack-compatible webhook URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a new WebhookNotifier for the given URL
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// webhookPayload is the JSON body sent to the webhook.
+// The text field is what Slack displays; the remaining fields serve generic consumers.
+type webhookPayload struct {
+	Text        string          `json:"text"`
+	RuleID      int64           `json:"rule_id,omitempty"`
+	Severity    string          `json:"severity"`
+	Description string          `json:"description"`
+	Status      string          `json:"status"`
+	Details     json.RawMessage `json:"details,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Notify sends the alert to the webhook
+func (n *WebhookNotifier) Notify(ctx context.Context, alert models.AnomalyAlert) error {
+	payload := webhookPayload{
+		Text:        fmt.Sprintf("[%s] %s", alert.Severity, alert.Description),
+		RuleID:      alert.RuleID,
+		Severity:    alert.Severity,
+		Description: alert.Description,
+		Status:      alert.Status,
+		Details:     alert.Details,
+		CreatedAt:   alert.CreatedAt,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingNotifier is an AlertNotifier that records every alert it is given
+type recordingNotifier struct {
+	alerts []models.AnomalyAlert
+	err    error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert models.AnomalyAlert) error {
+	n.alerts = append(n.alerts, alert)
+	return n.err
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	t.Run("posts alert payload", func(t *testing.T) {
+		var received map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		notifier := NewWebhookNotifier(server.URL)
+		err := notifier.Notify(context.Background(), models.AnomalyAlert{
+			Severity:    models.SeverityCritical,
+			Description: "Job job1: Alert if maximum salary is negative",
+			Details:     []byte(`{"job_id":"job1"}`),
+			CreatedAt:   time.Now(),
+			Status:      "open",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "[critical] Job job1: Alert if maximum salary is negative", received["text"])
+		assert.Equal(t, models.SeverityCritical, received["severity"])
+		assert.Equal(t, map[string]interface{}{"job_id": "job1"}, received["details"])
+	})
+
+	t.Run("returns error on non-2xx status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		notifier := NewWebhookNotifier(server.URL)
+		err := notifier.Notify(context.Background(), models.AnomalyAlert{Severity: models.SeverityHigh})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestNotifyAnomalyRespectsMinSeverity(t *testing.T) {
+	notifier := &recordingNotifier{err: assert.AnError}
+	cfg := config.DefaultDetectionConfig()
+	cfg.AlertMinSeverity = models.SeverityHigh
+	service := NewAnomalyService(nil, nil, cfg, notifier, nil)
+
+	service.notifyAnomaly(context.Background(), &models.Anomaly{JobID: "job1", Severity: models.SeverityMedium})
+	service.notifyAnomaly(context.Background(), &models.Anomaly{JobID: "job2", Severity: models.SeverityHigh})
+	service.notifyAnomaly(context.Background(), &models.Anomaly{JobID: "job3", Severity: models.SeverityCritical})
+
+	// Notifier failures are logged rather than propagated, so every qualifying alert is attempted
+	assert.Len(t, notifier.alerts, 2)
+	assert.Equal(t, models.SeverityHigh, notifier.alerts[0].Severity)
+	assert.Equal(t, models.SeverityCritical, notifier.alerts[1].Severity)
+}
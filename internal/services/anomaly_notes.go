@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// AddAnomalyNote attaches a note by author to the anomaly with the given ID and
+// returns it. The note body is required; author may be empty when unknown. An
+// error wrapping ErrNotFound is returned when no such anomaly exists.
+func (s *AnomalyService) AddAnomalyNote(ctx context.Context, anomalyID int64, author, body string) (*models.AnomalyNote, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: note body is required", ErrValidation)
+	}
+
+	// Inserting from the anomalies row checks that the anomaly exists in the same statement
+	query := `
+		INSERT INTO anomaly_notes (anomaly_id, author, body)
+		SELECT id, $2, $3 FROM anomalies WHERE id = $1
+		RETURNING id, created_at
+	`
+
+	note := &models.AnomalyNote{AnomalyID: anomalyID, Author: strings.TrimSpace(author), Body: body}
+	err := s.db.QueryRow(ctx, query, anomalyID, note.Author, note.Body).Scan(&note.ID, &note.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("anomaly with ID %d %w", anomalyID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error adding anomaly note: %w", err)
+	}
+	return note, nil
+}
+
+// GetAnomalyNotes returns the notes on the anomaly with the given ID, oldest
+// first, or an error wrapping ErrNotFound when no such anomaly exists
+func (s *AnomalyService) GetAnomalyNotes(ctx context.Context, anomalyID int64) ([]models.AnomalyNote, error) {
+	query := `
+		SELECT id, anomaly_id, author, body, created_at
+		FROM anomaly_notes
+		WHERE anomaly_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := s.db.Query(ctx, query, anomalyID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying anomaly notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []models.AnomalyNote{}
+	for rows.Next() {
+		var note models.AnomalyNote
+		if err := rows.Scan(&note.ID, &note.AnomalyID, &note.Author, &note.Body, &note.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning anomaly note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating anomaly notes: %w", err)
+	}
+
+	// An anomaly without notes is told apart from a missing one only when needed
+	if len(notes) == 0 {
+		var exists bool
+		if err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM anomalies WHERE id = $1)`, anomalyID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("error checking anomaly: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("anomaly with ID %d %w", anomalyID, ErrNotFound)
+		}
+	}
+
+	return notes, nil
+}
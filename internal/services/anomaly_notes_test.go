@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAnomalyNote(t *testing.T) {
+	createdAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	query := `INSERT INTO anomaly_notes \(anomaly_id, author, body\)\s+SELECT id, \$2, \$3 FROM anomalies WHERE id = \$1`
+
+	t.Run("existing anomaly", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery(query).
+			WithArgs(int64(42), "alice", "Salary looks like a typo").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, createdAt))
+
+		service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+		note, err := service.AddAnomalyNote(context.Background(), 42, " alice ", " Salary looks like a typo\n")
+
+		assert.NoError(t, err)
+		assert.Equal(t, &models.AnomalyNote{ID: 3, AnomalyID: 42, Author: "alice", Body: "Salary looks like a typo", CreatedAt: createdAt}, note)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("missing anomaly", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery(query).
+			WithArgs(int64(7), "", "note").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+		service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+		_, err = service.AddAnomalyNote(context.Background(), 7, "", "note")
+
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("missing body", func(t *testing.T) {
+		// No database calls are expected
+		service := NewAnomalyService(new(MockDB), nil, nil, nil, nil)
+		_, err := service.AddAnomalyNote(context.Background(), 42, "alice", "  ")
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestGetAnomalyNotes(t *testing.T) {
+	createdAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	columns := []string{"id", "anomaly_id", "author", "body", "created_at"}
+	notesQuery := `FROM anomaly_notes\s+WHERE anomaly_id = \$1\s+ORDER BY created_at, id`
+	existsQuery := `SELECT EXISTS \(SELECT 1 FROM anomalies WHERE id = \$1\)`
+
+	tests := []struct {
+		name          string
+		rows          *sqlmock.Rows
+		checksAnomaly bool // Whether an empty list makes it check that the anomaly exists
+		exists        bool
+		expected      []models.AnomalyNote
+		expectedError error
+	}{
+		{
+			name: "anomaly with notes",
+			rows: sqlmock.NewRows(columns).
+				AddRow(1, 42, "alice", "Looking into it", createdAt).
+				AddRow(2, 42, "", "Confirmed with the recruiter", createdAt.Add(time.Hour)),
+			expected: []models.AnomalyNote{
+				{ID: 1, AnomalyID: 42, Author: "alice", Body: "Looking into it", CreatedAt: createdAt},
+				{ID: 2, AnomalyID: 42, Body: "Confirmed with the recruiter", CreatedAt: createdAt.Add(time.Hour)},
+			},
+		},
+		{
+			name:          "anomaly without notes",
+			rows:          sqlmock.NewRows(columns),
+			checksAnomaly: true,
+			exists:        true,
+			expected:      []models.AnomalyNote{},
+		},
+		{
+			name:          "missing anomaly",
+			rows:          sqlmock.NewRows(columns),
+			checksAnomaly: true,
+			expectedError: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			sqlMock.ExpectQuery(notesQuery).WithArgs(int64(42)).WillReturnRows(tt.rows)
+			if tt.checksAnomaly {
+				sqlMock.ExpectQuery(existsQuery).WithArgs(int64(42)).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.exists))
+			}
+
+			service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+			notes, err := service.GetAnomalyNotes(context.Background(), 42)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, notes)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/lib/pq"
+)
+
+// SetNotifyChannel makes the service send a Postgres NOTIFY on channel, with an
+// AnomalyNotification as payload, whenever a new anomaly is stored. An empty
+// channel, the default, sends none. Like RegisterDetector it must be called
+// before detection starts.
+func (s *AnomalyService) SetNotifyChannel(channel string) {
+	s.notifyChannel = channel
+}
+
+// publishAnomaly announces a newly stored anomaly on the notify channel.
+// Failures are logged and never fail detection.
+func (s *AnomalyService) publishAnomaly(ctx context.Context, anomaly *models.Anomaly) {
+	if s.notifyChannel == "" {
+		return
+	}
+
+	payload, err := json.Marshal(models.AnomalyNotification{
+		ID:       anomaly.ID,
+		JobID:    anomaly.JobID,
+		Type:     anomaly.Type,
+		Severity: anomaly.Severity,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "error encoding anomaly notification", "job_id", anomaly.JobID, "err", err)
+		return
+	}
+
+	if _, err := s.db.Exec(ctx, `SELECT pg_notify($1, $2)`, s.notifyChannel, string(payload)); err != nil {
+		s.logger.WarnContext(ctx, "error sending anomaly notification", "channel", s.notifyChannel, "anomaly_id", anomaly.ID, "err", err)
+	}
+}
+
+const (
+	// listenerMinReconnect and listenerMaxReconnect bound the wait between
+	// attempts to reconnect a lost listener connection
+	listenerMinReconnect = time.Second
+	listenerMaxReconnect = time.Minute
+
+	// listenerPingInterval is how long Next waits without a notification before
+	// checking that the connection is still alive
+	listenerPingInterval = 90 * time.Second
+)
+
+// AnomalyListener receives the notifications sent for new anomalies, for
+// services that want them pushed rather than polling the API. For example:
+//
+//	listener, err := services.NewAnomalyListener(dbcfg.GetDSN(), alertcfg.NotifyChannel, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer listener.Close()
+//	for {
+//		notification, err := listener.Next(ctx)
+//		if err != nil {
+//			return err
+//		}
+//		// Fetch GET /api/anomalies/id/<notification.ID> ...
+//	}
+type AnomalyListener struct {
+	listener *pq.Listener
+	logger   *slog.Logger
+}
+
+// NewAnomalyListener connects to the database at dsn and listens on channel.
+// It returns once the LISTEN is in place, so anomalies stored afterwards are
+// received. A lost connection is re-established in the background; anomalies
+// stored while it was down are missed. A nil logger uses slog.Default().
+func NewAnomalyListener(dsn, channel string, logger *slog.Logger) (*AnomalyListener, error) {
+	logger = loggerOrDefault(logger)
+	listener := pq.NewListener(dsn, listenerMinReconnect, listenerMaxReconnect, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Warn("anomaly listener connection event", "event", event, "err", err)
+		}
+	})
+
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("error listening on %s: %w", channel, err)
+	}
+	return &AnomalyListener{listener: listener, logger: logger}, nil
+}
+
+// Next waits for the next anomaly notification. It returns the context's
+// error when ctx is done first. Payloads that cannot be decoded are logged
+// and skipped.
+func (l *AnomalyListener) Next(ctx context.Context) (models.AnomalyNotification, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return models.AnomalyNotification{}, ctx.Err()
+		case n := <-l.listener.Notify:
+			// A nil notification means the connection was re-established
+			if n == nil {
+				continue
+			}
+			var notification models.AnomalyNotification
+			if err := json.Unmarshal([]byte(n.Extra), &notification); err != nil {
+				l.logger.WarnContext(ctx, "skipping undecodable anomaly notification", "channel", n.Channel, "payload", n.Extra, "err", err)
+				continue
+			}
+			return notification, nil
+		case <-time.After(listenerPingInterval):
+			// Pinging surfaces a silently dropped connection so it is re-established
+			if err := l.listener.Ping(); err != nil {
+				l.logger.WarnContext(ctx, "anomaly listener ping failed", "err", err)
+			}
+		}
+	}
+}
+
+// Close stops listening and closes the connection
+func (l *AnomalyListener) Close() error {
+	return l.listener.Close()
+}
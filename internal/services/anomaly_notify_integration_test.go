@@ -0,0 +1,40 @@
+//go:build integration
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnomalyListenerReceivesNewAnomaly(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const channel = "anomaly_detected_test"
+	listener, err := NewAnomalyListener(testDBConfig.GetDSN(), channel, nil)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	job := &models.JobData{JobID: "notify-job", CompanyName: "Tech Corp", JobTitle: "Engineer"}
+	assert.NoError(t, NewJobDataService(db, nil).CreateJobData(ctx, job))
+
+	service := NewAnomalyService(db, nil, nil, nil, nil)
+	service.SetNotifyChannel(channel)
+	anomaly := &models.Anomaly{JobID: job.JobID, Type: models.AnomalyTypeNullValues, Description: "missing fields", Severity: models.SeverityHigh}
+	assert.NoError(t, service.saveAnomaly(ctx, anomaly))
+
+	notification, err := listener.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AnomalyNotification{
+		ID:       anomaly.ID,
+		JobID:    job.JobID,
+		Type:     models.AnomalyTypeNullValues,
+		Severity: models.SeverityHigh,
+	}, notification)
+}
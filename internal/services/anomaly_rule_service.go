@@ -1,44 +1,109 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/lib/pq"
 )
 
 // AnomalyRuleServiceInterface defines the interface for anomaly rule operations
 type AnomalyRuleServiceInterface interface {
-	GetAnomalyRules() ([]models.AnomalyRule, error)
-	GetAnomalyRule(id int64) (*models.AnomalyRule, error)
-	CreateAnomalyRule(rule *models.AnomalyRule) error
-	UpdateAnomalyRule(rule *models.AnomalyRule) error
-	DeleteAnomalyRule(id int64) error
-	ToggleAnomalyRule(id int64, isActive bool) error
+	GetAnomalyRules(ctx context.Context, sort SortOptions, tag string) ([]models.AnomalyRule, error)
+	GetAnomalyRule(ctx context.Context, id int64) (*models.AnomalyRule, error)
+	CreateAnomalyRule(ctx context.Context, rule *models.AnomalyRule) error
+	UpdateAnomalyRule(ctx context.Context, rule *models.AnomalyRule) error
+	DeleteAnomalyRule(ctx context.Context, id int64, actor string) error
+	ToggleAnomalyRule(ctx context.Context, id int64, isActive bool, actor string) error
+	ToggleRulesBulk(ctx context.Context, filter RuleFilter, isActive bool, actor string) (int, error)
+	GetRuleHistory(ctx context.Context, ruleID int64) ([]models.RuleAuditEntry, error)
+	ExportAnomalyRules(ctx context.Context) (*models.RuleExport, error)
+	ImportAnomalyRules(ctx context.Context, export *models.RuleExport, actor string) (*models.RuleImportResult, error)
+}
+
+// RuleFilter selects the rules changed by ToggleRulesBulk. Every non-empty
+// field must match, so a tag and a type select only rules of that type with that tag.
+type RuleFilter struct {
+	Tag  string             // Rules carrying this tag, compared case-insensitively
+	Type models.AnomalyType // Rules of this type
+	IDs  []int64            // Rules with one of these IDs
 }
 
 // AnomalyRuleService handles business logic for anomaly rules
 type AnomalyRuleService struct {
-	db DatabaseServiceInterface
+	db     DatabaseServiceInterface
+	logger *slog.Logger
 }
 
-// NewAnomalyRuleService creates a new AnomalyRuleService
-func NewAnomalyRuleService(db DatabaseServiceInterface) *AnomalyRuleService {
+// NewAnomalyRuleService creates a new AnomalyRuleService. A nil logger uses slog.Default().
+func NewAnomalyRuleService(db DatabaseServiceInterface, logger *slog.Logger) *AnomalyRuleService {
 	return &AnomalyRuleService{
-		db: db,
+		db:     db,
+		logger: loggerOrDefault(logger),
 	}
 }
 
-// GetAnomalyRules retrieves all anomaly rules using basic query methods
-func (s *AnomalyRuleService) GetAnomalyRules() ([]models.AnomalyRule, error) {
+// anomalyRuleColumns lists the anomaly_rules columns read by scanAnomalyRule, in scan order
+const anomalyRuleColumns = `id, name, description, type, operator, value, value_high, value_mode, field, text_value,
+		is_active, severity, logic, conditions, tags, created_at, updated_at, created_by, updated_by`
+
+// scanAnomalyRule scans a row selected with anomalyRuleColumns
+func scanAnomalyRule(row rowScanner) (models.AnomalyRule, error) {
+	var rule models.AnomalyRule
+	err := row.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.Description,
+		&rule.Type,
+		&rule.Operator,
+		&rule.Value,
+		&rule.ValueHigh,
+		&rule.ValueMode,
+		&rule.Field,
+		&rule.TextValue,
+		&rule.IsActive,
+		&rule.Severity,
+		&rule.Logic,
+		&rule.Conditions,
+		&rule.Tags,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+		&rule.CreatedBy,
+		&rule.UpdatedBy,
+	)
+	return rule, err
+}
+
+// GetAnomalyRules retrieves all anomaly rules using basic query methods.
+// The zero SortOptions orders rules newest first. A non-empty tag limits the
+// result to rules carrying that tag, compared case-insensitively.
+func (s *AnomalyRuleService) GetAnomalyRules(ctx context.Context, sort SortOptions, tag string) ([]models.AnomalyRule, error) {
+	orderBy, err := orderByClause(sort, anomalyRuleSortColumns, "created_at", SortDescending)
+	if err != nil {
+		return nil, err
+	}
+
+	where := ""
+	var args []interface{}
+	if tag = normalizeRuleTag(tag); tag != "" {
+		where = "WHERE tags ? $1"
+		args = append(args, tag)
+	}
+
 	query := `
-		SELECT id, name, description, type, operator, value, is_active, created_at, updated_at
+		SELECT ` + anomalyRuleColumns + `
 		FROM anomaly_rules
-		ORDER BY created_at DESC
-	`
+		` + where + `
+		ORDER BY ` + orderBy
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying anomaly rules: %w", err)
 	}
@@ -46,18 +111,7 @@ func (s *AnomalyRuleService) GetAnomalyRules() ([]models.AnomalyRule, error) {
 
 	var rules []models.AnomalyRule
 	for rows.Next() {
-		var rule models.AnomalyRule
-		err := rows.Scan(
-			&rule.ID,
-			&rule.Name,
-			&rule.Description,
-			&rule.Type,
-			&rule.Operator,
-			&rule.Value,
-			&rule.IsActive,
-			&rule.CreatedAt,
-			&rule.UpdatedAt,
-		)
+		rule, err := scanAnomalyRule(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning anomaly rule: %w", err)
 		}
@@ -72,30 +126,38 @@ func (s *AnomalyRuleService) GetAnomalyRules() ([]models.AnomalyRule, error) {
 }
 
 // GetAnomalyRule retrieves a specific anomaly rule using basic query methods
-func (s *AnomalyRuleService) GetAnomalyRule(id int64) (*models.AnomalyRule, error) {
+func (s *AnomalyRuleService) GetAnomalyRule(ctx context.Context, id int64) (*models.AnomalyRule, error) {
 	query := `
-		SELECT id, name, description, type, operator, value, is_active, created_at, updated_at
+		SELECT ` + anomalyRuleColumns + `
 		FROM anomaly_rules
 		WHERE id = $1
 	`
 
-	var rule models.AnomalyRule
-	row := s.db.QueryRow(query, id)
-	err := row.Scan(
-		&rule.ID,
-		&rule.Name,
-		&rule.Description,
-		&rule.Type,
-		&rule.Operator,
-		&rule.Value,
-		&rule.IsActive,
-		&rule.CreatedAt,
-		&rule.UpdatedAt,
-	)
+	rule, err := scanAnomalyRule(s.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("anomaly rule with ID %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("error querying or scanning anomaly rule: %w", err)
+	}
+
+	return &rule, nil
+}
 
+// lockAnomalyRule reads a rule inside tx, locking it until the transaction ends
+// so the snapshot recorded in its history matches the row being changed
+func lockAnomalyRule(ctx context.Context, tx *sql.Tx, id int64) (*models.AnomalyRule, error) {
+	query := `
+		SELECT ` + anomalyRuleColumns + `
+		FROM anomaly_rules
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	rule, err := scanAnomalyRule(tx.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("anomaly rule with ID %d not found", id) // More specific error
+			return nil, fmt.Errorf("anomaly rule with ID %d %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("error querying or scanning anomaly rule: %w", err)
 	}
@@ -103,41 +165,129 @@ func (s *AnomalyRuleService) GetAnomalyRule(id int64) (*models.AnomalyRule, erro
 	return &rule, nil
 }
 
-// CreateAnomalyRule creates a new anomaly rule using basic exec methods
-func (s *AnomalyRuleService) CreateAnomalyRule(rule *models.AnomalyRule) error {
+// recordRuleChange adds an entry to the rule's history inside tx. A nil oldRule
+// or newRule is stored as a null snapshot.
+func recordRuleChange(ctx context.Context, tx *sql.Tx, ruleID int64, action models.RuleAuditAction, oldRule, newRule *models.AnomalyRule, actor string) error {
+	// A nil interface rather than a nil slice, so the driver sends NULL
+	snapshot := func(rule *models.AnomalyRule) (interface{}, error) {
+		if rule == nil {
+			return nil, nil
+		}
+		return json.Marshal(rule)
+	}
+
+	oldValue, err := snapshot(oldRule)
+	if err != nil {
+		return fmt.Errorf("error encoding anomaly rule snapshot: %w", err)
+	}
+	newValue, err := snapshot(newRule)
+	if err != nil {
+		return fmt.Errorf("error encoding anomaly rule snapshot: %w", err)
+	}
+
+	query := `
+		INSERT INTO rule_audit_log (rule_id, action, old_value, new_value, actor)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.ExecContext(ctx, query, ruleID, action, oldValue, newValue, actor); err != nil {
+		return fmt.Errorf("error recording anomaly rule change: %w", err)
+	}
+	return nil
+}
+
+// CreateAnomalyRule creates a new anomaly rule and records it in the rule's history.
+// The rule's CreatedBy is also recorded as its first UpdatedBy. Suspicious but
+// valid settings are saved and reported in the rule's Warnings.
+func (s *AnomalyRuleService) CreateAnomalyRule(ctx context.Context, rule *models.AnomalyRule) error {
 	rule.CreatedAt = time.Now()
 	rule.UpdatedAt = rule.CreatedAt // Set UpdatedAt to CreatedAt on creation
+	rule.UpdatedBy = rule.CreatedBy
+	rule.Warnings = nil // Computed below, never taken from the request
+	applyRuleDefaults(rule)
+	if err := validateAnomalyRule(rule); err != nil {
+		return err
+	}
+
+	err := s.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		if err := insertAnomalyRule(ctx, tx, rule); err != nil {
+			return err
+		}
+		return recordRuleChange(ctx, tx, rule.ID, models.RuleAuditCreate, nil, rule, rule.CreatedBy)
+	})
+	if err != nil {
+		return err
+	}
+	rule.Warnings = ruleWarnings(rule)
+	return nil
+}
 
+// insertAnomalyRule inserts rule inside tx and reads its new ID back into rule
+func insertAnomalyRule(ctx context.Context, tx *sql.Tx, rule *models.AnomalyRule) error {
 	query := `
-		INSERT INTO anomaly_rules (name, description, type, operator, value, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO anomaly_rules (name, description, type, operator, value, value_high, value_mode, field, text_value, is_active, severity, logic, conditions, tags, created_at, updated_at, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id
 	`
 
 	// Use QueryRow because we need the returned ID
-	err := s.db.QueryRow(
+	err := tx.QueryRowContext(
+		ctx,
 		query,
 		rule.Name,
 		rule.Description,
 		rule.Type,
 		rule.Operator,
 		rule.Value,
+		rule.ValueHigh,
+		rule.ValueMode,
+		rule.Field,
+		rule.TextValue,
 		rule.IsActive,
+		rule.Severity,
+		rule.Logic,
+		rule.Conditions,
+		rule.Tags,
 		rule.CreatedAt,
 		rule.UpdatedAt,
+		rule.CreatedBy,
+		rule.UpdatedBy,
 	).Scan(&rule.ID)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %q", ErrDuplicateRuleName, rule.Name)
+		}
 		return fmt.Errorf("error creating anomaly rule: %w", err)
 	}
-
 	return nil
 }
 
-// UpdateAnomalyRule updates an existing anomaly rule using basic exec methods
-func (s *AnomalyRuleService) UpdateAnomalyRule(rule *models.AnomalyRule) error {
+// UpdateAnomalyRule updates an existing anomaly rule and records the change in
+// the rule's history. The rule's UpdatedBy is recorded, while its creation time
+// and CreatedBy are kept and read back into rule.
+func (s *AnomalyRuleService) UpdateAnomalyRule(ctx context.Context, rule *models.AnomalyRule) error {
 	rule.UpdatedAt = time.Now()
+	rule.Warnings = nil // Only reported on creation, never taken from the request
+	applyRuleDefaults(rule)
+	if err := validateAnomalyRule(rule); err != nil {
+		return err
+	}
 
+	return s.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		old, err := lockAnomalyRule(ctx, tx, rule.ID)
+		if err != nil {
+			return err
+		}
+		if err := updateAnomalyRuleRow(ctx, tx, rule); err != nil {
+			return err
+		}
+		return recordRuleChange(ctx, tx, rule.ID, models.RuleAuditUpdate, old, rule, rule.UpdatedBy)
+	})
+}
+
+// updateAnomalyRuleRow writes rule over the stored rule with the same ID inside tx,
+// reading its creation time and CreatedBy back into rule
+func updateAnomalyRuleRow(ctx context.Context, tx *sql.Tx, rule *models.AnomalyRule) error {
 	query := `
 		UPDATE anomaly_rules
 		SET name = $1,
@@ -145,76 +295,466 @@ func (s *AnomalyRuleService) UpdateAnomalyRule(rule *models.AnomalyRule) error {
 			type = $3,
 			operator = $4,
 			value = $5,
-			is_active = $6,
-			updated_at = $7
-		WHERE id = $8
+			value_high = $6,
+			value_mode = $7,
+			field = $8,
+			text_value = $9,
+			is_active = $10,
+			severity = $11,
+			logic = $12,
+			conditions = $13,
+			tags = $14,
+			updated_at = $15,
+			updated_by = $16
+		WHERE id = $17
+		RETURNING created_at, created_by
 	`
 
-	result, err := s.db.Exec(
+	err := tx.QueryRowContext(
+		ctx,
 		query,
 		rule.Name,
 		rule.Description,
 		rule.Type,
 		rule.Operator,
 		rule.Value,
+		rule.ValueHigh,
+		rule.ValueMode,
+		rule.Field,
+		rule.TextValue,
 		rule.IsActive,
+		rule.Severity,
+		rule.Logic,
+		rule.Conditions,
+		rule.Tags,
 		rule.UpdatedAt,
+		rule.UpdatedBy,
 		rule.ID,
-	)
+	).Scan(&rule.CreatedAt, &rule.CreatedBy)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %q", ErrDuplicateRuleName, rule.Name)
+		}
 		return fmt.Errorf("error updating anomaly rule: %w", err)
 	}
+	return nil
+}
+
+// DeleteAnomalyRule deletes an anomaly rule and records the deletion in the rule's
+// history, which is kept after the rule is gone
+func (s *AnomalyRuleService) DeleteAnomalyRule(ctx context.Context, id int64, actor string) error {
+	return s.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		old, err := lockAnomalyRule(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM anomaly_rules WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("error deleting anomaly rule: %w", err)
+		}
+
+		return recordRuleChange(ctx, tx, id, models.RuleAuditDelete, old, nil, actor)
+	})
+}
+
+// ToggleAnomalyRule sets the active state of an anomaly rule and records the
+// change in the rule's history
+func (s *AnomalyRuleService) ToggleAnomalyRule(ctx context.Context, id int64, isActive bool, actor string) error {
+	query := `
+		UPDATE anomaly_rules
+		SET is_active = $1,
+			updated_at = NOW(),
+			updated_by = $2
+		WHERE id = $3
+		RETURNING ` + anomalyRuleColumns
+
+	return s.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		old, err := lockAnomalyRule(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		toggled, err := scanAnomalyRule(tx.QueryRowContext(ctx, query, isActive, actor, id))
+		if err != nil {
+			return fmt.Errorf("error toggling anomaly rule: %w", err)
+		}
+
+		return recordRuleChange(ctx, tx, id, models.RuleAuditToggle, old, &toggled, actor)
+	})
+}
+
+// buildRuleFilter turns filter into the conditions of a WHERE clause, numbering
+// its arguments after the firstArg-1 arguments the caller already uses.
+// It returns ErrValidation when the filter is empty, so a bulk change cannot
+// select every rule by accident.
+func buildRuleFilter(filter RuleFilter, firstArg int) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+	if tag := normalizeRuleTag(filter.Tag); tag != "" {
+		args = append(args, tag)
+		conditions = append(conditions, fmt.Sprintf("tags ? $%d", firstArg+len(args)-1))
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		conditions = append(conditions, fmt.Sprintf("type = $%d", firstArg+len(args)-1))
+	}
+	if len(filter.IDs) > 0 {
+		args = append(args, pq.Array(filter.IDs))
+		conditions = append(conditions, fmt.Sprintf("id = ANY($%d)", firstArg+len(args)-1))
+	}
 
+	if len(conditions) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one of tag, type or ids is required", ErrValidation)
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// ToggleRulesBulk sets the active state of every rule matching filter and returns
+// how many rules changed. Rules already in the requested state are left alone
+// and not counted. The update and the history entry for each changed rule are
+// written by a single statement.
+func (s *AnomalyRuleService) ToggleRulesBulk(ctx context.Context, filter RuleFilter, isActive bool, actor string) (int, error) {
+	where, args, err := buildRuleFilter(filter, 3)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		WITH previous AS (
+			SELECT * FROM anomaly_rules
+			WHERE is_active <> $1 AND ` + where + `
+			FOR UPDATE
+		), toggled AS (
+			UPDATE anomaly_rules
+			SET is_active = $1,
+				updated_at = NOW(),
+				updated_by = $2
+			FROM previous
+			WHERE anomaly_rules.id = previous.id
+			RETURNING anomaly_rules.*
+		)
+		INSERT INTO rule_audit_log (rule_id, action, old_value, new_value, actor)
+		SELECT toggled.id, '` + string(models.RuleAuditToggle) + `', to_jsonb(previous), to_jsonb(toggled), $2
+		FROM toggled
+		JOIN previous ON previous.id = toggled.id`
+
+	result, err := s.db.Exec(ctx, query, append([]interface{}{isActive, actor}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("error toggling anomaly rules: %w", err)
+	}
+
+	// One history entry is written per changed rule
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		// Log this error but don't necessarily fail the operation
-		fmt.Printf("Could not get rows affected after update: %v\n", err)
-	} else if rowsAffected == 0 {
-		return fmt.Errorf("anomaly rule with ID %d not found for update", rule.ID)
+		return 0, fmt.Errorf("error counting toggled anomaly rules: %w", err)
 	}
 
-	return nil
+	s.logger.InfoContext(ctx, "toggled anomaly rules", "is_active", isActive, "count", rowsAffected)
+	return int(rowsAffected), nil
 }
 
-// DeleteAnomalyRule deletes an anomaly rule using basic exec methods
-func (s *AnomalyRuleService) DeleteAnomalyRule(id int64) error {
-	query := `DELETE FROM anomaly_rules WHERE id = $1`
-	result, err := s.db.Exec(query, id)
+// GetRuleHistory returns the recorded changes to a rule, oldest first. The history
+// of a deleted rule is still returned, and a rule without changes has an empty history.
+func (s *AnomalyRuleService) GetRuleHistory(ctx context.Context, ruleID int64) ([]models.RuleAuditEntry, error) {
+	query := `
+		SELECT id, rule_id, action, old_value, new_value, actor, created_at
+		FROM rule_audit_log
+		WHERE rule_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := s.db.Query(ctx, query, ruleID)
 	if err != nil {
-		return fmt.Errorf("error deleting anomaly rule: %w", err)
+		return nil, fmt.Errorf("error querying anomaly rule history: %w", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
+	entries := []models.RuleAuditEntry{}
+	for rows.Next() {
+		var entry models.RuleAuditEntry
+		var oldValue, newValue []byte
+		if err := rows.Scan(&entry.ID, &entry.RuleID, &entry.Action, &oldValue, &newValue, &entry.Actor, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning anomaly rule history: %w", err)
+		}
+		entry.OldValue = json.RawMessage(oldValue)
+		entry.NewValue = json.RawMessage(newValue)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating anomaly rule history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ExportAnomalyRules returns every rule as a portable document, ordered by name
+// so that exports of the same rules are identical
+func (s *AnomalyRuleService) ExportAnomalyRules(ctx context.Context) (*models.RuleExport, error) {
+	rules, err := s.GetAnomalyRules(ctx, SortOptions{Column: "name", Order: SortAscending}, "")
 	if err != nil {
-		fmt.Printf("Could not get rows affected after delete: %v\n", err)
-	} else if rowsAffected == 0 {
-		return fmt.Errorf("anomaly rule with ID %d not found for deletion", id)
+		return nil, err
 	}
 
-	return nil
+	export := &models.RuleExport{
+		Version:    models.RuleExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Rules:      make([]models.RuleDefinition, 0, len(rules)),
+	}
+	for _, rule := range rules {
+		export.Rules = append(export.Rules, rule.Definition())
+	}
+	return export, nil
 }
 
-// ToggleAnomalyRule toggles the active state of an anomaly rule using basic exec methods
-func (s *AnomalyRuleService) ToggleAnomalyRule(id int64, isActive bool) error {
+// ImportAnomalyRules creates or updates a rule for each definition in export,
+// matching existing rules by name, and reports how many were created, updated,
+// or already matched. Rules missing from the document are left alone. Every
+// definition is validated before anything is written, and the changes are
+// applied in one transaction, so a failed import changes nothing.
+func (s *AnomalyRuleService) ImportAnomalyRules(ctx context.Context, export *models.RuleExport, actor string) (*models.RuleImportResult, error) {
+	if export.Version != 0 && export.Version != models.RuleExportVersion {
+		return nil, fmt.Errorf("%w: unsupported rule export version %d", ErrValidation, export.Version)
+	}
+
+	imported := make([]models.AnomalyRule, 0, len(export.Rules))
+	seen := make(map[string]bool, len(export.Rules))
+	for i, definition := range export.Rules {
+		rule := definition.Rule()
+		rule.Name = strings.TrimSpace(rule.Name)
+		if rule.Name == "" {
+			return nil, fmt.Errorf("%w: rule %d: name is required", ErrValidation, i+1)
+		}
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("%w: rule %d: name %q appears more than once", ErrValidation, i+1, rule.Name)
+		}
+		seen[rule.Name] = true
+
+		applyRuleDefaults(&rule)
+		if err := validateAnomalyRule(&rule); err != nil {
+			return nil, fmt.Errorf("rule %d (%q): %w", i+1, rule.Name, err)
+		}
+		imported = append(imported, rule)
+	}
+
+	result := &models.RuleImportResult{}
+	now := time.Now()
+	err := s.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		*result = models.RuleImportResult{}
+		for i := range imported {
+			rule := &imported[i]
+			old, err := lockAnomalyRuleByName(ctx, tx, rule.Name)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case old == nil:
+				rule.CreatedAt, rule.UpdatedAt = now, now
+				rule.CreatedBy, rule.UpdatedBy = actor, actor
+				if err := insertAnomalyRule(ctx, tx, rule); err != nil {
+					return err
+				}
+				if err := recordRuleChange(ctx, tx, rule.ID, models.RuleAuditCreate, nil, rule, actor); err != nil {
+					return err
+				}
+				result.Created++
+			case sameRuleDefinition(old, rule):
+				result.Unchanged++
+			default:
+				rule.ID = old.ID
+				rule.UpdatedAt, rule.UpdatedBy = now, actor
+				if err := updateAnomalyRuleRow(ctx, tx, rule); err != nil {
+					return err
+				}
+				if err := recordRuleChange(ctx, tx, rule.ID, models.RuleAuditUpdate, old, rule, actor); err != nil {
+					return err
+				}
+				result.Updated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "imported anomaly rules", "created", result.Created, "updated", result.Updated, "unchanged", result.Unchanged)
+	return result, nil
+}
+
+// lockAnomalyRuleByName reads the rule with the given name inside tx, locking it
+// until the transaction ends. It returns nil when no rule has that name.
+func lockAnomalyRuleByName(ctx context.Context, tx *sql.Tx, name string) (*models.AnomalyRule, error) {
 	query := `
-		UPDATE anomaly_rules
-		SET is_active = $1,
-			updated_at = NOW()
-		WHERE id = $2
+		SELECT ` + anomalyRuleColumns + `
+		FROM anomaly_rules
+		WHERE name = $1
+		FOR UPDATE
 	`
 
-	result, err := s.db.Exec(query, isActive, id)
+	rule, err := scanAnomalyRule(tx.QueryRowContext(ctx, query, name))
 	if err != nil {
-		return fmt.Errorf("error toggling anomaly rule: %w", err)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error querying or scanning anomaly rule: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return &rule, nil
+}
+
+// sameRuleDefinition reports whether the stored rule already has every setting of
+// the imported one. Definitions are compared as JSON, so an empty and a missing
+// list of conditions or tags count as the same.
+func sameRuleDefinition(stored, imported *models.AnomalyRule) bool {
+	normalized := *stored
+	applyRuleDefaults(&normalized)
+	storedJSON, err := json.Marshal(normalized.Definition())
+	if err != nil {
+		return false
+	}
+	importedJSON, err := json.Marshal(imported.Definition())
 	if err != nil {
-		fmt.Printf("Could not get rows affected after toggle: %v\n", err)
-	} else if rowsAffected == 0 {
-		return fmt.Errorf("anomaly rule with ID %d not found for toggle", id)
+		return false
+	}
+	return bytes.Equal(storedJSON, importedJSON)
+}
+
+// applyRuleDefaults fills in optional rule fields that were left empty
+func applyRuleDefaults(rule *models.AnomalyRule) {
+	if rule.Severity == "" {
+		rule.Severity = models.SeverityMedium
 	}
+	if rule.Logic == "" {
+		rule.Logic = models.RuleLogicAnd
+	}
+	if rule.ValueMode == "" {
+		rule.ValueMode = models.ValueModeAbsolute
+	}
+	if rule.Type == "" && len(rule.Conditions) > 0 {
+		rule.Type = models.AnomalyTypeCompound
+	}
+	rule.Tags = normalizeRuleTags(rule.Tags)
+}
+
+// normalizeRuleTag trims and lowercases a tag so tags compare case-insensitively
+func normalizeRuleTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// normalizeRuleTags normalizes each tag, dropping blanks and duplicates.
+// It never returns nil so rules without tags are stored as an empty list.
+func normalizeRuleTags(tags models.StringSlice) models.StringSlice {
+	normalized := models.StringSlice{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalizeRuleTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// ruleFieldTypes are the anomaly types a rule condition can compare a job field against
+var ruleFieldTypes = map[models.AnomalyType]bool{
+	models.AnomalyTypeMaxSalary: true,
+	models.AnomalyTypeMinSalary: true,
+	models.AnomalyTypeRating:    true,
+}
 
+// validateAnomalyRule rejects rules that could never match because they reference
+// an unknown field type, comparison operator, or value mode, a percentile outside
+// 0-100, or a company rating threshold outside the range ratings are stored in
+func validateAnomalyRule(rule *models.AnomalyRule) error {
+	switch rule.ValueMode {
+	case "", models.ValueModeAbsolute:
+		for i, condition := range rule.EffectiveConditions() {
+			if condition.Type != models.AnomalyTypeRating {
+				continue
+			}
+			thresholds := []float64{condition.Value}
+			if condition.Operator.IsRange() {
+				thresholds = append(thresholds, condition.ValueHigh)
+			}
+			for _, threshold := range thresholds {
+				if threshold < MinCompanyRating || threshold > MaxCompanyRating {
+					return fmt.Errorf("%w: condition %d: company_rating threshold %g must be between %g and %g", ErrValidation, i+1, threshold, MinCompanyRating, MaxCompanyRating)
+				}
+			}
+		}
+	case models.ValueModePercentile:
+		for i, condition := range rule.EffectiveConditions() {
+			if condition.Type == models.AnomalyTypeText {
+				return fmt.Errorf("%w: condition %d: text conditions cannot use percentile values", ErrValidation, i+1)
+			}
+			percentiles := []float64{condition.Value}
+			if condition.Operator.IsRange() {
+				percentiles = append(percentiles, condition.ValueHigh)
+			}
+			for _, percentile := range percentiles {
+				if percentile < 0 || percentile > 100 {
+					return fmt.Errorf("%w: condition %d: percentile %g must be between 0 and 100", ErrValidation, i+1, percentile)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("%w: invalid value mode %q", ErrValidation, rule.ValueMode)
+	}
+
+	if len(rule.Conditions) == 0 {
+		return validateRuleCondition(rule.EffectiveConditions()[0])
+	}
+
+	if !rule.Type.IsValid() || (rule.Type != models.AnomalyTypeCompound && rule.Type != models.AnomalyTypeText && !ruleFieldTypes[rule.Type]) {
+		return fmt.Errorf("%w: unknown rule type %q", ErrValidation, rule.Type)
+	}
+	for i, condition := range rule.Conditions {
+		if err := validateRuleCondition(condition); err != nil {
+			return fmt.Errorf("condition %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateRuleCondition checks a single condition's type and operator, and that
+// range operators have an upper bound no lower than their lower bound
+func validateRuleCondition(condition models.RuleCondition) error {
+	if condition.Type == models.AnomalyTypeText {
+		return validateTextCondition(condition)
+	}
+	if !condition.Type.IsValid() || !ruleFieldTypes[condition.Type] {
+		return fmt.Errorf("%w: unknown rule type %q", ErrValidation, condition.Type)
+	}
+	if !condition.Operator.IsValid() || condition.Operator.IsText() {
+		return fmt.Errorf("%w: invalid operator %q", ErrValidation, condition.Operator)
+	}
+	if condition.Operator.IsRange() && condition.ValueHigh < condition.Value {
+		return fmt.Errorf("%w: %s upper bound %g is below lower bound %g", ErrValidation, condition.Operator, condition.ValueHigh, condition.Value)
+	}
+	return nil
+}
+
+// validateTextCondition checks a text condition's field, operator, and value.
+// Patterns are compiled here so invalid regular expressions are rejected when the
+// rule is saved rather than silently never matching during detection.
+func validateTextCondition(condition models.RuleCondition) error {
+	if !models.IsValidTextField(condition.Field) {
+		return fmt.Errorf("%w: unknown text field %q", ErrValidation, condition.Field)
+	}
+	if !condition.Operator.IsValid() || !condition.Operator.IsText() {
+		return fmt.Errorf("%w: invalid text operator %q", ErrValidation, condition.Operator)
+	}
+	if condition.TextValue == "" {
+		return fmt.Errorf("%w: %s requires a text_value", ErrValidation, condition.Operator)
+	}
+	if condition.Operator.IsPattern() {
+		if _, err := rulePattern(condition.TextValue); err != nil {
+			return fmt.Errorf("%w: invalid pattern %q: %v", ErrValidation, condition.TextValue, err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,802 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnomalyRuleValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		rule          models.AnomalyRule
+		expectedError string
+	}{
+		{
+			name: "invalid operator",
+			rule: models.AnomalyRule{
+				Name:     "Bad operator",
+				Type:     models.AnomalyTypeMaxSalary,
+				Operator: "!!",
+				Value:    500000,
+			},
+			expectedError: `invalid operator "!!"`,
+		},
+		{
+			name: "invalid type",
+			rule: models.AnomalyRule{
+				Name:     "Bad type",
+				Type:     "bogus",
+				Operator: models.GreaterThan,
+				Value:    500000,
+			},
+			expectedError: `unknown rule type "bogus"`,
+		},
+		{
+			name: "invalid condition in compound rule",
+			rule: models.AnomalyRule{
+				Name: "Bad condition",
+				Conditions: models.RuleConditions{
+					{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 500000},
+					{Type: models.AnomalyTypeRating, Operator: "=>", Value: 2},
+				},
+			},
+			expectedError: `condition 2: validation failed: invalid operator "=>"`,
+		},
+		{
+			name: "invalid value mode",
+			rule: models.AnomalyRule{
+				Name:      "Bad mode",
+				Type:      models.AnomalyTypeMaxSalary,
+				Operator:  models.GreaterThan,
+				Value:     99,
+				ValueMode: "relative",
+			},
+			expectedError: `invalid value mode "relative"`,
+		},
+		{
+			name: "percentile out of range",
+			rule: models.AnomalyRule{
+				Name:      "Bad percentile",
+				Type:      models.AnomalyTypeMaxSalary,
+				Operator:  models.GreaterThan,
+				Value:     150,
+				ValueMode: models.ValueModePercentile,
+			},
+			expectedError: `percentile 150 must be between 0 and 100`,
+		},
+		{
+			name: "between with upper bound below lower bound",
+			rule: models.AnomalyRule{
+				Name:      "Inverted range",
+				Type:      models.AnomalyTypeMaxSalary,
+				Operator:  models.Between,
+				Value:     1000,
+				ValueHigh: 0,
+			},
+			expectedError: `BETWEEN upper bound 0 is below lower bound 1000`,
+		},
+		{
+			name: "not between condition with inverted range",
+			rule: models.AnomalyRule{
+				Name: "Inverted condition range",
+				Conditions: models.RuleConditions{
+					{Type: models.AnomalyTypeRating, Operator: models.NotBetween, Value: 5, ValueHigh: 1},
+				},
+			},
+			expectedError: `condition 1: validation failed: NOT BETWEEN upper bound 1 is below lower bound 5`,
+		},
+		{
+			name: "percentile range upper bound out of range",
+			rule: models.AnomalyRule{
+				Name:      "Bad percentile range",
+				Type:      models.AnomalyTypeMaxSalary,
+				Operator:  models.Between,
+				Value:     10,
+				ValueHigh: 120,
+				ValueMode: models.ValueModePercentile,
+			},
+			expectedError: `percentile 120 must be between 0 and 100`,
+		},
+		{
+			name: "rating threshold above five",
+			rule: models.AnomalyRule{
+				Name:     "Impossible rating",
+				Type:     models.AnomalyTypeRating,
+				Operator: models.GreaterThan,
+				Value:    7,
+			},
+			expectedError: `company_rating threshold 7 must be between 0 and 5`,
+		},
+		{
+			name: "rating range below zero in compound rule",
+			rule: models.AnomalyRule{
+				Name: "Negative rating range",
+				Conditions: models.RuleConditions{
+					{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 500000},
+					{Type: models.AnomalyTypeRating, Operator: models.Between, Value: -1, ValueHigh: 2},
+				},
+			},
+			expectedError: `condition 2: company_rating threshold -1 must be between 0 and 5`,
+		},
+		{
+			name: "text rule with invalid pattern",
+			rule: models.AnomalyRule{
+				Name:      "Bad pattern",
+				Type:      models.AnomalyTypeText,
+				Operator:  models.Matches,
+				Field:     "company_website",
+				TextValue: "^https?://(",
+			},
+			expectedError: `invalid pattern "^https?://("`,
+		},
+		{
+			name: "text rule with unknown field",
+			rule: models.AnomalyRule{
+				Name:      "Bad field",
+				Type:      models.AnomalyTypeText,
+				Operator:  models.Contains,
+				Field:     "max_salary",
+				TextValue: "mlm",
+			},
+			expectedError: `unknown text field "max_salary"`,
+		},
+		{
+			name: "text rule with numeric operator",
+			rule: models.AnomalyRule{
+				Name:      "Bad text operator",
+				Type:      models.AnomalyTypeText,
+				Operator:  models.GreaterThan,
+				Field:     "job_description",
+				TextValue: "mlm",
+			},
+			expectedError: `invalid text operator ">"`,
+		},
+		{
+			name: "text rule without text value",
+			rule: models.AnomalyRule{
+				Name:     "Empty text",
+				Type:     models.AnomalyTypeText,
+				Operator: models.Contains,
+				Field:    "job_description",
+			},
+			expectedError: `contains requires a text_value`,
+		},
+		{
+			name: "numeric rule with text operator",
+			rule: models.AnomalyRule{
+				Name:     "Bad numeric operator",
+				Type:     models.AnomalyTypeMaxSalary,
+				Operator: models.Contains,
+				Value:    500000,
+			},
+			expectedError: `invalid operator "contains"`,
+		},
+		{
+			name: "text rule with percentile values",
+			rule: models.AnomalyRule{
+				Name:      "Percentile text",
+				Type:      models.AnomalyTypeText,
+				Operator:  models.Contains,
+				Field:     "job_description",
+				TextValue: "mlm",
+				ValueMode: models.ValueModePercentile,
+			},
+			expectedError: `text conditions cannot use percentile values`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No database calls are expected; validation must fail before any query runs
+			mockDB := new(MockDB)
+			service := NewAnomalyRuleService(mockDB, nil)
+
+			createRule := tt.rule
+			err := service.CreateAnomalyRule(context.Background(), &createRule)
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.Contains(t, err.Error(), tt.expectedError)
+
+			updateRule := tt.rule
+			updateRule.ID = 1
+			err = service.UpdateAnomalyRule(context.Background(), &updateRule)
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.Contains(t, err.Error(), tt.expectedError)
+
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestValidateAnomalyRuleAcceptsKnownRules(t *testing.T) {
+	rules := []models.AnomalyRule{
+		{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan},
+		{Type: models.AnomalyTypeMinSalary, Operator: models.LessThanOrEqual},
+		{Type: models.AnomalyTypeRating, Operator: models.Equal},
+		{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 99, ValueMode: models.ValueModePercentile},
+		{Type: models.AnomalyTypeMaxSalary, Operator: models.Between, Value: 0, ValueHigh: 1000},
+		{Type: models.AnomalyTypeRating, Operator: models.GreaterThan, Value: 90, ValueMode: models.ValueModePercentile},
+		{Type: models.AnomalyTypeRating, Operator: models.NotBetween, Value: 3, ValueHigh: 3},
+		{Type: models.AnomalyTypeText, Operator: models.Contains, Field: "job_description", TextValue: "MLM"},
+		{Type: models.AnomalyTypeText, Operator: models.NotMatches, Field: "company_website", TextValue: `^https?://`},
+		{
+			Type: models.AnomalyTypeCompound,
+			Conditions: models.RuleConditions{
+				{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 500000},
+				{Type: models.AnomalyTypeRating, Operator: models.LessThan, Value: 2},
+			},
+		},
+		{
+			Type: models.AnomalyTypeCompound,
+			Conditions: models.RuleConditions{
+				{Type: models.AnomalyTypeText, Operator: models.Matches, Field: "job_title", TextValue: `(?i)\bearn \$\d+`},
+				{Type: models.AnomalyTypeRating, Operator: models.LessThan, Value: 2},
+			},
+		},
+	}
+
+	for _, rule := range rules {
+		assert.NoError(t, validateAnomalyRule(&rule))
+	}
+}
+
+func TestCreateAnomalyRuleDuplicateName(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("INSERT INTO anomaly_rules").
+		WillReturnError(&pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "anomaly_rules_name_key"`})
+	sqlMock.ExpectRollback()
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	err = service.CreateAnomalyRule(context.Background(), &models.AnomalyRule{
+		Name:     "Negative Salary",
+		Type:     models.AnomalyTypeMaxSalary,
+		Operator: models.LessThan,
+		Value:    0,
+	})
+
+	assert.ErrorIs(t, err, ErrDuplicateRuleName)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NotContains(t, err.Error(), "duplicate key")
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+// anomalyRuleRowColumns are the columns selected with anomalyRuleColumns
+var anomalyRuleRowColumns = []string{"id", "name", "description", "type", "operator", "value", "value_high", "value_mode", "field", "text_value", "is_active", "severity", "logic", "conditions", "tags", "created_at", "updated_at", "created_by", "updated_by"}
+
+// anomalyRuleRow returns a stored max salary rule with the given ID and active state
+func anomalyRuleRow(id int64, isActive bool) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(anomalyRuleRowColumns).
+		AddRow(id, "High Salary", "", "max_salary", ">", 500000.0, 0.0, "absolute", "", "", isActive, "high", "and", nil, []byte(`[]`), now, now, "alice", "alice")
+}
+
+func TestGetAnomalyRulesByTag(t *testing.T) {
+	ruleColumns := anomalyRuleRowColumns
+
+	t.Run("tag filters rules", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		sqlMock.ExpectQuery("FROM anomaly_rules\\s+WHERE tags \\? \\$1\\s+ORDER BY created_at DESC").
+			WithArgs("fraud").
+			WillReturnRows(sqlmock.NewRows(ruleColumns).
+				AddRow(1, "High Salary", "", "max_salary", ">", 500000.0, 0.0, "absolute", "", "", true, "high", "and", nil, []byte(`["fraud","compliance"]`), now, now, "alice", "bob"))
+
+		service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+		rules, err := service.GetAnomalyRules(context.Background(), SortOptions{}, " Fraud ")
+
+		assert.NoError(t, err)
+		assert.Len(t, rules, 1)
+		assert.Equal(t, models.StringSlice{"fraud", "compliance"}, rules[0].Tags)
+		assert.Equal(t, "alice", rules[0].CreatedBy)
+		assert.Equal(t, "bob", rules[0].UpdatedBy)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("no tag returns every rule", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery("FROM anomaly_rules\\s+ORDER BY created_at DESC").
+			WithoutArgs().
+			WillReturnRows(sqlmock.NewRows(ruleColumns))
+
+		service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+		_, err = service.GetAnomalyRules(context.Background(), SortOptions{}, "")
+
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestCreateAnomalyRuleNormalizesTags(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// The tags argument is the fourteenth column of the insert
+	args := make([]driver.Value, 18)
+	for i := range args {
+		args[i] = sqlmock.AnyArg()
+	}
+	args[13] = []byte(`["fraud","data-quality"]`)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("INSERT INTO anomaly_rules").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	sqlMock.ExpectExec("INSERT INTO rule_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	rule := &models.AnomalyRule{
+		Name:     "High Salary",
+		Type:     models.AnomalyTypeMaxSalary,
+		Operator: models.GreaterThan,
+		Value:    500000,
+		Tags:     models.StringSlice{" Fraud", "data-quality", "", "FRAUD"},
+	}
+	assert.NoError(t, service.CreateAnomalyRule(context.Background(), rule))
+	assert.Equal(t, models.StringSlice{"fraud", "data-quality"}, rule.Tags)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCreateAnomalyRuleReportsWarnings(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("INSERT INTO anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	sqlMock.ExpectExec("INSERT INTO rule_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	rule := &models.AnomalyRule{
+		Name:     "Exact salary",
+		Type:     models.AnomalyTypeMaxSalary,
+		Operator: models.Equal,
+		Value:    100000,
+		Warnings: []string{"from the request"},
+	}
+	assert.NoError(t, service.CreateAnomalyRule(context.Background(), rule))
+	assert.Equal(t, int64(7), rule.ID)
+	assert.Equal(t, []string{"max_salary = 100000 compares a salary for exact equality, which rarely matches; use BETWEEN for a range"}, rule.Warnings)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestNormalizeRuleTagsNeverNil(t *testing.T) {
+	tags := normalizeRuleTags(nil)
+
+	assert.NotNil(t, tags)
+	assert.Empty(t, tags)
+}
+
+func TestToggleRulesBulk(t *testing.T) {
+	t.Run("returns the number of rules changed", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectExec(`WHERE is_active <> \$1 AND tags \? \$3 AND type = \$4 AND id = ANY\(\$5\)\s+FOR UPDATE[\s\S]+UPDATE anomaly_rules\s+SET is_active = \$1,\s+updated_at = NOW\(\),\s+updated_by = \$2[\s\S]+INSERT INTO rule_audit_log`).
+			WithArgs(false, "carol", "fraud", models.AnomalyTypeMaxSalary, "{1,2,3}").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+		filter := RuleFilter{Tag: " Fraud ", Type: models.AnomalyTypeMaxSalary, IDs: []int64{1, 2, 3}}
+		updated, err := service.ToggleRulesBulk(context.Background(), filter, false, "carol")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, updated)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("single criterion", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectExec(`WHERE is_active <> \$1 AND type = \$3\s+FOR UPDATE`).
+			WithArgs(true, "", models.AnomalyTypeRating).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+		updated, err := service.ToggleRulesBulk(context.Background(), RuleFilter{Type: models.AnomalyTypeRating}, true, "")
+
+		assert.NoError(t, err)
+		assert.Zero(t, updated)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("empty filter is rejected", func(t *testing.T) {
+		// No database calls are expected
+		mockDB := new(MockDB)
+		service := NewAnomalyRuleService(mockDB, nil)
+
+		_, err := service.ToggleRulesBulk(context.Background(), RuleFilter{Tag: "  ", IDs: []int64{}}, false, "")
+
+		assert.ErrorIs(t, err, ErrValidation)
+		mockDB.AssertExpectations(t)
+	})
+}
+
+func TestAnomalyRuleAuditFieldsRoundTrip(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// The creator is recorded as both created_by and updated_by, the last two insert columns
+	createArgs := make([]driver.Value, 18)
+	for i := range createArgs {
+		createArgs[i] = sqlmock.AnyArg()
+	}
+	createArgs[16], createArgs[17] = "alice", "alice"
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("INSERT INTO anomaly_rules").
+		WithArgs(createArgs...).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	sqlMock.ExpectExec("INSERT INTO rule_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+
+	// Updates record the editor and read back the stored creator
+	createdAt := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	updateArgs := make([]driver.Value, 17)
+	for i := range updateArgs {
+		updateArgs[i] = sqlmock.AnyArg()
+	}
+	updateArgs[15], updateArgs[16] = "bob", int64(7)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("FOR UPDATE").WithArgs(int64(7)).WillReturnRows(anomalyRuleRow(7, true))
+	sqlMock.ExpectQuery(`UPDATE anomaly_rules[\s\S]+updated_by = \$16\s+WHERE id = \$17\s+RETURNING created_at, created_by`).
+		WithArgs(updateArgs...).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "created_by"}).AddRow(createdAt, "alice"))
+	sqlMock.ExpectExec("INSERT INTO rule_audit_log").WillReturnResult(sqlmock.NewResult(2, 1))
+	sqlMock.ExpectCommit()
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	rule := &models.AnomalyRule{
+		Name:      "High Salary",
+		Type:      models.AnomalyTypeMaxSalary,
+		Operator:  models.GreaterThan,
+		Value:     500000,
+		CreatedBy: "alice",
+	}
+	assert.NoError(t, service.CreateAnomalyRule(context.Background(), rule))
+	assert.Equal(t, "alice", rule.CreatedBy)
+	assert.Equal(t, "alice", rule.UpdatedBy)
+
+	// A client-supplied creator is replaced by the stored one
+	update := *rule
+	update.CreatedBy = "mallory"
+	update.UpdatedBy = "bob"
+	assert.NoError(t, service.UpdateAnomalyRule(context.Background(), &update))
+	assert.Equal(t, "alice", update.CreatedBy)
+	assert.Equal(t, "bob", update.UpdatedBy)
+	assert.Equal(t, createdAt, update.CreatedAt)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUpdateAnomalyRuleNotFound(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("FOR UPDATE").
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows(anomalyRuleRowColumns))
+	sqlMock.ExpectRollback()
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	rule := &models.AnomalyRule{ID: 42, Name: "Missing", Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan}
+	err = service.UpdateAnomalyRule(context.Background(), rule)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+// auditSnapshot matches a rule_audit_log snapshot argument, which is null or the
+// JSON of a rule with the given active state
+type auditSnapshot struct {
+	null     bool
+	isActive bool
+}
+
+// Match implements sqlmock.Argument
+func (a auditSnapshot) Match(value driver.Value) bool {
+	if a.null {
+		return value == nil
+	}
+	raw, ok := value.([]byte)
+	if !ok {
+		return false
+	}
+	var rule models.AnomalyRule
+	return json.Unmarshal(raw, &rule) == nil && rule.IsActive == a.isActive
+}
+
+func TestRuleMutationsWriteAuditEntries(t *testing.T) {
+	rule := func() *models.AnomalyRule {
+		return &models.AnomalyRule{ID: 7, Name: "High Salary", Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 500000, IsActive: true}
+	}
+
+	tests := []struct {
+		name   string
+		expect func(sqlMock sqlmock.Sqlmock)
+		mutate func(service *AnomalyRuleService) error
+	}{
+		{
+			name: "create",
+			expect: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery("INSERT INTO anomaly_rules").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				sqlMock.ExpectExec("INSERT INTO rule_audit_log").
+					WithArgs(int64(7), models.RuleAuditCreate, auditSnapshot{null: true}, auditSnapshot{isActive: true}, "alice").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			mutate: func(service *AnomalyRuleService) error {
+				created := rule()
+				created.CreatedBy = "alice"
+				return service.CreateAnomalyRule(context.Background(), created)
+			},
+		},
+		{
+			name: "update",
+			expect: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery("FOR UPDATE").WithArgs(int64(7)).WillReturnRows(anomalyRuleRow(7, false))
+				sqlMock.ExpectQuery("UPDATE anomaly_rules").
+					WillReturnRows(sqlmock.NewRows([]string{"created_at", "created_by"}).AddRow(time.Now(), "alice"))
+				sqlMock.ExpectExec("INSERT INTO rule_audit_log").
+					WithArgs(int64(7), models.RuleAuditUpdate, auditSnapshot{isActive: false}, auditSnapshot{isActive: true}, "bob").
+					WillReturnResult(sqlmock.NewResult(2, 1))
+			},
+			mutate: func(service *AnomalyRuleService) error {
+				updated := rule()
+				updated.UpdatedBy = "bob"
+				return service.UpdateAnomalyRule(context.Background(), updated)
+			},
+		},
+		{
+			name: "delete",
+			expect: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery("FOR UPDATE").WithArgs(int64(7)).WillReturnRows(anomalyRuleRow(7, true))
+				sqlMock.ExpectExec("DELETE FROM anomaly_rules").WithArgs(int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec("INSERT INTO rule_audit_log").
+					WithArgs(int64(7), models.RuleAuditDelete, auditSnapshot{isActive: true}, auditSnapshot{null: true}, "carol").
+					WillReturnResult(sqlmock.NewResult(3, 1))
+			},
+			mutate: func(service *AnomalyRuleService) error {
+				return service.DeleteAnomalyRule(context.Background(), 7, "carol")
+			},
+		},
+		{
+			name: "toggle",
+			expect: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery("FOR UPDATE").WithArgs(int64(7)).WillReturnRows(anomalyRuleRow(7, true))
+				sqlMock.ExpectQuery("UPDATE anomaly_rules").
+					WithArgs(false, "dave", int64(7)).
+					WillReturnRows(anomalyRuleRow(7, false))
+				sqlMock.ExpectExec("INSERT INTO rule_audit_log").
+					WithArgs(int64(7), models.RuleAuditToggle, auditSnapshot{isActive: true}, auditSnapshot{isActive: false}, "dave").
+					WillReturnResult(sqlmock.NewResult(4, 1))
+			},
+			mutate: func(service *AnomalyRuleService) error {
+				return service.ToggleAnomalyRule(context.Background(), 7, false, "dave")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			// The change and its audit entry share one transaction
+			sqlMock.ExpectBegin()
+			tt.expect(sqlMock)
+			sqlMock.ExpectCommit()
+
+			assert.NoError(t, tt.mutate(NewAnomalyRuleService(&SQLDB{db: db}, nil)))
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRuleAuditEntryRolledBackWithChange(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// A failed history write undoes the delete
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("FOR UPDATE").WithArgs(int64(7)).WillReturnRows(anomalyRuleRow(7, true))
+	sqlMock.ExpectExec("DELETE FROM anomaly_rules").WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec("INSERT INTO rule_audit_log").WillReturnError(assert.AnError)
+	sqlMock.ExpectRollback()
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	err = service.DeleteAnomalyRule(context.Background(), 7, "carol")
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestGetRuleHistory(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	sqlMock.ExpectQuery("FROM rule_audit_log\\s+WHERE rule_id = \\$1\\s+ORDER BY created_at, id").
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rule_id", "action", "old_value", "new_value", "actor", "created_at"}).
+			AddRow(1, 7, "create", nil, []byte(`{"id":7,"is_active":true}`), "alice", now).
+			AddRow(2, 7, "delete", []byte(`{"id":7,"is_active":true}`), nil, "carol", now))
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	entries, err := service.GetRuleHistory(context.Background(), 7)
+
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, models.RuleAuditCreate, entries[0].Action)
+		assert.Nil(t, entries[0].OldValue)
+		assert.JSONEq(t, `{"id":7,"is_active":true}`, string(entries[0].NewValue))
+		assert.Equal(t, models.RuleAuditDelete, entries[1].Action)
+		assert.Equal(t, "carol", entries[1].Actor)
+		assert.Nil(t, entries[1].NewValue)
+	}
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestExportAnomalyRules(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("FROM anomaly_rules\\s+ORDER BY name ASC").WillReturnRows(anomalyRuleRow(7, true))
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	export, err := service.ExportAnomalyRules(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RuleExportVersion, export.Version)
+	assert.Equal(t, []models.RuleDefinition{{
+		Name:      "High Salary",
+		Type:      models.AnomalyTypeMaxSalary,
+		Operator:  models.GreaterThan,
+		Value:     500000,
+		ValueMode: models.ValueModeAbsolute,
+		IsActive:  true,
+		Severity:  models.SeverityHigh,
+		Logic:     models.RuleLogicAnd,
+		Tags:      models.StringSlice{},
+	}}, export.Rules)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestImportAnomalyRules(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	export := &models.RuleExport{
+		Version: models.RuleExportVersion,
+		Rules: []models.RuleDefinition{
+			// Stored with a 500000 threshold, so it is updated
+			{Name: "High Salary", Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 600000, IsActive: true, Severity: models.SeverityHigh},
+			// Not stored yet, so it is created
+			{Name: "Low Rating", Type: models.AnomalyTypeRating, Operator: models.LessThan, Value: 2, IsActive: true},
+			// Stored exactly like this once defaults are applied, so it is left alone
+			{Name: "Low Salary", Type: models.AnomalyTypeMinSalary, Operator: models.LessThan, Value: 20000, Tags: models.StringSlice{"Salary"}},
+		},
+	}
+
+	// Every rule is matched and written in one transaction
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("WHERE name = \\$1\\s+FOR UPDATE").WithArgs("High Salary").WillReturnRows(anomalyRuleRow(7, true))
+	sqlMock.ExpectQuery("UPDATE anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "created_by"}).AddRow(now, "alice"))
+	sqlMock.ExpectExec("INSERT INTO rule_audit_log").
+		WithArgs(int64(7), models.RuleAuditUpdate, auditSnapshot{isActive: true}, auditSnapshot{isActive: true}, "erin").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectQuery("WHERE name = \\$1\\s+FOR UPDATE").WithArgs("Low Rating").WillReturnRows(sqlmock.NewRows(anomalyRuleRowColumns))
+	sqlMock.ExpectQuery("INSERT INTO anomaly_rules").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	sqlMock.ExpectExec("INSERT INTO rule_audit_log").
+		WithArgs(int64(8), models.RuleAuditCreate, auditSnapshot{null: true}, auditSnapshot{isActive: true}, "erin").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	sqlMock.ExpectQuery("WHERE name = \\$1\\s+FOR UPDATE").WithArgs("Low Salary").
+		WillReturnRows(sqlmock.NewRows(anomalyRuleRowColumns).
+			AddRow(9, "Low Salary", "", "min_salary", "<", 20000.0, 0.0, "absolute", "", "", false, "medium", "and", nil, []byte(`["salary"]`), now, now, "alice", "alice"))
+	sqlMock.ExpectCommit()
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	result, err := service.ImportAnomalyRules(context.Background(), export, "erin")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &models.RuleImportResult{Created: 1, Updated: 1, Unchanged: 1}, result)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestImportAnomalyRulesRejectsInvalidDocuments(t *testing.T) {
+	valid := models.RuleDefinition{Name: "High Salary", Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 500000}
+
+	tests := []struct {
+		name   string
+		export *models.RuleExport
+	}{
+		{
+			name:   "unsupported version",
+			export: &models.RuleExport{Version: 99, Rules: []models.RuleDefinition{valid}},
+		},
+		{
+			name:   "missing name",
+			export: &models.RuleExport{Rules: []models.RuleDefinition{{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan}}},
+		},
+		{
+			name:   "repeated name",
+			export: &models.RuleExport{Rules: []models.RuleDefinition{valid, valid}},
+		},
+		{
+			name:   "invalid rule",
+			export: &models.RuleExport{Rules: []models.RuleDefinition{valid, {Name: "Bad", Type: "unknown", Operator: models.GreaterThan}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			// Nothing is written when any rule in the document is invalid
+			service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+			_, err = service.ImportAnomalyRules(context.Background(), tt.export, "erin")
+
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestImportAnomalyRulesRolledBackOnFailure(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	export := &models.RuleExport{Rules: []models.RuleDefinition{
+		{Name: "High Salary", Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 600000},
+		{Name: "Low Rating", Type: models.AnomalyTypeRating, Operator: models.LessThan, Value: 2},
+	}}
+
+	// The second rule fails to save, so the first rule's update is undone too
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("FOR UPDATE").WithArgs("High Salary").WillReturnRows(anomalyRuleRow(7, true))
+	sqlMock.ExpectQuery("UPDATE anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "created_by"}).AddRow(time.Now(), "alice"))
+	sqlMock.ExpectExec("INSERT INTO rule_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectQuery("FOR UPDATE").WithArgs("Low Rating").WillReturnRows(sqlmock.NewRows(anomalyRuleRowColumns))
+	sqlMock.ExpectQuery("INSERT INTO anomaly_rules").WillReturnError(assert.AnError)
+	sqlMock.ExpectRollback()
+
+	service := NewAnomalyRuleService(&SQLDB{db: db}, nil)
+	result, err := service.ImportAnomalyRules(context.Background(), export, "erin")
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Nil(t, result)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
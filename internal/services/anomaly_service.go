@@ -4,20 +4,52 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/metrics"
 	"github.com/ainesh01/anomaly_detection/internal/models"
 	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // AnomalyServiceInterface defines the interface for anomaly detection and retrieval operations
 type AnomalyServiceInterface interface {
-	DetectAnomalies(job *models.JobData) ([]models.Anomaly, error)
-	GetAnomaliesByJobID(jobID string) ([]models.Anomaly, error)
-	GetAllAnomalies() ([]models.Anomaly, error)
-	DetectAnomaliesForAllJobs() error
+	DetectAnomalies(ctx context.Context, job *models.JobData) ([]models.Anomaly, error)
+	GetAnomaliesByJobID(ctx context.Context, jobID string, includeResolved bool) ([]models.Anomaly, error)
+	GetAnomalyByID(ctx context.Context, id int64) (*models.Anomaly, error)
+	GetAllAnomalies(ctx context.Context, includeResolved bool) ([]models.Anomaly, error)
+	GetAllAnomaliesPaged(ctx context.Context, limit, offset int, sort SortOptions, filter AnomalyFilter) ([]models.Anomaly, int, error)
+	GetDetailedAnomaliesPaged(ctx context.Context, limit, offset int, sort SortOptions, filter AnomalyFilter) ([]models.DetailedAnomaly, int, error)
+	ResolveAnomaly(ctx context.Context, id int64, note string) error
+	AcknowledgeAnomaly(ctx context.Context, id int64, assignee string) error
+	AddAnomalyNote(ctx context.Context, anomalyID int64, author, body string) (*models.AnomalyNote, error)
+	GetAnomalyNotes(ctx context.Context, anomalyID int64) ([]models.AnomalyNote, error)
+	DeleteAnomalies(ctx context.Context, filter AnomalyFilter) (int, error)
+	EvaluateRule(ctx context.Context, ruleID int64, jobs []models.JobData) ([]models.RuleEvaluation, error)
+	DetectAnomaliesForAllJobs(ctx context.Context, dryRun bool) ([]models.Anomaly, error)
+	DetectAnomaliesSince(ctx context.Context, since time.Time) (int, error)
+	DetectDuplicates(ctx context.Context) ([]models.Anomaly, error)
+	GetAnomalyStats(ctx context.Context, window time.Duration) (*models.AnomalyStats, error)
+	GetAnomalyTypeCounts(ctx context.Context) (map[string]int, error)
+	GetAnomaliesByCompany(ctx context.Context, limit, offset int, sort SortOptions, includeResolved bool) ([]models.CompanyAnomalies, int, error)
+	GetJobRiskScore(ctx context.Context, jobID string) (float64, error)
+	GetJobsByRiskScore(ctx context.Context, limit, offset int) ([]models.JobRiskScore, int, error)
+	GetDetectionRuns(ctx context.Context, limit, offset int) ([]models.DetectionRun, int, error)
+	DetectionConfig() *config.DetectionConfig
 }
 
 // AnomalyType represents the specific type of anomaly detected
@@ -46,9 +78,18 @@ const (
 	LessThan           ComparisonOperator = "<"
 	LessThanOrEqual    ComparisonOperator = "<="
 	Equal              ComparisonOperator = "="
+	Between            ComparisonOperator = "BETWEEN"
+	NotBetween         ComparisonOperator = "NOT BETWEEN"
 
-	// Standard deviation threshold for anomaly detection
-	StdDevThreshold = 3.0
+	// Z-score magnitude above which a deviation anomaly is considered high severity
+	HighSeverityZScore = 5.0
+
+	// Multiple of the interquartile range beyond Q1/Q3 at which a value is an outlier
+	IQRMultiplier = 1.5
+
+	// Scales the median absolute deviation so the modified z-score is comparable
+	// to a standard z-score for normally distributed data
+	MADScaleFactor = 0.6745
 )
 
 // ValidOperators is a list of all valid comparison operators
@@ -58,6 +99,8 @@ var ValidOperators = []ComparisonOperator{
 	LessThan,
 	LessThanOrEqual,
 	Equal,
+	Between,
+	NotBetween,
 }
 
 // IsValidOperator checks if the given operator is valid
@@ -87,9 +130,18 @@ type Anomaly struct {
 
 // Statistics holds statistical measures used for relative anomaly detection
 type Statistics struct {
+	// Number of jobs the statistics were computed over
+	SampleCount int
+
 	// Salary statistics
 	AvgSalary    float64
 	SalaryStdDev float64
+	SalaryQ1     float64
+	SalaryQ3     float64
+
+	// Robust salary statistics, always computed over all jobs with a salary
+	SalaryMedian float64
+	SalaryMAD    float64 // Median absolute deviation from SalaryMedian
 
 	// Requirements statistics
 	AvgRequirements float64
@@ -98,220 +150,613 @@ type Statistics struct {
 	// Company rating statistics
 	AvgRating    float64
 	RatingStdDev float64
+	RatingQ1     float64
+	RatingQ3     float64
+
+	// Sample covariance of salary and company rating, for the multivariate check
+	SalaryRatingCovariance float64
 
 	// Location statistics
 	AvgLatitude     float64
 	LatitudeStdDev  float64
 	AvgLongitude    float64
 	LongitudeStdDev float64
+
+	// Statistics over recently collected jobs, set during detection when trend detection is enabled
+	Window *Statistics
 }
 
 // AnomalyService handles anomaly detection logic
 type AnomalyService struct {
 	db          DatabaseServiceInterface
 	ruleService AnomalyRuleServiceInterface // Inject rule service for getting rules
+	cfg         *config.DetectionConfig
+	notifier    AlertNotifier // Optional, alerts are not sent when nil
+	logger      *slog.Logger
+	detectors   []Detector // Run against every job, in order
+
+	notifyChannel string // Postgres channel announcing new anomalies, none when empty
 }
 
-// NewAnomalyService creates a new AnomalyService
-func NewAnomalyService(db DatabaseServiceInterface, ruleService AnomalyRuleServiceInterface) *AnomalyService {
+// NewAnomalyService creates a new AnomalyService.
+// A nil detection config falls back to the default detection settings,
+// a nil notifier disables alerting, and a nil logger uses slog.Default().
+func NewAnomalyService(db DatabaseServiceInterface, ruleService AnomalyRuleServiceInterface, cfg *config.DetectionConfig, notifier AlertNotifier, logger *slog.Logger) *AnomalyService {
+	if cfg == nil {
+		cfg = config.DefaultDetectionConfig()
+	}
 	return &AnomalyService{
 		db:          db,
 		ruleService: ruleService,
+		cfg:         cfg,
+		notifier:    notifier,
+		logger:      loggerOrDefault(logger),
+		detectors:   defaultDetectors(cfg),
 	}
 }
 
-// DetectAnomalies processes job data to detect anomalies based on rules
-func (s *AnomalyService) DetectAnomalies(job *models.JobData) ([]models.Anomaly, error) {
-	var detectedAnomalies []models.Anomaly
+// RegisterDetector adds a detector to run after the built-in ones. It must be
+// called before detection starts, as the detectors are read without locking.
+func (s *AnomalyService) RegisterDetector(detector Detector) {
+	s.detectors = append(s.detectors, detector)
+}
+
+// anomalySaver persists a detected anomaly, filling in its stored ID and creation time
+type anomalySaver func(ctx context.Context, anomaly *models.Anomaly) error
 
-	// Check for null values in required fields
-	var nullViolations []string
-	if job.CompanyName == "" {
-		nullViolations = append(nullViolations, "company_name")
+// detectionContext holds the statistics and rules that each job is compared against.
+// It is loaded once per detection batch so the aggregate queries don't run once per job.
+type detectionContext struct {
+	stats        *Statistics
+	groups       map[string]*Statistics // nil unless statistics are grouped
+	salaryMedian float64
+	salaryMAD    float64
+	windowStats  *Statistics // nil when trend detection is disabled
+	rules        []models.AnomalyRule
+}
+
+// loadDetectionContext runs the statistics queries and fetches the anomaly rules
+// that detection needs, with percentile rules resolved to absolute cutoffs
+func (s *AnomalyService) loadDetectionContext(ctx context.Context) (*detectionContext, error) {
+	var dc detectionContext
+	var err error
+
+	if dc.stats, err = s.getStatistics(ctx); err != nil {
+		return nil, fmt.Errorf("error getting statistics: %w", err)
 	}
-	if job.JobTitle == "" {
-		nullViolations = append(nullViolations, "job_title")
+	if s.cfg.StatsGroupBy != config.StatsGroupByNone {
+		if dc.groups, err = s.getGroupStatistics(ctx, s.cfg.StatsGroupBy); err != nil {
+			return nil, fmt.Errorf("error getting group statistics: %w", err)
+		}
 	}
-	if job.JobDescription == "" {
-		nullViolations = append(nullViolations, "job_description")
+	if dc.salaryMedian, dc.salaryMAD, err = s.getSalaryMAD(ctx); err != nil {
+		return nil, fmt.Errorf("error getting salary median absolute deviation: %w", err)
 	}
-	if job.City == "" {
-		nullViolations = append(nullViolations, "city")
+	if s.cfg.EnableTrend && s.cfg.TrendWindow > 0 {
+		if dc.windowStats, err = s.getWindowedStatistics(ctx, time.Now().Add(-s.cfg.TrendWindow)); err != nil {
+			return nil, fmt.Errorf("error getting windowed statistics: %w", err)
+		}
 	}
-	if job.CompanyAddress == "" {
-		nullViolations = append(nullViolations, "company_address")
+	if dc.rules, err = s.loadRules(ctx); err != nil {
+		return nil, err
 	}
-	if job.CompanyWebsite == "" {
-		nullViolations = append(nullViolations, "company_website")
+
+	return &dc, nil
+}
+
+// loadRules fetches the anomaly rules and resolves their percentile thresholds
+func (s *AnomalyService) loadRules(ctx context.Context) ([]models.AnomalyRule, error) {
+	ctx, span := startSpan(ctx, "AnomalyService.loadRules")
+	defer span.End()
+
+	rules, err := s.ruleService.GetAnomalyRules(ctx, SortOptions{}, "")
+	if err != nil {
+		err = fmt.Errorf("error getting anomaly rules via service: %w", err)
+		recordSpanError(span, err)
+		return nil, err
 	}
-	if job.JobLink == "" {
-		nullViolations = append(nullViolations, "job_link")
+	if rules, err = s.resolvePercentileRules(ctx, rules); err != nil {
+		recordSpanError(span, err)
+		return nil, err
 	}
+	span.SetAttributes(attribute.Int("rules", len(rules)))
+	return rules, nil
+}
 
-	// If there are null violations, create an anomaly
-	if len(nullViolations) > 0 {
-		nullAnomaly := models.Anomaly{
-			Type:        models.AnomalyTypeNullValues,
-			JobID:       job.JobID,
-			Description: "Required fields are null",
-			Value:       0,
-			Threshold:   0,
-			Operator:    models.Equal,
-			CreatedAt:   time.Now(),
-			Violations:  nullViolations,
+// DetectionConfig returns a copy of the detection settings the service runs with
+func (s *AnomalyService) DetectionConfig() *config.DetectionConfig {
+	return s.cfg.Clone()
+}
+
+// DetectAnomalies processes job data to detect anomalies based on rules
+func (s *AnomalyService) DetectAnomalies(ctx context.Context, job *models.JobData) ([]models.Anomaly, error) {
+	ctx, span := startSpan(ctx, "AnomalyService.DetectAnomalies", attribute.String("job_id", job.JobID))
+	defer span.End()
+
+	dc, err := s.loadDetectionContext(ctx)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	anomalies := s.detectAnomaliesWithContext(ctx, job, dc, s.saveAnomaly)
+	span.SetAttributes(attribute.Int("anomalies", len(anomalies)))
+	return anomalies, nil
+}
+
+// detectAnomaliesWithContext runs every registered detector against the job using the
+// preloaded statistics and rules in dc, handing each anomaly to save.
+// Anomalies that fail to save are logged and left out of the result. If the
+// job was deleted while detection ran, its remaining anomalies are skipped.
+// Detectors see the job's salaries converted to annual ones, matching the statistics.
+func (s *AnomalyService) detectAnomaliesWithContext(ctx context.Context, job *models.JobData, dc *detectionContext, save anomalySaver) []models.Anomaly {
+	job = annualizeSalaries(job, s.cfg.SalaryMultipliers)
+	var detectedAnomalies []models.Anomaly
+	jobDeleted := false
+
+	// record saves an anomaly and keeps it in the result, logging failures.
+	// Nothing more is saved once the job is gone.
+	record := func(anomaly *models.Anomaly) {
+		if jobDeleted {
+			return
 		}
-		if err := s.saveAnomaly(&nullAnomaly); err != nil {
-			fmt.Printf("Error saving null value anomaly for job %s: %v\n", job.JobID, err)
-		} else {
-			detectedAnomalies = append(detectedAnomalies, nullAnomaly)
+		err := save(ctx, anomaly)
+		switch {
+		case errors.Is(err, ErrJobDeleted):
+			jobDeleted = true
+			s.logger.WarnContext(ctx, "job was deleted during detection, skipping it", "job_id", job.JobID)
+		case err != nil:
+			s.logger.ErrorContext(ctx, "error saving anomaly", "job_id", job.JobID, "type", anomaly.Type, "err", err)
+		default:
+			detectedAnomalies = append(detectedAnomalies, *anomaly)
 		}
 	}
 
-	// Get statistics for standard deviation checks
-	stats, err := s.getStatistics()
-	if err != nil {
-		return nil, fmt.Errorf("error getting statistics: %w", err)
+	// Copy the statistics so the shared context is left untouched
+	stats := *dc.stats
+	if s.cfg.StatsGroupBy != config.StatsGroupByNone {
+		stats = *statisticsForJob(job, dc.stats, dc.groups, s.cfg.StatsGroupBy, s.cfg.MinGroupSamples)
 	}
+	stats.SalaryMedian, stats.SalaryMAD = dc.salaryMedian, dc.salaryMAD
+	stats.Window = dc.windowStats
 
-	// Check for standard deviation anomalies in numeric fields
-	if job.MaxSalary != nil {
-		zScore := (*job.MaxSalary - stats.AvgSalary) / stats.SalaryStdDev
-		if math.Abs(zScore) > StdDevThreshold {
-			deviationAnomaly := models.Anomaly{
-				Type:        models.AnomalyTypeDeviation,
-				JobID:       job.JobID,
-				Description: fmt.Sprintf("Salary deviates significantly from mean (z-score: %.2f)", zScore),
-				Value:       *job.MaxSalary,
-				Threshold:   stats.AvgSalary,
-				Operator:    models.Equal,
-				CreatedAt:   time.Now(),
-				Violations:  []string{"max_salary"},
-			}
-			if err := s.saveAnomaly(&deviationAnomaly); err != nil {
-				fmt.Printf("Error saving salary deviation anomaly for job %s: %v\n", job.JobID, err)
-			} else {
-				detectedAnomalies = append(detectedAnomalies, deviationAnomaly)
-			}
+	for _, detector := range s.detectors {
+		for _, anomaly := range detector.Detect(job, &stats, dc.rules) {
+			record(&anomaly)
 		}
 	}
 
-	if job.CompanyRating != 0 {
-		zScore := (job.CompanyRating - stats.AvgRating) / stats.RatingStdDev
-		if math.Abs(zScore) > StdDevThreshold {
-			deviationAnomaly := models.Anomaly{
-				Type:        models.AnomalyTypeDeviation,
-				JobID:       job.JobID,
-				Description: fmt.Sprintf("Company rating deviates significantly from mean (z-score: %.2f)", zScore),
-				Value:       job.CompanyRating,
-				Threshold:   stats.AvgRating,
-				Operator:    models.Equal,
-				CreatedAt:   time.Now(),
-				Violations:  []string{"company_rating"},
-			}
-			if err := s.saveAnomaly(&deviationAnomaly); err != nil {
-				fmt.Printf("Error saving rating deviation anomaly for job %s: %v\n", job.JobID, err)
-			} else {
-				detectedAnomalies = append(detectedAnomalies, deviationAnomaly)
-			}
-		}
+	s.logger.DebugContext(ctx, "detected anomalies", "job_id", job.JobID, "count", len(detectedAnomalies))
+	return detectedAnomalies
+}
+
+// statisticsColumns returns the aggregates shared by the global and grouped
+// statistics queries, computing the salary ones over the salary expression
+func statisticsColumns(salary string) string {
+	return `
+			COUNT(*) as sample_count,
+			AVG(` + salary + `) as avg_salary,
+			STDDEV(` + salary + `) as salary_stddev,
+			percentile_cont(0.25) WITHIN GROUP (ORDER BY ` + salary + `) as salary_q1,
+			percentile_cont(0.75) WITHIN GROUP (ORDER BY ` + salary + `) as salary_q3,
+			AVG(company_rating) as avg_rating,
+			STDDEV(company_rating) as rating_stddev,
+			percentile_cont(0.25) WITHIN GROUP (ORDER BY company_rating) as rating_q1,
+			percentile_cont(0.75) WITHIN GROUP (ORDER BY company_rating) as rating_q3,
+			COVAR_SAMP(` + salary + `, company_rating) as salary_rating_covariance,
+			AVG(latitude) as avg_latitude,
+			STDDEV(latitude) as latitude_stddev,
+			AVG(longitude) as avg_longitude,
+			STDDEV(longitude) as longitude_stddev`
+}
+
+// annualSalary returns an SQL expression for the salary column converted to an
+// annual salary with the configured multipliers
+func (s *AnomalyService) annualSalary(column string) string {
+	return annualSalaryExpr(column, s.cfg.SalaryMultipliers)
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// getStatistics calculates global statistical measures for anomaly detection
+func (s *AnomalyService) getStatistics(ctx context.Context) (*Statistics, error) {
+	ctx, span := startSpan(ctx, "AnomalyService.getStatistics")
+	defer span.End()
+
+	query := `
+		SELECT ` + statisticsColumns(s.annualSalary("max_salary")) + `
+		FROM jobs
+		WHERE max_salary IS NOT NULL AND company_rating IS NOT NULL
+	`
+
+	stats, err := scanStatistics(s.db.QueryRow(ctx, query))
+	if err != nil {
+		err = fmt.Errorf("error getting statistics: %w", err)
+		recordSpanError(span, err)
+		return nil, err
 	}
+	return stats, nil
+}
 
-	// Get active rules from the rule service
-	rules, err := s.ruleService.GetAnomalyRules()
+// getGroupStatistics calculates statistical measures for each distinct value of
+// the given job column. The column must be one of the config.StatsGroupBy values.
+func (s *AnomalyService) getGroupStatistics(ctx context.Context, column string) (map[string]*Statistics, error) {
+	switch column {
+	case config.StatsGroupByRoleType, config.StatsGroupByCity:
+	default:
+		return nil, fmt.Errorf("unsupported statistics grouping %q", column)
+	}
+
+	// column is checked against a fixed list above, so it is safe to interpolate
+	query := `
+		SELECT ` + column + `, ` + statisticsColumns(s.annualSalary("max_salary")) + `
+		FROM jobs
+		WHERE max_salary IS NOT NULL AND company_rating IS NOT NULL AND ` + column + ` IS NOT NULL
+		GROUP BY ` + column
+
+	rows, err := s.db.Query(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("error getting anomaly rules via service: %w", err)
+		return nil, fmt.Errorf("error querying group statistics: %w", err)
 	}
+	defer rows.Close()
 
-	// Apply each active rule
-	for _, rule := range rules {
-		if !rule.IsActive {
-			continue // Skip inactive rules
+	groups := make(map[string]*Statistics)
+	for rows.Next() {
+		var key string
+		stats, err := scanStatistics(rows, &key)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning group statistics: %w", err)
 		}
+		groups[key] = stats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group statistics: %w", err)
+	}
 
-		anomalyDetected := false
-		var actualValue float64
+	return groups, nil
+}
 
-		// Check based on rule type
-		switch rule.Type {
-		case models.AnomalyTypeMaxSalary:
-			if job.MaxSalary != nil {
-				actualValue = *job.MaxSalary
-				anomalyDetected = compareValues(actualValue, rule.Value, rule.Operator)
-			}
-		case models.AnomalyTypeMinSalary:
-			if job.MinSalary != nil {
-				actualValue = *job.MinSalary
-				anomalyDetected = compareValues(actualValue, rule.Value, rule.Operator)
-			}
-		case models.AnomalyTypeRating:
-			// Assuming CompanyRating is not a pointer and always present
-			actualValue = job.CompanyRating
-			anomalyDetected = compareValues(actualValue, rule.Value, rule.Operator)
-		default:
-			// Log or handle unknown rule type if necessary
+// scanStatistics reads a row selected with statisticsColumns, scanning any
+// leading columns into prefix first. AVG and STDDEV return NULL for an empty
+// set, and STDDEV also returns NULL for a single row, so values are scanned
+// as nullable and left at zero.
+func scanStatistics(row rowScanner, prefix ...interface{}) (*Statistics, error) {
+	var sampleCount int
+	var avgSalary, salaryStdDev, salaryQ1, salaryQ3 sql.NullFloat64
+	var avgRating, ratingStdDev, ratingQ1, ratingQ3, salaryRatingCovariance sql.NullFloat64
+	var avgLatitude, latitudeStdDev, avgLongitude, longitudeStdDev sql.NullFloat64
+	err := row.Scan(append(prefix,
+		&sampleCount,
+		&avgSalary,
+		&salaryStdDev,
+		&salaryQ1,
+		&salaryQ3,
+		&avgRating,
+		&ratingStdDev,
+		&ratingQ1,
+		&ratingQ3,
+		&salaryRatingCovariance,
+		&avgLatitude,
+		&latitudeStdDev,
+		&avgLongitude,
+		&longitudeStdDev,
+	)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statistics{
+		SampleCount:  sampleCount,
+		AvgSalary:    avgSalary.Float64,
+		SalaryStdDev: salaryStdDev.Float64,
+		SalaryQ1:     salaryQ1.Float64,
+		SalaryQ3:     salaryQ3.Float64,
+		AvgRating:    avgRating.Float64,
+		RatingStdDev: ratingStdDev.Float64,
+		RatingQ1:     ratingQ1.Float64,
+		RatingQ3:     ratingQ3.Float64,
+
+		SalaryRatingCovariance: salaryRatingCovariance.Float64,
+
+		AvgLatitude:     avgLatitude.Float64,
+		LatitudeStdDev:  latitudeStdDev.Float64,
+		AvgLongitude:    avgLongitude.Float64,
+		LongitudeStdDev: longitudeStdDev.Float64,
+	}, nil
+}
+
+// salaryMADQuery selects the median of the salary expression and the median
+// absolute deviation from it. The median is computed once in a subquery and
+// reused by the outer aggregate; both values are NULL when no job has a salary.
+func salaryMADQuery(salary string) string {
+	return `
+	WITH salary_median AS (
+		SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY ` + salary + `) AS median
+		FROM jobs
+		WHERE max_salary IS NOT NULL
+	)
+	SELECT
+		(SELECT median FROM salary_median) as salary_median,
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY ABS(` + salary + ` - (SELECT median FROM salary_median))) as salary_mad
+	FROM jobs
+	WHERE max_salary IS NOT NULL
+`
+}
+
+// getSalaryMAD returns the median annual max salary and its median absolute deviation
+func (s *AnomalyService) getSalaryMAD(ctx context.Context) (float64, float64, error) {
+	var median, mad sql.NullFloat64
+	if err := s.db.QueryRow(ctx, salaryMADQuery(s.annualSalary("max_salary"))).Scan(&median, &mad); err != nil {
+		return 0, 0, fmt.Errorf("error querying salary median absolute deviation: %w", err)
+	}
+	return median.Float64, mad.Float64, nil
+}
+
+// percentileFields maps the rule field types to the jobs column their percentiles
+// are computed over, the filter that excludes jobs missing that field, and
+// whether the column is a salary to convert to an annual one first
+var percentileFields = map[models.AnomalyType]struct {
+	column, filter string
+	salary         bool
+}{
+	models.AnomalyTypeMaxSalary: {column: "max_salary", filter: "max_salary IS NOT NULL", salary: true},
+	models.AnomalyTypeMinSalary: {column: "min_salary", filter: "min_salary IS NOT NULL", salary: true},
+	models.AnomalyTypeRating:    {column: "company_rating", filter: "company_rating IS NOT NULL"},
+}
+
+// getFieldPercentile returns the value of a job field at the given percentile (0-100).
+// The result is invalid when no job has a value for the field.
+func (s *AnomalyService) getFieldPercentile(ctx context.Context, fieldType models.AnomalyType, percentile float64) (sql.NullFloat64, error) {
+	field, ok := percentileFields[fieldType]
+	if !ok {
+		return sql.NullFloat64{}, fmt.Errorf("%w: percentiles are not supported for rule type %q", ErrValidation, fieldType)
+	}
+
+	value := field.column
+	if field.salary {
+		value = s.annualSalary(field.column)
+	}
+	query := `SELECT percentile_cont($1) WITHIN GROUP (ORDER BY ` + value + `) FROM jobs WHERE ` + field.filter
+
+	var cutoff sql.NullFloat64
+	if err := s.db.QueryRow(ctx, query, percentile/100).Scan(&cutoff); err != nil {
+		return sql.NullFloat64{}, fmt.Errorf("error querying %s percentile: %w", field.column, err)
+	}
+	return cutoff, nil
+}
+
+// resolvePercentileRules converts active percentile rules into absolute rules by
+// replacing each condition's percentile with the field's value at that percentile.
+// Each field/percentile cutoff is queried once. Rules whose field has no data yet
+// are left out, since there is nothing to compare against.
+func (s *AnomalyService) resolvePercentileRules(ctx context.Context, rules []models.AnomalyRule) ([]models.AnomalyRule, error) {
+	type cutoffKey struct {
+		fieldType  models.AnomalyType
+		percentile float64
+	}
+	cutoffs := make(map[cutoffKey]sql.NullFloat64)
+	cutoffFor := func(fieldType models.AnomalyType, percentile float64) (sql.NullFloat64, error) {
+		key := cutoffKey{fieldType: fieldType, percentile: percentile}
+		if cutoff, ok := cutoffs[key]; ok {
+			return cutoff, nil
+		}
+		cutoff, err := s.getFieldPercentile(ctx, fieldType, percentile)
+		if err != nil {
+			return sql.NullFloat64{}, err
+		}
+		cutoffs[key] = cutoff
+		return cutoff, nil
+	}
+
+	resolved := make([]models.AnomalyRule, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.IsActive || rule.ValueMode != models.ValueModePercentile {
+			resolved = append(resolved, rule)
 			continue
 		}
 
-		if anomalyDetected {
-			anomaly := models.Anomaly{
-				Type:        rule.Type,
-				JobID:       job.JobID,
-				Description: rule.Description,
-				Value:       actualValue,
-				Threshold:   rule.Value,
-				Operator:    rule.Operator,
-				CreatedAt:   time.Now(),
+		conditions := append(models.RuleConditions(nil), rule.EffectiveConditions()...)
+		hasData := true
+		for i, condition := range conditions {
+			cutoff, err := cutoffFor(condition.Type, condition.Value)
+			if err != nil {
+				return nil, fmt.Errorf("error resolving percentile rule %d: %w", rule.ID, err)
 			}
+			if !cutoff.Valid {
+				hasData = false
+				break
+			}
+			conditions[i].Value = cutoff.Float64
 
-			// Save the detected anomaly immediately
-			if err := s.saveAnomaly(&anomaly); err != nil {
-				// Log the error but continue processing other rules/anomalies
-				fmt.Printf("Error saving anomaly for job %s, rule %d: %v\n", job.JobID, rule.ID, err)
-			} else {
-				detectedAnomalies = append(detectedAnomalies, anomaly)
+			// Range operators resolve their upper bound the same way
+			if condition.Operator.IsRange() {
+				high, err := cutoffFor(condition.Type, condition.ValueHigh)
+				if err != nil {
+					return nil, fmt.Errorf("error resolving percentile rule %d: %w", rule.ID, err)
+				}
+				conditions[i].ValueHigh = high.Float64
 			}
 		}
+		if !hasData {
+			s.logger.WarnContext(ctx, "skipping percentile rule with no job data to compute its cutoff", "rule_id", rule.ID)
+			continue
+		}
+
+		rule.Conditions = conditions
+		rule.ValueMode = models.ValueModeAbsolute
+		resolved = append(resolved, rule)
 	}
 
-	return detectedAnomalies, nil
+	return resolved, nil
 }
 
-// getStatistics retrieves statistical measures for anomaly detection
-func (s *AnomalyService) getStatistics() (*Statistics, error) {
+// getWindowedStatistics calculates statistical measures over jobs collected after since
+func (s *AnomalyService) getWindowedStatistics(ctx context.Context, since time.Time) (*Statistics, error) {
 	query := `
-		SELECT 
-			AVG(max_salary) as avg_salary,
-			STDDEV(max_salary) as salary_stddev,
-			AVG(company_rating) as avg_rating,
-			STDDEV(company_rating) as rating_stddev
+		SELECT ` + statisticsColumns(s.annualSalary("max_salary")) + `
 		FROM jobs
-		WHERE max_salary IS NOT NULL AND company_rating > 0
+		WHERE max_salary IS NOT NULL AND date_collected > $1
 	`
 
-	var stats Statistics
-	err := s.db.QueryRow(query).Scan(
-		&stats.AvgSalary,
-		&stats.SalaryStdDev,
-		&stats.AvgRating,
-		&stats.RatingStdDev,
-	)
-
+	stats, err := scanStatistics(s.db.QueryRow(ctx, query, since))
 	if err != nil {
-		return nil, fmt.Errorf("error getting statistics: %w", err)
+		return nil, fmt.Errorf("error getting windowed statistics: %w", err)
+	}
+	return stats, nil
+}
+
+// trendAnomaly returns an anomaly when the job's max salary deviates from the
+// rolling window statistics by more than threshold standard deviations. Windows
+// with fewer than minSamples jobs are too sparse to compare against.
+func trendAnomaly(job *models.JobData, window *Statistics, threshold float64, minSamples int, severity string) *models.Anomaly {
+	if job.MaxSalary == nil || window.SampleCount < minSamples {
+		return nil
+	}
+	zScore, ok := safeZScore(*job.MaxSalary, window.AvgSalary, window.SalaryStdDev)
+	if !ok || math.Abs(zScore) <= threshold {
+		return nil
+	}
+	return &models.Anomaly{
+		Type:        models.AnomalyTypeTrend,
+		JobID:       job.JobID,
+		Description: fmt.Sprintf("Salary deviates from the recent rolling average (z-score: %.2f)", zScore),
+		Value:       *job.MaxSalary,
+		Threshold:   window.AvgSalary,
+		Operator:    deviationOperator(zScore),
+		CreatedAt:   time.Now(),
+		Violations:  []string{"max_salary"},
+		Severity:    deviationSeverity(zScore, severity),
+	}
+}
+
+// jobGroupKey returns the value of the grouping column for a job.
+// The boolean result is false when the job has no value for the column.
+func jobGroupKey(job *models.JobData, groupBy string) (string, bool) {
+	switch groupBy {
+	case config.StatsGroupByRoleType:
+		if job.RoleType != nil && *job.RoleType != "" {
+			return *job.RoleType, true
+		}
+	case config.StatsGroupByCity:
+		if job.City != "" {
+			return job.City, true
+		}
+	}
+	return "", false
+}
+
+// statisticsForJob picks the statistics bucket matching the job's group,
+// falling back to the global statistics when the job has no group or its
+// group has fewer than minSamples jobs
+func statisticsForJob(job *models.JobData, global *Statistics, groups map[string]*Statistics, groupBy string, minSamples int) *Statistics {
+	key, ok := jobGroupKey(job, groupBy)
+	if !ok {
+		return global
+	}
+	if groupStats, ok := groups[key]; ok && groupStats.SampleCount >= minSamples {
+		return groupStats
+	}
+	return global
+}
+
+// deviationSeverity raises the base severity to high when the z-score is extreme,
+// leaving it unchanged otherwise or when it is already high or above
+func deviationSeverity(zScore float64, base string) string {
+	if math.Abs(zScore) > HighSeverityZScore && models.SeverityRank(base) < models.SeverityRank(models.SeverityHigh) {
+		return models.SeverityHigh
 	}
+	return base
+}
 
-	return &stats, nil
+// deviationOperator describes which side of the mean a z-score lies on: a value
+// above the mean is reported as greater than it, one below as less than it
+func deviationOperator(zScore float64) models.ComparisonOperator {
+	if zScore > 0 {
+		return models.GreaterThan
+	}
+	return models.LessThan
 }
 
-// saveAnomaly saves a single anomaly using basic exec methods
-func (s *AnomalyService) saveAnomaly(anomaly *models.Anomaly) error {
+// iqrOutlier checks whether value falls outside the Tukey fences derived from q1 and q3.
+// It returns the fence that was crossed and the operator describing the violation.
+// A zero interquartile range is treated as insufficient spread and never flags a value.
+func iqrOutlier(value, q1, q3 float64) (float64, models.ComparisonOperator, bool) {
+	iqr := q3 - q1
+	if iqr <= 0 {
+		return 0, "", false
+	}
+	lower := q1 - IQRMultiplier*iqr
+	upper := q3 + IQRMultiplier*iqr
+	if value < lower {
+		return lower, models.LessThan, true
+	}
+	if value > upper {
+		return upper, models.GreaterThan, true
+	}
+	return 0, "", false
+}
+
+// safeZScore computes the z-score of value against mean and stddev.
+// The boolean result is false when the standard deviation is zero or not finite,
+// in which case the score is meaningless and no deviation check should be made.
+func safeZScore(value, mean, stddev float64) (float64, bool) {
+	if stddev == 0 || math.IsNaN(stddev) || math.IsInf(stddev, 0) {
+		return 0, false
+	}
+	zScore := (value - mean) / stddev
+	if math.IsNaN(zScore) || math.IsInf(zScore, 0) {
+		return 0, false
+	}
+	return zScore, true
+}
+
+// madOutlier computes the modified z-score MADScaleFactor*(value-median)/mad and
+// reports whether its magnitude exceeds cutoff. When more than half the values
+// equal the median the MAD is zero, which carries no spread information, so
+// nothing is flagged.
+func madOutlier(value, median, mad, cutoff float64) (float64, bool) {
+	if mad <= 0 || math.IsNaN(mad) || math.IsInf(mad, 0) {
+		return 0, false
+	}
+	modifiedZ := MADScaleFactor * (value - median) / mad
+	if math.IsNaN(modifiedZ) || math.IsInf(modifiedZ, 0) {
+		return 0, false
+	}
+	return modifiedZ, math.Abs(modifiedZ) > cutoff
+}
+
+// saveAnomaly saves a single anomaly using basic exec methods.
+// Anomalies are keyed on (job_id, type, violations), so re-detecting the same
+// anomaly refreshes the stored row instead of inserting a duplicate.
+func (s *AnomalyService) saveAnomaly(ctx context.Context, anomaly *models.Anomaly) error {
+	ctx, span := startSpan(ctx, "AnomalyService.saveAnomaly",
+		attribute.String("job_id", anomaly.JobID), attribute.String("type", string(anomaly.Type)))
+	defer span.End()
+
+	if anomaly.Violations == nil {
+		anomaly.Violations = []string{}
+	}
+	if anomaly.Severity == "" {
+		anomaly.Severity = s.cfg.SeverityFor(anomaly.Type)
+	}
+
 	query := `
-		INSERT INTO anomalies (job_id, type, description, value, threshold, operator, created_at, violations)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id
+		INSERT INTO anomalies (job_id, type, description, value, threshold, operator, created_at, violations, severity)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (job_id, type, violations) DO UPDATE SET
+			description = EXCLUDED.description,
+			value = EXCLUDED.value,
+			threshold = EXCLUDED.threshold,
+			operator = EXCLUDED.operator,
+			severity = EXCLUDED.severity
+		RETURNING id, created_at, (xmax = 0) AS inserted
 	`
 	// Use QueryRow as we need the ID back
+	var inserted bool
 	err := s.db.QueryRow(
+		ctx,
 		query,
 		anomaly.JobID,
 		anomaly.Type,
@@ -321,17 +766,330 @@ func (s *AnomalyService) saveAnomaly(anomaly *models.Anomaly) error {
 		anomaly.Operator,
 		anomaly.CreatedAt,
 		pq.Array(anomaly.Violations),
-	).Scan(&anomaly.ID)
+		anomaly.Severity,
+	).Scan(&anomaly.ID, &anomaly.CreatedAt, &inserted)
 
+	if isForeignKeyViolation(err) {
+		// The job was deleted after it was read, e.g. by a concurrent delete
+		return fmt.Errorf("job %s: %w", anomaly.JobID, ErrJobDeleted)
+	}
 	if err != nil {
-		return fmt.Errorf("error inserting anomaly: %w", err)
+		err = fmt.Errorf("error inserting anomaly: %w", err)
+		recordSpanError(span, err)
+		return err
+	}
+	span.SetAttributes(attribute.Bool("inserted", inserted))
+
+	// Only count and alert on newly stored anomalies, so repeated detection runs
+	// neither inflate the counter nor re-alert
+	if inserted {
+		metrics.AnomaliesDetected.WithLabelValues(string(anomaly.Type), anomaly.Severity).Inc()
+		s.notifyAnomaly(ctx, anomaly)
+		s.publishAnomaly(ctx, anomaly)
 	}
 	return nil
 }
 
-// compareValues performs the comparison based on the operator
-func compareValues(value, threshold float64, operator models.ComparisonOperator) bool {
-	switch operator {
+// notifyAnomaly sends an alert for an anomaly at or above the configured minimum severity.
+// Delivery failures are logged and never fail detection.
+func (s *AnomalyService) notifyAnomaly(ctx context.Context, anomaly *models.Anomaly) {
+	if s.notifier == nil || models.SeverityRank(anomaly.Severity) < models.SeverityRank(s.cfg.AlertMinSeverity) {
+		return
+	}
+
+	details, err := json.Marshal(anomaly)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "error encoding alert details", "job_id", anomaly.JobID, "err", err)
+		return
+	}
+
+	alert := models.AnomalyAlert{
+		Severity:    anomaly.Severity,
+		Description: fmt.Sprintf("Job %s: %s", anomaly.JobID, anomaly.Description),
+		Details:     details,
+		CreatedAt:   anomaly.CreatedAt,
+		Status:      "open",
+	}
+	if err := s.notifier.Notify(ctx, alert); err != nil {
+		s.logger.WarnContext(ctx, "error sending alert", "job_id", anomaly.JobID, "severity", anomaly.Severity, "err", err)
+	}
+}
+
+// geoOutlierAnomaly returns an anomaly when the job's latitude or longitude
+// deviates from the mean coordinates by more than threshold standard deviations.
+// Jobs without both coordinates are skipped. The anomaly's value is the larger
+// of the two z-scores by magnitude.
+func geoOutlierAnomaly(job *models.JobData, stats *Statistics, threshold float64, severity string) *models.Anomaly {
+	if job.Latitude == nil || job.Longitude == nil {
+		return nil
+	}
+
+	var violations []string
+	var maxZScore float64
+	latZScore, latOK := safeZScore(*job.Latitude, stats.AvgLatitude, stats.LatitudeStdDev)
+	if latOK && math.Abs(latZScore) > threshold {
+		violations = append(violations, "latitude")
+		maxZScore = latZScore
+	}
+	longZScore, longOK := safeZScore(*job.Longitude, stats.AvgLongitude, stats.LongitudeStdDev)
+	if longOK && math.Abs(longZScore) > threshold {
+		violations = append(violations, "longitude")
+		if math.Abs(longZScore) > math.Abs(maxZScore) {
+			maxZScore = longZScore
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return &models.Anomaly{
+		Type:  models.AnomalyTypeGeoOutlier,
+		JobID: job.JobID,
+		Description: fmt.Sprintf("Location (%.4f, %.4f) is far from the usual job locations (latitude z-score: %.2f, longitude z-score: %.2f)",
+			*job.Latitude, *job.Longitude, latZScore, longZScore),
+		Value:      maxZScore,
+		Threshold:  threshold,
+		Operator:   models.GreaterThan,
+		CreatedAt:  time.Now(),
+		Violations: violations,
+		Severity:   deviationSeverity(maxZScore, severity),
+	}
+}
+
+// salaryRatingDistance returns the Mahalanobis distance of a salary and company
+// rating pair from the mean of both, using the salary and rating variances and
+// their covariance. Unlike two separate z-scores it accounts for how the fields
+// vary together. It reports false when the covariance matrix is singular, such
+// as when either field has no spread or every job lies on one line.
+func salaryRatingDistance(salary, rating float64, stats *Statistics) (float64, bool) {
+	salaryVar := stats.SalaryStdDev * stats.SalaryStdDev
+	ratingVar := stats.RatingStdDev * stats.RatingStdDev
+	covariance := stats.SalaryRatingCovariance
+
+	// The determinant is compared relative to the variances, as salaries and
+	// ratings differ by orders of magnitude
+	det := salaryVar*ratingVar - covariance*covariance
+	if salaryVar == 0 || ratingVar == 0 || det <= 1e-9*salaryVar*ratingVar {
+		return 0, false
+	}
+
+	dSalary, dRating := salary-stats.AvgSalary, rating-stats.AvgRating
+	squared := (ratingVar*dSalary*dSalary - 2*covariance*dSalary*dRating + salaryVar*dRating*dRating) / det
+	distance := math.Sqrt(squared)
+	if math.IsNaN(distance) || math.IsInf(distance, 0) {
+		return 0, false
+	}
+	return distance, true
+}
+
+// multivariateAnomaly returns an anomaly when the Mahalanobis distance of the
+// job's max salary and company rating from their joint mean exceeds threshold.
+// Jobs missing either field are skipped.
+func multivariateAnomaly(job *models.JobData, stats *Statistics, threshold float64, severity string) *models.Anomaly {
+	if job.MaxSalary == nil || job.CompanyRating == nil {
+		return nil
+	}
+	distance, ok := salaryRatingDistance(*job.MaxSalary, *job.CompanyRating, stats)
+	if !ok || distance <= threshold {
+		return nil
+	}
+
+	return &models.Anomaly{
+		Type:  models.AnomalyTypeMultivariate,
+		JobID: job.JobID,
+		Description: fmt.Sprintf("Salary %.2f and company rating %.2f are unusual together (Mahalanobis distance: %.2f)",
+			*job.MaxSalary, *job.CompanyRating, distance),
+		Value:      distance,
+		Threshold:  threshold,
+		Operator:   models.GreaterThan,
+		CreatedAt:  time.Now(),
+		Violations: []string{"max_salary", "company_rating"},
+		Severity:   deviationSeverity(distance, severity),
+	}
+}
+
+// nullValueAnomaly returns an anomaly listing the required fields that are empty
+// on the job, or nil when every required field is present
+func nullValueAnomaly(job *models.JobData, requiredFields []string, severity string) *models.Anomaly {
+	nullViolations := job.MissingFields(requiredFields)
+	if len(nullViolations) == 0 {
+		return nil
+	}
+	return &models.Anomaly{
+		Type:        models.AnomalyTypeNullValues,
+		JobID:       job.JobID,
+		Description: "Required fields are null",
+		Value:       0,
+		Threshold:   0,
+		Operator:    models.Equal,
+		CreatedAt:   time.Now(),
+		Violations:  nullViolations,
+		Severity:    severity,
+	}
+}
+
+// salaryRangeAnomaly returns an anomaly when both salaries are present and the
+// minimum exceeds the maximum, or nil when the range is valid or incomplete
+func salaryRangeAnomaly(job *models.JobData, severity string) *models.Anomaly {
+	if job.MinSalary == nil || job.MaxSalary == nil || *job.MinSalary <= *job.MaxSalary {
+		return nil
+	}
+	return &models.Anomaly{
+		Type:        models.AnomalyTypeSalaryRange,
+		JobID:       job.JobID,
+		Description: fmt.Sprintf("Minimum salary %g exceeds maximum salary %g", *job.MinSalary, *job.MaxSalary),
+		Value:       *job.MinSalary,
+		Threshold:   *job.MaxSalary,
+		Operator:    models.GreaterThan,
+		CreatedAt:   time.Now(),
+		// Violations are part of the upsert key, so they name the fields without
+		// their values and a corrected salary updates the same anomaly
+		Violations: []string{"min_salary", "max_salary"},
+		Severity:   severity,
+	}
+}
+
+// staleAnomaly returns an anomaly when the job was posted more than maxAge before
+// it was collected, or nil when it is fresher or either time is missing. A zero
+// time means the field was absent, and comparing against it would make every
+// such job look stale.
+func staleAnomaly(job *models.JobData, maxAge time.Duration, severity string) *models.Anomaly {
+	if job.JobPostedTime.IsZero() || job.DateCollected.IsZero() {
+		return nil
+	}
+	age := job.DateCollected.Sub(job.JobPostedTime.Time)
+	if age <= maxAge {
+		return nil
+	}
+	const day = 24 * time.Hour
+	return &models.Anomaly{
+		Type:        models.AnomalyTypeStale,
+		JobID:       job.JobID,
+		Description: fmt.Sprintf("Job was posted %d days before it was collected", int(age/day)),
+		Value:       age.Hours() / 24,
+		Threshold:   maxAge.Hours() / 24,
+		Operator:    models.GreaterThan,
+		CreatedAt:   time.Now(),
+		Violations:  []string{"job_posted_time"},
+		Severity:    severity,
+	}
+}
+
+// jobFieldValue returns the numeric job field a rule condition type refers to.
+// The boolean result is false when the field is unknown or not set on the job.
+func jobFieldValue(job *models.JobData, fieldType models.AnomalyType) (float64, bool) {
+	switch fieldType {
+	case models.AnomalyTypeMaxSalary:
+		if job.MaxSalary != nil {
+			return *job.MaxSalary, true
+		}
+	case models.AnomalyTypeMinSalary:
+		if job.MinSalary != nil {
+			return *job.MinSalary, true
+		}
+	case models.AnomalyTypeRating:
+		if job.CompanyRating != nil {
+			return *job.CompanyRating, true
+		}
+	}
+	return 0, false
+}
+
+// evaluateCondition checks a job against a single condition, returning whether it
+// matched and the numeric field value it compared. Text conditions report a value of 0.
+func evaluateCondition(job *models.JobData, condition models.RuleCondition) (bool, float64) {
+	if condition.Operator.IsText() {
+		text, ok := job.TextField(condition.Field)
+		return ok && compareText(text, condition), 0
+	}
+	value, ok := jobFieldValue(job, condition.Type)
+	return ok && compareValues(value, condition), value
+}
+
+// evaluateRuleConditions checks a job against every condition of a rule.
+// With "and" logic evaluation stops at the first condition that does not match;
+// with "or" logic every condition is checked so that all contributing conditions
+// are recorded. It returns whether the rule matched, a description of each
+// matching condition, and the value and threshold of the first matching condition.
+func evaluateRuleConditions(job *models.JobData, rule models.AnomalyRule) (bool, []string, float64, float64) {
+	var violations []string
+	var actualValue, threshold float64
+	matched := false
+
+	for _, condition := range rule.EffectiveConditions() {
+		conditionMet, value := evaluateCondition(job, condition)
+
+		if !conditionMet {
+			if rule.Logic != models.RuleLogicOr {
+				return false, nil, 0, 0
+			}
+			continue
+		}
+
+		if !matched {
+			actualValue = value
+			threshold = condition.Value
+			matched = true
+		}
+		violations = append(violations, condition.String())
+	}
+
+	return matched, violations, actualValue, threshold
+}
+
+// EvaluateRule checks sample jobs against a stored rule, whether or not it is active,
+// without saving anomalies or sending alerts. Percentile rules are resolved
+// against the stored jobs, as they would be during detection.
+func (s *AnomalyService) EvaluateRule(ctx context.Context, ruleID int64, jobs []models.JobData) ([]models.RuleEvaluation, error) {
+	rule, err := s.ruleService.GetAnomalyRule(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Treat the rule as active so it can be tried out before it is switched on
+	candidate := *rule
+	candidate.IsActive = true
+	resolved, err := s.resolvePercentileRules(ctx, []models.AnomalyRule{candidate})
+	if err != nil {
+		return nil, err
+	}
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("%w: rule %d has no job data to compute its percentile cutoff from", ErrValidation, ruleID)
+	}
+
+	evaluations := make([]models.RuleEvaluation, 0, len(jobs))
+	for i := range jobs {
+		evaluations = append(evaluations, evaluateRule(annualizeSalaries(&jobs[i], s.cfg.SalaryMultipliers), resolved[0]))
+	}
+	return evaluations, nil
+}
+
+// evaluateRule reports whether the job matches the rule. When it does not, the
+// value and threshold of the rule's first condition are reported instead, so
+// callers can see how far the job was from triggering it.
+func evaluateRule(job *models.JobData, rule models.AnomalyRule) models.RuleEvaluation {
+	matched, violations, value, threshold := evaluateRuleConditions(job, rule)
+	if !matched {
+		first := rule.EffectiveConditions()[0]
+		value, _ = jobFieldValue(job, first.Type)
+		threshold = first.Value
+		violations = []string{}
+	}
+
+	return models.RuleEvaluation{
+		JobID:      job.JobID,
+		Triggered:  matched,
+		Value:      value,
+		Threshold:  threshold,
+		Violations: violations,
+	}
+}
+
+// compareValues compares a job field value with a condition's threshold, or with
+// its Value to ValueHigh range for range operators
+func compareValues(value float64, condition models.RuleCondition) bool {
+	threshold := condition.Value
+	switch condition.Operator {
 	case models.GreaterThan:
 		return value > threshold
 	case models.GreaterThanOrEqual:
@@ -342,21 +1100,156 @@ func compareValues(value, threshold float64, operator models.ComparisonOperator)
 		return value <= threshold
 	case models.Equal:
 		return value == threshold
+	case models.Between:
+		return value >= threshold && value <= condition.ValueHigh
+	case models.NotBetween:
+		return value < threshold || value > condition.ValueHigh
 	default:
 		return false // Unknown operator
 	}
 }
 
-// GetAnomaliesByJobID retrieves anomalies for a specific job using basic query methods
-func (s *AnomalyService) GetAnomaliesByJobID(jobID string) ([]models.Anomaly, error) {
+// anomalyColumns lists the anomalies columns read by scanAnomaly, in scan order
+const anomalyColumns = `id, job_id, type, description, value, threshold, operator, created_at, violations,
+		COALESCE(severity, ''), status, resolved_at, COALESCE(resolution_note, ''), COALESCE(assignee, ''), acknowledged_at`
+
+// scanAnomaly scans a row selected with anomalyColumns, followed by any columns
+// scanned into extra. The value, threshold, operator, and creation time columns
+// are nullable and read as zero when NULL.
+func scanAnomaly(row rowScanner, extra ...interface{}) (models.Anomaly, error) {
+	var anomaly models.Anomaly
+	var value, threshold sql.NullFloat64
+	var operator sql.NullString
+	var createdAt, resolvedAt, acknowledgedAt sql.NullTime
+	dest := []interface{}{
+		&anomaly.ID,
+		&anomaly.JobID,
+		&anomaly.Type,
+		&anomaly.Description,
+		&value,
+		&threshold,
+		&operator,
+		&createdAt,
+		pq.Array(&anomaly.Violations),
+		&anomaly.Severity,
+		&anomaly.Status,
+		&resolvedAt,
+		&anomaly.ResolutionNote,
+		&anomaly.Assignee,
+		&acknowledgedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return anomaly, err
+	}
+
+	anomaly.Value = value.Float64
+	anomaly.Threshold = threshold.Float64
+	anomaly.Operator = models.ComparisonOperator(operator.String)
+	anomaly.CreatedAt = createdAt.Time
+	if resolvedAt.Valid {
+		anomaly.ResolvedAt = &resolvedAt.Time
+	}
+	if acknowledgedAt.Valid {
+		anomaly.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	return anomaly, nil
+}
+
+// unresolvedFilter returns the condition that hides resolved anomalies from
+// listings, or an always-true condition when includeResolved is set
+func unresolvedFilter(includeResolved bool) string {
+	if includeResolved {
+		return "TRUE"
+	}
+	return "status <> '" + models.AnomalyStatusResolved + "'"
+}
+
+// GetAnomalyByID retrieves a single anomaly by its ID, whether or not it has been resolved
+func (s *AnomalyService) GetAnomalyByID(ctx context.Context, id int64) (*models.Anomaly, error) {
 	query := `
-		SELECT id, job_id, type, description, value, threshold, operator, created_at
+		SELECT ` + anomalyColumns + `
 		FROM anomalies
-		WHERE job_id = $1
+		WHERE id = $1
+	`
+
+	anomaly, err := scanAnomaly(s.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("anomaly with ID %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("error querying anomaly: %w", err)
+	}
+
+	return &anomaly, nil
+}
+
+// ResolveAnomaly marks an anomaly as resolved, recording when and why it was dismissed.
+// The row is kept for audit history; re-detecting the same anomaly does not reopen it.
+func (s *AnomalyService) ResolveAnomaly(ctx context.Context, id int64, note string) error {
+	query := `
+		UPDATE anomalies
+		SET status = $2, resolved_at = $3, resolution_note = NULLIF($4, '')
+		WHERE id = $1
+	`
+
+	result, err := s.db.Exec(ctx, query, id, models.AnomalyStatusResolved, time.Now(), note)
+	if err != nil {
+		return fmt.Errorf("error resolving anomaly: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("anomaly with ID %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// AcknowledgeAnomaly assigns an anomaly to assignee and records when it was
+// acknowledged. Acknowledging it again reassigns it and refreshes the time.
+func (s *AnomalyService) AcknowledgeAnomaly(ctx context.Context, id int64, assignee string) error {
+	assignee = strings.TrimSpace(assignee)
+	if assignee == "" {
+		return fmt.Errorf("%w: assignee is required", ErrValidation)
+	}
+
+	query := `
+		UPDATE anomalies
+		SET assignee = $2, acknowledged_at = $3
+		WHERE id = $1
+	`
+
+	result, err := s.db.Exec(ctx, query, id, assignee, time.Now())
+	if err != nil {
+		return fmt.Errorf("error acknowledging anomaly: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("anomaly with ID %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// GetAnomaliesByJobID retrieves anomalies for a specific job.
+// Resolved anomalies are skipped unless includeResolved is set.
+func (s *AnomalyService) GetAnomaliesByJobID(ctx context.Context, jobID string, includeResolved bool) ([]models.Anomaly, error) {
+	query := `
+		SELECT ` + anomalyColumns + `
+		FROM anomalies
+		WHERE job_id = $1 AND ` + unresolvedFilter(includeResolved) + `
 		ORDER BY created_at DESC
 	`
 
-	rows, err := s.db.Query(query, jobID)
+	rows, err := s.db.Query(ctx, query, jobID)
 	if err != nil {
 		return nil, fmt.Errorf("error querying anomalies by job ID: %w", err)
 	}
@@ -364,17 +1257,7 @@ func (s *AnomalyService) GetAnomaliesByJobID(jobID string) ([]models.Anomaly, er
 
 	var anomalies []models.Anomaly
 	for rows.Next() {
-		var anomaly models.Anomaly
-		err := rows.Scan(
-			&anomaly.ID,
-			&anomaly.JobID,
-			&anomaly.Type,
-			&anomaly.Description,
-			&anomaly.Value,
-			&anomaly.Threshold,
-			&anomaly.Operator,
-			&anomaly.CreatedAt,
-		)
+		anomaly, err := scanAnomaly(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning anomaly: %w", err)
 		}
@@ -393,15 +1276,17 @@ func (s *AnomalyService) GetAnomaliesByJobID(jobID string) ([]models.Anomaly, er
 	return anomalies, nil
 }
 
-// GetAllAnomalies retrieves all anomalies using basic query methods
-func (s *AnomalyService) GetAllAnomalies() ([]models.Anomaly, error) {
+// GetAllAnomalies retrieves all anomalies.
+// Resolved anomalies are skipped unless includeResolved is set.
+func (s *AnomalyService) GetAllAnomalies(ctx context.Context, includeResolved bool) ([]models.Anomaly, error) {
 	query := `
-		SELECT id, job_id, type, description, value, threshold, operator, created_at
+		SELECT ` + anomalyColumns + `
 		FROM anomalies
+		WHERE ` + unresolvedFilter(includeResolved) + `
 		ORDER BY created_at DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("error querying all anomalies: %w", err)
 	}
@@ -409,17 +1294,7 @@ func (s *AnomalyService) GetAllAnomalies() ([]models.Anomaly, error) {
 
 	var anomalies []models.Anomaly
 	for rows.Next() {
-		var anomaly models.Anomaly
-		err := rows.Scan(
-			&anomaly.ID,
-			&anomaly.JobID,
-			&anomaly.Type,
-			&anomaly.Description,
-			&anomaly.Value,
-			&anomaly.Threshold,
-			&anomaly.Operator,
-			&anomaly.CreatedAt,
-		)
+		anomaly, err := scanAnomaly(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning anomaly: %w", err)
 		}
@@ -433,45 +1308,584 @@ func (s *AnomalyService) GetAllAnomalies() ([]models.Anomaly, error) {
 	return anomalies, nil
 }
 
-// DetectAnomaliesForAllJobs processes all existing jobs to detect anomalies
-func (s *AnomalyService) DetectAnomaliesForAllJobs() error {
-	// Get all jobs
+// AnomalyFilter holds the filters accepted by GetAllAnomaliesPaged and
+// DeleteAnomalies. The zero AnomalyFilter lists every unresolved anomaly.
+type AnomalyFilter struct {
+	IncludeResolved bool               // Also list resolved anomalies
+	Assignee        string             // Only anomalies assigned to this user, when set
+	Acknowledged    *bool              // Only acknowledged (true) or unacknowledged (false) anomalies, when set
+	Type            models.AnomalyType // Only anomalies of this type, when set
+	JobID           string             // Only anomalies of this job, when set
+	CreatedBefore   time.Time          // Only anomalies created before this time, when not zero
+}
+
+// isEmpty reports whether filter selects every anomaly of the statuses it includes
+func (f AnomalyFilter) isEmpty() bool {
+	return strings.TrimSpace(f.Assignee) == "" && f.Acknowledged == nil && f.Type == "" &&
+		strings.TrimSpace(f.JobID) == "" && f.CreatedBefore.IsZero()
+}
+
+// buildAnomalyFilter turns filter into a WHERE clause and its arguments
+func buildAnomalyFilter(filter AnomalyFilter) (string, []interface{}) {
+	conditions := []string{unresolvedFilter(filter.IncludeResolved)}
+	var args []interface{}
+	if assignee := strings.TrimSpace(filter.Assignee); assignee != "" {
+		args = append(args, assignee)
+		conditions = append(conditions, fmt.Sprintf("assignee = $%d", len(args)))
+	}
+	if filter.Acknowledged != nil {
+		if *filter.Acknowledged {
+			conditions = append(conditions, "acknowledged_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "acknowledged_at IS NULL")
+		}
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		conditions = append(conditions, fmt.Sprintf("type = $%d", len(args)))
+	}
+	if jobID := strings.TrimSpace(filter.JobID); jobID != "" {
+		args = append(args, jobID)
+		conditions = append(conditions, fmt.Sprintf("job_id = $%d", len(args)))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		args = append(args, filter.CreatedBefore)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// DeleteAnomalies deletes every anomaly matching filter in a single statement and
+// returns how many were deleted. As when listing, resolved anomalies are kept
+// unless filter.IncludeResolved is set. A filter that narrows nothing down is
+// rejected with ErrValidation, so a bulk delete cannot empty the table by accident.
+func (s *AnomalyService) DeleteAnomalies(ctx context.Context, filter AnomalyFilter) (int, error) {
+	if filter.isEmpty() {
+		return 0, fmt.Errorf("%w: at least one of type, job_id, created_before, assignee or acknowledged is required; "+
+			"resolved anomalies are kept unless include_resolved is true", ErrValidation)
+	}
+
+	where, args := buildAnomalyFilter(filter)
+	result, err := s.db.Exec(ctx, `DELETE FROM anomalies `+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting anomalies: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// GetAllAnomaliesPaged retrieves a single page of anomalies matching filter along
+// with the total number of matches. The zero SortOptions orders anomalies newest
+// first. Resolved anomalies are skipped, and left out of the total, unless
+// filter.IncludeResolved is set.
+func (s *AnomalyService) GetAllAnomaliesPaged(ctx context.Context, limit, offset int, sort SortOptions, filter AnomalyFilter) ([]models.Anomaly, int, error) {
+	orderBy, err := orderByClause(sort, anomalySortColumns, "created_at", SortDescending)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where, args := buildAnomalyFilter(filter)
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM anomalies `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting anomalies: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM anomalies
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, anomalyColumns, where, orderBy, len(args)+1, len(args)+2)
+
+	rows, err := s.db.Query(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying anomalies page: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []models.Anomaly{}
+	for rows.Next() {
+		anomaly, err := scanAnomaly(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning anomaly: %w", err)
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating anomalies: %w", err)
+	}
+
+	return anomalies, total, nil
+}
+
+// detailedAnomalyJobColumns are the job columns read after anomalyColumns for a
+// DetailedAnomaly. Jobs are joined through a subquery that renames job_id so the
+// unqualified anomaly columns, filters, and sort keys stay unambiguous.
+const detailedAnomalyJobColumns = `COALESCE(j.company_name, ''), COALESCE(j.job_title, ''), COALESCE(j.city, ''), j.max_salary`
+
+// GetDetailedAnomaliesPaged is GetAllAnomaliesPaged with the key fields of each
+// anomaly's job read in the same query. The job fields are empty for an anomaly
+// whose job no longer exists.
+func (s *AnomalyService) GetDetailedAnomaliesPaged(ctx context.Context, limit, offset int, sort SortOptions, filter AnomalyFilter) ([]models.DetailedAnomaly, int, error) {
+	orderBy, err := orderByClause(sort, anomalySortColumns, "created_at", SortDescending)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where, args := buildAnomalyFilter(filter)
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM anomalies `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting anomalies: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM anomalies
+		LEFT JOIN (
+			SELECT job_id AS jobs_job_id, company_name, job_title, city, max_salary
+			FROM jobs
+		) j ON j.jobs_job_id = anomalies.job_id
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, anomalyColumns, detailedAnomalyJobColumns, where, orderBy, len(args)+1, len(args)+2)
+
+	rows, err := s.db.Query(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying detailed anomalies page: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []models.DetailedAnomaly{}
+	for rows.Next() {
+		var job models.AnomalyJob
+		var maxSalary sql.NullFloat64
+		anomaly, err := scanAnomaly(rows, &job.CompanyName, &job.JobTitle, &job.City, &maxSalary)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning detailed anomaly: %w", err)
+		}
+		job.MaxSalary = nullFloat64Ptr(maxSalary)
+		anomalies = append(anomalies, models.DetailedAnomaly{Anomaly: anomaly, Job: job})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating detailed anomalies: %w", err)
+	}
+
+	return anomalies, total, nil
+}
+
+// GetAnomaliesByCompany groups anomalies by the company of the job they were
+// detected on, returning one page of per-company counts and distinct anomaly
+// types along with the total number of companies that have anomalies.
+// The zero SortOptions orders companies by anomaly count, highest first.
+// Resolved anomalies are left out unless includeResolved is set.
+func (s *AnomalyService) GetAnomaliesByCompany(ctx context.Context, limit, offset int, sort SortOptions, includeResolved bool) ([]models.CompanyAnomalies, int, error) {
+	orderBy, err := orderByClauseWithTiebreak(sort, companyAnomalySortColumns, "count", SortDescending, "company_name")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := unresolvedFilter(includeResolved)
+
+	var total int
+	countQuery := `
+		SELECT COUNT(DISTINCT j.company_name)
+		FROM anomalies a
+		JOIN jobs j ON j.job_id = a.job_id
+		WHERE ` + filter
+	if err := s.db.QueryRow(ctx, countQuery).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting companies with anomalies: %w", err)
+	}
+
 	query := `
-		SELECT job_id, company_name, company_rating, job_title, min_salary, max_salary
-		FROM jobs
+		SELECT j.company_name, COUNT(*) AS anomaly_count, array_agg(DISTINCT a.type ORDER BY a.type) AS anomaly_types
+		FROM anomalies a
+		JOIN jobs j ON j.job_id = a.job_id
+		WHERE ` + filter + `
+		GROUP BY j.company_name
+		ORDER BY ` + orderBy + `
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.db.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying anomalies by company: %w", err)
+	}
+	defer rows.Close()
+
+	companies := []models.CompanyAnomalies{}
+	for rows.Next() {
+		var company models.CompanyAnomalies
+		var types []string
+		if err := rows.Scan(&company.CompanyName, &company.AnomalyCount, pq.Array(&types)); err != nil {
+			return nil, 0, fmt.Errorf("error scanning company anomalies: %w", err)
+		}
+		company.AnomalyTypes = make([]models.AnomalyType, len(types))
+		for i, anomalyType := range types {
+			company.AnomalyTypes[i] = models.AnomalyType(anomalyType)
+		}
+		companies = append(companies, company)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating company anomalies: %w", err)
+	}
+
+	return companies, total, nil
+}
+
+// GetJobRiskScore returns the risk score of a job: the sum, over its unresolved
+// anomalies, of the configured weight of each anomaly's severity. A job without
+// open anomalies scores zero; an unknown job is ErrNotFound.
+func (s *AnomalyService) GetJobRiskScore(ctx context.Context, jobID string) (float64, error) {
+	query := `
+		SELECT a.severity, COUNT(a.id)
+		FROM jobs j
+		LEFT JOIN anomalies a ON a.job_id = j.job_id AND a.` + unresolvedFilter(false) + `
+		WHERE j.job_id = $1
+		GROUP BY a.severity
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(ctx, query, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("error querying anomaly severities: %w", err)
+	}
+	defer rows.Close()
+
+	found := false
+	counts := make(map[string]int)
+	for rows.Next() {
+		found = true
+		var severity sql.NullString
+		var count int
+		if err := rows.Scan(&severity, &count); err != nil {
+			return 0, fmt.Errorf("error scanning anomaly severity count: %w", err)
+		}
+		if severity.Valid {
+			counts[severity.String] = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating anomaly severity counts: %w", err)
+	}
+	if !found {
+		return 0, fmt.Errorf("job %s %w", jobID, ErrNotFound)
+	}
+
+	return riskScore(counts, s.cfg.SeverityWeights), nil
+}
+
+// GetJobsByRiskScore returns one page of jobs with unresolved anomalies, ordered
+// by risk score, highest first, along with the total number of such jobs
+func (s *AnomalyService) GetJobsByRiskScore(ctx context.Context, limit, offset int) ([]models.JobRiskScore, int, error) {
+	filter := "a." + unresolvedFilter(false)
+
+	var total int
+	countQuery := `
+		SELECT COUNT(DISTINCT a.job_id)
+		FROM anomalies a
+		JOIN jobs j ON j.job_id = a.job_id
+		WHERE ` + filter
+	if err := s.db.QueryRow(ctx, countQuery).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting jobs with anomalies: %w", err)
+	}
+
+	weight, args := severityWeightExpr(s.cfg.SeverityWeights, 3)
+	query := `
+		SELECT j.job_id, j.company_name, j.job_title, COUNT(*) AS anomaly_count, SUM(` + weight + `) AS risk_score
+		FROM anomalies a
+		JOIN jobs j ON j.job_id = a.job_id
+		WHERE ` + filter + `
+		GROUP BY j.job_id, j.company_name, j.job_title
+		ORDER BY risk_score DESC, j.job_id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.db.Query(ctx, query, append([]interface{}{limit, offset}, args...)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying job risk scores: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.JobRiskScore{}
+	for rows.Next() {
+		var job models.JobRiskScore
+		if err := rows.Scan(&job.JobID, &job.CompanyName, &job.JobTitle, &job.AnomalyCount, &job.RiskScore); err != nil {
+			return nil, 0, fmt.Errorf("error scanning job risk score: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating job risk scores: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// riskScore sums the weight of each anomaly's severity, given the number of
+// anomalies per severity. Severities without a weight add nothing.
+func riskScore(counts map[string]int, weights map[string]float64) float64 {
+	score := 0.0
+	for severity, count := range counts {
+		score += float64(count) * weights[severity]
+	}
+	return score
+}
+
+// severityWeightExpr builds the SQL expression for the weight of an anomaly's
+// severity, matching riskScore. The severities and weights are bound as query
+// arguments numbered from first, in severity order so the query is stable.
+func severityWeightExpr(weights map[string]float64, first int) (string, []interface{}) {
+	severities := slices.Sorted(maps.Keys(weights))
+	if len(severities) == 0 {
+		return "0", nil
+	}
+	args := make([]interface{}, 0, 2*len(severities))
+	var expr strings.Builder
+	expr.WriteString("CASE a.severity")
+	for _, severity := range severities {
+		fmt.Fprintf(&expr, " WHEN $%d THEN $%d::double precision", first+len(args), first+len(args)+1)
+		args = append(args, severity, weights[severity])
+	}
+	expr.WriteString(" ELSE 0 END")
+	return expr.String(), args
+}
+
+// GetAnomalyStats returns anomaly counts by type and by severity across all
+// stored anomalies, plus per-day counts for anomalies created within window
+func (s *AnomalyService) GetAnomalyStats(ctx context.Context, window time.Duration) (*models.AnomalyStats, error) {
+	stats := &models.AnomalyStats{
+		ByType:     make(map[models.AnomalyType]int),
+		BySeverity: make(map[string]int),
+		Daily:      []models.DailyAnomalyCount{},
+	}
+
+	typeCounts, err := s.countAnomaliesBy(ctx, `type`)
+	if err != nil {
+		return nil, fmt.Errorf("error counting anomalies by type: %w", err)
+	}
+	for anomalyType, count := range typeCounts {
+		stats.ByType[models.AnomalyType(anomalyType)] = count
+		stats.Total += count
+	}
+
+	if stats.BySeverity, err = s.countAnomaliesBy(ctx, `COALESCE(severity, '')`); err != nil {
+		return nil, fmt.Errorf("error counting anomalies by severity: %w", err)
+	}
+
+	query := `
+		SELECT date_trunc('day', created_at AT TIME ZONE 'UTC') AS day, COUNT(*)
+		FROM anomalies
+		WHERE created_at >= $1
+		GROUP BY day
+		ORDER BY day
+	`
+	rows, err := s.db.Query(ctx, query, time.Now().Add(-window))
 	if err != nil {
-		return fmt.Errorf("error querying jobs: %w", err)
+		return nil, fmt.Errorf("error querying daily anomaly counts: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var job models.JobData
-		err := rows.Scan(
-			&job.JobID,
-			&job.CompanyName,
-			&job.CompanyRating,
-			&job.JobTitle,
-			&job.MinSalary,
-			&job.MaxSalary,
-		)
+		var day time.Time
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, fmt.Errorf("error scanning daily anomaly count: %w", err)
+		}
+		stats.Daily = append(stats.Daily, models.DailyAnomalyCount{Date: day.Format("2006-01-02"), Count: count})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily anomaly counts: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetAnomalyTypeCounts returns the number of anomalies of each type present in
+// the anomalies table, resolved ones included. It returns an empty map when
+// there are no anomalies.
+func (s *AnomalyService) GetAnomalyTypeCounts(ctx context.Context) (map[string]int, error) {
+	counts, err := s.countAnomaliesBy(ctx, `type`)
+	if err != nil {
+		return nil, fmt.Errorf("error counting anomalies by type: %w", err)
+	}
+	return counts, nil
+}
+
+// countAnomaliesBy counts anomalies grouped by expr, which must be a fixed
+// column expression and never caller input
+func (s *AnomalyService) countAnomaliesBy(ctx context.Context, expr string) (map[string]int, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+expr+` AS key, COUNT(*) FROM anomalies GROUP BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, err
+		}
+		counts[key] = count
+	}
+	return counts, rows.Err()
+}
+
+// DetectAnomaliesForAllJobs processes all existing jobs to detect anomalies.
+// In a dry run no anomalies are written and no alerts are sent; the anomalies
+// that would have been stored are returned instead. Otherwise the result is nil.
+// Every run, dry or not, is recorded in the detection run history.
+func (s *AnomalyService) DetectAnomaliesForAllJobs(ctx context.Context, dryRun bool) ([]models.Anomaly, error) {
+	save := s.saveAnomaly
+	if dryRun {
+		save = discardAnomaly
+	}
+
+	runID := s.startDetectionRun(ctx, dryRun)
+	batch, err := s.detectAnomaliesForJobs(ctx, "", nil, save, dryRun)
+	s.finishDetectionRun(ctx, runID, batch, err)
+	if err != nil {
+		return nil, err
+	}
+	return batch.anomalies, nil
+}
+
+// DetectAnomaliesSince runs detection only on jobs ingested after since and
+// returns how many jobs were processed. Statistics are still computed over the
+// whole jobs table so the new jobs are compared against the full dataset.
+func (s *AnomalyService) DetectAnomaliesSince(ctx context.Context, since time.Time) (int, error) {
+	batch, err := s.detectAnomaliesForJobs(ctx, "WHERE created_at > $1", []interface{}{since}, s.saveAnomaly, false)
+	if err != nil {
+		return 0, err
+	}
+	return batch.processed, nil
+}
+
+// detectionBatch is the outcome of running detection over a set of jobs
+type detectionBatch struct {
+	processed int              // Jobs checked
+	found     int              // Anomalies saved, or in a dry run that would have been
+	anomalies []models.Anomaly // The detected anomalies, when collected
+}
+
+// detectAnomaliesForJobs runs detection on every job matching the given WHERE
+// clause, or on all jobs when it is empty. Jobs are spread over a pool of
+// cfg.Workers goroutines sharing one detection context. It returns how many
+// jobs were processed and anomalies found and, when collect is true, the
+// anomalies themselves. On error the counts cover the work done before it.
+func (s *AnomalyService) detectAnomaliesForJobs(ctx context.Context, where string, args []interface{}, save anomalySaver, collect bool) (batch detectionBatch, err error) {
+	ctx, span := startSpan(ctx, "AnomalyService.detectAnomaliesForJobs")
+	defer func() {
+		span.SetAttributes(attribute.Int("processed", batch.processed), attribute.Int("found", batch.found))
 		if err != nil {
-			return fmt.Errorf("error scanning job: %w", err)
+			recordSpanError(span, err)
 		}
+		span.End()
+	}()
+
+	metrics.DetectionRuns.Inc()
+	timer := prometheus.NewTimer(metrics.DetectionDuration)
+	defer timer.ObserveDuration()
+
+	// Load statistics and rules once for the whole batch rather than once per job
+	dc, err := s.loadDetectionContext(ctx)
+	if err != nil {
+		return detectionBatch{}, err
+	}
+
+	// Detectors and rules read many job fields, so whole rows are loaded as for a single job
+	query := `
+		SELECT ` + jobSelectColumns + `
+		FROM jobs
+	` + where
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return detectionBatch{}, fmt.Errorf("error querying jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex // Guards detected
+		detected  []models.Anomaly
+		processed atomic.Int64
+		found     atomic.Int64
+	)
+	jobs := make(chan models.JobData)
+	for range max(s.cfg.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				// Drain without detecting once the batch is cancelled
+				if ctx.Err() != nil {
+					continue
+				}
+				anomalies := s.detectAnomaliesWithContext(ctx, &job, dc, save)
+				found.Add(int64(len(anomalies)))
+				if collect && len(anomalies) > 0 {
+					mu.Lock()
+					detected = append(detected, anomalies...)
+					mu.Unlock()
+				}
+				processed.Add(1)
+			}
+		}()
+	}
+
+	// Wait for the workers before reporting, so no detection outlives the call
+	err = feedJobs(ctx, rows, jobs)
+	close(jobs)
+	wg.Wait()
+	batch = detectionBatch{processed: int(processed.Load()), found: int(found.Load())}
+	if err != nil {
+		return batch, err
+	}
+	// Workers skip what is left once cancelled, so a late cancellation still fails the batch
+	if err := ctx.Err(); err != nil {
+		return batch, fmt.Errorf("anomaly detection cancelled: %w", err)
+	}
+
+	batch.anomalies = detected
+	return batch, nil
+}
 
-		// Detect anomalies for this job
-		_, err = s.DetectAnomalies(&job)
+// feedJobs scans each row into a job and hands it to the workers, stopping
+// early when the caller cancels or the deadline passes
+func feedJobs(ctx context.Context, rows *sql.Rows, jobs chan<- models.JobData) error {
+	for rows.Next() {
+		job, err := scanJob(rows)
 		if err != nil {
-			// Log the error but continue processing other jobs
-			fmt.Printf("Error detecting anomalies for job %s: %v\n", job.JobID, err)
+			return fmt.Errorf("error scanning job: %w", err)
+		}
+
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			return fmt.Errorf("anomaly detection cancelled: %w", ctx.Err())
 		}
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return fmt.Errorf("error iterating jobs: %w", err)
 	}
+	return nil
+}
 
+// discardAnomaly stands in for saveAnomaly during dry runs, leaving the database untouched
+func discardAnomaly(ctx context.Context, anomaly *models.Anomaly) error {
 	return nil
 }
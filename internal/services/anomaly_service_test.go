@@ -0,0 +1,2196 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"maps"
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/metrics"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSafeZScore(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         float64
+		mean          float64
+		stddev        float64
+		expectedScore float64
+		expectedOK    bool
+	}{
+		{
+			name:          "normal distribution",
+			value:         130000,
+			mean:          100000,
+			stddev:        10000,
+			expectedScore: 3,
+			expectedOK:    true,
+		},
+		{
+			name:          "identical values give zero stddev",
+			value:         100000,
+			mean:          100000,
+			stddev:        0,
+			expectedScore: 0,
+			expectedOK:    false,
+		},
+		{
+			name:          "single row with NULL stddev scanned as zero",
+			value:         250000,
+			mean:          250000,
+			stddev:        0,
+			expectedScore: 0,
+			expectedOK:    false,
+		},
+		{
+			name:          "NaN stddev",
+			value:         100000,
+			mean:          100000,
+			stddev:        math.NaN(),
+			expectedScore: 0,
+			expectedOK:    false,
+		},
+		{
+			name:          "infinite stddev",
+			value:         100000,
+			mean:          100000,
+			stddev:        math.Inf(1),
+			expectedScore: 0,
+			expectedOK:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, ok := safeZScore(tt.value, tt.mean, tt.stddev)
+
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.InDelta(t, tt.expectedScore, score, 1e-9)
+		})
+	}
+}
+
+func TestDeviationSeverity(t *testing.T) {
+	tests := []struct {
+		name     string
+		zScore   float64
+		base     string
+		expected string
+	}{
+		{name: "just above threshold", zScore: 3.5, base: models.SeverityMedium, expected: models.SeverityMedium},
+		{name: "at high severity boundary", zScore: 5, base: models.SeverityMedium, expected: models.SeverityMedium},
+		{name: "large positive deviation", zScore: 7.2, base: models.SeverityMedium, expected: models.SeverityHigh},
+		{name: "large negative deviation", zScore: -6, base: models.SeverityMedium, expected: models.SeverityHigh},
+		{name: "low base keeps moderate deviation low", zScore: 3.5, base: models.SeverityLow, expected: models.SeverityLow},
+		{name: "low base escalates on large deviation", zScore: 7.2, base: models.SeverityLow, expected: models.SeverityHigh},
+		{name: "critical base is never lowered", zScore: 7.2, base: models.SeverityCritical, expected: models.SeverityCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, deviationSeverity(tt.zScore, tt.base))
+		})
+	}
+}
+
+func TestIQROutlier(t *testing.T) {
+	tests := []struct {
+		name             string
+		value            float64
+		q1               float64
+		q3               float64
+		expectedBound    float64
+		expectedOperator models.ComparisonOperator
+		expectedOK       bool
+	}{
+		{
+			name:       "value inside fences",
+			value:      110000,
+			q1:         80000,
+			q3:         120000,
+			expectedOK: false,
+		},
+		{
+			name:             "value above upper fence",
+			value:            200000,
+			q1:               80000,
+			q3:               120000,
+			expectedBound:    180000,
+			expectedOperator: models.GreaterThan,
+			expectedOK:       true,
+		},
+		{
+			name:             "value below lower fence",
+			value:            10000,
+			q1:               80000,
+			q3:               120000,
+			expectedBound:    20000,
+			expectedOperator: models.LessThan,
+			expectedOK:       true,
+		},
+		{
+			name:       "zero interquartile range",
+			value:      200000,
+			q1:         100000,
+			q3:         100000,
+			expectedOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound, operator, ok := iqrOutlier(tt.value, tt.q1, tt.q3)
+
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedOperator, operator)
+			assert.InDelta(t, tt.expectedBound, bound, 1e-9)
+		})
+	}
+}
+
+func TestSalaryRangeAnomaly(t *testing.T) {
+	tests := []struct {
+		name               string
+		job                *models.JobData
+		expectAnomaly      bool
+		expectedViolations []string
+	}{
+		{
+			name:               "inverted salary range",
+			job:                &models.JobData{JobID: "job1", MinSalary: Float64Ptr(150000), MaxSalary: Float64Ptr(90000)},
+			expectAnomaly:      true,
+			expectedViolations: []string{"min_salary", "max_salary"},
+		},
+		{
+			name: "valid salary range",
+			job:  &models.JobData{JobID: "job1", MinSalary: Float64Ptr(90000), MaxSalary: Float64Ptr(150000)},
+		},
+		{
+			name: "equal salaries",
+			job:  &models.JobData{JobID: "job1", MinSalary: Float64Ptr(100000), MaxSalary: Float64Ptr(100000)},
+		},
+		{
+			name: "missing max salary",
+			job:  &models.JobData{JobID: "job1", MinSalary: Float64Ptr(150000)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomaly := salaryRangeAnomaly(tt.job, models.SeverityMedium)
+
+			if !tt.expectAnomaly {
+				assert.Nil(t, anomaly)
+				return
+			}
+			if assert.NotNil(t, anomaly) {
+				assert.Equal(t, models.AnomalyTypeSalaryRange, anomaly.Type)
+				assert.Equal(t, tt.job.JobID, anomaly.JobID)
+				assert.Equal(t, tt.expectedViolations, anomaly.Violations)
+				assert.Equal(t, "Minimum salary 150000 exceeds maximum salary 90000", anomaly.Description)
+				assert.Equal(t, 150000.0, anomaly.Value)
+				assert.Equal(t, 90000.0, anomaly.Threshold)
+			}
+		})
+	}
+
+	t.Run("changed salaries keep the same violations", func(t *testing.T) {
+		first := salaryRangeAnomaly(&models.JobData{JobID: "job1", MinSalary: Float64Ptr(150000), MaxSalary: Float64Ptr(90000)}, models.SeverityMedium)
+		second := salaryRangeAnomaly(&models.JobData{JobID: "job1", MinSalary: Float64Ptr(120000), MaxSalary: Float64Ptr(95000)}, models.SeverityMedium)
+		assert.Equal(t, first.Violations, second.Violations)
+	})
+}
+
+func TestStatisticsForJob(t *testing.T) {
+	global := &Statistics{SampleCount: 100, AvgSalary: 120000}
+	groups := map[string]*Statistics{
+		"engineering": {SampleCount: 20, AvgSalary: 180000},
+		"retail":      {SampleCount: 3, AvgSalary: 40000},
+	}
+
+	tests := []struct {
+		name     string
+		job      *models.JobData
+		groupBy  string
+		expected *Statistics
+	}{
+		{
+			name:     "group with enough samples",
+			job:      &models.JobData{RoleType: StringPtr("engineering")},
+			groupBy:  config.StatsGroupByRoleType,
+			expected: groups["engineering"],
+		},
+		{
+			name:     "group with too few samples falls back to global",
+			job:      &models.JobData{RoleType: StringPtr("retail")},
+			groupBy:  config.StatsGroupByRoleType,
+			expected: global,
+		},
+		{
+			name:     "unknown group falls back to global",
+			job:      &models.JobData{RoleType: StringPtr("farming")},
+			groupBy:  config.StatsGroupByRoleType,
+			expected: global,
+		},
+		{
+			name:     "job without a role type falls back to global",
+			job:      &models.JobData{},
+			groupBy:  config.StatsGroupByRoleType,
+			expected: global,
+		},
+		{
+			name:     "grouping disabled",
+			job:      &models.JobData{RoleType: StringPtr("engineering")},
+			groupBy:  config.StatsGroupByNone,
+			expected: global,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := statisticsForJob(tt.job, global, groups, tt.groupBy, config.DefaultMinGroupSamples)
+			assert.Same(t, tt.expected, stats)
+		})
+	}
+}
+
+func TestGetGroupStatistics(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	columns := []string{"city", "sample_count", "avg_salary", "salary_stddev", "salary_q1", "salary_q3",
+		"avg_rating", "rating_stddev", "rating_q1", "rating_q3", "salary_rating_covariance",
+		"avg_latitude", "latitude_stddev", "avg_longitude", "longitude_stddev"}
+	sqlMock.ExpectQuery("GROUP BY city").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow("Austin", 12, 110000.0, 15000.0, 95000.0, 125000.0, 4.1, 0.3, 3.9, 4.4, 1800.0, 30.27, 0.05, -97.74, 0.06).
+		AddRow("Boise", 1, 60000.0, nil, 60000.0, 60000.0, 3.5, nil, 3.5, 3.5, nil, nil, nil, nil, nil))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	groups, err := service.getGroupStatistics(context.Background(), config.StatsGroupByCity)
+
+	assert.NoError(t, err)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, 12, groups["Austin"].SampleCount)
+	assert.Equal(t, 110000.0, groups["Austin"].AvgSalary)
+	assert.Equal(t, 0.0, groups["Boise"].SalaryStdDev)
+	assert.Equal(t, 1800.0, groups["Austin"].SalaryRatingCovariance)
+	assert.Equal(t, 30.27, groups["Austin"].AvgLatitude)
+	assert.Equal(t, 0.06, groups["Austin"].LongitudeStdDev)
+	assert.Equal(t, 0.0, groups["Boise"].LatitudeStdDev)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestDetectAnomaliesForAllJobsGroupStatistics(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectDetectionRunStart(sqlMock, 1, true)
+	sqlMock.ExpectQuery("FROM jobs\\s+WHERE max_salary IS NOT NULL").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(30, 100000.0, 10000.0, 95000.0, 105000.0, 4.0, 0.5, 3.5, 4.5, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("GROUP BY role_type").
+		WillReturnRows(sqlmock.NewRows(append([]string{"role_type"}, statisticsRowColumns...)).
+			AddRow("executive", 10, 300000.0, 30000.0, 280000.0, 320000.0, 4.0, 0.5, 3.5, 4.5, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("WITH salary_median AS").
+		WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(100000.0, 5000.0))
+	sqlMock.ExpectQuery("FROM anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// The same salary is typical for an executive but far above the global average
+	sqlMock.ExpectQuery("SELECT\\s+job_id").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", map[string]driver.Value{"role_type": "executive", "company_rating": 4.0, "max_salary": 310000.0})...).
+			AddRow(jobRowWith("job2", map[string]driver.Value{"role_type": "retail", "company_rating": 4.0, "max_salary": 310000.0})...))
+	expectDetectionRunFinish(sqlMock, 1, models.DetectionRunSucceeded, 2, 5)
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	cfg.RequiredFields = nil
+	cfg.StatsGroupBy = config.StatsGroupByRoleType
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	anomalies, err := service.DetectAnomaliesForAllJobs(context.Background(), true)
+
+	assert.NoError(t, err)
+	types := map[string][]models.AnomalyType{}
+	for _, anomaly := range anomalies {
+		types[anomaly.JobID] = append(types[anomaly.JobID], anomaly.Type)
+	}
+	// Only the median absolute deviation, always taken over all jobs, flags the executive
+	assert.Equal(t, []models.AnomalyType{models.AnomalyTypeMAD}, types["job1"])
+	assert.Subset(t, types["job2"], []models.AnomalyType{models.AnomalyTypeDeviation, models.AnomalyTypeIQR})
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestGetGroupStatisticsRejectsUnknownColumn(t *testing.T) {
+	service := NewAnomalyService(new(MockDB), nil, nil, nil, nil)
+	_, err := service.getGroupStatistics(context.Background(), "company_name; DROP TABLE jobs")
+	assert.Error(t, err)
+}
+
+func TestCompareValuesRange(t *testing.T) {
+	between := models.RuleCondition{Type: models.AnomalyTypeMaxSalary, Operator: models.Between, Value: 0, ValueHigh: 1000}
+	notBetween := models.RuleCondition{Type: models.AnomalyTypeMaxSalary, Operator: models.NotBetween, Value: 0, ValueHigh: 1000}
+
+	tests := []struct {
+		name   string
+		value  float64
+		inside bool
+	}{
+		{"inside range", 500, true},
+		{"lower bound is inclusive", 0, true},
+		{"upper bound is inclusive", 1000, true},
+		{"below range", -1, false},
+		{"above range", 85000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.inside, compareValues(tt.value, between))
+			assert.Equal(t, !tt.inside, compareValues(tt.value, notBetween))
+		})
+	}
+
+	// Single-value operators ignore ValueHigh
+	assert.True(t, compareValues(1500, models.RuleCondition{Operator: models.GreaterThan, Value: 1000, ValueHigh: 2000}))
+}
+
+func TestEvaluateRuleConditions(t *testing.T) {
+	job := &models.JobData{
+		JobID:         "job1",
+		CompanyRating: Float64Ptr(1.5),
+		MaxSalary:     Float64Ptr(600000),
+	}
+
+	tests := []struct {
+		name               string
+		rule               models.AnomalyRule
+		expectedMatch      bool
+		expectedViolations []string
+		expectedValue      float64
+		expectedThreshold  float64
+	}{
+		{
+			name: "legacy single condition rule",
+			rule: models.AnomalyRule{
+				Type:     models.AnomalyTypeMaxSalary,
+				Operator: models.GreaterThan,
+				Value:    500000,
+			},
+			expectedMatch:      true,
+			expectedViolations: []string{"max_salary > 500000"},
+			expectedValue:      600000,
+			expectedThreshold:  500000,
+		},
+		{
+			name: "between rule",
+			rule: models.AnomalyRule{
+				Type:      models.AnomalyTypeRating,
+				Operator:  models.Between,
+				Value:     1,
+				ValueHigh: 2,
+			},
+			expectedMatch:      true,
+			expectedViolations: []string{"company_rating BETWEEN 1 AND 2"},
+			expectedValue:      1.5,
+			expectedThreshold:  1,
+		},
+		{
+			name: "not between rule with value inside the range",
+			rule: models.AnomalyRule{
+				Type:      models.AnomalyTypeMaxSalary,
+				Operator:  models.NotBetween,
+				Value:     100000,
+				ValueHigh: 1000000,
+			},
+			expectedMatch: false,
+		},
+		{
+			name: "and rule with all conditions met",
+			rule: models.AnomalyRule{
+				Logic: models.RuleLogicAnd,
+				Conditions: models.RuleConditions{
+					{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 500000},
+					{Type: models.AnomalyTypeRating, Operator: models.LessThan, Value: 2},
+				},
+			},
+			expectedMatch:      true,
+			expectedViolations: []string{"max_salary > 500000", "company_rating < 2"},
+			expectedValue:      600000,
+			expectedThreshold:  500000,
+		},
+		{
+			name: "and rule short-circuits on first unmet condition",
+			rule: models.AnomalyRule{
+				Logic: models.RuleLogicAnd,
+				Conditions: models.RuleConditions{
+					{Type: models.AnomalyTypeRating, Operator: models.GreaterThan, Value: 4},
+					{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 500000},
+				},
+			},
+			expectedMatch: false,
+		},
+		{
+			name: "and rule fails when a referenced field is missing",
+			rule: models.AnomalyRule{
+				Logic: models.RuleLogicAnd,
+				Conditions: models.RuleConditions{
+					{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 500000},
+					{Type: models.AnomalyTypeMinSalary, Operator: models.LessThan, Value: 1000},
+				},
+			},
+			expectedMatch: false,
+		},
+		{
+			name: "or rule records only contributing conditions",
+			rule: models.AnomalyRule{
+				Logic: models.RuleLogicOr,
+				Conditions: models.RuleConditions{
+					{Type: models.AnomalyTypeMaxSalary, Operator: models.LessThan, Value: 0},
+					{Type: models.AnomalyTypeRating, Operator: models.LessThan, Value: 2},
+					{Type: models.AnomalyTypeMinSalary, Operator: models.LessThan, Value: 1000},
+				},
+			},
+			expectedMatch:      true,
+			expectedViolations: []string{"company_rating < 2"},
+			expectedValue:      1.5,
+			expectedThreshold:  2,
+		},
+		{
+			name: "or rule with no conditions met",
+			rule: models.AnomalyRule{
+				Logic: models.RuleLogicOr,
+				Conditions: models.RuleConditions{
+					{Type: models.AnomalyTypeMaxSalary, Operator: models.LessThan, Value: 0},
+					{Type: models.AnomalyTypeRating, Operator: models.GreaterThan, Value: 4},
+				},
+			},
+			expectedMatch: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, violations, value, threshold := evaluateRuleConditions(job, tt.rule)
+
+			assert.Equal(t, tt.expectedMatch, matched)
+			assert.Equal(t, tt.expectedViolations, violations)
+			assert.Equal(t, tt.expectedValue, value)
+			assert.Equal(t, tt.expectedThreshold, threshold)
+		})
+	}
+}
+
+func TestDetectAnomaliesCompanyRating(t *testing.T) {
+	cfg := config.DefaultDetectionConfig()
+	cfg.RequiredFields = []string{"company_rating"}
+	service := NewAnomalyService(nil, nil, cfg, nil, nil)
+	dc := &detectionContext{
+		stats: &Statistics{AvgRating: 4.0, RatingStdDev: 0.5, RatingQ1: 3.8, RatingQ3: 4.2},
+	}
+
+	tests := []struct {
+		name          string
+		rating        *float64
+		expectedTypes []models.AnomalyType
+	}{
+		{
+			name:          "missing rating is only reported as a null value",
+			rating:        nil,
+			expectedTypes: []models.AnomalyType{models.AnomalyTypeNullValues},
+		},
+		{
+			name:          "zero rating is evaluated as a value",
+			rating:        Float64Ptr(0),
+			expectedTypes: []models.AnomalyType{models.AnomalyTypeDeviation, models.AnomalyTypeIQR},
+		},
+		{
+			name:   "typical rating is not flagged",
+			rating: Float64Ptr(4.0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.JobData{JobID: "job1", CompanyRating: tt.rating}
+			save := func(ctx context.Context, anomaly *models.Anomaly) error { return nil }
+
+			anomalies := service.detectAnomaliesWithContext(context.Background(), job, dc, save)
+
+			var types []models.AnomalyType
+			for _, anomaly := range anomalies {
+				types = append(types, anomaly.Type)
+				if anomaly.Type == models.AnomalyTypeNullValues {
+					assert.Equal(t, []string{"company_rating"}, anomaly.Violations)
+				} else {
+					assert.Equal(t, 0.0, anomaly.Value)
+				}
+			}
+			assert.Equal(t, tt.expectedTypes, types)
+		})
+	}
+}
+
+func TestDetectAnomaliesSeverityMap(t *testing.T) {
+	cfg := config.DefaultDetectionConfig()
+	cfg.RequiredFields = []string{"company_name"}
+	cfg.SeverityMap[models.AnomalyTypeNullValues] = models.SeverityLow
+	cfg.SeverityMap[models.AnomalyTypeSalaryRange] = models.SeverityCritical
+	service := NewAnomalyService(nil, nil, cfg, nil, nil)
+	dc := &detectionContext{
+		stats: &Statistics{},
+		rules: []models.AnomalyRule{
+			{ID: 1, Type: models.AnomalyTypeMaxSalary, Operator: models.LessThan, Value: 50000, IsActive: true},
+		},
+	}
+
+	// Missing company name, inverted salary range, and a rule without its own severity
+	job := &models.JobData{JobID: "job1", MinSalary: Float64Ptr(45000), MaxSalary: Float64Ptr(40000)}
+	save := func(ctx context.Context, anomaly *models.Anomaly) error { return nil }
+
+	severities := make(map[models.AnomalyType]string)
+	for _, anomaly := range service.detectAnomaliesWithContext(context.Background(), job, dc, save) {
+		severities[anomaly.Type] = anomaly.Severity
+	}
+
+	assert.Equal(t, map[models.AnomalyType]string{
+		models.AnomalyTypeNullValues:  models.SeverityLow,
+		models.AnomalyTypeSalaryRange: models.SeverityCritical,
+		models.AnomalyTypeMaxSalary:   models.SeverityMedium,
+	}, severities)
+}
+
+func TestDetectAnomaliesDisabledDetectors(t *testing.T) {
+	dc := &detectionContext{
+		stats: &Statistics{
+			AvgSalary: 100000, SalaryStdDev: 10000, SalaryQ1: 95000, SalaryQ3: 105000,
+			AvgRating: 4, RatingStdDev: 0.1, RatingQ1: 3.9, RatingQ3: 4.1,
+		},
+		rules: []models.AnomalyRule{
+			{ID: 1, Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 300000, IsActive: true},
+		},
+	}
+	// Missing company name, inverted salary range, and salary and rating far from the mean
+	job := &models.JobData{JobID: "job1", MinSalary: Float64Ptr(450000), MaxSalary: Float64Ptr(400000), CompanyRating: Float64Ptr(1)}
+	save := func(ctx context.Context, anomaly *models.Anomaly) error { return nil }
+
+	tests := []struct {
+		name     string
+		disable  func(cfg *config.DetectionConfig)
+		expected map[models.AnomalyType]int
+	}{
+		{
+			name:    "all detectors enabled",
+			disable: func(cfg *config.DetectionConfig) {},
+			expected: map[models.AnomalyType]int{
+				models.AnomalyTypeNullValues:   1,
+				models.AnomalyTypeSalaryRange:  1,
+				models.AnomalyTypeDeviation:    2,
+				models.AnomalyTypeIQR:          2,
+				models.AnomalyTypeMaxSalary:    1,
+				models.AnomalyTypeMultivariate: 1,
+			},
+		},
+		{
+			name:    "null check disabled",
+			disable: func(cfg *config.DetectionConfig) { cfg.EnableNullCheck = false },
+			expected: map[models.AnomalyType]int{
+				models.AnomalyTypeSalaryRange:  1,
+				models.AnomalyTypeDeviation:    2,
+				models.AnomalyTypeIQR:          2,
+				models.AnomalyTypeMaxSalary:    1,
+				models.AnomalyTypeMultivariate: 1,
+			},
+		},
+		{
+			name:    "deviation disabled for salary and rating",
+			disable: func(cfg *config.DetectionConfig) { cfg.EnableDeviation = false },
+			expected: map[models.AnomalyType]int{
+				models.AnomalyTypeNullValues:   1,
+				models.AnomalyTypeSalaryRange:  1,
+				models.AnomalyTypeIQR:          2,
+				models.AnomalyTypeMaxSalary:    1,
+				models.AnomalyTypeMultivariate: 1,
+			},
+		},
+		{
+			name: "only rule checks",
+			disable: func(cfg *config.DetectionConfig) {
+				cfg.EnableNullCheck = false
+				cfg.EnableSalaryRange = false
+				cfg.EnableDeviation = false
+				cfg.EnableIQR = false
+				cfg.EnableMAD = false
+				cfg.EnableTrend = false
+				cfg.EnableGeoOutlier = false
+				cfg.EnableMultivariate = false
+				cfg.EnableStale = false
+			},
+			expected: map[models.AnomalyType]int{
+				models.AnomalyTypeMaxSalary: 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultDetectionConfig()
+			cfg.RequiredFields = []string{"company_name"}
+			tt.disable(cfg)
+			service := NewAnomalyService(nil, nil, cfg, nil, nil)
+
+			counts := make(map[models.AnomalyType]int)
+			for _, anomaly := range service.detectAnomaliesWithContext(context.Background(), job, dc, save) {
+				counts[anomaly.Type]++
+			}
+			assert.Equal(t, tt.expected, counts)
+		})
+	}
+}
+
+func TestDetectAnomaliesForAllJobsNullValues(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// job1 has every default required field, job2 only lacks a job link
+	complete := map[string]driver.Value{
+		"company_rating":  4.0,
+		"company_address": "1 Main St",
+		"company_website": "https://techcorp.example",
+		"job_link":        "https://techcorp.example/jobs/1",
+		"job_description": "Build services",
+	}
+	incomplete := maps.Clone(complete)
+	incomplete["job_link"] = nil
+
+	expectDetectionRunStart(sqlMock, 1, true)
+	expectDetectionContext(sqlMock, sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery("SELECT\\s+job_id").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", complete)...).
+			AddRow(jobRowWith("job2", incomplete)...))
+	expectDetectionRunFinish(sqlMock, 1, models.DetectionRunSucceeded, 2, 1)
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	anomalies, err := service.DetectAnomaliesForAllJobs(context.Background(), true)
+
+	assert.NoError(t, err)
+	if assert.Len(t, anomalies, 1) {
+		assert.Equal(t, "job2", anomalies[0].JobID)
+		assert.Equal(t, models.AnomalyTypeNullValues, anomalies[0].Type)
+		assert.Equal(t, []string{"job_link"}, anomalies[0].Violations)
+	}
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestJobFieldValueCompanyRating(t *testing.T) {
+	_, ok := jobFieldValue(&models.JobData{}, models.AnomalyTypeRating)
+	assert.False(t, ok)
+
+	value, ok := jobFieldValue(&models.JobData{CompanyRating: Float64Ptr(0)}, models.AnomalyTypeRating)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, value)
+
+	value, ok = jobFieldValue(&models.JobData{CompanyRating: Float64Ptr(4.5)}, models.AnomalyTypeRating)
+	assert.True(t, ok)
+	assert.Equal(t, 4.5, value)
+}
+
+func TestDetectAnomaliesIsIdempotent(t *testing.T) {
+	db := newTestDatabase(t)
+	jobDataService := NewJobDataService(db, nil)
+	anomalyService := NewAnomalyService(db, NewAnomalyRuleService(db, nil), nil, nil, nil)
+
+	// Missing required fields and a negative salary trigger the null-value check and the default rule
+	job := &models.JobData{
+		JobID:       "dedup-job",
+		CompanyName: "Tech Corp",
+		JobTitle:    "Software Engineer",
+		MaxSalary:   Float64Ptr(-100),
+	}
+	assert.NoError(t, jobDataService.CreateJobData(context.Background(), job))
+
+	first, err := anomalyService.DetectAnomalies(context.Background(), job)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, first)
+
+	stored, err := anomalyService.GetAnomaliesByJobID(context.Background(), job.JobID, true)
+	assert.NoError(t, err)
+	countAfterFirstRun := len(stored)
+
+	second, err := anomalyService.DetectAnomalies(context.Background(), job)
+	assert.NoError(t, err)
+	assert.Len(t, second, len(first))
+
+	stored, err = anomalyService.GetAnomaliesByJobID(context.Background(), job.JobID, true)
+	assert.NoError(t, err)
+	assert.Len(t, stored, countAfterFirstRun)
+	for i := range first {
+		assert.Equal(t, first[i].ID, second[i].ID)
+	}
+}
+
+func TestDetectAnomaliesForAllJobsCancellation(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// The statistics query that starts the batch stalls long enough that only cancellation can end it early
+	sqlMock.ExpectQuery("FROM jobs").
+		WillDelayFor(5 * time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"sample_count"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	start := time.Now()
+	_, err = service.DetectAnomaliesForAllJobs(ctx, false)
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestDetectAnomaliesForAllJobsSkipsDeletedJob(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectDetectionRunStart(sqlMock, 1, false)
+	sqlMock.ExpectQuery("FROM jobs").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(2, 100000.0, nil, 100000.0, 100000.0, 4.0, nil, 4.0, 4.0, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("WITH salary_median AS").
+		WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(100000.0, 0.0))
+	sqlMock.ExpectQuery("FROM anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// Both jobs are missing a company name and have an inverted salary range
+	sqlMock.ExpectQuery("SELECT job_id").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", map[string]driver.Value{"company_name": "", "company_rating": 4.0, "min_salary": 150000.0, "max_salary": 100000.0})...).
+			AddRow(jobRowWith("job2", map[string]driver.Value{"company_name": "", "company_rating": 4.0, "min_salary": 150000.0, "max_salary": 100000.0})...))
+
+	// job1 is deleted before its first anomaly is saved, so its second anomaly is never attempted
+	anyArgs := []driver.Value{sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()}
+	sqlMock.ExpectQuery("INSERT INTO anomalies").
+		WithArgs(append([]driver.Value{"job1"}, anyArgs...)...).
+		WillReturnError(&pq.Error{Code: foreignKeyViolationCode})
+	for i := 1; i <= 2; i++ {
+		sqlMock.ExpectQuery("INSERT INTO anomalies").
+			WithArgs(append([]driver.Value{"job2"}, anyArgs...)...).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(i, time.Now(), true))
+	}
+	// Only job2's anomalies were saved
+	expectDetectionRunFinish(sqlMock, 1, models.DetectionRunSucceeded, 2, 2)
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	cfg.RequiredFields = []string{"company_name"}
+	cfg.Workers = 1 // The expected saves are ordered by job
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	_, err = service.DetectAnomaliesForAllJobs(context.Background(), false)
+
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSaveAnomalyDeletedJob(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("INSERT INTO anomalies").
+		WillReturnError(&pq.Error{Code: foreignKeyViolationCode})
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	err = service.saveAnomaly(context.Background(), &models.Anomaly{JobID: "job1", Type: models.AnomalyTypeNullValues})
+
+	assert.ErrorIs(t, err, ErrJobDeleted)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Contains(t, err.Error(), "job1")
+}
+
+func TestSaveAnomalyNotifiesNewAnomalies(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rows := func(inserted bool) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(7, time.Now(), inserted)
+	}
+	// A new anomaly is announced with its id and job id
+	sqlMock.ExpectQuery("INSERT INTO anomalies").WillReturnRows(rows(true))
+	sqlMock.ExpectExec(regexp.QuoteMeta("SELECT pg_notify($1, $2)")).
+		WithArgs("anomaly_detected", `{"id":"7","job_id":"job1","type":"null_values","severity":"high"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Detecting it again updates the stored anomaly without another notification
+	sqlMock.ExpectQuery("INSERT INTO anomalies").WillReturnRows(rows(false))
+	// A failed notification does not fail the save
+	sqlMock.ExpectQuery("INSERT INTO anomalies").WillReturnRows(rows(true))
+	sqlMock.ExpectExec(regexp.QuoteMeta("SELECT pg_notify($1, $2)")).
+		WillReturnError(errors.New("connection reset"))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	service.SetNotifyChannel("anomaly_detected")
+	for range 3 {
+		anomaly := &models.Anomaly{JobID: "job1", Type: models.AnomalyTypeNullValues, Severity: models.SeverityHigh}
+		assert.NoError(t, service.saveAnomaly(context.Background(), anomaly))
+	}
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSaveAnomalyCountsOnlyNewAnomalies(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// The anomaly is stored once and then updated by two later detection runs
+	for _, inserted := range []bool{true, false, false} {
+		sqlMock.ExpectQuery("INSERT INTO anomalies").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(7, time.Now(), inserted))
+	}
+
+	counter := metrics.AnomaliesDetected.WithLabelValues(string(models.AnomalyTypeGeoOutlier), models.SeverityCritical)
+	before := counterValue(t, counter)
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	for range 3 {
+		anomaly := &models.Anomaly{JobID: "job1", Type: models.AnomalyTypeGeoOutlier, Severity: models.SeverityCritical}
+		assert.NoError(t, service.saveAnomaly(context.Background(), anomaly))
+	}
+
+	assert.Equal(t, 1.0, counterValue(t, counter)-before)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+// counterValue reads the current value of a Prometheus counter
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	var metric dto.Metric
+	assert.NoError(t, counter.Write(&metric))
+	return metric.GetCounter().GetValue()
+}
+
+func TestSaveAnomalyWithoutNotifyChannel(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Only the insert is expected; a NOTIFY would be an unexpected statement
+	sqlMock.ExpectQuery("INSERT INTO anomalies").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(7, time.Now(), true))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	assert.NoError(t, service.saveAnomaly(context.Background(), &models.Anomaly{JobID: "job1", Type: models.AnomalyTypeNullValues}))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestTrendAnomaly(t *testing.T) {
+	window := &Statistics{SampleCount: 40, AvgSalary: 100000, SalaryStdDev: 10000}
+
+	tests := []struct {
+		name             string
+		job              *models.JobData
+		window           *Statistics
+		expectAnomaly    bool
+		expectedSeverity string
+		expectedOperator models.ComparisonOperator
+	}{
+		{
+			name:   "salary in line with the recent window",
+			job:    &models.JobData{JobID: "job1", MaxSalary: Float64Ptr(115000)},
+			window: window,
+		},
+		{
+			name:             "salary far above the recent window",
+			job:              &models.JobData{JobID: "job1", MaxSalary: Float64Ptr(140000)},
+			window:           window,
+			expectAnomaly:    true,
+			expectedSeverity: models.SeverityMedium,
+			expectedOperator: models.GreaterThan,
+		},
+		{
+			name:             "salary extremely far below the recent window",
+			job:              &models.JobData{JobID: "job1", MaxSalary: Float64Ptr(20000)},
+			window:           window,
+			expectAnomaly:    true,
+			expectedSeverity: models.SeverityHigh,
+			expectedOperator: models.LessThan,
+		},
+		{
+			name:   "window with too few samples",
+			job:    &models.JobData{JobID: "job1", MaxSalary: Float64Ptr(140000)},
+			window: &Statistics{SampleCount: 2, AvgSalary: 100000, SalaryStdDev: 10000},
+		},
+		{
+			name:   "window without spread",
+			job:    &models.JobData{JobID: "job1", MaxSalary: Float64Ptr(140000)},
+			window: &Statistics{SampleCount: 40, AvgSalary: 100000},
+		},
+		{
+			name:   "job without a max salary",
+			job:    &models.JobData{JobID: "job1"},
+			window: window,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomaly := trendAnomaly(tt.job, tt.window, config.DefaultStdDevThreshold, config.DefaultMinGroupSamples, models.SeverityMedium)
+
+			if !tt.expectAnomaly {
+				assert.Nil(t, anomaly)
+				return
+			}
+			if assert.NotNil(t, anomaly) {
+				assert.Equal(t, models.AnomalyTypeTrend, anomaly.Type)
+				assert.Equal(t, tt.expectedSeverity, anomaly.Severity)
+				assert.Equal(t, tt.window.AvgSalary, anomaly.Threshold)
+				assert.Equal(t, tt.expectedOperator, anomaly.Operator)
+			}
+		})
+	}
+}
+
+func TestGetWindowedStatistics(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	since := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"sample_count", "avg_salary", "salary_stddev", "salary_q1", "salary_q3",
+		"avg_rating", "rating_stddev", "rating_q1", "rating_q3", "salary_rating_covariance",
+		"avg_latitude", "latitude_stddev", "avg_longitude", "longitude_stddev"}
+	sqlMock.ExpectQuery("date_collected > \\$1").
+		WithArgs(since).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(25, 98000.0, 12000.0, 90000.0, 105000.0, 4.0, 0.4, 3.8, 4.3, 1500.0, 39.74, 0.8, -104.99, 1.1))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	stats, err := service.getWindowedStatistics(context.Background(), since)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 25, stats.SampleCount)
+	assert.Equal(t, 98000.0, stats.AvgSalary)
+	assert.Equal(t, 12000.0, stats.SalaryStdDev)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestMADOutlier(t *testing.T) {
+	tests := []struct {
+		name              string
+		value             float64
+		median            float64
+		mad               float64
+		expectedModifiedZ float64
+		expectedOK        bool
+	}{
+		{
+			name:              "value within cutoff",
+			value:             140000,
+			median:            100000,
+			mad:               10000,
+			expectedModifiedZ: 2.698,
+			expectedOK:        false,
+		},
+		{
+			name:              "value above cutoff",
+			value:             160000,
+			median:            100000,
+			mad:               10000,
+			expectedModifiedZ: 4.047,
+			expectedOK:        true,
+		},
+		{
+			name:              "value below cutoff",
+			value:             40000,
+			median:            100000,
+			mad:               10000,
+			expectedModifiedZ: -4.047,
+			expectedOK:        true,
+		},
+		{
+			name:              "value exactly at cutoff",
+			value:             100000 + 3.5*10000/MADScaleFactor,
+			median:            100000,
+			mad:               10000,
+			expectedModifiedZ: 3.5,
+			expectedOK:        false,
+		},
+		{
+			name:       "zero MAD",
+			value:      1000000,
+			median:     100000,
+			mad:        0,
+			expectedOK: false,
+		},
+		{
+			name:       "NaN MAD",
+			value:      1000000,
+			median:     100000,
+			mad:        math.NaN(),
+			expectedOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modifiedZ, ok := madOutlier(tt.value, tt.median, tt.mad, config.DefaultMADCutoff)
+
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.InDelta(t, tt.expectedModifiedZ, modifiedZ, 1e-3)
+		})
+	}
+}
+
+func TestGetSalaryMAD(t *testing.T) {
+	tests := []struct {
+		name           string
+		median         interface{}
+		mad            interface{}
+		expectedMedian float64
+		expectedMAD    float64
+	}{
+		{name: "populated table", median: 100000.0, mad: 12500.0, expectedMedian: 100000, expectedMAD: 12500},
+		{name: "no salaries", median: nil, mad: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			sqlMock.ExpectQuery("WITH salary_median AS").
+				WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(tt.median, tt.mad))
+
+			service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+			median, mad, err := service.getSalaryMAD(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedMedian, median)
+			assert.Equal(t, tt.expectedMAD, mad)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestNullValueAnomaly(t *testing.T) {
+	job := &models.JobData{
+		JobID:          "job1",
+		CompanyName:    "Tech Corp",
+		JobTitle:       "Engineer",
+		JobDescription: "Builds things",
+		City:           "Denver",
+		CompanyAddress: "1 Main St",
+		CompanyWebsite: "https://example.com",
+		JobLink:        "https://example.com/jobs/1",
+	}
+
+	tests := []struct {
+		name               string
+		requiredFields     []string
+		expectedViolations []string
+	}{
+		{
+			name:           "default fields present",
+			requiredFields: config.DefaultRequiredFields,
+		},
+		{
+			name:               "custom fields missing",
+			requiredFields:     []string{"company_name", "max_salary", "state", "zip"},
+			expectedViolations: []string{"max_salary", "state", "zip"},
+		},
+		{
+			name:           "no required fields",
+			requiredFields: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomaly := nullValueAnomaly(job, tt.requiredFields, models.SeverityMedium)
+
+			if tt.expectedViolations == nil {
+				assert.Nil(t, anomaly)
+				return
+			}
+			assert.NotNil(t, anomaly)
+			assert.Equal(t, models.AnomalyTypeNullValues, anomaly.Type)
+			assert.Equal(t, tt.expectedViolations, anomaly.Violations)
+		})
+	}
+}
+
+func TestDefaultRequiredFieldsAreValid(t *testing.T) {
+	for _, field := range config.DefaultRequiredFields {
+		assert.True(t, models.IsValidRequiredField(field), field)
+	}
+	assert.False(t, models.IsValidRequiredField("companyName"))
+	assert.False(t, models.IsValidRequiredField("job_id"))
+}
+
+// statisticsRowColumns matches the columns selected by statisticsColumns
+var statisticsRowColumns = []string{"sample_count", "avg_salary", "salary_stddev", "salary_q1", "salary_q3",
+	"avg_rating", "rating_stddev", "rating_q1", "rating_q3", "salary_rating_covariance",
+	"avg_latitude", "latitude_stddev", "avg_longitude", "longitude_stddev"}
+
+// expectDetectionContext expects the queries loading a detection context with
+// typical global statistics and the given anomaly_rules rows, without grouping
+// or a trend window
+func expectDetectionContext(sqlMock sqlmock.Sqlmock, rules *sqlmock.Rows) {
+	sqlMock.ExpectQuery("FROM jobs").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(3, 100000.0, 10000.0, 95000.0, 105000.0, 4.0, 0.5, 3.5, 4.5, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("WITH salary_median AS").
+		WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(100000.0, 5000.0))
+	sqlMock.ExpectQuery("FROM anomaly_rules").WillReturnRows(rules)
+}
+
+func TestDetectAnomaliesForAllJobsDryRun(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Besides the run record only reads are expected; any INSERT INTO anomalies would be an unexpected query
+	expectDetectionRunStart(sqlMock, 1, true)
+	sqlMock.ExpectQuery("FROM jobs").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(1, 100000.0, nil, 100000.0, 100000.0, 4.0, nil, 4.0, 4.0, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("WITH salary_median AS").
+		WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(100000.0, 0.0))
+	sqlMock.ExpectQuery("FROM anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery("SELECT job_id").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", map[string]driver.Value{"company_name": "", "company_rating": 4.0, "min_salary": 150000.0, "max_salary": 100000.0})...))
+	expectDetectionRunFinish(sqlMock, 1, models.DetectionRunSucceeded, 1, 2)
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	anomalies, err := service.DetectAnomaliesForAllJobs(context.Background(), true)
+
+	assert.NoError(t, err)
+	types := make([]models.AnomalyType, len(anomalies))
+	for i, anomaly := range anomalies {
+		types[i] = anomaly.Type
+		assert.Zero(t, anomaly.ID)
+	}
+	assert.ElementsMatch(t, []models.AnomalyType{models.AnomalyTypeNullValues, models.AnomalyTypeSalaryRange}, types)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestDetectAnomaliesForAllJobsWorkerPool(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectDetectionRunStart(sqlMock, 1, true)
+	sqlMock.ExpectQuery("FROM jobs").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(1, 100000.0, nil, 100000.0, 100000.0, 4.0, nil, 4.0, 4.0, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("WITH salary_median AS").
+		WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(100000.0, 0.0))
+	sqlMock.ExpectQuery("FROM anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// Every job is missing a company name, so each yields exactly one anomaly
+	const jobCount = 100
+	jobRows := sqlmock.NewRows(jobRowColumns)
+	var expectedJobIDs []string
+	for i := 0; i < jobCount; i++ {
+		jobID := "job" + strconv.Itoa(i)
+		jobRows.AddRow(jobRowWith(jobID, map[string]driver.Value{"company_name": "", "company_rating": 4.0, "min_salary": 100000.0, "max_salary": 100000.0})...)
+		expectedJobIDs = append(expectedJobIDs, jobID)
+	}
+	sqlMock.ExpectQuery("SELECT job_id").WillReturnRows(jobRows)
+	expectDetectionRunFinish(sqlMock, 1, models.DetectionRunSucceeded, jobCount, jobCount)
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	cfg.RequiredFields = []string{"company_name"}
+	cfg.Workers = 8
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	anomalies, err := service.DetectAnomaliesForAllJobs(context.Background(), true)
+
+	assert.NoError(t, err)
+	jobIDs := make([]string, len(anomalies))
+	for i, anomaly := range anomalies {
+		jobIDs[i] = anomaly.JobID
+	}
+	assert.ElementsMatch(t, expectedJobIDs, jobIDs)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestDetectAnomaliesSince(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	since := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	// Statistics cover the whole table while only the newer jobs are fetched
+	sqlMock.ExpectQuery("FROM jobs\\s+WHERE max_salary IS NOT NULL").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(3, 100000.0, 10000.0, 90000.0, 110000.0, 4.0, 0.5, 3.5, 4.5, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("WITH salary_median AS").
+		WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(100000.0, 5000.0))
+	sqlMock.ExpectQuery("FROM anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery("SELECT job_id.*FROM jobs\\s+WHERE created_at > \\$1").
+		WithArgs(since).
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", map[string]driver.Value{"company_name": "Tech Corp", "company_rating": 4.0, "min_salary": 90000.0, "max_salary": 95000.0})...).
+			AddRow(jobRowWith("job2", map[string]driver.Value{"company_name": "Tech Corp", "company_rating": 4.2, "min_salary": 100000.0, "max_salary": 105000.0})...))
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	cfg.RequiredFields = nil
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	processed, err := service.DetectAnomaliesSince(context.Background(), since)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, processed)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestGetAnomalyStats(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("SELECT type AS key, COUNT\\(\\*\\) FROM anomalies GROUP BY key").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "count"}).
+			AddRow("null_values", 4).
+			AddRow("iqr_outlier", 2))
+	sqlMock.ExpectQuery("SELECT COALESCE\\(severity, ''\\) AS key").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "count"}).
+			AddRow("medium", 5).
+			AddRow("high", 1))
+	sqlMock.ExpectQuery("date_trunc\\('day'").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"day", "count"}).
+			AddRow(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), 3).
+			AddRow(time.Date(2025, 4, 3, 0, 0, 0, 0, time.UTC), 1))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	stats, err := service.GetAnomalyStats(context.Background(), 7*24*time.Hour)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &models.AnomalyStats{
+		Total:      6,
+		ByType:     map[models.AnomalyType]int{models.AnomalyTypeNullValues: 4, models.AnomalyTypeIQR: 2},
+		BySeverity: map[string]int{models.SeverityMedium: 5, models.SeverityHigh: 1},
+		Daily: []models.DailyAnomalyCount{
+			{Date: "2025-04-01", Count: 3},
+			{Date: "2025-04-03", Count: 1},
+		},
+	}, stats)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestGetAnomalyTypeCounts(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	query := "SELECT type AS key, COUNT\\(\\*\\) FROM anomalies GROUP BY key"
+	sqlMock.ExpectQuery(query).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "count"}).
+			AddRow("null_values", 4).
+			AddRow("iqr_outlier", 2))
+	sqlMock.ExpectQuery(query).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "count"}))
+	sqlMock.ExpectQuery(query).
+		WillReturnError(errors.New("connection refused"))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+
+	counts, err := service.GetAnomalyTypeCounts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"null_values": 4, "iqr_outlier": 2}, counts)
+
+	counts, err = service.GetAnomalyTypeCounts(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, counts)
+	assert.Empty(t, counts)
+
+	_, err = service.GetAnomalyTypeCounts(context.Background())
+	assert.ErrorContains(t, err, "error counting anomalies by type")
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestGeoOutlierAnomaly(t *testing.T) {
+	// A tight cluster of jobs around Denver plus a single job in Anchorage
+	var latitudes, longitudes []float64
+	for i := 0; i < 20; i++ {
+		latitudes = append(latitudes, 39.70+float64(i%5)*0.02)
+		longitudes = append(longitudes, -105.00+float64(i%4)*0.02)
+	}
+	latitudes = append(latitudes, 61.22)
+	longitudes = append(longitudes, -149.90)
+
+	// Match Postgres AVG and sample STDDEV over every job, including the outlier
+	meanAndStdDev := func(values []float64) (float64, float64) {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		mean := sum / float64(len(values))
+		var squares float64
+		for _, v := range values {
+			squares += (v - mean) * (v - mean)
+		}
+		return mean, math.Sqrt(squares / float64(len(values)-1))
+	}
+	stats := &Statistics{}
+	stats.AvgLatitude, stats.LatitudeStdDev = meanAndStdDev(latitudes)
+	stats.AvgLongitude, stats.LongitudeStdDev = meanAndStdDev(longitudes)
+
+	t.Run("far away job is flagged", func(t *testing.T) {
+		job := &models.JobData{JobID: "anchorage", Latitude: Float64Ptr(61.22), Longitude: Float64Ptr(-149.90)}
+
+		anomaly := geoOutlierAnomaly(job, stats, config.DefaultStdDevThreshold, models.SeverityMedium)
+
+		assert.NotNil(t, anomaly)
+		assert.Equal(t, models.AnomalyTypeGeoOutlier, anomaly.Type)
+		assert.Equal(t, []string{"latitude", "longitude"}, anomaly.Violations)
+		assert.Greater(t, math.Abs(anomaly.Value), config.DefaultStdDevThreshold)
+	})
+
+	t.Run("clustered jobs are not flagged", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			job := &models.JobData{JobID: "denver", Latitude: Float64Ptr(latitudes[i]), Longitude: Float64Ptr(longitudes[i])}
+			assert.Nil(t, geoOutlierAnomaly(job, stats, config.DefaultStdDevThreshold, models.SeverityMedium))
+		}
+	})
+
+	t.Run("missing coordinates are skipped", func(t *testing.T) {
+		assert.Nil(t, geoOutlierAnomaly(&models.JobData{JobID: "nowhere"}, stats, config.DefaultStdDevThreshold, models.SeverityMedium))
+		assert.Nil(t, geoOutlierAnomaly(&models.JobData{JobID: "half", Latitude: Float64Ptr(61.22)}, stats, config.DefaultStdDevThreshold, models.SeverityMedium))
+	})
+
+	t.Run("no spread in coordinates", func(t *testing.T) {
+		flat := &Statistics{AvgLatitude: 39.74, AvgLongitude: -104.99}
+		job := &models.JobData{JobID: "anchorage", Latitude: Float64Ptr(61.22), Longitude: Float64Ptr(-149.90)}
+		assert.Nil(t, geoOutlierAnomaly(job, flat, config.DefaultStdDevThreshold, models.SeverityMedium))
+	})
+}
+
+func TestSalaryRatingDistance(t *testing.T) {
+	// Salary and rating are strongly correlated (0.8), so a high salary with a
+	// high rating is expected while a high salary with a low rating is not
+	stats := &Statistics{AvgSalary: 100000, SalaryStdDev: 20000, AvgRating: 4, RatingStdDev: 0.5, SalaryRatingCovariance: 8000}
+
+	tests := []struct {
+		name     string
+		salary   float64
+		rating   float64
+		stats    *Statistics
+		expected float64
+		ok       bool
+	}{
+		{name: "at the mean", salary: 100000, rating: 4, stats: stats, expected: 0, ok: true},
+		{name: "along the correlation", salary: 140000, rating: 5, stats: stats, expected: math.Sqrt(40.0 / 9), ok: true},
+		{name: "against the correlation", salary: 140000, rating: 3, stats: stats, expected: math.Sqrt(40), ok: true},
+		{
+			name:   "uncorrelated fields add up like z-scores",
+			salary: 160000, rating: 2,
+			stats:    &Statistics{AvgSalary: 100000, SalaryStdDev: 20000, AvgRating: 4, RatingStdDev: 0.5},
+			expected: 5, ok: true,
+		},
+		{
+			name:   "perfectly correlated fields are singular",
+			salary: 140000, rating: 3,
+			stats: &Statistics{AvgSalary: 100000, SalaryStdDev: 20000, AvgRating: 4, RatingStdDev: 0.5, SalaryRatingCovariance: 10000},
+		},
+		{
+			name:   "no rating spread",
+			salary: 140000, rating: 3,
+			stats: &Statistics{AvgSalary: 100000, SalaryStdDev: 20000, AvgRating: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance, ok := salaryRatingDistance(tt.salary, tt.rating, tt.stats)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.expected, distance, 1e-9)
+		})
+	}
+}
+
+func TestMultivariateAnomaly(t *testing.T) {
+	stats := &Statistics{AvgSalary: 100000, SalaryStdDev: 20000, AvgRating: 4, RatingStdDev: 0.5, SalaryRatingCovariance: 8000}
+
+	t.Run("jointly unusual job is flagged", func(t *testing.T) {
+		// Each field is only two standard deviations from its mean
+		job := &models.JobData{JobID: "job1", MaxSalary: Float64Ptr(140000), CompanyRating: Float64Ptr(3)}
+		for _, zScore := range []float64{(140000 - 100000) / 20000.0, (3 - 4) / 0.5} {
+			assert.LessOrEqual(t, math.Abs(zScore), config.DefaultStdDevThreshold)
+		}
+
+		anomaly := multivariateAnomaly(job, stats, config.DefaultMultivariateThreshold, models.SeverityMedium)
+
+		assert.NotNil(t, anomaly)
+		assert.Equal(t, models.AnomalyTypeMultivariate, anomaly.Type)
+		assert.Equal(t, []string{"max_salary", "company_rating"}, anomaly.Violations)
+		assert.InDelta(t, math.Sqrt(40), anomaly.Value, 1e-9)
+		assert.Equal(t, config.DefaultMultivariateThreshold, anomaly.Threshold)
+		assert.Equal(t, models.SeverityHigh, anomaly.Severity)
+	})
+
+	t.Run("expected combination is not flagged", func(t *testing.T) {
+		job := &models.JobData{JobID: "job1", MaxSalary: Float64Ptr(140000), CompanyRating: Float64Ptr(5)}
+		assert.Nil(t, multivariateAnomaly(job, stats, config.DefaultMultivariateThreshold, models.SeverityMedium))
+	})
+
+	t.Run("missing fields are skipped", func(t *testing.T) {
+		assert.Nil(t, multivariateAnomaly(&models.JobData{JobID: "job1", MaxSalary: Float64Ptr(140000)}, stats, config.DefaultMultivariateThreshold, models.SeverityMedium))
+		assert.Nil(t, multivariateAnomaly(&models.JobData{JobID: "job1", CompanyRating: Float64Ptr(3)}, stats, config.DefaultMultivariateThreshold, models.SeverityMedium))
+	})
+}
+
+func TestStaleAnomaly(t *testing.T) {
+	collected := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	job := func(posted, collectedAt time.Time) *models.JobData {
+		return &models.JobData{
+			JobID:         "job1",
+			JobPostedTime: models.CustomTime{Time: posted},
+			DateCollected: models.CustomTime{Time: collectedAt},
+		}
+	}
+
+	t.Run("stale job is flagged", func(t *testing.T) {
+		anomaly := staleAnomaly(job(collected.AddDate(0, 0, -120), collected), config.DefaultStaleAge, models.SeverityMedium)
+
+		assert.NotNil(t, anomaly)
+		assert.Equal(t, models.AnomalyTypeStale, anomaly.Type)
+		assert.Equal(t, "Job was posted 120 days before it was collected", anomaly.Description)
+		assert.Equal(t, 120.0, anomaly.Value)
+		assert.Equal(t, 90.0, anomaly.Threshold)
+		assert.Equal(t, []string{"job_posted_time"}, anomaly.Violations)
+		assert.Equal(t, models.SeverityMedium, anomaly.Severity)
+	})
+
+	t.Run("fresh job is not flagged", func(t *testing.T) {
+		assert.Nil(t, staleAnomaly(job(collected.AddDate(0, 0, -10), collected), config.DefaultStaleAge, models.SeverityMedium))
+		assert.Nil(t, staleAnomaly(job(collected.Add(-config.DefaultStaleAge), collected), config.DefaultStaleAge, models.SeverityMedium), "exactly the maximum age")
+		assert.Nil(t, staleAnomaly(job(collected.Add(time.Hour), collected), config.DefaultStaleAge, models.SeverityMedium), "posted after collection")
+	})
+
+	t.Run("missing timestamps are skipped", func(t *testing.T) {
+		assert.Nil(t, staleAnomaly(job(time.Time{}, collected), config.DefaultStaleAge, models.SeverityMedium))
+		assert.Nil(t, staleAnomaly(job(collected.AddDate(-1, 0, 0), time.Time{}), config.DefaultStaleAge, models.SeverityMedium))
+	})
+}
+
+func TestDetectAnomaliesForAllJobsStalePosting(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	collected := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	expectDetectionRunStart(sqlMock, 1, true)
+	expectDetectionContext(sqlMock, sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery("SELECT\\s+job_id").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", map[string]driver.Value{"job_posted_time": collected.AddDate(0, 0, -120), "date_collected": collected})...).
+			AddRow(jobRowWith("job2", map[string]driver.Value{"job_posted_time": collected.AddDate(0, 0, -10), "date_collected": collected})...))
+	expectDetectionRunFinish(sqlMock, 1, models.DetectionRunSucceeded, 2, 1)
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	cfg.RequiredFields = nil
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	anomalies, err := service.DetectAnomaliesForAllJobs(context.Background(), true)
+
+	assert.NoError(t, err)
+	if assert.Len(t, anomalies, 1) {
+		assert.Equal(t, "job1", anomalies[0].JobID)
+		assert.Equal(t, models.AnomalyTypeStale, anomalies[0].Type)
+		assert.Equal(t, 120.0, anomalies[0].Value)
+	}
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+// anomalyRowColumns matches the columns selected by anomalyColumns
+var anomalyRowColumns = []string{"id", "job_id", "type", "description", "value", "threshold", "operator", "created_at", "violations", "severity", "status", "resolved_at", "resolution_note", "assignee", "acknowledged_at"}
+
+func TestGetAnomalyByID(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		createdAt := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+		resolvedAt := createdAt.Add(time.Hour)
+		sqlMock.ExpectQuery("FROM anomalies\\s+WHERE id = \\$1").
+			WithArgs(int64(42)).
+			WillReturnRows(sqlmock.NewRows(anomalyRowColumns).
+				AddRow(42, "job1", "null_values", "Required fields are null", 0.0, 0.0, "=", createdAt, "{city,job_link}", "medium", "resolved", resolvedAt, "Listing fixed upstream", "", nil))
+
+		service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+		anomaly, err := service.GetAnomalyByID(context.Background(), 42)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &models.Anomaly{
+			ID:             "42",
+			JobID:          "job1",
+			Type:           models.AnomalyTypeNullValues,
+			Description:    "Required fields are null",
+			Operator:       models.Equal,
+			CreatedAt:      createdAt,
+			Violations:     []string{"city", "job_link"},
+			Severity:       models.SeverityMedium,
+			Status:         models.AnomalyStatusResolved,
+			ResolvedAt:     &resolvedAt,
+			ResolutionNote: "Listing fixed upstream",
+		}, anomaly)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery("FROM anomalies").
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows(anomalyRowColumns))
+
+		service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+		anomaly, err := service.GetAnomalyByID(context.Background(), 7)
+
+		assert.Nil(t, anomaly)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestResolveAnomaly(t *testing.T) {
+	tests := []struct {
+		name          string
+		rowsAffected  int64
+		expectedError error
+	}{
+		{name: "existing anomaly", rowsAffected: 1},
+		{name: "missing anomaly", rowsAffected: 0, expectedError: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			sqlMock.ExpectExec("UPDATE anomalies\\s+SET status = \\$2, resolved_at = \\$3").
+				WithArgs(int64(42), models.AnomalyStatusResolved, sqlmock.AnyArg(), "Rule threshold was too strict").
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+			err = service.ResolveAnomaly(context.Background(), 42, "Rule threshold was too strict")
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetAllAnomaliesPagedResolvedFilter(t *testing.T) {
+	tests := []struct {
+		name            string
+		includeResolved bool
+		expectedWhere   string
+	}{
+		{name: "resolved hidden by default", expectedWhere: "WHERE status <> 'resolved'"},
+		{name: "resolved included on request", includeResolved: true, expectedWhere: "WHERE TRUE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM anomalies " + tt.expectedWhere)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			sqlMock.ExpectQuery(regexp.QuoteMeta(tt.expectedWhere)).
+				WithArgs(10, 0).
+				WillReturnRows(sqlmock.NewRows(anomalyRowColumns).
+					AddRow(1, "job1", "null_values", "Required fields are null", 0.0, 0.0, "=", time.Now(), "{city}", "medium", "open", nil, "", "", nil))
+
+			service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+			anomalies, total, err := service.GetAllAnomaliesPaged(context.Background(), 10, 0, SortOptions{}, AnomalyFilter{IncludeResolved: tt.includeResolved})
+
+			assert.NoError(t, err)
+			assert.Equal(t, 1, total)
+			assert.Len(t, anomalies, 1)
+			assert.Equal(t, models.AnomalyStatusOpen, anomalies[0].Status)
+			assert.Nil(t, anomalies[0].ResolvedAt)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAcknowledgeAnomaly(t *testing.T) {
+	tests := []struct {
+		name          string
+		rowsAffected  int64
+		expectedError error
+	}{
+		{name: "existing anomaly", rowsAffected: 1},
+		{name: "missing anomaly", rowsAffected: 0, expectedError: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			sqlMock.ExpectExec("UPDATE anomalies\\s+SET assignee = \\$2, acknowledged_at = \\$3").
+				WithArgs(int64(42), "alice", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+			err = service.AcknowledgeAnomaly(context.Background(), 42, " alice ")
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("missing assignee", func(t *testing.T) {
+		// No database calls are expected
+		service := NewAnomalyService(new(MockDB), nil, nil, nil, nil)
+		err := service.AcknowledgeAnomaly(context.Background(), 42, "  ")
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestGetAllAnomaliesPagedAcknowledgementFilter(t *testing.T) {
+	acknowledged, unacknowledged := true, false
+	tests := []struct {
+		name          string
+		filter        AnomalyFilter
+		expectedWhere string
+		args          []driver.Value
+	}{
+		{
+			name:          "assignee",
+			filter:        AnomalyFilter{Assignee: "alice"},
+			expectedWhere: "WHERE status <> 'resolved' AND assignee = $1",
+			args:          []driver.Value{"alice"},
+		},
+		{
+			name:          "acknowledged",
+			filter:        AnomalyFilter{Acknowledged: &acknowledged},
+			expectedWhere: "WHERE status <> 'resolved' AND acknowledged_at IS NOT NULL",
+		},
+		{
+			name:          "unacknowledged including resolved",
+			filter:        AnomalyFilter{IncludeResolved: true, Acknowledged: &unacknowledged},
+			expectedWhere: "WHERE TRUE AND acknowledged_at IS NULL",
+		},
+		{
+			name:          "assignee and acknowledged",
+			filter:        AnomalyFilter{Assignee: "bob", Acknowledged: &acknowledged},
+			expectedWhere: "WHERE status <> 'resolved' AND assignee = $1 AND acknowledged_at IS NOT NULL",
+			args:          []driver.Value{"bob"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			acknowledgedAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+			sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM anomalies " + tt.expectedWhere)).
+				WithArgs(tt.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			sqlMock.ExpectQuery(regexp.QuoteMeta(tt.expectedWhere)).
+				WithArgs(append(tt.args, 10, 0)...).
+				WillReturnRows(sqlmock.NewRows(anomalyRowColumns).
+					AddRow(1, "job1", "null_values", "Required fields are null", 0.0, 0.0, "=", time.Now(), "{city}", "medium", "open", nil, "", "alice", acknowledgedAt))
+
+			service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+			anomalies, total, err := service.GetAllAnomaliesPaged(context.Background(), 10, 0, SortOptions{}, tt.filter)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 1, total)
+			assert.Len(t, anomalies, 1)
+			assert.Equal(t, "alice", anomalies[0].Assignee)
+			assert.Equal(t, &acknowledgedAt, anomalies[0].AcknowledgedAt)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteAnomalies(t *testing.T) {
+	cutoff := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	acknowledged := true
+	tests := []struct {
+		name          string
+		filter        AnomalyFilter
+		expectedWhere string
+		args          []driver.Value
+	}{
+		{
+			name:          "type",
+			filter:        AnomalyFilter{Type: models.AnomalyTypeTrend},
+			expectedWhere: "WHERE status <> 'resolved' AND type = $1",
+			args:          []driver.Value{"salary_trend"},
+		},
+		{
+			name:          "job including resolved",
+			filter:        AnomalyFilter{JobID: " job1 ", IncludeResolved: true},
+			expectedWhere: "WHERE TRUE AND job_id = $1",
+			args:          []driver.Value{"job1"},
+		},
+		{
+			name:          "type, job, and creation time",
+			filter:        AnomalyFilter{Type: models.AnomalyTypeNullValues, JobID: "job1", CreatedBefore: cutoff},
+			expectedWhere: "WHERE status <> 'resolved' AND type = $1 AND job_id = $2 AND created_at < $3",
+			args:          []driver.Value{"null_values", "job1", cutoff},
+		},
+		{
+			name:          "assignee and acknowledgement",
+			filter:        AnomalyFilter{Assignee: "alice", Acknowledged: &acknowledged},
+			expectedWhere: "WHERE status <> 'resolved' AND assignee = $1 AND acknowledged_at IS NOT NULL",
+			args:          []driver.Value{"alice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			sqlMock.ExpectExec(regexp.QuoteMeta("DELETE FROM anomalies " + tt.expectedWhere)).
+				WithArgs(tt.args...).
+				WillReturnResult(sqlmock.NewResult(0, 3))
+
+			service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+			deleted, err := service.DeleteAnomalies(context.Background(), tt.filter)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 3, deleted)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("empty filter", func(t *testing.T) {
+		// No database calls are expected
+		service := NewAnomalyService(new(MockDB), nil, nil, nil, nil)
+		for _, filter := range []AnomalyFilter{{}, {IncludeResolved: true}, {JobID: "  "}} {
+			_, err := service.DeleteAnomalies(context.Background(), filter)
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.ErrorContains(t, err, "assignee or acknowledged")
+			assert.ErrorContains(t, err, "include_resolved")
+		}
+	})
+}
+
+func TestGetDetailedAnomaliesPaged(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	createdAt := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	columns := append(append([]string{}, anomalyRowColumns...), "company_name", "job_title", "city", "max_salary")
+	sqlMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM anomalies WHERE status <> 'resolved' AND assignee = $1")).
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	sqlMock.ExpectQuery(`LEFT JOIN \(\s+SELECT job_id AS jobs_job_id, company_name, job_title, city, max_salary\s+FROM jobs\s+\) j ON j.jobs_job_id = anomalies.job_id\s+WHERE status <> 'resolved' AND assignee = \$1\s+ORDER BY created_at DESC, id DESC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs("alice", 2, 0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "job1", "max_salary", "High salary", 900000.0, 500000.0, ">", createdAt, "{max_salary}", "high", "open", nil, "", "alice", nil,
+				"Tech Corp", "Software Engineer", "Austin", 900000.0).
+			// A job deleted after its anomaly was read leaves the job fields empty
+			AddRow(2, "job2", "null_values", "Required fields are null", 0.0, 0.0, "=", createdAt, "{city}", "medium", "open", nil, "", "alice", nil,
+				"", "", "", nil))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	anomalies, total, err := service.GetDetailedAnomaliesPaged(context.Background(), 2, 0, SortOptions{}, AnomalyFilter{Assignee: "alice"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Len(t, anomalies, 2)
+	assert.Equal(t, "1", anomalies[0].ID)
+	assert.Equal(t, models.AnomalyTypeMaxSalary, anomalies[0].Type)
+	assert.Equal(t, 900000.0, anomalies[0].Value)
+	assert.Equal(t, models.AnomalyJob{CompanyName: "Tech Corp", JobTitle: "Software Engineer", City: "Austin", MaxSalary: Float64Ptr(900000)}, anomalies[0].Job)
+	assert.Equal(t, "job2", anomalies[1].JobID)
+	assert.Equal(t, models.AnomalyJob{}, anomalies[1].Job)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestResolveAnomalyHidesItFromListings(t *testing.T) {
+	db := newTestDatabase(t)
+	jobDataService := NewJobDataService(db, nil)
+	anomalyService := NewAnomalyService(db, NewAnomalyRuleService(db, nil), nil, nil, nil)
+
+	// A negative salary with missing fields is flagged by the null-value check and the default rule
+	job := &models.JobData{
+		JobID:       "resolve-job",
+		CompanyName: "Tech Corp",
+		JobTitle:    "Software Engineer",
+		MaxSalary:   Float64Ptr(-100),
+	}
+	assert.NoError(t, jobDataService.CreateJobData(context.Background(), job))
+	_, err := anomalyService.DetectAnomalies(context.Background(), job)
+	assert.NoError(t, err)
+
+	open, err := anomalyService.GetAnomaliesByJobID(context.Background(), job.JobID, false)
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, open) {
+		return
+	}
+
+	id, err := strconv.ParseInt(open[0].ID, 10, 64)
+	assert.NoError(t, err)
+	assert.NoError(t, anomalyService.ResolveAnomaly(context.Background(), id, "Known data entry issue"))
+
+	remaining, err := anomalyService.GetAnomaliesByJobID(context.Background(), job.JobID, false)
+	assert.NoError(t, err)
+	assert.Len(t, remaining, len(open)-1)
+
+	all, err := anomalyService.GetAnomaliesByJobID(context.Background(), job.JobID, true)
+	assert.NoError(t, err)
+	assert.Len(t, all, len(open))
+
+	resolved, err := anomalyService.GetAnomalyByID(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AnomalyStatusResolved, resolved.Status)
+	assert.NotNil(t, resolved.ResolvedAt)
+	assert.Equal(t, "Known data entry issue", resolved.ResolutionNote)
+}
+
+func TestDetectAnomaliesForAllJobsLoadsStatisticsOnce(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Each aggregate and the rules query is expected exactly once; a per-job
+	// repeat would be an unexpected query and fail the run
+	expectDetectionRunStart(sqlMock, 1, true)
+	sqlMock.ExpectQuery("FROM jobs\\s+WHERE max_salary IS NOT NULL").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(3, 100000.0, 10000.0, 90000.0, 110000.0, 4.0, 0.5, 3.5, 4.5, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("WITH salary_median AS").
+		WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(100000.0, 5000.0))
+	sqlMock.ExpectQuery("date_collected > \\$1").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(3, 100000.0, 10000.0, 90000.0, 110000.0, 4.0, 0.5, 3.5, 4.5, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("FROM anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery("SELECT job_id").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", map[string]driver.Value{"company_name": "Tech Corp", "company_rating": 4.0, "min_salary": 90000.0, "max_salary": 95000.0})...).
+			AddRow(jobRowWith("job2", map[string]driver.Value{"company_name": "Tech Corp", "company_rating": 4.2, "min_salary": 100000.0, "max_salary": 105000.0})...).
+			AddRow(jobRowWith("job3", map[string]driver.Value{"company_name": "Tech Corp", "company_rating": 3.8, "min_salary": 95000.0, "max_salary": 100000.0})...))
+	expectDetectionRunFinish(sqlMock, 1, models.DetectionRunSucceeded, 3, 3)
+
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), nil, nil, nil)
+	_, err = service.DetectAnomaliesForAllJobs(context.Background(), true)
+
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestResolvePercentileRules(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	absolute := models.AnomalyRule{ID: 1, Type: models.AnomalyTypeMaxSalary, Operator: models.LessThan, Value: 0, ValueMode: models.ValueModeAbsolute, IsActive: true}
+	highSalary := models.AnomalyRule{ID: 2, Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 99, ValueMode: models.ValueModePercentile, IsActive: true}
+	sameCutoff := models.AnomalyRule{ID: 3, Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThanOrEqual, Value: 99, ValueMode: models.ValueModePercentile, IsActive: true}
+	inactive := models.AnomalyRule{ID: 4, Type: models.AnomalyTypeMinSalary, Operator: models.LessThan, Value: 1, ValueMode: models.ValueModePercentile}
+	noData := models.AnomalyRule{ID: 5, Type: models.AnomalyTypeRating, Operator: models.LessThan, Value: 5, ValueMode: models.ValueModePercentile, IsActive: true}
+
+	// Rules 2 and 3 share a cutoff, which is only queried once; the inactive rule is never resolved.
+	// Salary percentiles are taken over annual salaries.
+	sqlMock.ExpectQuery("percentile_cont\\(\\$1\\) WITHIN GROUP \\(ORDER BY CASE LOWER\\(TRIM\\(salary_granularity\\)\\) .* ELSE max_salary END\\) FROM jobs WHERE max_salary IS NOT NULL").
+		WithArgs(0.99).
+		WillReturnRows(sqlmock.NewRows([]string{"percentile_cont"}).AddRow(185000.0))
+	sqlMock.ExpectQuery("ORDER BY company_rating\\) FROM jobs WHERE company_rating IS NOT NULL").
+		WithArgs(0.05).
+		WillReturnRows(sqlmock.NewRows([]string{"percentile_cont"}).AddRow(nil))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	rules, err := service.resolvePercentileRules(context.Background(), []models.AnomalyRule{absolute, highSalary, sameCutoff, inactive, noData})
+
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+	if !assert.Len(t, rules, 4) {
+		return
+	}
+	assert.Equal(t, absolute, rules[0])
+	assert.Equal(t, inactive, rules[3])
+
+	resolved := rules[1]
+	assert.Equal(t, models.ValueModeAbsolute, resolved.ValueMode)
+	assert.Equal(t, models.RuleConditions{{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 185000}}, resolved.Conditions)
+	assert.Equal(t, 185000.0, rules[2].Conditions[0].Value)
+
+	// The resolved rule flags salaries above the computed cutoff like any absolute rule
+	matched, _, value, threshold := evaluateRuleConditions(&models.JobData{MaxSalary: Float64Ptr(190000)}, resolved)
+	assert.True(t, matched)
+	assert.Equal(t, 190000.0, value)
+	assert.Equal(t, 185000.0, threshold)
+
+	matched, _, _, _ = evaluateRuleConditions(&models.JobData{MaxSalary: Float64Ptr(150000)}, resolved)
+	assert.False(t, matched)
+}
+
+func TestResolvePercentileRangeRule(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Salaries outside the middle 90% of the data
+	rule := models.AnomalyRule{ID: 1, Type: models.AnomalyTypeMaxSalary, Operator: models.NotBetween, Value: 5, ValueHigh: 95, ValueMode: models.ValueModePercentile, IsActive: true}
+
+	sqlMock.ExpectQuery("ELSE max_salary END\\) FROM jobs").
+		WithArgs(0.05).
+		WillReturnRows(sqlmock.NewRows([]string{"percentile_cont"}).AddRow(40000.0))
+	sqlMock.ExpectQuery("ELSE max_salary END\\) FROM jobs").
+		WithArgs(0.95).
+		WillReturnRows(sqlmock.NewRows([]string{"percentile_cont"}).AddRow(210000.0))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	rules, err := service.resolvePercentileRules(context.Background(), []models.AnomalyRule{rule})
+
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+	if !assert.Len(t, rules, 1) {
+		return
+	}
+	assert.Equal(t, models.RuleConditions{{Type: models.AnomalyTypeMaxSalary, Operator: models.NotBetween, Value: 40000, ValueHigh: 210000}}, rules[0].Conditions)
+
+	matched, _, _, _ := evaluateRuleConditions(&models.JobData{MaxSalary: Float64Ptr(500)}, rules[0])
+	assert.True(t, matched)
+	matched, _, _, _ = evaluateRuleConditions(&models.JobData{MaxSalary: Float64Ptr(90000)}, rules[0])
+	assert.False(t, matched)
+}
+
+func TestEvaluateRule(t *testing.T) {
+	rule := models.AnomalyRule{Type: models.AnomalyTypeRating, Operator: models.LessThan, Value: 2}
+
+	low := evaluateRule(&models.JobData{JobID: "job1", CompanyRating: Float64Ptr(1.5)}, rule)
+	assert.Equal(t, models.RuleEvaluation{
+		JobID:      "job1",
+		Triggered:  true,
+		Value:      1.5,
+		Threshold:  2,
+		Violations: []string{"company_rating < 2"},
+	}, low)
+
+	high := evaluateRule(&models.JobData{JobID: "job2", CompanyRating: Float64Ptr(4.5)}, rule)
+	assert.Equal(t, models.RuleEvaluation{
+		JobID:      "job2",
+		Triggered:  false,
+		Value:      4.5,
+		Threshold:  2,
+		Violations: []string{},
+	}, high)
+}
+
+func TestGetAnomaliesByCompany(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("SELECT COUNT\\(DISTINCT j.company_name\\)\\s+FROM anomalies a\\s+JOIN jobs j ON j.job_id = a.job_id\\s+WHERE status <> 'resolved'").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	sqlMock.ExpectQuery("GROUP BY j.company_name\\s+ORDER BY anomaly_count DESC, company_name DESC\\s+LIMIT \\$1 OFFSET \\$2").
+		WithArgs(2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"company_name", "anomaly_count", "anomaly_types"}).
+			AddRow("Tech Corp", 120, "{max_salary,null_values}").
+			AddRow("Acme", 4, "{salary_range}"))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	companies, total, err := service.GetAnomaliesByCompany(context.Background(), 2, 0, SortOptions{}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []models.CompanyAnomalies{
+		{CompanyName: "Tech Corp", AnomalyCount: 120, AnomalyTypes: []models.AnomalyType{models.AnomalyTypeMaxSalary, models.AnomalyTypeNullValues}},
+		{CompanyName: "Acme", AnomalyCount: 4, AnomalyTypes: []models.AnomalyType{models.AnomalyTypeSalaryRange}},
+	}, companies)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRiskScore(t *testing.T) {
+	weights := map[string]float64{
+		models.SeverityLow:      1,
+		models.SeverityMedium:   2,
+		models.SeverityHigh:     5,
+		models.SeverityCritical: 10,
+	}
+
+	tests := []struct {
+		name     string
+		counts   map[string]int
+		expected float64
+	}{
+		{"no anomalies", map[string]int{}, 0},
+		{"single severity", map[string]int{models.SeverityHigh: 3}, 15},
+		{"mixed severities", map[string]int{models.SeverityLow: 2, models.SeverityMedium: 1, models.SeverityCritical: 1}, 14},
+		{"unweighted severity adds nothing", map[string]int{"": 4, models.SeverityLow: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, riskScore(tt.counts, weights))
+		})
+	}
+}
+
+func TestGetJobRiskScore(t *testing.T) {
+	query := "FROM jobs j\\s+LEFT JOIN anomalies a ON a.job_id = j.job_id AND a.status <> 'resolved'\\s+WHERE j.job_id = \\$1\\s+GROUP BY a.severity"
+
+	tests := []struct {
+		name          string
+		rows          *sqlmock.Rows
+		expected      float64
+		expectedError error
+	}{
+		{
+			name: "weighted sum of open anomalies",
+			rows: sqlmock.NewRows([]string{"severity", "count"}).
+				AddRow("high", 2).
+				AddRow("low", 3),
+			expected: 13,
+		},
+		{
+			name:     "job without anomalies scores zero",
+			rows:     sqlmock.NewRows([]string{"severity", "count"}).AddRow(nil, 0),
+			expected: 0,
+		},
+		{
+			name:          "unknown job",
+			rows:          sqlmock.NewRows([]string{"severity", "count"}),
+			expectedError: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			sqlMock.ExpectQuery(query).WithArgs("job1").WillReturnRows(tt.rows)
+
+			cfg := config.DefaultDetectionConfig()
+			cfg.SeverityWeights[models.SeverityHigh] = 5
+			cfg.SeverityWeights[models.SeverityLow] = 1
+			service := NewAnomalyService(&SQLDB{db: db}, nil, cfg, nil, nil)
+			score, err := service.GetJobRiskScore(context.Background(), "job1")
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, score)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetJobsByRiskScore(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("SELECT COUNT\\(DISTINCT a.job_id\\)\\s+FROM anomalies a\\s+JOIN jobs j ON j.job_id = a.job_id\\s+WHERE a.status <> 'resolved'").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	// Weights are bound in severity order, and jobs come back highest score first
+	sqlMock.ExpectQuery("SUM\\(CASE a.severity WHEN \\$3 THEN \\$4::double precision WHEN \\$5 THEN \\$6::double precision ELSE 0 END\\) AS risk_score.*ORDER BY risk_score DESC, j.job_id\\s+LIMIT \\$1 OFFSET \\$2").
+		WithArgs(2, 0, models.SeverityCritical, 10.0, models.SeverityLow, 1.0).
+		WillReturnRows(sqlmock.NewRows([]string{"job_id", "company_name", "job_title", "anomaly_count", "risk_score"}).
+			AddRow("job2", "Acme", "Analyst", 1, 10.0).
+			AddRow("job1", "Tech Corp", "Engineer", 3, 3.0))
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.SeverityWeights = map[string]float64{models.SeverityLow: 1, models.SeverityCritical: 10}
+	service := NewAnomalyService(&SQLDB{db: db}, nil, cfg, nil, nil)
+	jobs, total, err := service.GetJobsByRiskScore(context.Background(), 2, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []models.JobRiskScore{
+		{JobID: "job2", CompanyName: "Acme", JobTitle: "Analyst", AnomalyCount: 1, RiskScore: 10},
+		{JobID: "job1", CompanyName: "Tech Corp", JobTitle: "Engineer", AnomalyCount: 3, RiskScore: 3},
+	}, jobs)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestGetAnomalyByIDNullColumns(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Rows written outside the service may leave the nullable columns empty
+	sqlMock.ExpectQuery("FROM anomalies\\s+WHERE id = \\$1").
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows(anomalyRowColumns).
+			AddRow(42, "job1", "max_salary", "Imported anomaly", nil, nil, nil, nil, "{}", "", "open", nil, "", "", nil))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	anomaly, err := service.GetAnomalyByID(context.Background(), 42)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, anomaly.Value)
+	assert.Equal(t, 0.0, anomaly.Threshold)
+	assert.Empty(t, anomaly.Operator)
+	assert.True(t, anomaly.CreatedAt.IsZero())
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestFeedJobsNullRating(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("FROM jobs").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", map[string]driver.Value{"max_salary": 90000.0})...).
+			AddRow(jobRowWith("job2", map[string]driver.Value{"company_rating": 4.5})...))
+
+	rows, err := (&SQLDB{db: db}).Query(context.Background(), "SELECT * FROM jobs")
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	jobs := make(chan models.JobData, 2)
+	assert.NoError(t, feedJobs(context.Background(), rows, jobs))
+	close(jobs)
+
+	first, second := <-jobs, <-jobs
+	assert.Nil(t, first.CompanyRating)
+	assert.Equal(t, Float64Ptr(90000), first.MaxSalary)
+	assert.Equal(t, Float64Ptr(4.5), second.CompanyRating)
+	assert.Nil(t, second.MaxSalary)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -1,18 +1,22 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/lib/pq"
 )
 
 // DatabaseServiceInterface defines the interface for basic database operations
 type DatabaseServiceInterface interface {
-	Exec(query string, args ...interface{}) (sql.Result, error)
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row
+	Begin(ctx context.Context) (*sql.Tx, error)
+	RunInTx(ctx context.Context, fn func(tx *sql.Tx) error) error
 	Close() error
 }
 
@@ -22,219 +26,155 @@ type SQLDB struct {
 }
 
 // InitializeDatabaseService sets up the database connection and creates tables.
-// Returns the simplified DatabaseServiceInterface.
-func InitializeDatabaseService(cfg *config.DBConfig) (DatabaseServiceInterface, error) {
-	dbService, err := NewDatabaseService(cfg) // This now returns DatabaseServiceInterface (SQLDB)
+// Returns the simplified DatabaseServiceInterface. Errors are returned to the
+// caller rather than exiting, so the connection is closed if setup fails.
+// Progress and retried errors are logged to logger, or slog.Default() if nil.
+func InitializeDatabaseService(ctx context.Context, cfg *config.DBConfig, logger *slog.Logger) (DatabaseServiceInterface, error) {
+	logger = loggerOrDefault(logger)
+	dbService, err := NewDatabaseService(ctx, cfg, logger) // This now returns DatabaseServiceInterface (RetryingDB over SQLDB)
 	if err != nil {
-		log.Fatalf("Error initializing database service: %v", err) // Use Fatalf for critical init errors
+		return nil, err
 	}
 	// Keep defer dbService.Close() in main.go where the service is used
 
+	// The tables are created in the configured schema through the connection's search_path
+	if err := createSchema(ctx, dbService, cfg.Schema); err != nil {
+		dbService.Close()
+		return nil, err
+	}
+
 	// Create database tables using the interface
-	if err := createTables(dbService); err != nil {
-		dbService.Close() // Attempt to close before fatal exit
-		log.Fatalf("Error creating tables: %v", err)
+	if err := createTables(ctx, dbService, cfg.Reset, logger); err != nil {
+		dbService.Close()
+		return nil, fmt.Errorf("error creating tables: %w", err)
 	}
 
 	return dbService, nil
 }
 
-// NewDatabaseService creates a new database connection wrapped by SQLDB.
-// Returns the simplified DatabaseServiceInterface.
-func NewDatabaseService(cfg *config.DBConfig) (DatabaseServiceInterface, error) {
+// NewDatabaseService creates a new database connection wrapped by SQLDB, with
+// transient connection errors retried as configured by cfg.RetryAttempts and
+// cfg.RetryBackoff. Returns the simplified DatabaseServiceInterface.
+func NewDatabaseService(ctx context.Context, cfg *config.DBConfig, logger *slog.Logger) (DatabaseServiceInterface, error) {
+	logger = loggerOrDefault(logger)
 	db, err := sql.Open("postgres", cfg.GetDSN())
 	if err != nil {
-		return nil, fmt.Errorf("error opening database: %v", err)
+		return nil, fmt.Errorf("error opening database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		db.Close() // Close if ping fails
-		return nil, fmt.Errorf("error connecting to database: %v", err)
+		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
 
-	log.Println("Database connection successful")
-	return &SQLDB{db: db}, nil
+	logger.InfoContext(ctx, "database connection successful", "host", cfg.Host, "port", cfg.Port, "dbname", cfg.DBName, "schema", cfg.Schema)
+	return NewRetryingDB(&SQLDB{db: db}, cfg.RetryAttempts, cfg.RetryBackoff, logger), nil
 }
 
 // Exec executes a query without returning rows.
-func (s *SQLDB) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return s.db.Exec(query, args...)
+func (s *SQLDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.db.ExecContext(ctx, query, args...)
 }
 
 // Query executes a query that returns rows.
-func (s *SQLDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return s.db.Query(query, args...)
+func (s *SQLDB) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, query, args...)
 }
 
 // QueryRow executes a query that is expected to return at most one row.
-func (s *SQLDB) QueryRow(query string, args ...interface{}) *sql.Row {
-	return s.db.QueryRow(query, args...)
+func (s *SQLDB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRowContext(ctx, query, args...)
 }
 
-// Close closes the database connection.
-func (s *SQLDB) Close() error {
-	if s.db != nil {
-		return s.db.Close()
-	}
-	return nil
+// Begin starts a new transaction.
+func (s *SQLDB) Begin(ctx context.Context) (*sql.Tx, error) {
+	return s.db.BeginTx(ctx, nil)
 }
 
-// createTables creates the necessary database tables if they don't exist.
-// It now accepts the interface to execute queries.
-func createTables(dbService DatabaseServiceInterface) error {
-	// Drop tables in reverse order of dependencies
-	dropQueries := []string{
-		`DROP TABLE IF EXISTS anomalies;`,
-		`DROP TABLE IF EXISTS jobs;`,
-		`DROP TABLE IF EXISTS anomaly_rules;`,
+// RunInTx runs fn inside a transaction, committing when fn succeeds and
+// rolling back when it returns an error or panics.
+func (s *SQLDB) RunInTx(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
 	}
 
-	for _, query := range dropQueries {
-		_, err := dbService.Exec(query)
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
 		if err != nil {
-			return fmt.Errorf("error dropping tables: %v", err)
+			tx.Rollback()
 		}
-	}
+	}()
 
-	// Create tables in order of dependencies
-	if err := createJobsTable(dbService); err != nil {
-		return err
-	}
-	if err := createAnomaliesTable(dbService); err != nil {
-		return err
-	}
-	if err := createAnomalyRulesTable(dbService); err != nil {
+	if err = fn(tx); err != nil {
 		return err
 	}
 
-	// Create default anomaly rules
-	if err := createDefaultAnomalyRules(dbService); err != nil {
-		return err
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
 	}
-
 	return nil
 }
 
-func createJobsTable(dbService DatabaseServiceInterface) error {
-	query := `
-		CREATE TABLE jobs (
-			job_id TEXT PRIMARY KEY,
-			company_name TEXT NOT NULL,
-			company_rating DOUBLE PRECISION,
-			company_address TEXT,
-			company_website TEXT,
-			job_title TEXT NOT NULL,
-			job_posted_time TIMESTAMP WITH TIME ZONE,
-			job_link TEXT,
-			job_description TEXT,
-			job_requirements TEXT[],
-			job_benefits TEXT[],
-			job_types TEXT[],
-			is_new_job BOOLEAN,
-			is_no_resume_job BOOLEAN,
-			is_urgently_hiring BOOLEAN,
-			role_type TEXT,
-			min_salary DOUBLE PRECISION,
-			max_salary DOUBLE PRECISION,
-			salary_granularity TEXT,
-			hires_needed TEXT,
-			city TEXT,
-			state TEXT,
-			zip TEXT,
-			place_id TEXT,
-			latitude DOUBLE PRECISION,
-			longitude DOUBLE PRECISION,
-			location_count INTEGER,
-			facebook TEXT,
-			instagram TEXT,
-			tiktok TEXT,
-			youtube TEXT,
-			twitter TEXT,
-			yelp TEXT,
-			scheduling_link TEXT,
-			invocation_id TEXT,
-			task_id TEXT,
-			date_represented TIMESTAMP WITH TIME ZONE,
-			date_collected TIMESTAMP WITH TIME ZONE,
-			attempt_id TEXT,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-		);
-	`
-
-	_, err := dbService.Exec(query)
-	if err != nil {
-		return fmt.Errorf("error creating jobs table: %v", err)
+// Close closes the database connection.
+func (s *SQLDB) Close() error {
+	if s.db != nil {
+		return s.db.Close()
 	}
-	log.Println("Jobs table created successfully.")
 	return nil
 }
 
-// Added anomalies table creation based on model fields previously used
-func createAnomaliesTable(dbService DatabaseServiceInterface) error {
-	query := `
-		CREATE TABLE anomalies (
-			id BIGSERIAL PRIMARY KEY,
-			job_id TEXT NOT NULL REFERENCES jobs(job_id),
-			type TEXT NOT NULL,
-			description TEXT NOT NULL,
-			value DOUBLE PRECISION,
-			threshold DOUBLE PRECISION,
-			operator TEXT,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-			violations TEXT[]
-		);
-
-		CREATE INDEX idx_anomalies_job_id ON anomalies(job_id);
-		CREATE INDEX idx_anomalies_type ON anomalies(type);
-	`
-	_, err := dbService.Exec(query)
-	if err != nil {
-		return fmt.Errorf("error creating anomalies table: %v", err)
+// createSchema creates the schema holding the application's tables if it does
+// not exist yet. The default public schema always exists and is left alone.
+func createSchema(ctx context.Context, dbService DatabaseServiceInterface, schema string) error {
+	if schema == "" || schema == config.DefaultSchema {
+		return nil
+	}
+	if _, err := dbService.Exec(ctx, `CREATE SCHEMA IF NOT EXISTS `+pq.QuoteIdentifier(schema)); err != nil {
+		return fmt.Errorf("error creating schema %s: %w", schema, err)
 	}
-	log.Println("Anomalies table created successfully.")
 	return nil
 }
 
-func createAnomalyRulesTable(dbService DatabaseServiceInterface) error {
-	query := `
-		CREATE TABLE anomaly_rules (
-			id BIGSERIAL PRIMARY KEY,
-			name TEXT UNIQUE NOT NULL,
-			description TEXT NOT NULL,
-			type TEXT NOT NULL,
-			operator TEXT NOT NULL,
-			value DOUBLE PRECISION NOT NULL,
-			is_active BOOLEAN NOT NULL DEFAULT true,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE INDEX idx_anomaly_rules_name ON anomaly_rules(name);
-		CREATE INDEX idx_anomaly_rules_active ON anomaly_rules(is_active);
-	`
-
-	_, err := dbService.Exec(query)
-	if err != nil {
-		return fmt.Errorf("error creating anomaly rules table: %v", err)
+// createTables brings the schema up to date by applying any pending migrations.
+// Existing tables and their rows are preserved unless reset is true, in which
+// case every table is dropped and the schema is rebuilt empty.
+func createTables(ctx context.Context, dbService DatabaseServiceInterface, reset bool, logger *slog.Logger) error {
+	if reset {
+		if err := dropTables(ctx, dbService, logger); err != nil {
+			return err
+		}
 	}
-	log.Println("Anomaly rules table created successfully.")
-	return nil
+
+	return MigrateUp(ctx, dbService, logger)
 }
 
-// createDefaultAnomalyRules creates some default rules for anomaly detection
-func createDefaultAnomalyRules(dbService DatabaseServiceInterface) error {
-	query := `
-		INSERT INTO anomaly_rules (name, description, type, operator, value, is_active, created_at, updated_at)
-		VALUES 
-		('Negative Salary', 'Alert if maximum salary is negative', 'max_salary', '<', 0.0, true, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		ON CONFLICT (name) DO NOTHING;
-	`
+// dropTables removes every application table. This destroys all stored data.
+func dropTables(ctx context.Context, dbService DatabaseServiceInterface, logger *slog.Logger) error {
+	logger = loggerOrDefault(logger)
+	logger.WarnContext(ctx, "resetting database: dropping all tables")
 
-	_, err := dbService.Exec(query)
-	if err != nil {
-		return fmt.Errorf("error creating default anomaly rules: %v", err)
+	// Drop tables in reverse order of dependencies
+	dropQueries := []string{
+		`DROP TABLE IF EXISTS anomaly_run_history;`,
+		`DROP TABLE IF EXISTS rule_audit_log;`,
+		`DROP TABLE IF EXISTS anomaly_notes;`,
+		`DROP TABLE IF EXISTS anomalies;`,
+		`DROP TABLE IF EXISTS jobs;`,
+		`DROP TABLE IF EXISTS anomaly_rules;`,
+		`DROP TABLE IF EXISTS idempotency_keys;`,
+		`DROP TABLE IF EXISTS ingested_files;`,
+		`DROP TABLE IF EXISTS schema_migrations;`,
+	}
+
+	for _, query := range dropQueries {
+		_, err := dbService.Exec(ctx, query)
+		if err != nil {
+			return fmt.Errorf("error dropping tables: %w", err)
+		}
 	}
-	log.Println("Default anomaly rules created successfully.")
 	return nil
 }
@@ -1,10 +1,12 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ainesh01/anomaly_detection/internal/config"
 	"github.com/ainesh01/anomaly_detection/internal/models"
 	"github.com/stretchr/testify/assert"
@@ -16,21 +18,31 @@ type MockDB struct {
 	mock.Mock
 }
 
-func (m *MockDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+func (m *MockDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	arguments := m.Called(query, args)
 	return arguments.Get(0).(sql.Result), arguments.Error(1)
 }
 
-func (m *MockDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+func (m *MockDB) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	arguments := m.Called(query, args)
 	return arguments.Get(0).(*sql.Rows), arguments.Error(1)
 }
 
-func (m *MockDB) QueryRow(query string, args ...interface{}) *sql.Row {
+func (m *MockDB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	arguments := m.Called(query, args)
 	return arguments.Get(0).(*sql.Row)
 }
 
+func (m *MockDB) Begin(ctx context.Context) (*sql.Tx, error) {
+	arguments := m.Called()
+	return arguments.Get(0).(*sql.Tx), arguments.Error(1)
+}
+
+func (m *MockDB) RunInTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	arguments := m.Called(fn)
+	return arguments.Error(0)
+}
+
 func (m *MockDB) Close() error {
 	arguments := m.Called()
 	return arguments.Error(0)
@@ -106,6 +118,33 @@ func (m *MockResult) RowsAffected() (int64, error) {
 	return arguments.Get(0).(int64), arguments.Error(1)
 }
 
+// testDBConfig is the configuration for the local Postgres test database
+var testDBConfig = &config.DBConfig{
+	Host:     "localhost",
+	Port:     5432,
+	User:     "postgres",
+	Password: "postgres",
+	DBName:   "anomaly_detection_test",
+}
+
+// newTestDatabase connects to the test database and recreates an empty schema,
+// skipping the test when no Postgres instance is reachable
+func newTestDatabase(t *testing.T) DatabaseServiceInterface {
+	t.Helper()
+
+	db, err := NewDatabaseService(context.Background(), testDBConfig, nil)
+	if err != nil {
+		t.Skipf("skipping integration test, test database unavailable: %v", err)
+	}
+	if err := createTables(context.Background(), db, true, nil); err != nil {
+		db.Close()
+		t.Fatalf("error creating tables: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
 func TestInitializeDatabaseService(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -113,14 +152,8 @@ func TestInitializeDatabaseService(t *testing.T) {
 		expectError bool
 	}{
 		{
-			name: "valid configuration",
-			config: &config.DBConfig{
-				Host:     "localhost",
-				Port:     5432,
-				User:     "postgres",
-				Password: "postgres",
-				DBName:   "anomaly_detection_test",
-			},
+			name:        "valid configuration",
+			config:      testDBConfig,
 			expectError: false,
 		},
 		{
@@ -138,7 +171,11 @@ func TestInitializeDatabaseService(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service, err := InitializeDatabaseService(tt.config)
+			if !tt.expectError {
+				// Connecting needs a running Postgres
+				newTestDatabase(t)
+			}
+			service, err := InitializeDatabaseService(context.Background(), tt.config, nil)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -155,113 +192,100 @@ func TestInitializeDatabaseService(t *testing.T) {
 }
 
 func TestGetJobsByRowIndexRange(t *testing.T) {
-	// Create mock database
-	mockDB := new(MockDB)
-
-	// Test data
-	now := time.Now()
-	testJobs := []models.JobData{
-		{
-			JobID:           "test1",
-			JobTitle:        "Software Engineer",
-			CompanyName:     "Test Company 1",
-			JobDescription:  "Test job description 1",
-			JobPostedTime:   models.CustomTime{Time: now},
-			DateRepresented: models.CustomTime{Time: now},
-			DateCollected:   models.CustomTime{Time: now},
-			CreatedAt:       now,
-			UpdatedAt:       now,
-		},
-		{
-			JobID:           "test2",
-			JobTitle:        "Data Scientist",
-			CompanyName:     "Test Company 2",
-			JobDescription:  "Test job description 2",
-			JobPostedTime:   models.CustomTime{Time: now},
-			DateRepresented: models.CustomTime{Time: now},
-			DateCollected:   models.CustomTime{Time: now},
-			CreatedAt:       now,
-			UpdatedAt:       now,
-		},
-		{
-			JobID:           "test3",
-			JobTitle:        "Product Manager",
-			CompanyName:     "Test Company 3",
-			JobDescription:  "Test job description 3",
-			JobPostedTime:   models.CustomTime{Time: now},
-			DateRepresented: models.CustomTime{Time: now},
-			DateCollected:   models.CustomTime{Time: now},
-			CreatedAt:       now,
-			UpdatedAt:       now,
-		},
-	}
+	query := "FROM jobs\\s+ORDER BY created_at, job_id\\s+LIMIT \\$1 OFFSET \\$2"
 
 	tests := []struct {
 		name         string
 		start        int64
 		end          int64
-		expectedJobs []models.JobData
+		setupMock    func(sqlMock sqlmock.Sqlmock)
+		expectedJobs []string
 		expectError  bool
-		setupMock    func()
 	}{
 		{
-			name:         "valid range",
-			start:        1,
-			end:          3,
-			expectedJobs: testJobs,
-			expectError:  false,
-			setupMock: func() {
-				// Setup mock to return test jobs
-				mockDB.On("GetJobsByRowIndexRange", int64(1), int64(3)).
-					Return(testJobs, nil)
+			name:  "valid range",
+			start: 1,
+			end:   3,
+			setupMock: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(query).
+					WithArgs(int64(3), int64(1)).
+					WillReturnRows(sqlmock.NewRows(jobRowColumns).
+						AddRow(jobRow("test1")...).
+						AddRow(jobRow("test2")...).
+						AddRow(jobRow("test3")...))
 			},
+			expectedJobs: []string{"test1", "test2", "test3"},
 		},
 		{
-			name:         "empty range",
-			start:        10,
-			end:          20,
-			expectedJobs: []models.JobData{},
-			expectError:  false,
-			setupMock: func() {
-				// Setup mock to return empty result
-				mockDB.On("GetJobsByRowIndexRange", int64(10), int64(20)).
-					Return([]models.JobData{}, nil)
+			name:  "empty range",
+			start: 10,
+			end:   20,
+			setupMock: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(query).
+					WithArgs(int64(11), int64(10)).
+					WillReturnRows(sqlmock.NewRows(jobRowColumns))
 			},
+			expectedJobs: []string{},
 		},
 		{
-			name:         "invalid range",
-			start:        5,
-			end:          3,
-			expectedJobs: nil,
-			expectError:  true,
-			setupMock:    func() {},
+			name:        "invalid range",
+			start:       5,
+			end:         3,
+			setupMock:   func(sqlMock sqlmock.Sqlmock) {},
+			expectError: true,
+		},
+		{
+			name:        "negative start",
+			start:       -1,
+			end:         3,
+			setupMock:   func(sqlMock sqlmock.Sqlmock) {},
+			expectError: true,
+		},
+		{
+			name:  "largest range",
+			start: 100,
+			end:   100 + MaxRowRange - 1,
+			setupMock: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectQuery(query).
+					WithArgs(int64(MaxRowRange), int64(100)).
+					WillReturnRows(sqlmock.NewRows(jobRowColumns))
+			},
+			expectedJobs: []string{},
+		},
+		{
+			name:        "range too large",
+			start:       0,
+			end:         MaxRowRange,
+			setupMock:   func(sqlMock sqlmock.Sqlmock) {},
+			expectError: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Setup mock
-			tt.setupMock()
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			tt.setupMock(sqlMock)
 
-			// Call the function
-			jobs, err := mockDB.GetJobsByRowIndexRange(tt.start, tt.end)
+			service := NewJobDataService(&SQLDB{db: db}, nil)
+			jobs, err := service.GetJobsByRowIndexRange(context.Background(), tt.start, tt.end)
 
-			// Assert results
 			if tt.expectError {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, ErrValidation)
 				assert.Nil(t, jobs)
 			} else {
 				assert.NoError(t, err)
-				assert.NotNil(t, jobs)
-				assert.Equal(t, len(tt.expectedJobs), len(jobs))
+				jobIDs := []string{}
+				for _, job := range jobs {
+					jobIDs = append(jobIDs, job.JobID)
+				}
+				assert.Equal(t, tt.expectedJobs, jobIDs)
 			}
-
-			// Verify mock expectations
-			mockDB.AssertExpectations(t)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
 		})
 	}
 }
-
 func TestGetAllJobs(t *testing.T) {
 	// Create mock database
 	mockDB := new(MockDB)
@@ -318,3 +342,208 @@ func TestGetAllJobs(t *testing.T) {
 	// Verify mock expectations
 	mockDB.AssertExpectations(t)
 }
+
+func TestRunInTx(t *testing.T) {
+	t.Run("commits when fn succeeds", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec("UPDATE anomaly_rules").WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+
+		service := &SQLDB{db: db}
+		err = service.RunInTx(context.Background(), func(tx *sql.Tx) error {
+			_, err := tx.Exec("UPDATE anomaly_rules SET is_active = false")
+			return err
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back when fn fails", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+
+		service := &SQLDB{db: db}
+		err = service.RunInTx(context.Background(), func(tx *sql.Tx) error {
+			return assert.AnError
+		})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestCreateTablesOnlyDropsWhenReset(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset bool
+	}{
+		{name: "default path preserves tables", reset: false},
+		{name: "reset drops tables", reset: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			// sqlmock fails on any statement that was not expected, so the default
+			// path cannot issue a DROP without this test failing
+			if tt.reset {
+				for i := 0; i < 9; i++ {
+					sqlMock.ExpectExec("DROP TABLE IF EXISTS").WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+			}
+			sqlMock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+			rows := sqlmock.NewRows([]string{"version"})
+			migrations, err := loadMigrations(migrationFiles)
+			assert.NoError(t, err)
+			for _, migration := range migrations {
+				rows.AddRow(migration.Version)
+			}
+			sqlMock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(rows)
+
+			assert.NoError(t, createTables(context.Background(), &SQLDB{db: db}, tt.reset, nil))
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreateTablesReturnsErrors(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnError(assert.AnError)
+
+	err = createTables(context.Background(), &SQLDB{db: db}, false, nil)
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestInitializeDatabaseServicePreservesData(t *testing.T) {
+	db := newTestDatabase(t)
+	jobDataService := NewJobDataService(db, nil)
+	job := &models.JobData{JobID: "survivor", CompanyName: "Tech Corp", JobTitle: "Engineer"}
+	assert.NoError(t, jobDataService.CreateJobData(context.Background(), job))
+
+	// Two consecutive startups without a reset must keep existing rows
+	for i := 0; i < 2; i++ {
+		restarted, err := InitializeDatabaseService(context.Background(), testDBConfig, nil)
+		assert.NoError(t, err)
+		restarted.Close()
+	}
+
+	stored, err := jobDataService.GetJobData(context.Background(), job.JobID)
+	assert.NoError(t, err)
+	assert.Equal(t, job.JobID, stored.JobID)
+}
+
+func TestDeletedDefaultRuleStaysDeleted(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	_, err := db.Exec(ctx, `DELETE FROM anomaly_rules WHERE name = 'Negative Salary'`)
+	assert.NoError(t, err)
+
+	// Startups without a reset must not re-seed the default rule the user deleted
+	for i := 0; i < 2; i++ {
+		restarted, err := InitializeDatabaseService(ctx, testDBConfig, nil)
+		assert.NoError(t, err)
+		restarted.Close()
+	}
+
+	var count int
+	assert.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM anomaly_rules WHERE name = 'Negative Salary'`).Scan(&count))
+	assert.Zero(t, count)
+}
+
+func TestDefaultRulesOnlySeedEmptyTable(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	// Simulate a table created before the seed migration ran that already holds user rules
+	_, err := db.Exec(ctx, `DELETE FROM anomaly_rules`)
+	assert.NoError(t, err)
+	_, err = db.Exec(ctx, `INSERT INTO anomaly_rules (name, description, type, operator, value) VALUES ('Low Rating', 'Rating below 2', 'company_rating', '<', 2)`)
+	assert.NoError(t, err)
+	_, err = db.Exec(ctx, `DELETE FROM schema_migrations WHERE version >= 2`)
+	assert.NoError(t, err)
+
+	assert.NoError(t, MigrateUp(ctx, db, nil))
+
+	var names []string
+	rows, err := db.Query(ctx, `SELECT name FROM anomaly_rules`)
+	assert.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		assert.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"Low Rating"}, names)
+}
+
+func TestCreateSchema(t *testing.T) {
+	t.Run("tenant schema is created", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectExec(`CREATE SCHEMA IF NOT EXISTS "tenant_a"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, createSchema(context.Background(), &SQLDB{db: db}, "tenant_a"))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("public schema is left alone", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		// sqlmock fails on any statement that was not expected
+		assert.NoError(t, createSchema(context.Background(), &SQLDB{db: db}, config.DefaultSchema))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestInitializeDatabaseServiceUsesSchema(t *testing.T) {
+	admin := newTestDatabase(t)
+	const schema = "tenant_test"
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), `DROP SCHEMA IF EXISTS `+schema+` CASCADE`)
+	})
+
+	cfg := *testDBConfig
+	cfg.Schema = schema
+	db, err := InitializeDatabaseService(context.Background(), &cfg, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Unqualified queries made through the tenant connection land in the tenant schema
+	jobDataService := NewJobDataService(db, nil)
+	assert.NoError(t, jobDataService.CreateJobData(context.Background(), &models.JobData{JobID: "tenant-job", CompanyName: "Tech Corp", JobTitle: "Engineer"}))
+
+	var tables int
+	err = admin.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = $1 AND table_name IN ('jobs', 'anomalies', 'anomaly_rules')`,
+		schema).Scan(&tables)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, tables)
+
+	var tenantJobs, publicJobs int
+	assert.NoError(t, admin.QueryRow(context.Background(), `SELECT COUNT(*) FROM `+schema+`.jobs WHERE job_id = 'tenant-job'`).Scan(&tenantJobs))
+	assert.NoError(t, admin.QueryRow(context.Background(), `SELECT COUNT(*) FROM public.jobs WHERE job_id = 'tenant-job'`).Scan(&publicJobs))
+	assert.Equal(t, 1, tenantJobs)
+	assert.Equal(t, 0, publicJobs)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// startDetectionRun records the start of a run of detection over all jobs and
+// returns its ID. Failing to record it is logged rather than stopping detection,
+// and returns zero so finishDetectionRun skips the run.
+func (s *AnomalyService) startDetectionRun(ctx context.Context, dryRun bool) int64 {
+	var id int64
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO anomaly_run_history (status, dry_run, started_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		RETURNING id
+	`, models.DetectionRunRunning, dryRun).Scan(&id)
+	if err != nil {
+		s.logger.WarnContext(ctx, "error recording detection run start", "err", err)
+		return 0
+	}
+	return id
+}
+
+// finishDetectionRun records the outcome of the run started as id: its counts,
+// and whether it succeeded or failed with runErr. The run is recorded even when
+// ctx was cancelled, since a cancelled run is one of the outcomes worth keeping.
+func (s *AnomalyService) finishDetectionRun(ctx context.Context, id int64, batch detectionBatch, runErr error) {
+	if id == 0 {
+		return
+	}
+
+	status := models.DetectionRunSucceeded
+	var errMsg sql.NullString
+	if runErr != nil {
+		s.logger.ErrorContext(ctx, "detection run failed", "run_id", id, "err", runErr)
+		status = models.DetectionRunFailed
+		errMsg = sql.NullString{String: detectionRunError(ctx, runErr), Valid: true}
+	}
+
+	_, err := s.db.Exec(context.WithoutCancel(ctx), `
+		UPDATE anomaly_run_history
+		SET status = $1, completed_at = CURRENT_TIMESTAMP, jobs_processed = $2, anomalies_found = $3, error = $4
+		WHERE id = $5
+	`, status, batch.processed, batch.found, errMsg, id)
+	if err != nil {
+		s.logger.WarnContext(ctx, "error recording detection run result", "run_id", id, "err", err)
+	}
+}
+
+// detectionRunError returns the message stored for a failed run. The history is
+// served by the API, so like API error responses it only carries the text of
+// validation and not-found errors; anything else is reduced to a generic message
+// and the full error is left to the logs. A run whose ctx is done was cancelled,
+// whatever error the driver reported for it.
+func detectionRunError(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(err, ErrValidation), errors.Is(err, ErrNotFound):
+		return err.Error()
+	case ctx.Err() != nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "detection was cancelled"
+	default:
+		return "an internal error occurred"
+	}
+}
+
+// GetDetectionRuns returns one page of recorded detection runs, newest first,
+// along with the total number of runs
+func (s *AnomalyService) GetDetectionRuns(ctx context.Context, limit, offset int) ([]models.DetectionRun, int, error) {
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM anomaly_run_history`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting detection runs: %w", err)
+	}
+
+	query := `
+		SELECT id, status, dry_run, started_at, completed_at, jobs_processed, anomalies_found, error
+		FROM anomaly_run_history
+		ORDER BY started_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.db.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying detection runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.DetectionRun{}
+	for rows.Next() {
+		var run models.DetectionRun
+		var completedAt sql.NullTime
+		var errMsg sql.NullString
+		err := rows.Scan(&run.ID, &run.Status, &run.DryRun, &run.StartedAt, &completedAt, &run.JobsProcessed, &run.AnomaliesFound, &errMsg)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning detection run: %w", err)
+		}
+		if completedAt.Valid {
+			run.CompletedAt = &completedAt.Time
+		}
+		if errMsg.Valid {
+			run.Error = &errMsg.String
+		}
+		runs = append(runs, run)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating detection runs: %w", err)
+	}
+
+	return runs, total, nil
+}
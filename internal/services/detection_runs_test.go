@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectDetectionRunStart expects a detection run to be recorded as running and given id
+func expectDetectionRunStart(sqlMock sqlmock.Sqlmock, id int64, dryRun bool) {
+	sqlMock.ExpectQuery("INSERT INTO anomaly_run_history").
+		WithArgs(models.DetectionRunRunning, dryRun).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+}
+
+// expectDetectionRunFinish expects the run with id to be recorded as finished
+// with status and the given counts
+func expectDetectionRunFinish(sqlMock sqlmock.Sqlmock, id int64, status string, processed, found int) {
+	sqlMock.ExpectExec("UPDATE anomaly_run_history\\s+SET status = \\$1, completed_at = CURRENT_TIMESTAMP").
+		WithArgs(status, processed, found, nil, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectDetectionRunFailure expects the run with id to be recorded as failed
+// with errMsg and the given counts
+func expectDetectionRunFailure(sqlMock sqlmock.Sqlmock, id int64, processed, found int, errMsg string) {
+	sqlMock.ExpectExec("UPDATE anomaly_run_history\\s+SET status = \\$1, completed_at = CURRENT_TIMESTAMP").
+		WithArgs(models.DetectionRunFailed, processed, found, errMsg, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestDetectAnomaliesForAllJobsRecordsFailedRun(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expectDetectionRunStart(sqlMock, 7, false)
+	sqlMock.ExpectQuery("FROM jobs").WillReturnError(errors.New("connection reset"))
+	// The stored message is served by the API, so the database error stays in the logs
+	expectDetectionRunFailure(sqlMock, 7, 0, 0, "an internal error occurred")
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	_, err = service.DetectAnomaliesForAllJobs(context.Background(), false)
+
+	assert.ErrorContains(t, err, "connection reset")
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestDetectAnomaliesForAllJobsRecordsCancelledRun(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	expectDetectionRunStart(sqlMock, 3, false)
+	sqlMock.ExpectQuery("FROM jobs").
+		WillDelayFor(5 * time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"sample_count"}))
+	// The result is still written after the run's context is cancelled
+	expectDetectionRunFailure(sqlMock, 3, 0, 0, "detection was cancelled")
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	_, err = service.DetectAnomaliesForAllJobs(ctx, false)
+
+	assert.Error(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestDetectAnomaliesForAllJobsRunsWithoutHistory(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// A run that cannot be recorded still runs, and is not updated afterwards
+	sqlMock.ExpectQuery("INSERT INTO anomaly_run_history").WillReturnError(errors.New("relation does not exist"))
+	sqlMock.ExpectQuery("FROM jobs").WillReturnError(errors.New("connection reset"))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	_, err = service.DetectAnomaliesForAllJobs(context.Background(), false)
+
+	assert.ErrorContains(t, err, "connection reset")
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestGetDetectionRuns(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	started := time.Date(2025, 4, 1, 2, 0, 0, 0, time.UTC)
+	completed := started.Add(time.Minute)
+	sqlMock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM anomaly_run_history").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+	sqlMock.ExpectQuery("FROM anomaly_run_history\\s+ORDER BY started_at DESC, id DESC\\s+LIMIT \\$1 OFFSET \\$2").
+		WithArgs(2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "dry_run", "started_at", "completed_at", "jobs_processed", "anomalies_found", "error"}).
+			AddRow(12, models.DetectionRunRunning, false, started, nil, 0, 0, nil).
+			AddRow(11, models.DetectionRunFailed, false, started, completed, 40, 3, "connection reset"))
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	runs, total, err := service.GetDetectionRuns(context.Background(), 2, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 12, total)
+	errMsg := "connection reset"
+	assert.Equal(t, []models.DetectionRun{
+		{ID: 12, Status: models.DetectionRunRunning, StartedAt: started},
+		{ID: 11, Status: models.DetectionRunFailed, StartedAt: started, CompletedAt: &completed, JobsProcessed: 40, AnomaliesFound: 3, Error: &errMsg},
+	}, runs)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// Detector finds anomalies in a single job. stats holds the statistics the job
+// is compared against (its group's when statistics are grouped) and rules the
+// anomaly rules loaded for the detection batch. Detectors must not modify
+// either, since they are shared with the other detectors.
+type Detector interface {
+	Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly
+}
+
+// DetectorFunc adapts an ordinary function to the Detector interface
+type DetectorFunc func(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly
+
+// Detect calls f(job, stats, rules)
+func (f DetectorFunc) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	return f(job, stats, rules)
+}
+
+// defaultDetectors returns the built-in detectors enabled in cfg, in the order
+// their anomalies are reported
+func defaultDetectors(cfg *config.DetectionConfig) []Detector {
+	var detectors []Detector
+	if cfg.EnableNullCheck {
+		detectors = append(detectors, nullValueDetector{cfg})
+	}
+	if cfg.EnableSalaryRange {
+		detectors = append(detectors, salaryRangeDetector{cfg})
+	}
+	if cfg.EnableStale {
+		detectors = append(detectors, staleDetector{cfg})
+	}
+	if cfg.EnableDeviation {
+		detectors = append(detectors, deviationDetector{cfg})
+	}
+	if cfg.EnableIQR {
+		detectors = append(detectors, iqrDetector{cfg})
+	}
+	if cfg.EnableGeoOutlier {
+		detectors = append(detectors, geoOutlierDetector{cfg})
+	}
+	if cfg.EnableMAD {
+		detectors = append(detectors, madDetector{cfg})
+	}
+	if cfg.EnableTrend {
+		detectors = append(detectors, trendDetector{cfg})
+	}
+	if cfg.EnableMultivariate {
+		detectors = append(detectors, multivariateDetector{cfg})
+	}
+	// Rules are always applied; inactive rules are skipped by the detector
+	return append(detectors, ruleDetector{cfg})
+}
+
+// anomalies wraps an optional anomaly into a detector result
+func anomalies(anomaly *models.Anomaly) []models.Anomaly {
+	if anomaly == nil {
+		return nil
+	}
+	return []models.Anomaly{*anomaly}
+}
+
+// nullValueDetector reports required fields that are empty on the job
+type nullValueDetector struct{ cfg *config.DetectionConfig }
+
+func (d nullValueDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	return anomalies(nullValueAnomaly(job, d.cfg.RequiredFields, d.cfg.SeverityFor(models.AnomalyTypeNullValues)))
+}
+
+// salaryRangeDetector reports salary ranges whose minimum exceeds the maximum
+type salaryRangeDetector struct{ cfg *config.DetectionConfig }
+
+func (d salaryRangeDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	return anomalies(salaryRangeAnomaly(job, d.cfg.SeverityFor(models.AnomalyTypeSalaryRange)))
+}
+
+// staleDetector reports jobs that had been posted for longer than the
+// configured age when they were collected
+type staleDetector struct{ cfg *config.DetectionConfig }
+
+func (d staleDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	return anomalies(staleAnomaly(job, d.cfg.StaleAge, d.cfg.SeverityFor(models.AnomalyTypeStale)))
+}
+
+// deviationDetector reports salaries and company ratings more than the
+// configured number of standard deviations from the mean
+type deviationDetector struct{ cfg *config.DetectionConfig }
+
+func (d deviationDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	var detected []models.Anomaly
+	if job.MaxSalary != nil {
+		zScore, ok := safeZScore(*job.MaxSalary, stats.AvgSalary, stats.SalaryStdDev)
+		if ok && math.Abs(zScore) > d.cfg.StdDevThreshold {
+			detected = append(detected, models.Anomaly{
+				Type:        models.AnomalyTypeDeviation,
+				JobID:       job.JobID,
+				Description: fmt.Sprintf("Salary deviates significantly from mean (z-score: %.2f)", zScore),
+				Value:       *job.MaxSalary,
+				Threshold:   stats.AvgSalary,
+				Operator:    models.Equal,
+				CreatedAt:   time.Now(),
+				Violations:  []string{"max_salary"},
+				Severity:    deviationSeverity(zScore, d.cfg.SeverityFor(models.AnomalyTypeDeviation)),
+			})
+		}
+	}
+
+	if job.CompanyRating != nil {
+		zScore, ok := safeZScore(*job.CompanyRating, stats.AvgRating, stats.RatingStdDev)
+		if ok && math.Abs(zScore) > d.cfg.StdDevThreshold {
+			detected = append(detected, models.Anomaly{
+				Type:        models.AnomalyTypeDeviation,
+				JobID:       job.JobID,
+				Description: fmt.Sprintf("Company rating deviates significantly from mean (z-score: %.2f)", zScore),
+				Value:       *job.CompanyRating,
+				Threshold:   stats.AvgRating,
+				Operator:    models.Equal,
+				CreatedAt:   time.Now(),
+				Violations:  []string{"company_rating"},
+				Severity:    deviationSeverity(zScore, d.cfg.SeverityFor(models.AnomalyTypeDeviation)),
+			})
+		}
+	}
+	return detected
+}
+
+// iqrDetector reports salaries and company ratings outside the interquartile range fences
+type iqrDetector struct{ cfg *config.DetectionConfig }
+
+func (d iqrDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	var detected []models.Anomaly
+	if job.MaxSalary != nil {
+		if bound, operator, ok := iqrOutlier(*job.MaxSalary, stats.SalaryQ1, stats.SalaryQ3); ok {
+			detected = append(detected, models.Anomaly{
+				Type:        models.AnomalyTypeIQR,
+				JobID:       job.JobID,
+				Description: fmt.Sprintf("Salary is outside the interquartile range fence (Q1: %.2f, Q3: %.2f)", stats.SalaryQ1, stats.SalaryQ3),
+				Value:       *job.MaxSalary,
+				Threshold:   bound,
+				Operator:    operator,
+				CreatedAt:   time.Now(),
+				Violations:  []string{"max_salary"},
+				Severity:    d.cfg.SeverityFor(models.AnomalyTypeIQR),
+			})
+		}
+	}
+
+	if job.CompanyRating != nil {
+		if bound, operator, ok := iqrOutlier(*job.CompanyRating, stats.RatingQ1, stats.RatingQ3); ok {
+			detected = append(detected, models.Anomaly{
+				Type:        models.AnomalyTypeIQR,
+				JobID:       job.JobID,
+				Description: fmt.Sprintf("Company rating is outside the interquartile range fence (Q1: %.2f, Q3: %.2f)", stats.RatingQ1, stats.RatingQ3),
+				Value:       *job.CompanyRating,
+				Threshold:   bound,
+				Operator:    operator,
+				CreatedAt:   time.Now(),
+				Violations:  []string{"company_rating"},
+				Severity:    d.cfg.SeverityFor(models.AnomalyTypeIQR),
+			})
+		}
+	}
+	return detected
+}
+
+// geoOutlierDetector reports jobs located far outside the usual cluster of coordinates
+type geoOutlierDetector struct{ cfg *config.DetectionConfig }
+
+func (d geoOutlierDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	return anomalies(geoOutlierAnomaly(job, stats, d.cfg.StdDevThreshold, d.cfg.SeverityFor(models.AnomalyTypeGeoOutlier)))
+}
+
+// madDetector reports robust salary outliers using the median absolute deviation
+type madDetector struct{ cfg *config.DetectionConfig }
+
+func (d madDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	if job.MaxSalary == nil {
+		return nil
+	}
+	modifiedZ, ok := madOutlier(*job.MaxSalary, stats.SalaryMedian, stats.SalaryMAD, d.cfg.MADCutoff)
+	if !ok {
+		return nil
+	}
+	return []models.Anomaly{{
+		Type:        models.AnomalyTypeMAD,
+		JobID:       job.JobID,
+		Description: fmt.Sprintf("Salary is a robust outlier from the median (modified z-score: %.2f)", modifiedZ),
+		Value:       *job.MaxSalary,
+		Threshold:   stats.SalaryMedian,
+		Operator:    models.Equal,
+		CreatedAt:   time.Now(),
+		Violations:  []string{"max_salary"},
+		Severity:    deviationSeverity(modifiedZ, d.cfg.SeverityFor(models.AnomalyTypeMAD)),
+	}}
+}
+
+// trendDetector compares the salary against the rolling statistics of recently
+// collected jobs. It does nothing when no window statistics were loaded.
+type trendDetector struct{ cfg *config.DetectionConfig }
+
+func (d trendDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	if stats.Window == nil {
+		return nil
+	}
+	return anomalies(trendAnomaly(job, stats.Window, d.cfg.StdDevThreshold, d.cfg.MinGroupSamples, d.cfg.SeverityFor(models.AnomalyTypeTrend)))
+}
+
+// multivariateDetector reports jobs whose salary and company rating are unusual
+// together, even when each is unremarkable on its own
+type multivariateDetector struct{ cfg *config.DetectionConfig }
+
+func (d multivariateDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	return anomalies(multivariateAnomaly(job, stats, d.cfg.MultivariateThreshold, d.cfg.SeverityFor(models.AnomalyTypeMultivariate)))
+}
+
+// ruleDetector applies each active anomaly rule to the job
+type ruleDetector struct{ cfg *config.DetectionConfig }
+
+func (d ruleDetector) Detect(job *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+	var detected []models.Anomaly
+	for _, rule := range rules {
+		if !rule.IsActive {
+			continue // Skip inactive rules
+		}
+
+		anomalyDetected, violations, actualValue, threshold := evaluateRuleConditions(job, rule)
+		if !anomalyDetected {
+			continue
+		}
+
+		severity := rule.Severity
+		if severity == "" {
+			severity = d.cfg.SeverityFor(rule.Type)
+		}
+		detected = append(detected, models.Anomaly{
+			Type:        rule.Type,
+			JobID:       job.JobID,
+			Description: rule.Description,
+			Value:       actualValue,
+			Threshold:   threshold,
+			Operator:    rule.Operator,
+			CreatedAt:   time.Now(),
+			Violations:  violations,
+			Severity:    severity,
+		})
+	}
+	return detected
+}
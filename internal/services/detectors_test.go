@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultDetectors(t *testing.T) {
+	cfg := config.DefaultDetectionConfig()
+	cfg.EnableNullCheck = true
+	cfg.EnableSalaryRange = true
+	cfg.EnableDeviation = true
+	cfg.EnableIQR = true
+	cfg.EnableGeoOutlier = true
+	cfg.EnableMAD = true
+	cfg.EnableTrend = true
+	cfg.EnableMultivariate = true
+	cfg.EnableStale = true
+
+	assert.Equal(t, []Detector{
+		nullValueDetector{cfg},
+		salaryRangeDetector{cfg},
+		staleDetector{cfg},
+		deviationDetector{cfg},
+		iqrDetector{cfg},
+		geoOutlierDetector{cfg},
+		madDetector{cfg},
+		trendDetector{cfg},
+		multivariateDetector{cfg},
+		ruleDetector{cfg},
+	}, defaultDetectors(cfg))
+
+	// Rules are applied even with every statistical detector switched off
+	cfg = &config.DetectionConfig{}
+	assert.Equal(t, []Detector{ruleDetector{cfg}}, defaultDetectors(cfg))
+}
+
+func TestDetectAnomaliesRunsEveryDetector(t *testing.T) {
+	service := NewAnomalyService(nil, nil, config.DefaultDetectionConfig(), nil, nil)
+	dc := &detectionContext{
+		stats:        &Statistics{AvgSalary: 100000},
+		salaryMedian: 95000,
+		rules:        []models.AnomalyRule{{ID: 7, IsActive: true}},
+	}
+	job := &models.JobData{JobID: "job1"}
+
+	// Each fake reports one anomaly of its own type and checks what it was given
+	var ran []models.AnomalyType
+	fake := func(anomalyType models.AnomalyType) Detector {
+		return DetectorFunc(func(got *models.JobData, stats *Statistics, rules []models.AnomalyRule) []models.Anomaly {
+			ran = append(ran, anomalyType)
+			assert.Equal(t, job, got)
+			assert.Equal(t, 100000.0, stats.AvgSalary)
+			assert.Equal(t, 95000.0, stats.SalaryMedian)
+			assert.Equal(t, dc.rules, rules)
+			return []models.Anomaly{{JobID: got.JobID, Type: anomalyType}}
+		})
+	}
+	service.detectors = []Detector{fake(models.AnomalyTypeNullValues), fake(models.AnomalyTypeDeviation)}
+	service.RegisterDetector(fake(models.AnomalyTypeTrend))
+	service.RegisterDetector(DetectorFunc(func(*models.JobData, *Statistics, []models.AnomalyRule) []models.Anomaly {
+		ran = append(ran, "")
+		return nil // Finding nothing adds nothing to the result
+	}))
+
+	var saved []models.AnomalyType
+	save := func(ctx context.Context, anomaly *models.Anomaly) error {
+		saved = append(saved, anomaly.Type)
+		return nil
+	}
+	detected := service.detectAnomaliesWithContext(context.Background(), job, dc, save)
+
+	expected := []models.AnomalyType{models.AnomalyTypeNullValues, models.AnomalyTypeDeviation, models.AnomalyTypeTrend}
+	assert.Equal(t, append(expected, ""), ran)
+	assert.Equal(t, expected, saved)
+	var types []models.AnomalyType
+	for _, anomaly := range detected {
+		types = append(types, anomaly.Type)
+	}
+	assert.Equal(t, expected, types)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// duplicateCandidatesQuery returns every job sharing its normalized company name,
+// job title, and description hash with at least one other job. Names are
+// compared trimmed and case-insensitively, and descriptions are hashed after
+// collapsing whitespace so reformatted reposts still match.
+const duplicateCandidatesQuery = `
+	WITH keyed AS (
+		SELECT
+			job_id,
+			lower(btrim(company_name)) AS company_key,
+			lower(btrim(job_title)) AS title_key,
+			md5(lower(regexp_replace(btrim(COALESCE(job_description, '')), '\s+', ' ', 'g'))) AS description_key
+		FROM jobs
+		WHERE btrim(COALESCE(company_name, '')) <> '' AND btrim(COALESCE(job_title, '')) <> ''
+	), counted AS (
+		SELECT *, COUNT(*) OVER (PARTITION BY company_key, title_key, description_key) AS group_size
+		FROM keyed
+	)
+	SELECT job_id, company_key, title_key, description_key
+	FROM counted
+	WHERE group_size > 1
+	ORDER BY company_key, title_key, description_key, job_id
+`
+
+// duplicateKey identifies listings that are considered the same job
+type duplicateKey struct {
+	company     string
+	title       string
+	description string // Hash of the normalized job description
+}
+
+// duplicateCandidate is a job returned by duplicateCandidatesQuery
+type duplicateCandidate struct {
+	jobID string
+	key   duplicateKey
+}
+
+// duplicateViolations are the fields a duplicate listing has in common with the
+// rest of its group. They are constant so re-detection refreshes the stored anomaly.
+var duplicateViolations = []string{"company_name", "job_title", "job_description"}
+
+// groupDuplicateJobs groups candidate jobs by their duplicate key, keeping the
+// order in which keys and jobs first appear. Groups with a single job are dropped.
+func groupDuplicateJobs(candidates []duplicateCandidate) [][]string {
+	index := make(map[duplicateKey]int)
+	var groups [][]string
+	for _, candidate := range candidates {
+		i, ok := index[candidate.key]
+		if !ok {
+			i = len(groups)
+			index[candidate.key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], candidate.jobID)
+	}
+
+	duplicates := groups[:0]
+	for _, group := range groups {
+		if len(group) > 1 {
+			duplicates = append(duplicates, group)
+		}
+	}
+	return duplicates
+}
+
+// duplicateAnomaly builds the anomaly recorded for jobID, one member of group
+func duplicateAnomaly(jobID string, group []string, severity string) models.Anomaly {
+	others := make([]string, 0, len(group)-1)
+	for _, id := range group {
+		if id != jobID {
+			others = append(others, id)
+		}
+	}
+	return models.Anomaly{
+		Type:        models.AnomalyTypeDuplicate,
+		JobID:       jobID,
+		Description: fmt.Sprintf("Listing duplicates %d other job(s) with the same company, title, and description: %s", len(others), strings.Join(others, ", ")),
+		Value:       float64(len(group)),
+		Threshold:   1,
+		Operator:    models.GreaterThan,
+		CreatedAt:   time.Now(),
+		Violations:  duplicateViolations,
+		Severity:    severity,
+	}
+}
+
+// DetectDuplicates flags every job listed more than once under different job IDs
+// and returns the anomalies that were saved
+func (s *AnomalyService) DetectDuplicates(ctx context.Context) ([]models.Anomaly, error) {
+	rows, err := s.db.Query(ctx, duplicateCandidatesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error querying duplicate jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []duplicateCandidate
+	for rows.Next() {
+		var candidate duplicateCandidate
+		if err := rows.Scan(&candidate.jobID, &candidate.key.company, &candidate.key.title, &candidate.key.description); err != nil {
+			return nil, fmt.Errorf("error scanning duplicate job: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicate jobs: %w", err)
+	}
+
+	severity := s.cfg.SeverityFor(models.AnomalyTypeDuplicate)
+	var detected []models.Anomaly
+	for _, group := range groupDuplicateJobs(candidates) {
+		for _, jobID := range group {
+			anomaly := duplicateAnomaly(jobID, group, severity)
+			err := s.saveAnomaly(ctx, &anomaly)
+			switch {
+			case errors.Is(err, ErrJobDeleted):
+				s.logger.WarnContext(ctx, "job was deleted during duplicate detection, skipping it", "job_id", jobID)
+			case err != nil:
+				return nil, err
+			default:
+				detected = append(detected, anomaly)
+			}
+		}
+	}
+
+	s.logger.InfoContext(ctx, "detected duplicate listings", "count", len(detected))
+	return detected, nil
+}
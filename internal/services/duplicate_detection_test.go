@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var duplicateCandidateColumns = []string{"job_id", "company_key", "title_key", "description_key"}
+
+func TestGroupDuplicateJobs(t *testing.T) {
+	engineer := duplicateKey{company: "acme", title: "engineer", description: "d1"}
+	analyst := duplicateKey{company: "acme", title: "analyst", description: "d1"}
+	rewritten := duplicateKey{company: "acme", title: "engineer", description: "d2"}
+
+	tests := []struct {
+		name       string
+		candidates []duplicateCandidate
+		expected   [][]string
+	}{
+		{
+			name: "no candidates",
+		},
+		{
+			name: "single group",
+			candidates: []duplicateCandidate{
+				{jobID: "job1", key: engineer},
+				{jobID: "job2", key: engineer},
+				{jobID: "job3", key: engineer},
+			},
+			expected: [][]string{{"job1", "job2", "job3"}},
+		},
+		{
+			name: "groups split on any part of the key",
+			candidates: []duplicateCandidate{
+				{jobID: "job1", key: engineer},
+				{jobID: "job2", key: analyst},
+				{jobID: "job3", key: engineer},
+				{jobID: "job4", key: analyst},
+			},
+			expected: [][]string{{"job1", "job3"}, {"job2", "job4"}},
+		},
+		{
+			name: "singletons are dropped",
+			candidates: []duplicateCandidate{
+				{jobID: "job1", key: engineer},
+				{jobID: "job2", key: rewritten},
+				{jobID: "job3", key: engineer},
+			},
+			expected: [][]string{{"job1", "job3"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, groupDuplicateJobs(tt.candidates))
+		})
+	}
+}
+
+func TestDuplicateAnomaly(t *testing.T) {
+	anomaly := duplicateAnomaly("job2", []string{"job1", "job2", "job3"}, models.SeverityHigh)
+
+	assert.Equal(t, models.AnomalyTypeDuplicate, anomaly.Type)
+	assert.Equal(t, "job2", anomaly.JobID)
+	assert.Equal(t, 3.0, anomaly.Value)
+	assert.Equal(t, models.SeverityHigh, anomaly.Severity)
+	assert.Equal(t, duplicateViolations, anomaly.Violations)
+	assert.Contains(t, anomaly.Description, "job1, job3")
+	assert.NotContains(t, anomaly.Description, "job2")
+}
+
+func TestDetectDuplicates(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("PARTITION BY company_key, title_key, description_key").
+		WillReturnRows(sqlmock.NewRows(duplicateCandidateColumns).
+			AddRow("job1", "acme", "engineer", "d1").
+			AddRow("job2", "acme", "engineer", "d1").
+			AddRow("job3", "globex", "analyst", "d2").
+			AddRow("job4", "globex", "analyst", "d2"))
+	for i, jobID := range []string{"job1", "job2", "job3"} {
+		sqlMock.ExpectQuery("INSERT INTO anomalies").
+			WithArgs(jobID, models.AnomalyTypeDuplicate, sqlmock.AnyArg(), 2.0, 1.0, models.GreaterThan, sqlmock.AnyArg(), sqlmock.AnyArg(), models.SeverityMedium).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(i+1, time.Now(), true))
+	}
+	// job4 is deleted before its anomaly is saved
+	sqlMock.ExpectQuery("INSERT INTO anomalies").
+		WithArgs("job4", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnError(&pq.Error{Code: foreignKeyViolationCode})
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	anomalies, err := service.DetectDuplicates(context.Background())
+
+	assert.NoError(t, err)
+	if assert.Len(t, anomalies, 3) {
+		assert.Equal(t, "job1", anomalies[0].JobID)
+		assert.Contains(t, anomalies[0].Description, "job2")
+		assert.Equal(t, "job3", anomalies[2].JobID)
+		assert.Contains(t, anomalies[2].Description, "job4")
+	}
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestDetectDuplicatesQueryError(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("PARTITION BY").WillReturnError(assert.AnError)
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	_, err = service.DetectDuplicates(context.Background())
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
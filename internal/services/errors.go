@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolationCode is the Postgres error code for a unique constraint violation
+const uniqueViolationCode = "23505"
+
+// foreignKeyViolationCode is the Postgres error code for a foreign key violation
+const foreignKeyViolationCode = "23503"
+
+var (
+	// ErrNotFound is returned when a requested record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrValidation is returned when input fails business validation
+	ErrValidation = errors.New("validation failed")
+	// ErrConflict is returned when a write clashes with an existing record
+	ErrConflict = errors.New("conflict")
+	// ErrDuplicateRuleName is returned when an anomaly rule name is already taken
+	ErrDuplicateRuleName = fmt.Errorf("%w: an anomaly rule with this name already exists", ErrConflict)
+	// ErrJobDeleted is returned when an anomaly references a job that no longer exists
+	ErrJobDeleted = fmt.Errorf("%w: job no longer exists", ErrNotFound)
+)
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key constraint violation
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolationCode
+}
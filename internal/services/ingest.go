@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// maxIngestErrors caps the number of errors kept in an IngestResult
+const maxIngestErrors = 20
+
+// JobBatchStore saves parsed jobs in batches
+type JobBatchStore interface {
+	CreateJobDataBatch(ctx context.Context, jobs []models.JobData, mode UpsertMode) error
+}
+
+// IngestResult summarizes an ingestion run
+type IngestResult struct {
+	Lines   int // Lines read, including malformed ones
+	Parsed  int // Lines decoded into a job
+	Saved   int // Jobs saved to the database
+	Skipped int // Lines not saved because they were malformed, invalid, or in a batch that failed to save
+
+	// Errors describes why lines were skipped, keeping only the first
+	// maxIngestErrors; Skipped counts them all
+	Errors []string
+}
+
+// FailureRatio returns the share of lines read that were skipped, or 0 when no
+// lines were read
+func (r IngestResult) FailureRatio() float64 {
+	if r.Lines == 0 {
+		return 0
+	}
+	return float64(r.Skipped) / float64(r.Lines)
+}
+
+// Ingester validates parsed jobs and saves them in batches, keeping count of
+// what happened to each line. A failed save is logged and its jobs skipped
+// rather than stopping the ingestion.
+type Ingester struct {
+	ctx       context.Context
+	store     JobBatchStore
+	mode      UpsertMode
+	batchSize int
+	logger    *slog.Logger
+
+	batch   []models.JobData
+	saveErr error // First failed save since the last Flush
+	result  IngestResult
+}
+
+// NewIngester creates an Ingester saving jobs to store batchSize at a time with
+// the given upsert mode. A nil logger uses slog.Default().
+func NewIngester(ctx context.Context, store JobBatchStore, mode UpsertMode, batchSize int, logger *slog.Logger) *Ingester {
+	return &Ingester{
+		ctx:       ctx,
+		store:     store,
+		mode:      mode,
+		batchSize: batchSize,
+		logger:    loggerOrDefault(logger),
+		batch:     make([]models.JobData, 0, batchSize),
+	}
+}
+
+// Add validates job and buffers it, saving the buffer once it holds a full
+// batch. An invalid job is logged and skipped. It never returns an error, so it
+// can be passed to the JSONL parsers without stopping them.
+func (in *Ingester) Add(job models.JobData) error {
+	in.result.Lines++
+	in.result.Parsed++
+	if err := ValidateJobData(&job); err != nil {
+		in.logger.Warn("skipping invalid job", "job_id", job.JobID, "err", err)
+		in.skip(1, fmt.Errorf("job %s: %w", job.JobID, err))
+		return nil
+	}
+	in.batch = append(in.batch, job)
+	if len(in.batch) == in.batchSize {
+		in.save()
+	}
+	return nil
+}
+
+// Flush saves the buffered jobs and returns the first save that failed since
+// the previous Flush, if any
+func (in *Ingester) Flush() error {
+	in.save()
+	err := in.saveErr
+	in.saveErr = nil
+	return err
+}
+
+// ParseError records an error returned by a JSONL parser. Each malformed line
+// it reports counts as a line read and skipped; lines after it in the same file
+// are not read, so they are not counted.
+func (in *Ingester) ParseError(err error) {
+	if err == nil {
+		return
+	}
+	for _, err := range splitErrors(err) {
+		lines := countLineErrors(err)
+		in.result.Lines += lines
+		in.skip(lines, err)
+	}
+}
+
+// Result returns the counts so far. Call Flush first so buffered jobs are included.
+func (in *Ingester) Result() IngestResult {
+	return in.result
+}
+
+// save writes the buffered jobs in one batch and empties the buffer
+func (in *Ingester) save() {
+	if len(in.batch) == 0 {
+		return
+	}
+	if err := in.store.CreateJobDataBatch(in.ctx, in.batch, in.mode); err != nil {
+		in.logger.Error("error saving job batch", "jobs", len(in.batch), "err", err)
+		if in.saveErr == nil {
+			in.saveErr = err
+		}
+		in.skip(len(in.batch), fmt.Errorf("batch of %d jobs: %w", len(in.batch), err))
+	} else {
+		in.result.Saved += len(in.batch)
+	}
+	in.batch = in.batch[:0]
+}
+
+// skip counts lines as skipped and keeps err if there is room for it
+func (in *Ingester) skip(lines int, err error) {
+	in.result.Skipped += lines
+	if len(in.result.Errors) < maxIngestErrors {
+		in.result.Errors = append(in.result.Errors, err.Error())
+	}
+}
+
+// splitErrors returns the errors joined into err by errors.Join, or err alone
+func splitErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// countLineErrors returns the number of LineErrors in err's tree
+func countLineErrors(err error) int {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		count := 0
+		for _, err := range joined.Unwrap() {
+			count += countLineErrors(err)
+		}
+		return count
+	}
+	var lineErr *LineError
+	if errors.As(err, &lineErr) {
+		return 1
+	}
+	return 0
+}
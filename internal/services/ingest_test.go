@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchStore records saved job IDs, failing any batch holding a job in failIDs
+type batchStore struct {
+	failIDs []string
+	saved   []string
+	batches int
+}
+
+func (s *batchStore) CreateJobDataBatch(ctx context.Context, jobs []models.JobData, mode UpsertMode) error {
+	s.batches++
+	for _, job := range jobs {
+		if slices.Contains(s.failIDs, job.JobID) {
+			return errors.New("connection reset")
+		}
+	}
+	for _, job := range jobs {
+		s.saved = append(s.saved, job.JobID)
+	}
+	return nil
+}
+
+func TestIngesterMixedBatches(t *testing.T) {
+	store := &batchStore{failIDs: []string{"job4"}}
+	ingester := NewIngester(context.Background(), store, UpsertReplace, 2, nil)
+
+	// job1 and job2 are saved, job3 is invalid, job4 fails its batch along with
+	// job5, and job6 is saved by the final flush
+	for _, id := range []string{"job1", "job2", "", "job4", "job5", "job6"} {
+		assert.NoError(t, ingester.Add(models.JobData{JobID: id}))
+	}
+	assert.Error(t, ingester.Flush())
+	assert.NoError(t, ingester.Flush(), "a failed save is only reported once")
+
+	result := ingester.Result()
+	assert.Equal(t, []string{"job1", "job2", "job6"}, store.saved)
+	assert.Equal(t, 3, store.batches)
+	assert.Equal(t, 6, result.Lines)
+	assert.Equal(t, 6, result.Parsed)
+	assert.Equal(t, 3, result.Saved)
+	assert.Equal(t, 3, result.Skipped)
+	assert.Equal(t, 0.5, result.FailureRatio())
+	if assert.Len(t, result.Errors, 2) {
+		assert.Contains(t, result.Errors[0], "job_id is required")
+		assert.Equal(t, "batch of 2 jobs: connection reset", result.Errors[1])
+	}
+}
+
+func TestIngesterAllSaved(t *testing.T) {
+	store := &batchStore{}
+	ingester := NewIngester(context.Background(), store, UpsertReplace, 10, nil)
+
+	for _, id := range []string{"job1", "job2", "job3"} {
+		assert.NoError(t, ingester.Add(models.JobData{JobID: id}))
+	}
+	assert.NoError(t, ingester.Flush())
+
+	result := ingester.Result()
+	assert.Equal(t, IngestResult{Lines: 3, Parsed: 3, Saved: 3}, result)
+	assert.Zero(t, result.FailureRatio())
+	assert.Zero(t, IngestResult{}.FailureRatio())
+}
+
+func TestIngesterParseErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.jsonl": "{\"jobID\":\"job1\"}\nnot json\n{\"jobID\":\"job2\"}\n",
+		"b.jsonl": "{\"jobID\":\"job3\"}\n",
+		"c.jsonl": "{\"jobID\":\"job4\"}\n{\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	store := &batchStore{}
+	ingester := NewIngester(context.Background(), store, UpsertReplace, 10, nil)
+	err := ParseJSONLDirStream(dir, ingester.Add)
+	assert.Error(t, err)
+	ingester.ParseError(err)
+	assert.NoError(t, ingester.Flush())
+
+	// Reading a.jsonl stops at its bad line, so job2 is never read
+	result := ingester.Result()
+	assert.Equal(t, []string{"job1", "job3", "job4"}, store.saved)
+	assert.Equal(t, 5, result.Lines)
+	assert.Equal(t, 3, result.Parsed)
+	assert.Equal(t, 3, result.Saved)
+	assert.Equal(t, 2, result.Skipped)
+	if assert.Len(t, result.Errors, 2) {
+		assert.Contains(t, result.Errors[0], "a.jsonl: error parsing line 2")
+		assert.Contains(t, result.Errors[1], "c.jsonl: error parsing line 2")
+	}
+}
+
+func TestIngesterCapsErrors(t *testing.T) {
+	ingester := NewIngester(context.Background(), &batchStore{}, UpsertReplace, 10, nil)
+
+	for i := range maxIngestErrors + 5 {
+		assert.NoError(t, ingester.Add(models.JobData{JobID: "job1", CompanyRating: Float64Ptr(float64(10 + i))}))
+	}
+	// An error that is not about a line does not count as a skipped line
+	ingester.ParseError(fmt.Errorf("missing.jsonl: %w", os.ErrNotExist))
+	assert.NoError(t, ingester.Flush())
+
+	result := ingester.Result()
+	assert.Equal(t, maxIngestErrors+5, result.Skipped)
+	assert.Equal(t, maxIngestErrors+5, result.Lines)
+	assert.Len(t, result.Errors, maxIngestErrors)
+	assert.Equal(t, 1.0, result.FailureRatio())
+}
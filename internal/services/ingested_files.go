@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// IngestedFileStore remembers which files have been ingested
+type IngestedFileStore interface {
+	GetIngestedFile(ctx context.Context, contentHash string) (*models.IngestedFile, error)
+	RecordIngestedFile(ctx context.Context, file *models.IngestedFile) error
+}
+
+// GetIngestedFile returns the record of the file ingested with the given content
+// hash, or an error wrapping ErrNotFound when no such file was ingested
+func (s *JobDataService) GetIngestedFile(ctx context.Context, contentHash string) (*models.IngestedFile, error) {
+	query := `
+		SELECT content_hash, path, jobs, ingested_at
+		FROM ingested_files
+		WHERE content_hash = $1
+	`
+
+	var file models.IngestedFile
+	err := s.db.QueryRow(ctx, query, contentHash).Scan(&file.ContentHash, &file.Path, &file.Jobs, &file.IngestedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("ingested file %s %w", contentHash, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting ingested file: %w", err)
+	}
+	return &file, nil
+}
+
+// RecordIngestedFile stores file as ingested, replacing any earlier record of
+// the same content, and reads the time it was recorded back into file
+func (s *JobDataService) RecordIngestedFile(ctx context.Context, file *models.IngestedFile) error {
+	query := `
+		INSERT INTO ingested_files (content_hash, path, jobs, ingested_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (content_hash) DO UPDATE SET
+			path = EXCLUDED.path,
+			jobs = EXCLUDED.jobs,
+			ingested_at = EXCLUDED.ingested_at
+		RETURNING ingested_at
+	`
+
+	if err := s.db.QueryRow(ctx, query, file.ContentHash, file.Path, file.Jobs).Scan(&file.IngestedAt); err != nil {
+		return fmt.Errorf("error recording ingested file: %w", err)
+	}
+	return nil
+}
+
+// HashFile returns the hex-encoded SHA-256 of the file's bytes. Compressed
+// files are hashed as stored, without decompressing them.
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("error hashing %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// IngestGuard parses JSONL files like ParseJSONLFileStream and its directory and
+// path list variants, but skips any file whose content was ingested before, so
+// loading the same dump twice does not import it again
+type IngestGuard struct {
+	store  IngestedFileStore
+	force  bool
+	logger *slog.Logger
+
+	// BeforeRecord, when set, is called once a file has been parsed and before it
+	// is recorded as ingested, for example to save jobs that are still buffered.
+	// An error leaves the file unrecorded so it is ingested again next time.
+	BeforeRecord func() error
+}
+
+// NewIngestGuard creates an IngestGuard recording files in store. With force
+// set, files are parsed even if they were ingested before. A nil logger uses
+// slog.Default().
+func NewIngestGuard(store IngestedFileStore, force bool, logger *slog.Logger) *IngestGuard {
+	return &IngestGuard{
+		store:  store,
+		force:  force,
+		logger: loggerOrDefault(logger),
+	}
+}
+
+// ParseFile parses the file at path, invoking fn for each job, and records it as
+// ingested once every job has been read. A file with the same content as one
+// ingested before is logged and skipped without calling fn. A file that fails to
+// parse is not recorded.
+func (g *IngestGuard) ParseFile(ctx context.Context, path string, fn func(models.JobData) error) error {
+	contentHash, err := HashFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !g.force {
+		previous, err := g.store.GetIngestedFile(ctx, contentHash)
+		switch {
+		case err == nil:
+			g.logger.Info("skipping file, identical content was already ingested",
+				"file", path, "previous_path", previous.Path, "ingested_at", previous.IngestedAt, "jobs", previous.Jobs)
+			return nil
+		case !errors.Is(err, ErrNotFound):
+			return err
+		}
+	}
+
+	jobs := 0
+	err = ParseJSONLFileStream(path, func(job models.JobData) error {
+		jobs++
+		return fn(job)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The jobs were read either way, so failing to record the file is only logged;
+	// it is ingested again next time
+	if g.BeforeRecord != nil {
+		if err := g.BeforeRecord(); err != nil {
+			g.logger.Warn("not recording file as ingested", "file", path, "err", err)
+			return nil
+		}
+	}
+	if err := g.store.RecordIngestedFile(ctx, &models.IngestedFile{ContentHash: contentHash, Path: path, Jobs: jobs}); err != nil {
+		g.logger.Warn("not recording file as ingested", "file", path, "err", err)
+	}
+	return nil
+}
+
+// ParseDir is ParseJSONLDirStream, skipping files that were already ingested
+func (g *IngestGuard) ParseDir(ctx context.Context, dir string, fn func(models.JobData) error) error {
+	return parseJSONLDirWith(dir, g.fileParser(ctx), fn)
+}
+
+// ParsePathList is ParseJSONLPathListStream, skipping files that were already ingested
+func (g *IngestGuard) ParsePathList(ctx context.Context, r io.Reader, fn func(models.JobData) error) error {
+	return parseJSONLPathListWith(r, g.fileParser(ctx), fn)
+}
+
+// fileParser binds ctx to ParseFile
+func (g *IngestGuard) fileParser(ctx context.Context) FileParseFunc {
+	return func(path string, fn func(models.JobData) error) error {
+		return g.ParseFile(ctx, path, fn)
+	}
+}
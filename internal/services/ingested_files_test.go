@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryIngestedFileStore keeps ingested file records in a map
+type memoryIngestedFileStore struct {
+	files map[string]models.IngestedFile
+}
+
+func newMemoryIngestedFileStore() *memoryIngestedFileStore {
+	return &memoryIngestedFileStore{files: map[string]models.IngestedFile{}}
+}
+
+func (m *memoryIngestedFileStore) GetIngestedFile(ctx context.Context, contentHash string) (*models.IngestedFile, error) {
+	file, ok := m.files[contentHash]
+	if !ok {
+		return nil, fmt.Errorf("ingested file %s %w", contentHash, ErrNotFound)
+	}
+	return &file, nil
+}
+
+func (m *memoryIngestedFileStore) RecordIngestedFile(ctx context.Context, file *models.IngestedFile) error {
+	file.IngestedAt = time.Now()
+	m.files[file.ContentHash] = *file
+	return nil
+}
+
+// collectJobIDs returns a parse callback appending each job's ID to ids
+func collectJobIDs(ids *[]string) func(models.JobData) error {
+	return func(job models.JobData) error {
+		*ids = append(*ids, job.JobID)
+		return nil
+	}
+}
+
+func TestIngestGuardSkipsIdenticalFile(t *testing.T) {
+	path := writeJSONLFile(t, "jobs.jsonl", []models.JobData{{JobID: "job1"}, {JobID: "job2"}})
+	store := newMemoryIngestedFileStore()
+	guard := NewIngestGuard(store, false, nil)
+
+	var first []string
+	assert.NoError(t, guard.ParseFile(context.Background(), path, collectJobIDs(&first)))
+	assert.Equal(t, []string{"job1", "job2"}, first)
+
+	hash, err := HashFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, path, store.files[hash].Path)
+	assert.Equal(t, 2, store.files[hash].Jobs)
+
+	// The same content under another name is skipped too
+	copyPath := filepath.Join(t.TempDir(), "copy.jsonl")
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(copyPath, data, 0o644))
+
+	for _, repeat := range []string{path, copyPath} {
+		var second []string
+		assert.NoError(t, guard.ParseFile(context.Background(), repeat, collectJobIDs(&second)))
+		assert.Empty(t, second, repeat)
+	}
+	assert.Len(t, store.files, 1)
+}
+
+func TestIngestGuardForce(t *testing.T) {
+	path := writeJSONLFile(t, "jobs.jsonl", []models.JobData{{JobID: "job1"}})
+	store := newMemoryIngestedFileStore()
+
+	var ids []string
+	assert.NoError(t, NewIngestGuard(store, false, nil).ParseFile(context.Background(), path, collectJobIDs(&ids)))
+	assert.NoError(t, NewIngestGuard(store, true, nil).ParseFile(context.Background(), path, collectJobIDs(&ids)))
+
+	assert.Equal(t, []string{"job1", "job1"}, ids)
+	assert.Len(t, store.files, 1)
+}
+
+func TestIngestGuardChangedFileIsIngested(t *testing.T) {
+	path := writeJSONLFile(t, "jobs.jsonl", []models.JobData{{JobID: "job1"}})
+	store := newMemoryIngestedFileStore()
+	guard := NewIngestGuard(store, false, nil)
+
+	var ids []string
+	assert.NoError(t, guard.ParseFile(context.Background(), path, collectJobIDs(&ids)))
+	assert.NoError(t, os.WriteFile(path, []byte(`{"jobID":"job2"}`+"\n"), 0o644))
+	assert.NoError(t, guard.ParseFile(context.Background(), path, collectJobIDs(&ids)))
+
+	assert.Equal(t, []string{"job1", "job2"}, ids)
+	assert.Len(t, store.files, 2)
+}
+
+func TestIngestGuardDoesNotRecordFailures(t *testing.T) {
+	t.Run("parse error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bad.jsonl")
+		assert.NoError(t, os.WriteFile(path, []byte("{\"jobID\":\"job1\"}\nnot json\n"), 0o644))
+		store := newMemoryIngestedFileStore()
+
+		err := NewIngestGuard(store, false, nil).ParseFile(context.Background(), path, func(models.JobData) error { return nil })
+
+		assert.ErrorContains(t, err, "error parsing line 2")
+		assert.Empty(t, store.files)
+	})
+
+	t.Run("jobs not saved", func(t *testing.T) {
+		path := writeJSONLFile(t, "jobs.jsonl", []models.JobData{{JobID: "job1"}})
+		store := newMemoryIngestedFileStore()
+		guard := NewIngestGuard(store, false, nil)
+		guard.BeforeRecord = func() error { return errors.New("connection refused") }
+
+		var ids []string
+		assert.NoError(t, guard.ParseFile(context.Background(), path, collectJobIDs(&ids)))
+
+		assert.Equal(t, []string{"job1"}, ids)
+		assert.Empty(t, store.files)
+	})
+}
+
+func TestIngestGuardParseDirAndPathList(t *testing.T) {
+	first := writeJSONLFile(t, "first.jsonl", []models.JobData{{JobID: "job1"}})
+	second := writeJSONLFile(t, "second.jsonl", []models.JobData{{JobID: "job2"}})
+	store := newMemoryIngestedFileStore()
+	guard := NewIngestGuard(store, false, nil)
+
+	var ids []string
+	assert.NoError(t, guard.ParseDir(context.Background(), filepath.Dir(first), collectJobIDs(&ids)))
+	assert.Equal(t, []string{"job1"}, ids)
+
+	// Only the file that was not seen before is parsed
+	paths := strings.NewReader(first + "\n" + second + "\n")
+	assert.NoError(t, guard.ParsePathList(context.Background(), paths, collectJobIDs(&ids)))
+	assert.Equal(t, []string{"job1", "job2"}, ids)
+	assert.Len(t, store.files, 2)
+}
+
+func TestGetIngestedFile(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ingestedAt := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	query := regexp.QuoteMeta("FROM ingested_files") + `\s+WHERE content_hash = \$1`
+	sqlMock.ExpectQuery(query).
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows([]string{"content_hash", "path", "jobs", "ingested_at"}).
+			AddRow("abc", "/data/jobs.jsonl.gz", 3, ingestedAt))
+	sqlMock.ExpectQuery(query).
+		WithArgs("def").
+		WillReturnRows(sqlmock.NewRows([]string{"content_hash", "path", "jobs", "ingested_at"}))
+
+	service := NewJobDataService(&SQLDB{db: db}, nil)
+	file, err := service.GetIngestedFile(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.IngestedFile{ContentHash: "abc", Path: "/data/jobs.jsonl.gz", Jobs: 3, IngestedAt: ingestedAt}, file)
+
+	_, err = service.GetIngestedFile(context.Background(), "def")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRecordIngestedFile(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ingestedAt := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	sqlMock.ExpectQuery(`INSERT INTO ingested_files .*ON CONFLICT \(content_hash\) DO UPDATE`).
+		WithArgs("abc", "/data/jobs.jsonl.gz", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"ingested_at"}).AddRow(ingestedAt))
+
+	service := NewJobDataService(&SQLDB{db: db}, nil)
+	file := &models.IngestedFile{ContentHash: "abc", Path: "/data/jobs.jsonl.gz", Jobs: 3}
+	assert.NoError(t, service.RecordIngestedFile(context.Background(), file))
+	assert.Equal(t, ingestedAt, file.IngestedAt)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -2,50 +2,190 @@ package services
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/ainesh01/anomaly_detection/internal/config"
 	"github.com/ainesh01/anomaly_detection/internal/models"
 )
 
+// initialLineBufferSize is the initial buffer size used when scanning JSONL lines
+const initialLineBufferSize = 64 * 1024
+
+// MaxLineSize is the largest JSONL line, in bytes, that the parser accepts.
+// It can be overridden at startup for feeds with unusually large records.
+var MaxLineSize = config.DefaultMaxLineSize
+
+// LineError reports a JSONL line that is not a valid job record
+type LineError struct {
+	Line int // 1-based line number
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("error parsing line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
 // ParseJSONLFile reads a JSONL file (optionally gzipped) and returns a slice of JobData
 func ParseJSONLFile(filePath string) ([]models.JobData, error) {
-	file, err := os.Open(filePath)
+	var jobs []models.JobData
+	err := ParseJSONLFileStream(filePath, func(job models.JobData) error {
+		jobs = append(jobs, job)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return jobs, nil
+}
 
-	var reader *bufio.Reader
+// ParseJSONLFileStream reads a JSONL file (optionally gzipped, which is detected
+// from its content rather than its name) and invokes fn for each parsed job.
+// Only one record is held in memory at a time. Parsing stops at the first error returned by fn.
+func ParseJSONLFileStream(filePath string, fn func(models.JobData) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
-	// Check if the file is gzipped
-	if strings.HasSuffix(filepath.Base(filePath), ".gz") {
-		gzReader, err := gzip.NewReader(file)
-		if err != nil {
-			return nil, err
-		}
-		defer gzReader.Close()
-		reader = bufio.NewReader(gzReader)
-	} else {
-		reader = bufio.NewReader(file)
+	reader, err := decompressedReader(file)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", filePath, err)
 	}
+	defer reader.Close()
 
-	var jobs []models.JobData
 	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, min(initialLineBufferSize, MaxLineSize)), MaxLineSize)
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		var job models.JobData
 		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
-			return nil, err
+			return &LineError{Line: lineNum, Err: err}
+		}
+		if err := fn(job); err != nil {
+			return err
 		}
-		jobs = append(jobs, job)
 	}
 
 	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("line %d exceeds the maximum line size of %d bytes: %w", lineNum+1, MaxLineSize, err)
+		}
+		return fmt.Errorf("error reading line %d: %w", lineNum+1, err)
+	}
+
+	return nil
+}
+
+// gzipMagic is the two bytes every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompressedReader returns a reader over the contents of r, decompressing
+// them when they start with the gzip magic bytes. The file's content decides,
+// not its extension, so a gzipped file without a .gz suffix is still read, and
+// a plain file with one is read as is.
+func decompressedReader(r io.Reader) (io.ReadCloser, error) {
+	// Peeking leaves the bytes in the buffer, so reading starts from the beginning
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
 		return nil, err
 	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return io.NopCloser(buffered), nil
+	}
+	return gzip.NewReader(buffered)
+}
 
-	return jobs, nil
+// ParseJSONLDirStream walks dir and parses every .jsonl and .jsonl.gz file it
+// contains, in lexical path order, invoking fn for each parsed job. A file that
+// fails to parse does not stop the remaining files; every per-file error is
+// collected into the returned error. Jobs read from a file before its error
+// have already been passed to fn.
+func ParseJSONLDirStream(dir string, fn func(models.JobData) error) error {
+	return parseJSONLDirWith(dir, ParseJSONLFileStream, fn)
+}
+
+// FileParseFunc parses the JSONL file at path, invoking fn for each parsed job
+type FileParseFunc func(path string, fn func(models.JobData) error) error
+
+// parseJSONLDirWith is ParseJSONLDirStream parsing each file with parseFile
+func parseJSONLDirWith(dir string, parseFile FileParseFunc, fn func(models.JobData) error) error {
+	paths, err := jsonlFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, path := range paths {
+		if err := parseFile(path, fn); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseJSONLPathListStream reads newline-separated file paths from r, such as
+// the output of find, and parses each file in turn, invoking fn for each parsed
+// job. Blank lines are skipped. As with ParseJSONLDirStream, a file that fails
+// to parse does not stop the remaining files; every per-file error is collected
+// into the returned error.
+func ParseJSONLPathListStream(r io.Reader, fn func(models.JobData) error) error {
+	return parseJSONLPathListWith(r, ParseJSONLFileStream, fn)
+}
+
+// parseJSONLPathListWith is ParseJSONLPathListStream parsing each file with parseFile
+func parseJSONLPathListWith(r io.Reader, parseFile FileParseFunc, fn func(models.JobData) error) error {
+	var errs []error
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		path := strings.TrimSpace(scanner.Text())
+		if path == "" {
+			continue
+		}
+		if err := parseFile(path, fn); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("error reading file list: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// jsonlFiles returns the paths of the JSONL files under dir in lexical order
+func jsonlFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isJSONLFile(d.Name()) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %w", dir, err)
+	}
+	return paths, nil
+}
+
+// isJSONLFile reports whether name has a .jsonl or .jsonl.gz extension
+func isJSONLFile(name string) bool {
+	return strings.HasSuffix(name, ".jsonl") || strings.HasSuffix(name, ".jsonl.gz")
 }
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeJSONLFile writes the given jobs as JSONL to a file in a temporary directory
+func writeJSONLFile(t *testing.T, name string, jobs []models.JobData) string {
+	t.Helper()
+
+	var lines []string
+	for _, job := range jobs {
+		line, err := json.Marshal(job)
+		assert.NoError(t, err)
+		lines = append(lines, string(line))
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+	return path
+}
+
+func TestParseJSONLFileLargeLine(t *testing.T) {
+	largeDescription := strings.Repeat("<p>benefits</p>", 10000) // ~150KB, well above bufio's 64KB default
+	path := writeJSONLFile(t, "large.jsonl", []models.JobData{
+		{JobID: "job1", JobDescription: largeDescription},
+		{JobID: "job2", JobDescription: "short"},
+	})
+
+	jobs, err := ParseJSONLFile(path)
+
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, largeDescription, jobs[0].JobDescription)
+	assert.Greater(t, len(jobs[0].JobDescription), 64*1024)
+}
+
+func TestParseJSONLFileLineTooLong(t *testing.T) {
+	originalMaxLineSize := MaxLineSize
+	MaxLineSize = 1024
+	defer func() { MaxLineSize = originalMaxLineSize }()
+
+	path := writeJSONLFile(t, "too_long.jsonl", []models.JobData{
+		{JobID: "job1", JobDescription: "short"},
+		{JobID: "job2", JobDescription: strings.Repeat("x", 4096)},
+	})
+
+	jobs, err := ParseJSONLFile(path)
+
+	assert.Error(t, err)
+	assert.Nil(t, jobs)
+	assert.ErrorIs(t, err, bufio.ErrTooLong)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestParseJSONLFileDetectsGzipByContent(t *testing.T) {
+	data := []byte(`{"jobID":"job1"}` + "\n" + `{"jobID":"job2"}` + "\n")
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	tests := []struct {
+		name    string
+		file    string
+		content []byte
+	}{
+		{name: "gzip without .gz suffix", file: "data.jsonl", content: compressed.Bytes()},
+		{name: "plain text with .gz suffix", file: "data.jsonl.gz", content: data},
+		{name: "gzip with .gz suffix", file: "data.jsonl.gz", content: compressed.Bytes()},
+		{name: "plain text without .gz suffix", file: "data.jsonl", content: data},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			assert.NoError(t, os.WriteFile(path, tt.content, 0o644))
+
+			jobs, err := ParseJSONLFile(path)
+
+			assert.NoError(t, err)
+			if assert.Len(t, jobs, 2) {
+				assert.Equal(t, "job1", jobs[0].JobID)
+				assert.Equal(t, "job2", jobs[1].JobID)
+			}
+		})
+	}
+}
+
+func TestParseJSONLFileEmpty(t *testing.T) {
+	for _, name := range []string{"empty.jsonl", "empty.jsonl.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+		assert.NoError(t, os.WriteFile(path, nil, 0o644))
+
+		jobs, err := ParseJSONLFile(path)
+
+		assert.NoError(t, err, name)
+		assert.Empty(t, jobs, name)
+	}
+}
+
+func TestParseJSONLDirStream(t *testing.T) {
+	dir := t.TempDir()
+
+	// Shards are written out of order to check they are read in sorted order
+	jsonl := func(ids ...string) []byte {
+		var lines []string
+		for _, id := range ids {
+			line, err := json.Marshal(models.JobData{JobID: id})
+			assert.NoError(t, err)
+			lines = append(lines, string(line))
+		}
+		return []byte(strings.Join(lines, "\n") + "\n")
+	}
+	writeShard := func(name string, data []byte) {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		if strings.HasSuffix(name, ".gz") {
+			file, err := os.Create(path)
+			assert.NoError(t, err)
+			gz := gzip.NewWriter(file)
+			_, err = gz.Write(data)
+			assert.NoError(t, err)
+			assert.NoError(t, gz.Close())
+			assert.NoError(t, file.Close())
+			return
+		}
+		assert.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+	writeShard("part-0002.jsonl.gz", jsonl("job3", "job4"))
+	writeShard("part-0001.jsonl", jsonl("job1", "job2"))
+	writeShard("part-0003.jsonl", append(jsonl("job5"), []byte("{not json\n")...))
+	writeShard("part-0004/part-0001.jsonl.gz", jsonl("job6"))
+	writeShard("README.txt", []byte("not a shard"))
+
+	var ids []string
+	err := ParseJSONLDirStream(dir, func(job models.JobData) error {
+		ids = append(ids, job.JobID)
+		return nil
+	})
+
+	// The broken shard is reported but every other shard is still read
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "part-0003.jsonl")
+	assert.Contains(t, err.Error(), "line 2")
+	assert.Equal(t, []string{"job1", "job2", "job3", "job4", "job5", "job6"}, ids)
+}
+
+func TestParseJSONLDirStreamMissingDir(t *testing.T) {
+	err := ParseJSONLDirStream(filepath.Join(t.TempDir(), "missing"), func(job models.JobData) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestParseJSONLPathListStream(t *testing.T) {
+	first := writeJSONLFile(t, "first.jsonl", []models.JobData{{JobID: "job1"}, {JobID: "job2"}})
+	second := writeJSONLFile(t, "second.jsonl", []models.JobData{{JobID: "job3"}})
+	missing := filepath.Join(t.TempDir(), "missing.jsonl")
+
+	// Paths arrive as find would print them, with a missing file and a blank line mixed in
+	paths := strings.Join([]string{first, missing, "", second}, "\n") + "\n"
+
+	var ids []string
+	err := ParseJSONLPathListStream(strings.NewReader(paths), func(job models.JobData) error {
+		ids = append(ids, job.JobID)
+		return nil
+	})
+
+	// The missing file is reported but the files after it are still read
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Contains(t, err.Error(), missing)
+	assert.Equal(t, []string{"job1", "job2", "job3"}, ids)
+}
@@ -1,102 +1,358 @@
 package services
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/ainesh01/anomaly_detection/internal/config"
 	"github.com/ainesh01/anomaly_detection/internal/models"
 	"github.com/lib/pq" // Needed for pq.Array
 )
 
 // JobDataServiceInterface defines the interface for job data service operations
 type JobDataServiceInterface interface {
-	CreateJobData(job *models.JobData) error
-	GetJobData(jobID string) (*models.JobData, error)
-	GetAllJobData() ([]models.JobData, error)
+	CreateJobData(ctx context.Context, job *models.JobData) error
+	CreateJobDataIdempotent(ctx context.Context, key string, job *models.JobData) (*models.JobData, bool, error)
+	GetJobData(ctx context.Context, jobID string) (*models.JobData, error)
+	GetAllJobData(ctx context.Context) ([]models.JobData, error)
+	GetAllJobDataPaged(ctx context.Context, limit, offset int) ([]models.JobData, int, error)
+	GetJobsByRowIndexRange(ctx context.Context, start, end int64) ([]models.JobData, error)
+	SearchJobs(ctx context.Context, criteria SearchCriteria, limit, offset int) ([]models.JobData, int, error)
+	SampleJobs(ctx context.Context, n int, seed int64) ([]models.JobData, error)
+	CreateJobDataBatch(ctx context.Context, jobs []models.JobData, mode UpsertMode) error
+	GetSummary(ctx context.Context) (*models.JobSummary, error)
+	PatchJobData(ctx context.Context, jobID string, fields map[string]interface{}) error
+	DeleteJobData(ctx context.Context, jobID string) error
 }
 
 // JobDataService handles business logic for job data operations
 type JobDataService struct {
-	db DatabaseServiceInterface
+	db             DatabaseServiceInterface
+	logger         *slog.Logger
+	requiredFields []string // Columns a job counts as missing in the summary
 }
 
-// NewJobDataService creates a new JobDataService
-func NewJobDataService(db DatabaseServiceInterface) *JobDataService {
+// NewJobDataService creates a new JobDataService. A nil logger uses slog.Default().
+func NewJobDataService(db DatabaseServiceInterface, logger *slog.Logger) *JobDataService {
 	return &JobDataService{
-		db: db,
+		db:             db,
+		logger:         loggerOrDefault(logger),
+		requiredFields: config.DefaultRequiredFields,
 	}
 }
 
-// CreateJobData creates or updates a job data entry using basic exec methods
-func (s *JobDataService) CreateJobData(job *models.JobData) error {
-	// Set timestamps
+// SetRequiredFields sets the job columns the summary checks when counting jobs
+// missing required fields, which should match the fields null value detection
+// checks. The default is config.DefaultRequiredFields.
+func (s *JobDataService) SetRequiredFields(fields []string) {
+	s.requiredFields = append([]string(nil), fields...)
+}
+
+// jobInsertColumns is the column list used when inserting into the jobs table
+const jobInsertColumns = `
+	job_id, company_name, company_rating, company_address, company_website,
+	job_title, job_posted_time, job_link, job_description,
+	job_requirements, job_benefits, job_types, is_new_job,
+	is_no_resume_job, is_urgently_hiring, role_type, min_salary,
+	max_salary, salary_granularity, hires_needed, city, state,
+	zip, place_id, latitude, longitude, location_count, facebook,
+	instagram, tiktok, youtube, twitter, yelp, scheduling_link,
+	invocation_id, task_id, date_represented, date_collected, attempt_id,
+	created_at, updated_at
+`
+
+// jobInsertColumnCount is the number of columns in jobInsertColumns
+const jobInsertColumnCount = 41
+
+// jobUpdateColumns are the columns of an existing job updated on job_id conflict.
+// created_at keeps the time the job was first stored.
+var jobUpdateColumns = []string{
+	"company_name", "company_rating", "company_address", "company_website",
+	"job_title", "job_posted_time", "job_link", "job_description",
+	"job_requirements", "job_benefits", "job_types", "is_new_job",
+	"is_no_resume_job", "is_urgently_hiring", "role_type", "min_salary",
+	"max_salary", "salary_granularity", "hires_needed", "city", "state",
+	"zip", "place_id", "latitude", "longitude", "location_count", "facebook",
+	"instagram", "tiktok", "youtube", "twitter", "yelp", "scheduling_link",
+	"invocation_id", "task_id", "date_represented", "date_collected", "attempt_id",
+	"updated_at",
+}
+
+// jobNullableColumns are the columns a job leaves NULL when the field is missing:
+// the optional pointer fields, unset timestamps and absent lists
+var jobNullableColumns = map[string]bool{
+	"company_rating":     true,
+	"job_posted_time":    true,
+	"job_requirements":   true,
+	"job_benefits":       true,
+	"job_types":          true,
+	"role_type":          true,
+	"min_salary":         true,
+	"max_salary":         true,
+	"salary_granularity": true,
+	"hires_needed":       true,
+	"state":              true,
+	"zip":                true,
+	"place_id":           true,
+	"latitude":           true,
+	"longitude":          true,
+	"facebook":           true,
+	"instagram":          true,
+	"tiktok":             true,
+	"youtube":            true,
+	"twitter":            true,
+	"yelp":               true,
+	"scheduling_link":    true,
+	"date_represented":   true,
+	"date_collected":     true,
+}
+
+// UpsertMode selects how a saved job updates an existing job with the same ID
+type UpsertMode string
+
+const (
+	// UpsertReplace overwrites every column of the existing job
+	UpsertReplace UpsertMode = "replace"
+	// UpsertFillMissing keeps the existing value of a nullable column the saved
+	// job leaves NULL, so a partial record does not erase what a full one stored
+	UpsertFillMissing UpsertMode = "fill-missing"
+)
+
+// ParseUpsertMode returns the upsert mode with the given name. An empty name is
+// UpsertReplace; an unknown one returns an error wrapping ErrValidation.
+func ParseUpsertMode(name string) (UpsertMode, error) {
+	switch mode := UpsertMode(name); mode {
+	case "":
+		return UpsertReplace, nil
+	case UpsertReplace, UpsertFillMissing:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: unknown upsert mode %q, expected %q or %q", ErrValidation, name, UpsertReplace, UpsertFillMissing)
+	}
+}
+
+// jobUpsertClause builds the ON CONFLICT clause updating an existing job in the given mode
+func jobUpsertClause(mode UpsertMode) string {
+	var clause strings.Builder
+	clause.WriteString("\n\tON CONFLICT (job_id) DO UPDATE SET")
+	for i, col := range jobUpdateColumns {
+		if i > 0 {
+			clause.WriteString(",")
+		}
+		if mode == UpsertFillMissing && jobNullableColumns[col] {
+			fmt.Fprintf(&clause, "\n\t\t%s = COALESCE(EXCLUDED.%s, jobs.%s)", col, col, col)
+		} else {
+			fmt.Fprintf(&clause, "\n\t\t%s = EXCLUDED.%s", col, col)
+		}
+	}
+	clause.WriteString("\n")
+	return clause.String()
+}
+
+// jobBatchSize is the number of rows inserted per statement by CreateJobDataBatch,
+// keeping the parameter count well below Postgres' limit of 65535
+const jobBatchSize = 500
+
+// CreateJobData creates or updates a job data entry using basic exec methods.
+// An existing job is overwritten as with UpsertReplace.
+func (s *JobDataService) CreateJobData(ctx context.Context, job *models.JobData) error {
+	setJobTimestamps(job, time.Now())
+
+	// Use ON CONFLICT to handle potential existing job_id
+	query := buildJobInsertQuery(1, UpsertReplace)
+
+	_, err := s.db.Exec(ctx, query, jobInsertArgs(job)...)
+	if err != nil {
+		return fmt.Errorf("error saving job data: %w", err)
+	}
+
+	return nil
+}
+
+// errIdempotencyKeyTaken rolls back a write whose idempotency key was claimed by another request
+var errIdempotencyKeyTaken = errors.New("idempotency key already used")
+
+// CreateJobDataIdempotent creates or updates a job like CreateJobData and records
+// the stored job under key. When key has been used before, the job stored by the
+// first request is returned with replayed set to true and nothing is written.
+// Reusing a key for a different job returns an error wrapping ErrConflict.
+func (s *JobDataService) CreateJobDataIdempotent(ctx context.Context, key string, job *models.JobData) (stored *models.JobData, replayed bool, err error) {
+	requestHash, err := jobRequestHash(job)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stored, err = s.getIdempotentJob(ctx, key, requestHash)
+	if err == nil {
+		return stored, true, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, false, err
+	}
+
+	setJobTimestamps(job, time.Now())
+	response, err := json.Marshal(job)
+	if err != nil {
+		return nil, false, fmt.Errorf("error encoding job data: %w", err)
+	}
+
+	err = s.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		// Claim the key before writing; a concurrent request with the same key
+		// waits here until this transaction finishes and then finds it taken
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO idempotency_keys (key, request_hash, response)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (key) DO NOTHING
+		`, key, requestHash, response)
+		if err != nil {
+			return fmt.Errorf("error saving idempotency key: %w", err)
+		}
+		if claimed, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("error saving idempotency key: %w", err)
+		} else if claimed == 0 {
+			return errIdempotencyKeyTaken
+		}
+
+		if _, err := tx.ExecContext(ctx, buildJobInsertQuery(1, UpsertReplace), jobInsertArgs(job)...); err != nil {
+			return fmt.Errorf("error saving job data: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, errIdempotencyKeyTaken) {
+		stored, err = s.getIdempotentJob(ctx, key, requestHash)
+		if err != nil {
+			return nil, false, err
+		}
+		return stored, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return job, false, nil
+}
+
+// getIdempotentJob returns the job stored under an idempotency key, an error
+// wrapping ErrNotFound when the key is unused, or one wrapping ErrConflict when
+// the key was used for a different request
+func (s *JobDataService) getIdempotentJob(ctx context.Context, key, requestHash string) (*models.JobData, error) {
+	var storedHash string
+	var response []byte
+	err := s.db.QueryRow(ctx, `SELECT request_hash, response FROM idempotency_keys WHERE key = $1`, key).
+		Scan(&storedHash, &response)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("idempotency key %q %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting idempotency key: %w", err)
+	}
+	if storedHash != requestHash {
+		return nil, fmt.Errorf("%w: idempotency key %q was already used for a different request", ErrConflict, key)
+	}
+
+	var job models.JobData
+	if err := json.Unmarshal(response, &job); err != nil {
+		return nil, fmt.Errorf("error decoding stored job data: %w", err)
+	}
+	return &job, nil
+}
+
+// jobRequestHash fingerprints a job as submitted, before any timestamps are set,
+// so a retried request can be told apart from a different one reusing its key
+func jobRequestHash(job *models.JobData) (string, error) {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return "", fmt.Errorf("error encoding job data: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CreateJobDataBatch creates or updates many job data entries inside a single transaction.
+// Rows are written with multi-row INSERT statements so large imports avoid per-row round-trips.
+// mode decides how jobs that already exist are updated.
+func (s *JobDataService) CreateJobDataBatch(ctx context.Context, jobs []models.JobData, mode UpsertMode) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
 	now := time.Now()
+	return s.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		for start := 0; start < len(jobs); start += jobBatchSize {
+			end := min(start+jobBatchSize, len(jobs))
+			chunk := dedupeJobsByID(jobs[start:end])
+
+			args := make([]interface{}, 0, len(chunk)*jobInsertColumnCount)
+			for i := range chunk {
+				setJobTimestamps(chunk[i], now)
+				args = append(args, jobInsertArgs(chunk[i])...)
+			}
+
+			if _, err := tx.ExecContext(ctx, buildJobInsertQuery(len(chunk), mode), args...); err != nil {
+				return fmt.Errorf("error saving job data batch: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// setJobTimestamps sets the created and updated timestamps of a job before it is saved
+func setJobTimestamps(job *models.JobData, now time.Time) {
 	if job.CreatedAt.IsZero() {
 		job.CreatedAt = now
 	}
 	job.UpdatedAt = now
+}
 
-	// Use ON CONFLICT to handle potential existing job_id
-	query := `
-		INSERT INTO jobs (
-			job_id, company_name, company_rating, company_address, company_website,
-			job_title, job_posted_time, job_link, job_description,
-			job_requirements, job_benefits, job_types, is_new_job,
-			is_no_resume_job, is_urgently_hiring, role_type, min_salary,
-			max_salary, salary_granularity, hires_needed, city, state,
-			zip, place_id, latitude, longitude, location_count, facebook,
-			instagram, tiktok, youtube, twitter, yelp, scheduling_link,
-			invocation_id, task_id, date_represented, date_collected, attempt_id,
-			created_at, updated_at
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14,
-			$15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26,
-			$27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41
-		)
-		ON CONFLICT (job_id) DO UPDATE SET
-			company_name = EXCLUDED.company_name,
-			company_rating = EXCLUDED.company_rating,
-			company_address = EXCLUDED.company_address,
-			company_website = EXCLUDED.company_website,
-			job_title = EXCLUDED.job_title,
-			job_posted_time = EXCLUDED.job_posted_time,
-			job_link = EXCLUDED.job_link,
-			job_description = EXCLUDED.job_description,
-			job_requirements = EXCLUDED.job_requirements,
-			job_benefits = EXCLUDED.job_benefits,
-			job_types = EXCLUDED.job_types,
-			is_new_job = EXCLUDED.is_new_job,
-			is_no_resume_job = EXCLUDED.is_no_resume_job,
-			is_urgently_hiring = EXCLUDED.is_urgently_hiring,
-			role_type = EXCLUDED.role_type,
-			min_salary = EXCLUDED.min_salary,
-			max_salary = EXCLUDED.max_salary,
-			salary_granularity = EXCLUDED.salary_granularity,
-			hires_needed = EXCLUDED.hires_needed,
-			city = EXCLUDED.city,
-			state = EXCLUDED.state,
-			zip = EXCLUDED.zip,
-			place_id = EXCLUDED.place_id,
-			latitude = EXCLUDED.latitude,
-			longitude = EXCLUDED.longitude,
-			location_count = EXCLUDED.location_count,
-			facebook = EXCLUDED.facebook,
-			instagram = EXCLUDED.instagram,
-			tiktok = EXCLUDED.tiktok,
-			youtube = EXCLUDED.youtube,
-			twitter = EXCLUDED.twitter,
-			yelp = EXCLUDED.yelp,
-			scheduling_link = EXCLUDED.scheduling_link,
-			invocation_id = EXCLUDED.invocation_id,
-			task_id = EXCLUDED.task_id,
-			date_represented = EXCLUDED.date_represented,
-			date_collected = EXCLUDED.date_collected,
-			attempt_id = EXCLUDED.attempt_id,
-			updated_at = EXCLUDED.updated_at
-	`
+// dedupeJobsByID returns pointers to the given jobs, keeping only the last occurrence of each job ID.
+// Postgres rejects an upsert that touches the same row twice within one statement.
+func dedupeJobsByID(jobs []models.JobData) []*models.JobData {
+	lastIndex := make(map[string]int, len(jobs))
+	for i := range jobs {
+		lastIndex[jobs[i].JobID] = i
+	}
+
+	deduped := make([]*models.JobData, 0, len(lastIndex))
+	for i := range jobs {
+		if lastIndex[jobs[i].JobID] == i {
+			deduped = append(deduped, &jobs[i])
+		}
+	}
+	return deduped
+}
+
+// buildJobInsertQuery builds an upsert statement into the jobs table for rowCount rows
+func buildJobInsertQuery(rowCount int, mode UpsertMode) string {
+	var values strings.Builder
+	for row := 0; row < rowCount; row++ {
+		if row > 0 {
+			values.WriteString(", ")
+		}
+		values.WriteString("(")
+		for col := 0; col < jobInsertColumnCount; col++ {
+			if col > 0 {
+				values.WriteString(", ")
+			}
+			fmt.Fprintf(&values, "$%d", row*jobInsertColumnCount+col+1)
+		}
+		values.WriteString(")")
+	}
 
-	_, err := s.db.Exec(query,
+	return "INSERT INTO jobs (" + jobInsertColumns + ") VALUES " + values.String() + jobUpsertClause(mode)
+}
+
+// jobInsertArgs returns the query arguments for a job, in the order of jobInsertColumns
+func jobInsertArgs(job *models.JobData) []interface{} {
+	return []interface{}{
 		job.JobID,
 		job.CompanyName,
 		job.CompanyRating,
@@ -138,65 +394,223 @@ func (s *JobDataService) CreateJobData(job *models.JobData) error {
 		job.AttemptID,
 		job.CreatedAt,
 		job.UpdatedAt,
-	)
+	}
+}
 
+// DeleteJobData deletes a job. Its anomalies are removed by the ON DELETE CASCADE
+// foreign key on anomalies.job_id.
+func (s *JobDataService) DeleteJobData(ctx context.Context, jobID string) error {
+	result, err := s.db.Exec(ctx, `DELETE FROM jobs WHERE job_id = $1`, jobID)
 	if err != nil {
-		return fmt.Errorf("error saving job data: %w", err)
+		return fmt.Errorf("error deleting job data: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.logger.WarnContext(ctx, "could not get rows affected after delete", "job_id", jobID, "err", err)
+	} else if rowsAffected == 0 {
+		return fmt.Errorf("job data with ID %s %w", jobID, ErrNotFound)
 	}
 
 	return nil
 }
 
-// GetJobData retrieves a specific job data entry using basic query methods
-func (s *JobDataService) GetJobData(jobID string) (*models.JobData, error) {
-	// Select all columns from the jobs table
-	query := `
-		SELECT
-			job_id, company_name, company_rating, company_address, company_website,
-			job_title, job_posted_time, job_link, job_description,
-			job_requirements, job_benefits, job_types, is_new_job,
-			is_no_resume_job, is_urgently_hiring, role_type, min_salary,
-			max_salary, salary_granularity, hires_needed, city, state,
-			zip, place_id, latitude, longitude, location_count, facebook,
-			instagram, tiktok, youtube, twitter, yelp, scheduling_link,
-			invocation_id, task_id, date_represented, date_collected, attempt_id,
-			created_at, updated_at
-		FROM jobs
-		WHERE job_id = $1
-	`
+// jobPatchableColumns maps the JSON field names accepted by PatchJobData to the
+// jobs columns they update. job_id and the database timestamps are not patchable.
+var jobPatchableColumns = map[string]string{
+	"companyName":       "company_name",
+	"companyRating":     "company_rating",
+	"companyAddress":    "company_address",
+	"companyWebsite":    "company_website",
+	"jobTitle":          "job_title",
+	"jobPostedTime":     "job_posted_time",
+	"jobLink":           "job_link",
+	"jobDescription":    "job_description",
+	"jobRequirements":   "job_requirements",
+	"jobBenefits":       "job_benefits",
+	"jobTypes":          "job_types",
+	"isNewJob":          "is_new_job",
+	"isNoResumeJob":     "is_no_resume_job",
+	"isUrgentlyHiring":  "is_urgently_hiring",
+	"roleType":          "role_type",
+	"minSalary":         "min_salary",
+	"maxSalary":         "max_salary",
+	"salaryGranularity": "salary_granularity",
+	"hiresNeeded":       "hires_needed",
+	"city":              "city",
+	"state":             "state",
+	"zip":               "zip",
+	"placeId":           "place_id",
+	"latitude":          "latitude",
+	"longitude":         "longitude",
+	"locationCount":     "location_count",
+	"facebook":          "facebook",
+	"instagram":         "instagram",
+	"tiktok":            "tiktok",
+	"youtube":           "youtube",
+	"twitter":           "twitter",
+	"yelp":              "yelp",
+	"schedulingLink":    "scheduling_link",
+	"invocationID":      "invocation_id",
+	"taskID":            "task_id",
+	"dateRepresented":   "date_represented",
+	"dateCollected":     "date_collected",
+	"attemptID":         "attempt_id",
+}
+
+// PatchJobData updates only the given fields of an existing job, leaving every other
+// column untouched. Field names are the JobData JSON names listed in jobPatchableColumns.
+// The patch is applied to the stored job first, and is rejected with ErrValidation
+// when a value has the wrong JSON type or the patched job fails ValidateJobData.
+func (s *JobDataService) PatchJobData(ctx context.Context, jobID string, fields map[string]interface{}) error {
+	if err := checkJobPatchFields(fields); err != nil {
+		return err
+	}
+
+	job, err := s.GetJobData(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if err := mergeJobPatch(job, fields); err != nil {
+		return err
+	}
+	if err := ValidateJobData(job); err != nil {
+		return err
+	}
+
+	query, args := buildJobPatchQuery(job, fields, time.Now())
+	result, err := s.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error patching job data: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.logger.WarnContext(ctx, "could not get rows affected after patch", "job_id", jobID, "err", err)
+	} else if rowsAffected == 0 {
+		return fmt.Errorf("job data with ID %s %w", jobID, ErrNotFound)
+	}
+
+	return nil
+}
+
+// checkJobPatchFields rejects an empty patch and any field that is not in
+// jobPatchableColumns, so that no caller-supplied text reaches the SQL
+func checkJobPatchFields(fields map[string]interface{}) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: no fields to update", ErrValidation)
+	}
+	for name := range fields {
+		if _, ok := jobPatchableColumns[name]; !ok {
+			return fmt.Errorf("%w: field %q cannot be updated", ErrValidation, name)
+		}
+	}
+	return nil
+}
+
+// mergeJobPatch decodes fields onto job as if they had been sent in its JSON body.
+// A value of the wrong type for its field is reported as ErrValidation.
+func mergeJobPatch(job *models.JobData, fields map[string]interface{}) error {
+	patch, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+	if err := json.Unmarshal(patch, job); err != nil {
+		return fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+	return nil
+}
+
+// buildJobPatchQuery builds an UPDATE of the jobs table that sets only the columns
+// named in fields, in a stable order, to their values in the patched job. A null
+// field clears its column. fields must have passed checkJobPatchFields.
+func buildJobPatchQuery(job *models.JobData, fields map[string]interface{}, now time.Time) (string, []interface{}) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := jobColumnArgs(job)
+	assignments := make([]string, 0, len(names)+1)
+	args := make([]interface{}, 0, len(names)+2)
+	for _, name := range names {
+		column := jobPatchableColumns[name]
+		if fields[name] == nil {
+			args = append(args, nil)
+		} else {
+			args = append(args, values[column])
+		}
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	args = append(args, now)
+	assignments = append(assignments, fmt.Sprintf("updated_at = $%d", len(args)))
+	args = append(args, job.JobID)
+
+	query := fmt.Sprintf("UPDATE jobs SET %s WHERE job_id = $%d", strings.Join(assignments, ", "), len(args))
+	return query, args
+}
 
-	row := s.db.QueryRow(query, jobID)
-	job := &models.JobData{}
+// jobColumnArgs maps each column of jobInsertColumns to its query argument for job
+func jobColumnArgs(job *models.JobData) map[string]interface{} {
+	args := jobInsertArgs(job)
+	byColumn := make(map[string]interface{}, len(args))
+	for i, column := range strings.Split(jobInsertColumns, ",") {
+		byColumn[strings.TrimSpace(column)] = args[i]
+	}
+	return byColumn
+}
 
-	// Scan all columns into the JobData struct
+// jobSelectColumns lists every jobs column in the order scanJob reads them
+const jobSelectColumns = `
+	job_id, company_name, company_rating, company_address, company_website,
+	job_title, job_posted_time, job_link, job_description,
+	job_requirements, job_benefits, job_types, is_new_job,
+	is_no_resume_job, is_urgently_hiring, role_type, min_salary,
+	max_salary, salary_granularity, hires_needed, city, state,
+	zip, place_id, latitude, longitude, location_count, facebook,
+	instagram, tiktok, youtube, twitter, yelp, scheduling_link,
+	invocation_id, task_id, date_represented, date_collected, attempt_id,
+	created_at, updated_at`
+
+// scanJob reads a row selected with jobSelectColumns into a JobData. Nullable
+// columns without a pointer field are scanned as nullable and left at their
+// zero value when NULL, as rows written outside the API may leave them empty.
+func scanJob(row rowScanner) (models.JobData, error) {
+	var job models.JobData
+	var companyAddress, companyWebsite, jobLink, jobDescription, city sql.NullString
+	var invocationID, taskID, attemptID sql.NullString
+	var isNewJob, isNoResumeJob, isUrgentlyHiring sql.NullBool
+	var locationCount sql.NullInt64
+	var createdAt, updatedAt sql.NullTime
 	err := row.Scan(
 		&job.JobID,
 		&job.CompanyName,
 		&job.CompanyRating,
-		&job.CompanyAddress,
-		&job.CompanyWebsite,
+		&companyAddress,
+		&companyWebsite,
 		&job.JobTitle,
 		&job.JobPostedTime,
-		&job.JobLink,
-		&job.JobDescription,
+		&jobLink,
+		&jobDescription,
 		pq.Array(&job.JobRequirements),
 		pq.Array(&job.JobBenefits),
 		pq.Array(&job.JobTypes),
-		&job.IsNewJob,
-		&job.IsNoResumeJob,
-		&job.IsUrgentlyHiring,
+		&isNewJob,
+		&isNoResumeJob,
+		&isUrgentlyHiring,
 		&job.RoleType,
 		&job.MinSalary,
 		&job.MaxSalary,
 		&job.SalaryGranularity,
 		&job.HiresNeeded,
-		&job.City,
+		&city,
 		&job.State,
 		&job.Zip,
 		&job.PlaceID,
 		&job.Latitude,
 		&job.Longitude,
-		&job.LocationCount,
+		&locationCount,
 		&job.Facebook,
 		&job.Instagram,
 		&job.Tiktok,
@@ -204,44 +618,65 @@ func (s *JobDataService) GetJobData(jobID string) (*models.JobData, error) {
 		&job.Twitter,
 		&job.Yelp,
 		&job.SchedulingLink,
-		&job.InvocationID,
-		&job.TaskID,
+		&invocationID,
+		&taskID,
 		&job.DateRepresented,
 		&job.DateCollected,
-		&job.AttemptID,
-		&job.CreatedAt,
-		&job.UpdatedAt,
+		&attemptID,
+		&createdAt,
+		&updatedAt,
 	)
+	if err != nil {
+		return job, err
+	}
+
+	job.CompanyAddress = companyAddress.String
+	job.CompanyWebsite = companyWebsite.String
+	job.JobLink = jobLink.String
+	job.JobDescription = jobDescription.String
+	job.IsNewJob = isNewJob.Bool
+	job.IsNoResumeJob = isNoResumeJob.Bool
+	job.IsUrgentlyHiring = isUrgentlyHiring.Bool
+	job.City = city.String
+	job.LocationCount = int(locationCount.Int64)
+	job.InvocationID = invocationID.String
+	job.TaskID = taskID.String
+	job.AttemptID = attemptID.String
+	job.CreatedAt = createdAt.Time
+	job.UpdatedAt = updatedAt.Time
+	return job, nil
+}
 
+// GetJobData retrieves a specific job data entry using basic query methods
+func (s *JobDataService) GetJobData(ctx context.Context, jobID string) (*models.JobData, error) {
+	// Select all columns from the jobs table
+	query := `
+		SELECT ` + jobSelectColumns + `
+		FROM jobs
+		WHERE job_id = $1
+	`
+
+	job, err := scanJob(s.db.QueryRow(ctx, query, jobID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("job data with ID %s not found", jobID)
+			return nil, fmt.Errorf("job data with ID %s %w", jobID, ErrNotFound)
 		}
 		return nil, fmt.Errorf("error querying or scanning job data: %w", err)
 	}
 
-	return job, nil
+	return &job, nil
 }
 
 // GetAllJobData retrieves all job data entries
-func (s *JobDataService) GetAllJobData() ([]models.JobData, error) {
+func (s *JobDataService) GetAllJobData(ctx context.Context) ([]models.JobData, error) {
 	// Select all fields from the jobs table
 	query := `
-		SELECT
-			job_id, company_name, company_rating, company_address, company_website,
-			job_title, job_posted_time, job_link, job_description,
-			job_requirements, job_benefits, job_types, is_new_job,
-			is_no_resume_job, is_urgently_hiring, role_type, min_salary,
-			max_salary, salary_granularity, hires_needed, city, state,
-			zip, place_id, latitude, longitude, location_count, facebook,
-			instagram, tiktok, youtube, twitter, yelp, scheduling_link,
-			invocation_id, task_id, date_represented, date_collected, attempt_id,
-			created_at, updated_at
+		SELECT ` + jobSelectColumns + `
 		FROM jobs
 		ORDER BY created_at DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("error querying all job data: %w", err)
 	}
@@ -249,51 +684,90 @@ func (s *JobDataService) GetAllJobData() ([]models.JobData, error) {
 
 	var jobs []models.JobData
 	for rows.Next() {
-		var job models.JobData
-		// Scan all fields into the JobData struct
-		err := rows.Scan(
-			&job.JobID,
-			&job.CompanyName,
-			&job.CompanyRating,
-			&job.CompanyAddress,
-			&job.CompanyWebsite,
-			&job.JobTitle,
-			&job.JobPostedTime,
-			&job.JobLink,
-			&job.JobDescription,
-			pq.Array(&job.JobRequirements),
-			pq.Array(&job.JobBenefits),
-			pq.Array(&job.JobTypes),
-			&job.IsNewJob,
-			&job.IsNoResumeJob,
-			&job.IsUrgentlyHiring,
-			&job.RoleType,
-			&job.MinSalary,
-			&job.MaxSalary,
-			&job.SalaryGranularity,
-			&job.HiresNeeded,
-			&job.City,
-			&job.State,
-			&job.Zip,
-			&job.PlaceID,
-			&job.Latitude,
-			&job.Longitude,
-			&job.LocationCount,
-			&job.Facebook,
-			&job.Instagram,
-			&job.Tiktok,
-			&job.Youtube,
-			&job.Twitter,
-			&job.Yelp,
-			&job.SchedulingLink,
-			&job.InvocationID,
-			&job.TaskID,
-			&job.DateRepresented,
-			&job.DateCollected,
-			&job.AttemptID,
-			&job.CreatedAt,
-			&job.UpdatedAt,
-		)
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning job data row: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job data rows: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// GetAllJobDataPaged retrieves a single page of job data entries along with the total job count
+func (s *JobDataService) GetAllJobDataPaged(ctx context.Context, limit, offset int) ([]models.JobData, int, error) {
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM jobs`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting job data: %w", err)
+	}
+
+	query := `
+		SELECT ` + jobSelectColumns + `
+		FROM jobs
+		ORDER BY created_at DESC, job_id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.db.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying job data page: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.JobData{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning job data row: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating job data rows: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// MaxRowRange is the most jobs a single GetJobsByRowIndexRange call may cover
+const MaxRowRange = 500
+
+// GetJobsByRowIndexRange returns the jobs at row indexes start through end,
+// inclusive, counting from zero in order of ingestion (created_at, then job_id).
+// Indexes past the last job are ignored, so a range beyond it is empty. A range
+// covering more than MaxRowRange jobs is rejected with ErrValidation.
+func (s *JobDataService) GetJobsByRowIndexRange(ctx context.Context, start, end int64) ([]models.JobData, error) {
+	if start < 0 {
+		return nil, fmt.Errorf("%w: start must not be negative", ErrValidation)
+	}
+	if start > end {
+		return nil, fmt.Errorf("%w: start %d is after end %d", ErrValidation, start, end)
+	}
+	if end-start >= MaxRowRange {
+		return nil, fmt.Errorf("%w: at most %d rows may be requested at once", ErrValidation, MaxRowRange)
+	}
+
+	query := `
+		SELECT ` + jobSelectColumns + `
+		FROM jobs
+		ORDER BY created_at, job_id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.db.Query(ctx, query, end-start+1, start)
+	if err != nil {
+		return nil, fmt.Errorf("error querying job data range: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.JobData{}
+	for rows.Next() {
+		job, err := scanJob(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning job data row: %w", err)
 		}
@@ -306,3 +780,210 @@ func (s *JobDataService) GetAllJobData() ([]models.JobData, error) {
 
 	return jobs, nil
 }
+
+// SearchCriteria holds the filters accepted by SearchJobs. Each non-empty field
+// is matched case-insensitively as a substring of its column, and all provided
+// fields must match.
+type SearchCriteria struct {
+	Company string
+	Title   string
+	City    string
+}
+
+// likeEscaper escapes the LIKE wildcards so user input is matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// buildJobSearchFilter turns criteria into a WHERE clause and its arguments.
+// It returns ErrValidation when no criterion is set.
+func buildJobSearchFilter(criteria SearchCriteria) (string, []interface{}, error) {
+	filters := []struct {
+		column string
+		value  string
+	}{
+		{"company_name", criteria.Company},
+		{"job_title", criteria.Title},
+		{"city", criteria.City},
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, f := range filters {
+		value := strings.TrimSpace(f.value)
+		if value == "" {
+			continue
+		}
+		args = append(args, "%"+likeEscaper.Replace(value)+"%")
+		conditions = append(conditions, fmt.Sprintf("%s ILIKE $%d", f.column, len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one of company, title or city is required", ErrValidation)
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// SearchJobs returns a page of jobs matching criteria along with the total number of matches
+func (s *JobDataService) SearchJobs(ctx context.Context, criteria SearchCriteria, limit, offset int) ([]models.JobData, int, error) {
+	where, args, err := buildJobSearchFilter(criteria)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM jobs `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting matching jobs: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM jobs
+		%s
+		ORDER BY created_at DESC, job_id
+		LIMIT $%d OFFSET $%d
+	`, jobSelectColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := s.db.Query(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error searching job data: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.JobData{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning job data row: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating job data rows: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// sampleSeed maps seed onto the -1 to 1 range Postgres setseed accepts. Seeds
+// that differ modulo 2^31 map to different values.
+func sampleSeed(seed int64) float64 {
+	return float64(seed%(1<<31)) / (1 << 31)
+}
+
+// SampleJobs returns up to n jobs picked at random. The same seed returns the
+// same sample as long as the jobs table is unchanged.
+func (s *JobDataService) SampleJobs(ctx context.Context, n int, seed int64) ([]models.JobData, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("%w: sample size must be positive", ErrValidation)
+	}
+
+	// random() is only reproducible after setseed on the same connection, and
+	// over rows fed to it in a fixed order
+	query := `
+		SELECT ` + jobSelectColumns + `
+		FROM (SELECT * FROM jobs ORDER BY job_id) AS ordered_jobs
+		ORDER BY random()
+		LIMIT $1
+	`
+
+	jobs := []models.JobData{}
+	err := s.db.RunInTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT setseed($1)`, sampleSeed(seed)); err != nil {
+			return fmt.Errorf("error seeding job sample: %w", err)
+		}
+
+		rows, err := tx.QueryContext(ctx, query, n)
+		if err != nil {
+			return fmt.Errorf("error sampling job data: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			job, err := scanJob(rows)
+			if err != nil {
+				return fmt.Errorf("error scanning job data row: %w", err)
+			}
+			jobs = append(jobs, job)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating job data rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// jobSummaryQuery computes every JobSummary field in a single pass over the jobs table.
+// A job is missing a required field when any of requiredFields is missing, as
+// JobData.MissingFields decides it.
+func jobSummaryQuery(requiredFields []string) string {
+	return `
+	SELECT
+		COUNT(*),
+		COUNT(*) FILTER (WHERE ` + missingFieldsCondition(requiredFields) + `),
+		AVG(max_salary),
+		MIN(min_salary),
+		MAX(max_salary),
+		COUNT(DISTINCT NULLIF(company_name, '')),
+		COUNT(DISTINCT NULLIF(city, ''))
+	FROM jobs
+`
+}
+
+// missingFieldsCondition returns an SQL condition that holds when any of the
+// fields is missing from a row: text columns when NULL or empty, others when
+// NULL. Fields that are not valid required fields are ignored, so only known
+// column names reach the query; with none left the condition is FALSE.
+func missingFieldsCondition(fields []string) string {
+	var conditions []string
+	for _, field := range fields {
+		switch {
+		case !models.IsValidRequiredField(field):
+			continue
+		case models.IsValidTextField(field):
+			conditions = append(conditions, "COALESCE("+field+", '') = ''")
+		default:
+			conditions = append(conditions, field+" IS NULL")
+		}
+	}
+	if len(conditions) == 0 {
+		return "FALSE"
+	}
+	return strings.Join(conditions, " OR ")
+}
+
+// GetSummary returns aggregate counts and salary figures across all jobs
+func (s *JobDataService) GetSummary(ctx context.Context) (*models.JobSummary, error) {
+	var summary models.JobSummary
+	var avgSalary, minSalary, maxSalary sql.NullFloat64
+	err := s.db.QueryRow(ctx, jobSummaryQuery(s.requiredFields)).Scan(
+		&summary.TotalJobs,
+		&summary.JobsMissingRequiredFields,
+		&avgSalary,
+		&minSalary,
+		&maxSalary,
+		&summary.DistinctCompanies,
+		&summary.DistinctCities,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying job data summary: %w", err)
+	}
+
+	summary.AvgSalary = nullFloat64Ptr(avgSalary)
+	summary.MinSalary = nullFloat64Ptr(minSalary)
+	summary.MaxSalary = nullFloat64Ptr(maxSalary)
+
+	return &summary, nil
+}
+
+// nullFloat64Ptr converts a nullable column value into a pointer, nil when NULL
+func nullFloat64Ptr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
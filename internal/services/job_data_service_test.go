@@ -1,10 +1,16 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ainesh01/anomaly_detection/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -14,7 +20,7 @@ func TestJobDataService(t *testing.T) {
 	t.Run("CreateJobData", func(t *testing.T) {
 		// Setup
 		mockDB := new(MockDB)
-		service := NewJobDataService(mockDB)
+		service := NewJobDataService(mockDB, nil)
 		job := &models.JobData{
 			JobID:          "job1",
 			JobTitle:       "Software Engineer",
@@ -26,7 +32,7 @@ func TestJobDataService(t *testing.T) {
 				"Go",
 				"Python",
 			},
-			CompanyRating:   4.5,
+			CompanyRating:   Float64Ptr(4.5),
 			Latitude:        Float64Ptr(37.7749),
 			Longitude:       Float64Ptr(-122.4194),
 			JobPostedTime:   models.CustomTime{Time: time.Now()},
@@ -40,7 +46,7 @@ func TestJobDataService(t *testing.T) {
 		mockDB.On("Exec", mock.Anything, mock.Anything).Return(mockResult, nil)
 
 		// Test
-		err := service.CreateJobData(job)
+		err := service.CreateJobData(context.Background(), job)
 
 		// Assertions
 		assert.NoError(t, err)
@@ -51,106 +57,65 @@ func TestJobDataService(t *testing.T) {
 
 	t.Run("GetJobData", func(t *testing.T) {
 		// Setup
-		mockDB := new(MockDB)
-		service := NewJobDataService(mockDB)
-		expectedJob := &models.JobData{
-			JobID:          "job1",
-			JobTitle:       "Software Engineer",
-			CompanyName:    "Tech Corp",
-			JobDescription: "Job description",
-			MinSalary:      Float64Ptr(50000.0),
-			MaxSalary:      Float64Ptr(100000.0),
-			JobRequirements: []string{
-				"Go",
-				"Python",
-			},
-			CompanyRating:   4.5,
-			Latitude:        Float64Ptr(37.7749),
-			Longitude:       Float64Ptr(-122.4194),
-			JobPostedTime:   models.CustomTime{Time: time.Now()},
-			DateRepresented: models.CustomTime{Time: time.Now()},
-			DateCollected:   models.CustomTime{Time: time.Now()},
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
-		}
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		service := NewJobDataService(&SQLDB{db: db}, nil)
 
-		// Setup mock row
-		mockRow := &sql.Row{}
-		mockDB.On("QueryRow", mock.Anything, "job1").Return(mockRow)
+		sqlMock.ExpectQuery("FROM jobs\\s+WHERE job_id = \\$1").
+			WithArgs("job1").
+			WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(jobRowWith("job1", map[string]driver.Value{
+				"job_title":        "Software Engineer",
+				"job_requirements": "{Go,Python}",
+				"company_rating":   4.5,
+				"min_salary":       50000.0,
+				"max_salary":       100000.0,
+			})...))
 
 		// Test
-		job, err := service.GetJobData("job1")
+		job, err := service.GetJobData(context.Background(), "job1")
 
 		// Assertions
 		assert.NoError(t, err)
-		assert.Equal(t, expectedJob, job)
-		mockDB.AssertExpectations(t)
+		assert.Equal(t, "job1", job.JobID)
+		assert.Equal(t, "Software Engineer", job.JobTitle)
+		assert.Equal(t, "Tech Corp", job.CompanyName)
+		assert.Equal(t, []string{"Go", "Python"}, job.JobRequirements)
+		assert.Equal(t, Float64Ptr(4.5), job.CompanyRating)
+		assert.Equal(t, Float64Ptr(50000.0), job.MinSalary)
+		assert.Equal(t, Float64Ptr(100000.0), job.MaxSalary)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
 	})
 
 	t.Run("GetAllJobData", func(t *testing.T) {
 		// Setup
-		mockDB := new(MockDB)
-		service := NewJobDataService(mockDB)
-		expectedJobs := []models.JobData{
-			{
-				JobID:          "job1",
-				JobTitle:       "Software Engineer",
-				CompanyName:    "Tech Corp",
-				JobDescription: "Job description",
-				MinSalary:      Float64Ptr(50000.0),
-				MaxSalary:      Float64Ptr(100000.0),
-				JobRequirements: []string{
-					"Go",
-					"Python",
-				},
-				CompanyRating:   4.5,
-				Latitude:        Float64Ptr(37.7749),
-				Longitude:       Float64Ptr(-122.4194),
-				JobPostedTime:   models.CustomTime{Time: time.Now()},
-				DateRepresented: models.CustomTime{Time: time.Now()},
-				DateCollected:   models.CustomTime{Time: time.Now()},
-				CreatedAt:       time.Now(),
-				UpdatedAt:       time.Now(),
-			},
-			{
-				JobID:          "job2",
-				JobTitle:       "Data Scientist",
-				CompanyName:    "Data Corp",
-				JobDescription: "Data job description",
-				MinSalary:      Float64Ptr(60000.0),
-				MaxSalary:      Float64Ptr(120000.0),
-				JobRequirements: []string{
-					"Python",
-					"R",
-				},
-				CompanyRating:   4.0,
-				Latitude:        Float64Ptr(37.7749),
-				Longitude:       Float64Ptr(-122.4194),
-				JobPostedTime:   models.CustomTime{Time: time.Now()},
-				DateRepresented: models.CustomTime{Time: time.Now()},
-				DateCollected:   models.CustomTime{Time: time.Now()},
-				CreatedAt:       time.Now(),
-				UpdatedAt:       time.Now(),
-			},
-		}
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		service := NewJobDataService(&SQLDB{db: db}, nil)
 
-		// Setup mock rows
-		mockRows := &sql.Rows{}
-		mockDB.On("Query", mock.Anything).Return(mockRows, nil)
+		sqlMock.ExpectQuery("FROM jobs\\s+ORDER BY created_at DESC").
+			WillReturnRows(sqlmock.NewRows(jobRowColumns).
+				AddRow(jobRow("job1")...).
+				AddRow(jobRowWith("job2", map[string]driver.Value{"company_name": "Data Corp"})...))
 
 		// Test
-		jobs, err := service.GetAllJobData()
+		jobs, err := service.GetAllJobData(context.Background())
 
 		// Assertions
 		assert.NoError(t, err)
-		assert.Equal(t, expectedJobs, jobs)
-		mockDB.AssertExpectations(t)
+		if assert.Len(t, jobs, 2) {
+			assert.Equal(t, "job1", jobs[0].JobID)
+			assert.Equal(t, "job2", jobs[1].JobID)
+			assert.Equal(t, "Data Corp", jobs[1].CompanyName)
+		}
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
 	})
 
 	t.Run("Error Cases", func(t *testing.T) {
 		// Setup
 		mockDB := new(MockDB)
-		service := NewJobDataService(mockDB)
+		service := NewJobDataService(mockDB, nil)
 		expectedError := assert.AnError
 
 		t.Run("CreateJobData Error", func(t *testing.T) {
@@ -165,32 +130,689 @@ func TestJobDataService(t *testing.T) {
 					"Go",
 					"Python",
 				},
-				CompanyRating:   4.5,
+				CompanyRating:   Float64Ptr(4.5),
 				Latitude:        Float64Ptr(37.7749),
 				Longitude:       Float64Ptr(-122.4194),
 				JobPostedTime:   models.CustomTime{Time: time.Now()},
 				DateRepresented: models.CustomTime{Time: time.Now()},
 				DateCollected:   models.CustomTime{Time: time.Now()},
 			}
-			mockDB.On("Exec", mock.Anything, mock.Anything).Return(nil, expectedError)
-			err := service.CreateJobData(job)
-			assert.Error(t, err)
-			assert.Equal(t, expectedError, err)
+			mockDB.On("Exec", mock.Anything, mock.Anything).Return((*MockResult)(nil), expectedError)
+			err := service.CreateJobData(context.Background(), job)
+			assert.ErrorIs(t, err, expectedError)
 		})
 
 		t.Run("GetJobData Error", func(t *testing.T) {
-			mockDB.On("QueryRow", mock.Anything, "job1").Return(nil)
-			job, err := service.GetJobData("job1")
-			assert.Error(t, err)
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			sqlMock.ExpectQuery("FROM jobs\\s+WHERE job_id = \\$1").WillReturnError(expectedError)
+
+			job, err := NewJobDataService(&SQLDB{db: db}, nil).GetJobData(context.Background(), "job1")
+			assert.ErrorIs(t, err, expectedError)
 			assert.Nil(t, job)
 		})
 
 		t.Run("GetAllJobData Error", func(t *testing.T) {
-			mockDB.On("Query", mock.Anything).Return(nil, expectedError)
-			jobs, err := service.GetAllJobData()
-			assert.Error(t, err)
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			sqlMock.ExpectQuery("FROM jobs\\s+ORDER BY created_at DESC").WillReturnError(expectedError)
+
+			jobs, err := NewJobDataService(&SQLDB{db: db}, nil).GetAllJobData(context.Background())
+			assert.ErrorIs(t, err, expectedError)
 			assert.Nil(t, jobs)
-			assert.Equal(t, expectedError, err)
 		})
 	})
 }
+
+func TestBuildJobInsertQuery(t *testing.T) {
+	query := buildJobInsertQuery(2, UpsertReplace)
+
+	assert.Contains(t, query, "($1, $2,")
+	assert.Contains(t, query, "$41), ($42,")
+	assert.Contains(t, query, "$82)")
+	assert.NotContains(t, query, "$83")
+	assert.Contains(t, query, "ON CONFLICT (job_id) DO UPDATE SET")
+}
+
+func TestJobUpsertClause(t *testing.T) {
+	replace := jobUpsertClause(UpsertReplace)
+	assert.Contains(t, replace, "max_salary = EXCLUDED.max_salary,")
+	assert.Contains(t, replace, "company_name = EXCLUDED.company_name,")
+	assert.NotContains(t, replace, "COALESCE")
+	assert.NotContains(t, replace, "created_at")
+
+	fill := jobUpsertClause(UpsertFillMissing)
+	assert.Contains(t, fill, "max_salary = COALESCE(EXCLUDED.max_salary, jobs.max_salary),")
+	assert.Contains(t, fill, "date_collected = COALESCE(EXCLUDED.date_collected, jobs.date_collected),")
+	// Required and non-nullable columns are still overwritten
+	assert.Contains(t, fill, "company_name = EXCLUDED.company_name,")
+	assert.Contains(t, fill, "location_count = EXCLUDED.location_count,")
+	assert.Contains(t, fill, "updated_at = EXCLUDED.updated_at")
+	assert.NotContains(t, fill, "created_at")
+
+	assert.Equal(t, strings.Count(replace, "EXCLUDED."), strings.Count(fill, "EXCLUDED."))
+}
+
+func TestParseUpsertMode(t *testing.T) {
+	for name, want := range map[string]UpsertMode{
+		"":             UpsertReplace,
+		"replace":      UpsertReplace,
+		"fill-missing": UpsertFillMissing,
+	} {
+		mode, err := ParseUpsertMode(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, want, mode, name)
+	}
+
+	_, err := ParseUpsertMode("merge")
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestCreateJobDataBatchUpsertModes(t *testing.T) {
+	full := models.JobData{
+		JobID:         "job1",
+		CompanyName:   "Tech Corp",
+		JobTitle:      "Engineer",
+		MinSalary:     Float64Ptr(50000),
+		MaxSalary:     Float64Ptr(100000),
+		CompanyRating: Float64Ptr(4.2),
+	}
+	// A later record for the same job that is missing the salaries and rating
+	partial := models.JobData{JobID: "job1", CompanyName: "Tech Corp", JobTitle: "Senior Engineer"}
+
+	tests := []struct {
+		mode      UpsertMode
+		maxSalary string
+	}{
+		{UpsertReplace, `max_salary = EXCLUDED\.max_salary,`},
+		{UpsertFillMissing, `max_salary = COALESCE\(EXCLUDED\.max_salary, jobs\.max_salary\),`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			query := `INSERT INTO jobs (.|\n)*job_title = EXCLUDED\.job_title,(.|\n)*` + tt.maxSalary
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			// The partial record sends NULL for the salary it is missing
+			partialArgs := make([]driver.Value, jobInsertColumnCount)
+			for i := range partialArgs {
+				partialArgs[i] = sqlmock.AnyArg()
+			}
+			partialArgs[17] = nil // max_salary
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectExec(query).WithArgs(partialArgs...).WillReturnResult(sqlmock.NewResult(0, 1))
+			sqlMock.ExpectCommit()
+
+			service := NewJobDataService(&SQLDB{db: db}, nil)
+			assert.NoError(t, service.CreateJobDataBatch(context.Background(), []models.JobData{full}, tt.mode))
+			assert.NoError(t, service.CreateJobDataBatch(context.Background(), []models.JobData{partial}, tt.mode))
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDedupeJobsByID(t *testing.T) {
+	jobs := []models.JobData{
+		{JobID: "job1", JobTitle: "first"},
+		{JobID: "job2", JobTitle: "only"},
+		{JobID: "job1", JobTitle: "second"},
+	}
+
+	deduped := dedupeJobsByID(jobs)
+
+	assert.Len(t, deduped, 2)
+	assert.Equal(t, "job2", deduped[0].JobID)
+	assert.Equal(t, "job1", deduped[1].JobID)
+	assert.Equal(t, "second", deduped[1].JobTitle)
+}
+
+func TestGetSummary(t *testing.T) {
+	columns := []string{"count", "missing", "avg", "min", "max", "companies", "cities"}
+
+	t.Run("populated table", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery("SELECT(.|\n)*COUNT\\(DISTINCT(.|\n)*FROM jobs").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(120, 7, 95000.5, 30000.0, 250000.0, 42, 18))
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		summary, err := service.GetSummary(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, &models.JobSummary{
+			TotalJobs:                 120,
+			JobsMissingRequiredFields: 7,
+			AvgSalary:                 Float64Ptr(95000.5),
+			MinSalary:                 Float64Ptr(30000.0),
+			MaxSalary:                 Float64Ptr(250000.0),
+			DistinctCompanies:         42,
+			DistinctCities:            18,
+		}, summary)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("empty table", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery("FROM jobs").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(0, 0, nil, nil, nil, 0, 0))
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		summary, err := service.GetSummary(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, summary.TotalJobs)
+		assert.Nil(t, summary.AvgSalary)
+		assert.Nil(t, summary.MinSalary)
+		assert.Nil(t, summary.MaxSalary)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("configured required fields", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery(regexp.QuoteMeta("COUNT(*) FILTER (WHERE COALESCE(state, '') = '' OR company_rating IS NULL)")).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 1, nil, nil, nil, 1, 1))
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		service.SetRequiredFields([]string{"state", "company_rating"})
+		summary, err := service.GetSummary(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, summary.JobsMissingRequiredFields)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestMissingFieldsCondition(t *testing.T) {
+	assert.Equal(t, "COALESCE(company_name, '') = '' OR latitude IS NULL OR job_posted_time IS NULL",
+		missingFieldsCondition([]string{"company_name", "latitude", "job_posted_time"}))
+	assert.Equal(t, "COALESCE(city, '') = ''", missingFieldsCondition([]string{"city", "job_id; DROP TABLE jobs"}))
+	assert.Equal(t, "FALSE", missingFieldsCondition(nil))
+}
+
+func TestPatchJobData(t *testing.T) {
+	t.Run("updates only provided columns", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery("FROM jobs").WithArgs("job1").
+			WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(jobRow("job1")...))
+		sqlMock.ExpectExec(regexp.QuoteMeta(`UPDATE jobs SET city = $1, max_salary = $2, updated_at = $3 WHERE job_id = $4`)).
+			WithArgs("Austin", 150000.0, sqlmock.AnyArg(), "job1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		err = service.PatchJobData(context.Background(), "job1", map[string]interface{}{
+			"maxSalary": 150000.0,
+			"city":      "Austin",
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("null clears the column", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery("FROM jobs").WithArgs("job1").
+			WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(jobRow("job1")...))
+		sqlMock.ExpectExec(regexp.QuoteMeta(`UPDATE jobs SET city = $1, updated_at = $2 WHERE job_id = $3`)).
+			WithArgs(nil, sqlmock.AnyArg(), "job1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		err = service.PatchJobData(context.Background(), "job1", map[string]interface{}{"city": nil})
+
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("missing job", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery("FROM jobs").WithArgs("missing").WillReturnRows(sqlmock.NewRows(jobRowColumns))
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		err = service.PatchJobData(context.Background(), "missing", map[string]interface{}{"city": "Austin"})
+
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestPatchJobDataRejectsInvalidPatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		stored bool
+	}{
+		{name: "no fields", fields: map[string]interface{}{}},
+		{name: "job id", fields: map[string]interface{}{"jobID": "other"}},
+		{name: "timestamps", fields: map[string]interface{}{"created_at": "2025-01-01T00:00:00Z"}},
+		{name: "column injection", fields: map[string]interface{}{"city = 'x', company_name": "y"}},
+		{name: "array of non-strings", fields: map[string]interface{}{"jobTypes": []interface{}{1.0}}, stored: true},
+		{name: "number for a string", fields: map[string]interface{}{"city": 12.0}, stored: true},
+		{name: "string for a number", fields: map[string]interface{}{"maxSalary": "lots"}, stored: true},
+		{name: "string for a bool", fields: map[string]interface{}{"isNewJob": "yes"}, stored: true},
+		{name: "rating out of range", fields: map[string]interface{}{"companyRating": 9.0}, stored: true},
+		{name: "negative salary", fields: map[string]interface{}{"minSalary": -1.0}, stored: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			// Invalid patches are rejected before any UPDATE is sent
+			if tt.stored {
+				sqlMock.ExpectQuery("FROM jobs").WithArgs("job1").
+					WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(jobRow("job1")...))
+			}
+
+			service := NewJobDataService(&SQLDB{db: db}, nil)
+			err = service.PatchJobData(context.Background(), "job1", tt.fields)
+
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPatchJobDataPreservesUnspecifiedFields(t *testing.T) {
+	db := newTestDatabase(t)
+	service := NewJobDataService(db, nil)
+	job := &models.JobData{
+		JobID:           "patched",
+		CompanyName:     "Tech Corp",
+		JobTitle:        "Engineer",
+		City:            "Denver",
+		MinSalary:       Float64Ptr(80000),
+		MaxSalary:       Float64Ptr(120000),
+		JobRequirements: []string{"Go"},
+	}
+	assert.NoError(t, service.CreateJobData(context.Background(), job))
+
+	err := service.PatchJobData(context.Background(), job.JobID, map[string]interface{}{
+		"city":      "Austin",
+		"jobTypes":  []interface{}{"full-time"},
+		"maxSalary": 130000.0,
+	})
+	assert.NoError(t, err)
+
+	stored, err := service.GetJobData(context.Background(), job.JobID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Austin", stored.City)
+	assert.Equal(t, []string{"full-time"}, stored.JobTypes)
+	assert.Equal(t, 130000.0, *stored.MaxSalary)
+	assert.Equal(t, "Tech Corp", stored.CompanyName)
+	assert.Equal(t, "Engineer", stored.JobTitle)
+	assert.Equal(t, 80000.0, *stored.MinSalary)
+	assert.Equal(t, []string{"Go"}, stored.JobRequirements)
+}
+
+func TestDeleteJobDataMissingJob(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectExec("DELETE FROM jobs WHERE job_id = \\$1").
+		WithArgs("missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	service := NewJobDataService(&SQLDB{db: db}, nil)
+	err = service.DeleteJobData(context.Background(), "missing")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestDeleteJobDataCascadesToAnomalies(t *testing.T) {
+	db := newTestDatabase(t)
+	service := NewJobDataService(db, nil)
+	job := &models.JobData{JobID: "doomed", CompanyName: "Tech Corp", JobTitle: "Engineer"}
+	assert.NoError(t, service.CreateJobData(context.Background(), job))
+
+	_, err := db.Exec(context.Background(), `
+		INSERT INTO anomalies (job_id, type, description, severity)
+		VALUES ($1, $2, 'Required fields are null', $3)
+	`, job.JobID, models.AnomalyTypeNullValues, models.SeverityMedium)
+	assert.NoError(t, err)
+
+	assert.NoError(t, service.DeleteJobData(context.Background(), job.JobID))
+
+	_, err = service.GetJobData(context.Background(), job.JobID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var remaining int
+	err = db.QueryRow(context.Background(), `SELECT COUNT(*) FROM anomalies WHERE job_id = $1`, job.JobID).Scan(&remaining)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, remaining)
+}
+
+func TestBuildJobSearchFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		criteria      SearchCriteria
+		expectedWhere string
+		expectedArgs  []interface{}
+	}{
+		{
+			name:          "single criterion",
+			criteria:      SearchCriteria{Title: "Engineer"},
+			expectedWhere: "WHERE job_title ILIKE $1",
+			expectedArgs:  []interface{}{"%Engineer%"},
+		},
+		{
+			name:          "all criteria are ANDed",
+			criteria:      SearchCriteria{Company: "acme", Title: "engineer", City: "austin"},
+			expectedWhere: "WHERE company_name ILIKE $1 AND job_title ILIKE $2 AND city ILIKE $3",
+			expectedArgs:  []interface{}{"%acme%", "%engineer%", "%austin%"},
+		},
+		{
+			name:          "blank criteria are skipped",
+			criteria:      SearchCriteria{Company: "  ", City: " austin "},
+			expectedWhere: "WHERE city ILIKE $1",
+			expectedArgs:  []interface{}{"%austin%"},
+		},
+		{
+			name:          "wildcards are matched literally",
+			criteria:      SearchCriteria{Company: `100%_co\`},
+			expectedWhere: "WHERE company_name ILIKE $1",
+			expectedArgs:  []interface{}{`%100\%\_co\\%`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args, err := buildJobSearchFilter(tt.criteria)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedWhere, where)
+			assert.Equal(t, tt.expectedArgs, args)
+		})
+	}
+}
+
+func TestSearchJobs(t *testing.T) {
+	t.Run("filters count and page queries", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM jobs WHERE company_name ILIKE \\$1 AND city ILIKE \\$2").
+			WithArgs("%acme%", "%austin%").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		sqlMock.ExpectQuery("FROM jobs\\s+WHERE company_name ILIKE \\$1 AND city ILIKE \\$2\\s+ORDER BY created_at DESC, job_id\\s+LIMIT \\$3 OFFSET \\$4").
+			WithArgs("%acme%", "%austin%", 2, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"job_id"}))
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		jobs, total, err := service.SearchJobs(context.Background(), SearchCriteria{Company: "acme", City: "austin"}, 2, 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, total)
+		assert.Empty(t, jobs)
+		assert.NotNil(t, jobs)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("no criteria", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		_, _, err = service.SearchJobs(context.Background(), SearchCriteria{}, 10, 0)
+
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+// jobRowColumns matches the columns selected by jobSelectColumns
+var jobRowColumns = strings.Split(strings.Join(strings.Fields(jobSelectColumns), ""), ",")
+
+// jobRow builds a jobs row for jobRowColumns, with every optional column NULL
+func jobRow(jobID string) []driver.Value {
+	now := time.Now()
+	required := map[string]driver.Value{
+		"job_id":             jobID,
+		"company_name":       "Tech Corp",
+		"company_address":    "",
+		"company_website":    "",
+		"job_title":          "Engineer",
+		"job_link":           "",
+		"job_description":    "",
+		"job_requirements":   "{}",
+		"job_benefits":       "{}",
+		"job_types":          "{}",
+		"is_new_job":         false,
+		"is_no_resume_job":   false,
+		"is_urgently_hiring": false,
+		"city":               "Austin",
+		"location_count":     1,
+		"invocation_id":      "",
+		"task_id":            "",
+		"attempt_id":         "",
+		"created_at":         now,
+		"updated_at":         now,
+	}
+	row := make([]driver.Value, len(jobRowColumns))
+	for i, column := range jobRowColumns {
+		row[i] = required[column]
+	}
+	return row
+}
+
+// jobRowWith builds a jobRow with the given columns set to other values
+func jobRowWith(jobID string, values map[string]driver.Value) []driver.Value {
+	row := jobRow(jobID)
+	for i, column := range jobRowColumns {
+		if value, ok := values[column]; ok {
+			row[i] = value
+		}
+	}
+	return row
+}
+
+func TestSampleJobs(t *testing.T) {
+	t.Run("seeds the sample in the same transaction", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec("SELECT setseed\\(\\$1\\)").
+			WithArgs(sampleSeed(123)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectQuery("ORDER BY job_id\\) AS ordered_jobs\\s+ORDER BY random\\(\\)\\s+LIMIT \\$1").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(jobRowColumns).
+				AddRow(jobRow("job7")...).
+				AddRow(jobRow("job2")...).
+				AddRow(jobRow("job5")...))
+		sqlMock.ExpectCommit()
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		jobs, err := service.SampleJobs(context.Background(), 3, 123)
+
+		assert.NoError(t, err)
+		assert.Len(t, jobs, 3)
+		assert.Equal(t, "job7", jobs[0].JobID)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		service := NewJobDataService(&SQLDB{db: db}, nil)
+		_, err = service.SampleJobs(context.Background(), 0, 123)
+
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestSampleSeed(t *testing.T) {
+	for _, seed := range []int64{0, 1, 123, -123, 1<<31 - 1, 1 << 40, -1 << 62} {
+		value := sampleSeed(seed)
+		assert.True(t, value > -1 && value < 1, "seed %d maps to %f", seed, value)
+	}
+	assert.NotEqual(t, sampleSeed(1), sampleSeed(2))
+	assert.Equal(t, sampleSeed(42), sampleSeed(42))
+}
+
+func TestCreateJobDataIdempotent(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	service := NewJobDataService(&SQLDB{db: db}, nil)
+	job := &models.JobData{JobID: "job1", CompanyName: "Tech Corp"}
+	requestHash, err := jobRequestHash(job)
+	assert.NoError(t, err)
+
+	// The first request claims the key and writes the job in one transaction
+	sqlMock.ExpectQuery("SELECT request_hash, response FROM idempotency_keys").
+		WithArgs("retry-1").
+		WillReturnError(sql.ErrNoRows)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs("retry-1", requestHash, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec("INSERT INTO jobs").WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	first, replayed, err := service.CreateJobDataIdempotent(context.Background(), "retry-1", job)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.False(t, first.CreatedAt.IsZero())
+
+	// A retry reads back the stored result without writing
+	response, err := json.Marshal(first)
+	assert.NoError(t, err)
+	sqlMock.ExpectQuery("SELECT request_hash, response FROM idempotency_keys").
+		WithArgs("retry-1").
+		WillReturnRows(sqlmock.NewRows([]string{"request_hash", "response"}).AddRow(requestHash, response))
+
+	second, replayed, err := service.CreateJobDataIdempotent(context.Background(), "retry-1", &models.JobData{JobID: "job1", CompanyName: "Tech Corp"})
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.JobID, second.JobID)
+	assert.True(t, first.CreatedAt.Equal(second.CreatedAt))
+
+	// Reusing the key for a different job is a conflict
+	sqlMock.ExpectQuery("SELECT request_hash, response FROM idempotency_keys").
+		WithArgs("retry-1").
+		WillReturnRows(sqlmock.NewRows([]string{"request_hash", "response"}).AddRow(requestHash, response))
+
+	_, _, err = service.CreateJobDataIdempotent(context.Background(), "retry-1", &models.JobData{JobID: "job2"})
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCreateJobDataIdempotentLosesRace(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	service := NewJobDataService(&SQLDB{db: db}, nil)
+	job := &models.JobData{JobID: "job1", CompanyName: "Tech Corp"}
+	requestHash, err := jobRequestHash(job)
+	assert.NoError(t, err)
+	winner := models.JobData{JobID: "job1", CompanyName: "Tech Corp", CreatedAt: time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)}
+	response, err := json.Marshal(winner)
+	assert.NoError(t, err)
+
+	// Another request with the same key commits first, so this write is rolled back
+	sqlMock.ExpectQuery("SELECT request_hash, response FROM idempotency_keys").
+		WillReturnError(sql.ErrNoRows)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("INSERT INTO idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectRollback()
+	sqlMock.ExpectQuery("SELECT request_hash, response FROM idempotency_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"request_hash", "response"}).AddRow(requestHash, response))
+
+	stored, replayed, err := service.CreateJobDataIdempotent(context.Background(), "retry-1", job)
+
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.True(t, winner.CreatedAt.Equal(stored.CreatedAt))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCreateJobDataIdempotentAgainstDatabase(t *testing.T) {
+	db := newTestDatabase(t)
+	service := NewJobDataService(db, nil)
+
+	first, replayed, err := service.CreateJobDataIdempotent(context.Background(), "retry-1", &models.JobData{JobID: "job1", CompanyName: "Tech Corp"})
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+
+	second, replayed, err := service.CreateJobDataIdempotent(context.Background(), "retry-1", &models.JobData{JobID: "job1", CompanyName: "Tech Corp"})
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+
+	firstJSON, err := json.Marshal(first)
+	assert.NoError(t, err)
+	secondJSON, err := json.Marshal(second)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(firstJSON), string(secondJSON))
+}
+
+func TestGetJobDataNullColumns(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Only the NOT NULL columns are set; the rating, text, flag and count columns are all NULL
+	row := make([]driver.Value, len(jobRowColumns))
+	for i, column := range jobRowColumns {
+		switch column {
+		case "job_id":
+			row[i] = "job1"
+		case "company_name":
+			row[i] = "Tech Corp"
+		case "job_title":
+			row[i] = "Engineer"
+		}
+	}
+	sqlMock.ExpectQuery("FROM jobs\\s+WHERE job_id = \\$1").
+		WithArgs("job1").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).AddRow(row...))
+
+	service := NewJobDataService(&SQLDB{db: db}, nil)
+	job, err := service.GetJobData(context.Background(), "job1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "job1", job.JobID)
+	assert.Nil(t, job.CompanyRating)
+	assert.Empty(t, job.CompanyAddress)
+	assert.Empty(t, job.City)
+	assert.False(t, job.IsNewJob)
+	assert.Zero(t, job.LocationCount)
+	assert.True(t, job.CreatedAt.IsZero())
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
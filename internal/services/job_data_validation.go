@@ -0,0 +1,62 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// Bounds enforced on incoming job data
+const (
+	MinCompanyRating = 0.0
+	MaxCompanyRating = 5.0
+	MaxLatitude      = 90.0
+	MaxLongitude     = 180.0
+)
+
+// requiredJobFields are the JobData fields, by Go name, that ValidateJobData
+// rejects when empty
+var requiredJobFields = map[string]bool{
+	"JobID": true,
+}
+
+// JobDataSchema describes the JSON fields of JobData, marking the ones
+// ValidateJobData requires
+func JobDataSchema() []models.JobDataField {
+	fields := models.JobDataFields()
+	for i := range fields {
+		fields[i].Required = requiredJobFields[fields[i].Name]
+	}
+	return fields
+}
+
+// ValidateJobData checks a job against basic business rules before it is stored.
+// Every failing field is reported in a single error wrapping ErrValidation.
+func ValidateJobData(job *models.JobData) error {
+	var problems []string
+
+	if strings.TrimSpace(job.JobID) == "" {
+		problems = append(problems, "job_id is required")
+	}
+	if job.CompanyRating != nil && (*job.CompanyRating < MinCompanyRating || *job.CompanyRating > MaxCompanyRating) {
+		problems = append(problems, fmt.Sprintf("company_rating %g must be between %g and %g", *job.CompanyRating, MinCompanyRating, MaxCompanyRating))
+	}
+	if job.Latitude != nil && (*job.Latitude < -MaxLatitude || *job.Latitude > MaxLatitude) {
+		problems = append(problems, fmt.Sprintf("latitude %g must be between %g and %g", *job.Latitude, -MaxLatitude, MaxLatitude))
+	}
+	if job.Longitude != nil && (*job.Longitude < -MaxLongitude || *job.Longitude > MaxLongitude) {
+		problems = append(problems, fmt.Sprintf("longitude %g must be between %g and %g", *job.Longitude, -MaxLongitude, MaxLongitude))
+	}
+	if job.MinSalary != nil && *job.MinSalary < 0 {
+		problems = append(problems, fmt.Sprintf("min_salary %g must not be negative", *job.MinSalary))
+	}
+	if job.MaxSalary != nil && *job.MaxSalary < 0 {
+		problems = append(problems, fmt.Sprintf("max_salary %g must not be negative", *job.MaxSalary))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrValidation, strings.Join(problems, "; "))
+	}
+	return nil
+}
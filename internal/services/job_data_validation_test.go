@@ -0,0 +1,108 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateJobData(t *testing.T) {
+	tests := []struct {
+		name          string
+		job           models.JobData
+		expectedError string
+	}{
+		{
+			name: "valid job",
+			job: models.JobData{
+				JobID:         "job1",
+				CompanyRating: Float64Ptr(4.2),
+				Latitude:      Float64Ptr(30.27),
+				Longitude:     Float64Ptr(-97.74),
+				MinSalary:     Float64Ptr(90000),
+				MaxSalary:     Float64Ptr(120000),
+			},
+		},
+		{
+			name: "valid job with optional fields missing",
+			job:  models.JobData{JobID: "job1"},
+		},
+		{
+			name: "zero rating",
+			job:  models.JobData{JobID: "job1", CompanyRating: Float64Ptr(0)},
+		},
+		{
+			name:          "empty job_id",
+			job:           models.JobData{JobID: "  "},
+			expectedError: "job_id is required",
+		},
+		{
+			name:          "rating above five",
+			job:           models.JobData{JobID: "job1", CompanyRating: Float64Ptr(7)},
+			expectedError: "company_rating 7 must be between 0 and 5",
+		},
+		{
+			name:          "negative rating",
+			job:           models.JobData{JobID: "job1", CompanyRating: Float64Ptr(-1)},
+			expectedError: "company_rating -1 must be between 0 and 5",
+		},
+		{
+			name:          "latitude out of range",
+			job:           models.JobData{JobID: "job1", Latitude: Float64Ptr(91)},
+			expectedError: "latitude 91 must be between -90 and 90",
+		},
+		{
+			name:          "longitude out of range",
+			job:           models.JobData{JobID: "job1", Longitude: Float64Ptr(-200)},
+			expectedError: "longitude -200 must be between -180 and 180",
+		},
+		{
+			name:          "negative min salary",
+			job:           models.JobData{JobID: "job1", MinSalary: Float64Ptr(-5)},
+			expectedError: "min_salary -5 must not be negative",
+		},
+		{
+			name:          "negative max salary",
+			job:           models.JobData{JobID: "job1", MaxSalary: Float64Ptr(-100)},
+			expectedError: "max_salary -100 must not be negative",
+		},
+		{
+			name:          "multiple problems are reported together",
+			job:           models.JobData{CompanyRating: Float64Ptr(6)},
+			expectedError: "job_id is required; company_rating 6 must be between 0 and 5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJobData(&tt.job)
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
+
+func TestJobDataSchemaRequiredFieldsAreValidated(t *testing.T) {
+	var required []string
+	for _, field := range JobDataSchema() {
+		if field.Required {
+			required = append(required, field.Name)
+		}
+	}
+	assert.Equal(t, []string{"JobID"}, required)
+
+	// Leaving out any required field must fail validation
+	for _, name := range required {
+		job := models.JobData{JobID: "job1"}
+		field := reflect.ValueOf(&job).Elem().FieldByName(name)
+		field.Set(reflect.Zero(field.Type()))
+		assert.ErrorIs(t, ValidateJobData(&job), ErrValidation, name)
+	}
+}
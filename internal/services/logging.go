@@ -0,0 +1,11 @@
+package services
+
+import "log/slog"
+
+// loggerOrDefault returns logger, or the process-wide default logger when logger is nil
+func loggerOrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// migrationFiles holds the numbered schema migrations shipped with the binary.
+// Each migration is a pair of files named NNNN_description.up.sql and
+// NNNN_description.down.sql.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFilePattern matches migration file names and captures the version,
+// description, and direction
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrateUp applies every embedded migration that has not been recorded in
+// schema_migrations, in version order. Each applied migration is logged to
+// logger, or slog.Default() if nil.
+func MigrateUp(ctx context.Context, dbService DatabaseServiceInterface, logger *slog.Logger) error {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return err
+	}
+	return applyMigrations(ctx, dbService, migrations, logger)
+}
+
+// MigrateDown reverts the most recently applied migrations, newest first.
+// At most steps migrations are reverted, each logged to logger, or
+// slog.Default() if nil.
+func MigrateDown(ctx context.Context, dbService DatabaseServiceInterface, steps int, logger *slog.Logger) error {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return err
+	}
+	return revertMigrations(ctx, dbService, migrations, steps, logger)
+}
+
+// loadMigrations reads and pairs the up/down files in fsys, sorted by version.
+// Every migration must have both an up and a down file.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	paths, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("error listing migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, path := range paths {
+		name := path[len("migrations/"):]
+		match := migrationFilePattern.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", name, err)
+		}
+		contents, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %q: %w", name, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// applyMigrations runs each pending migration in its own transaction, recording
+// its version so it is skipped on later runs
+func applyMigrations(ctx context.Context, dbService DatabaseServiceInterface, migrations []Migration, logger *slog.Logger) error {
+	logger = loggerOrDefault(logger)
+	applied, err := appliedMigrationVersions(ctx, dbService)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+
+		err := dbService.RunInTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error applying migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		logger.InfoContext(ctx, "applied migration", "version", migration.Version, "name", migration.Name)
+	}
+
+	return nil
+}
+
+// revertMigrations runs the down file of up to steps applied migrations,
+// newest first, removing each from schema_migrations
+func revertMigrations(ctx context.Context, dbService DatabaseServiceInterface, migrations []Migration, steps int, logger *slog.Logger) error {
+	logger = loggerOrDefault(logger)
+	applied, err := appliedMigrationVersions(ctx, dbService)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		migration := migrations[i]
+		if !applied[migration.Version] {
+			continue
+		}
+
+		err := dbService.RunInTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		logger.InfoContext(ctx, "reverted migration", "version", migration.Version, "name", migration.Name)
+		steps--
+	}
+
+	return nil
+}
+
+// appliedMigrationVersions creates the schema_migrations tracking table if needed
+// and returns the set of versions that have already been applied
+func appliedMigrationVersions(ctx context.Context, dbService DatabaseServiceInterface) (map[int64]bool, error) {
+	_, err := dbService.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+
+	rows, err := dbService.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("error scanning applied migration: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS anomalies;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS anomaly_rules;
@@ -0,0 +1,78 @@
+CREATE TABLE IF NOT EXISTS jobs (
+	job_id TEXT PRIMARY KEY,
+	company_name TEXT NOT NULL,
+	company_rating DOUBLE PRECISION,
+	company_address TEXT,
+	company_website TEXT,
+	job_title TEXT NOT NULL,
+	job_posted_time TIMESTAMP WITH TIME ZONE,
+	job_link TEXT,
+	job_description TEXT,
+	job_requirements TEXT[],
+	job_benefits TEXT[],
+	job_types TEXT[],
+	is_new_job BOOLEAN,
+	is_no_resume_job BOOLEAN,
+	is_urgently_hiring BOOLEAN,
+	role_type TEXT,
+	min_salary DOUBLE PRECISION,
+	max_salary DOUBLE PRECISION,
+	salary_granularity TEXT,
+	hires_needed TEXT,
+	city TEXT,
+	state TEXT,
+	zip TEXT,
+	place_id TEXT,
+	latitude DOUBLE PRECISION,
+	longitude DOUBLE PRECISION,
+	location_count INTEGER,
+	facebook TEXT,
+	instagram TEXT,
+	tiktok TEXT,
+	youtube TEXT,
+	twitter TEXT,
+	yelp TEXT,
+	scheduling_link TEXT,
+	invocation_id TEXT,
+	task_id TEXT,
+	date_represented TIMESTAMP WITH TIME ZONE,
+	date_collected TIMESTAMP WITH TIME ZONE,
+	attempt_id TEXT,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS anomalies (
+	id BIGSERIAL PRIMARY KEY,
+	job_id TEXT NOT NULL REFERENCES jobs(job_id),
+	type TEXT NOT NULL,
+	description TEXT NOT NULL,
+	value DOUBLE PRECISION,
+	threshold DOUBLE PRECISION,
+	operator TEXT,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	violations TEXT[] NOT NULL DEFAULT '{}',
+	severity TEXT,
+	CONSTRAINT uq_anomalies_job_type_violations UNIQUE (job_id, type, violations)
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomalies_job_id ON anomalies(job_id);
+CREATE INDEX IF NOT EXISTS idx_anomalies_type ON anomalies(type);
+
+CREATE TABLE IF NOT EXISTS anomaly_rules (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	description TEXT NOT NULL,
+	type TEXT NOT NULL,
+	operator TEXT NOT NULL,
+	value DOUBLE PRECISION NOT NULL,
+	is_active BOOLEAN NOT NULL DEFAULT true,
+	severity TEXT NOT NULL DEFAULT 'medium',
+	logic TEXT NOT NULL DEFAULT 'and',
+	conditions JSONB,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomaly_rules_name ON anomaly_rules(name);
+CREATE INDEX IF NOT EXISTS idx_anomaly_rules_active ON anomaly_rules(is_active);
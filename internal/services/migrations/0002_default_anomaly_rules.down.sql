@@ -0,0 +1 @@
+DELETE FROM anomaly_rules WHERE name = 'Negative Salary';
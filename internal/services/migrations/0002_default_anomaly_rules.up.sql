@@ -0,0 +1,7 @@
+-- Default rules are seeded once, and only into an empty table. This migration is
+-- recorded in schema_migrations, so a default rule a user deletes is never
+-- re-inserted, and a table that already holds user rules (for example one
+-- created before migrations were introduced) is left as it is.
+INSERT INTO anomaly_rules (name, description, type, operator, value, is_active, created_at, updated_at)
+SELECT 'Negative Salary', 'Alert if maximum salary is negative', 'max_salary', '<', 0.0, true, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+WHERE NOT EXISTS (SELECT 1 FROM anomaly_rules);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_jobs_date_collected ON jobs(date_collected);
@@ -0,0 +1,4 @@
+-- Deleting a job removes its anomalies instead of failing on the foreign key
+ALTER TABLE anomalies DROP CONSTRAINT IF EXISTS anomalies_job_id_fkey;
+ALTER TABLE anomalies
+	ADD CONSTRAINT anomalies_job_id_fkey FOREIGN KEY (job_id) REFERENCES jobs(job_id) ON DELETE CASCADE;
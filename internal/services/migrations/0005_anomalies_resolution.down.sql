@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_anomalies_status;
+ALTER TABLE anomalies
+	DROP COLUMN IF EXISTS resolution_note,
+	DROP COLUMN IF EXISTS resolved_at,
+	DROP COLUMN IF EXISTS status;
@@ -0,0 +1,7 @@
+-- Resolved anomalies are kept for audit history but hidden from default listings
+ALTER TABLE anomalies
+	ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open',
+	ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP WITH TIME ZONE,
+	ADD COLUMN IF NOT EXISTS resolution_note TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_anomalies_status ON anomalies(status);
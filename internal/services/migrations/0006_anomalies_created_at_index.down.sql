@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_anomalies_job_id ON anomalies(job_id);
+DROP INDEX IF EXISTS idx_anomalies_job_id_created_at;
+DROP INDEX IF EXISTS idx_anomalies_created_at;
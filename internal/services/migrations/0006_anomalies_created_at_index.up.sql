@@ -0,0 +1,5 @@
+-- Support time-ordered anomaly listings, globally and per job.
+-- The composite index also serves job_id lookups, so it replaces idx_anomalies_job_id.
+CREATE INDEX IF NOT EXISTS idx_anomalies_created_at ON anomalies(created_at);
+CREATE INDEX IF NOT EXISTS idx_anomalies_job_id_created_at ON anomalies(job_id, created_at);
+DROP INDEX IF EXISTS idx_anomalies_job_id;
@@ -0,0 +1,2 @@
+-- Rules can express their threshold as a percentile of the field instead of an absolute value
+ALTER TABLE anomaly_rules ADD COLUMN IF NOT EXISTS value_mode TEXT NOT NULL DEFAULT 'absolute';
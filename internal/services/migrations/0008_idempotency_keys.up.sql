@@ -0,0 +1,7 @@
+-- Results of job writes made with an Idempotency-Key header, so retried requests can be replayed
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	key TEXT PRIMARY KEY,
+	request_hash TEXT NOT NULL,
+	response JSONB NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_anomaly_rules_tags;
+ALTER TABLE anomaly_rules DROP COLUMN IF EXISTS tags;
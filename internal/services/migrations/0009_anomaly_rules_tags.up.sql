@@ -0,0 +1,3 @@
+-- Rules can be tagged (e.g. "data-quality", "fraud") so operators can group them
+ALTER TABLE anomaly_rules ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
+CREATE INDEX IF NOT EXISTS idx_anomaly_rules_tags ON anomaly_rules USING GIN (tags);
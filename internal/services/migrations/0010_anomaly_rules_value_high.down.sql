@@ -0,0 +1 @@
+ALTER TABLE anomaly_rules DROP COLUMN IF EXISTS value_high;
@@ -0,0 +1,2 @@
+-- Upper bound for rules using the BETWEEN and NOT BETWEEN operators
+ALTER TABLE anomaly_rules ADD COLUMN IF NOT EXISTS value_high DOUBLE PRECISION NOT NULL DEFAULT 0;
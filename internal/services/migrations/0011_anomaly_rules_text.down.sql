@@ -0,0 +1,2 @@
+ALTER TABLE anomaly_rules DROP COLUMN IF EXISTS text_value;
+ALTER TABLE anomaly_rules DROP COLUMN IF EXISTS field;
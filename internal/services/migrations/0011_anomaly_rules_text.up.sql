@@ -0,0 +1,3 @@
+-- Job text field and substring or pattern compared by rules using the text operators
+ALTER TABLE anomaly_rules ADD COLUMN IF NOT EXISTS field TEXT NOT NULL DEFAULT '';
+ALTER TABLE anomaly_rules ADD COLUMN IF NOT EXISTS text_value TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE anomaly_rules DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE anomaly_rules DROP COLUMN IF EXISTS created_by;
@@ -0,0 +1,3 @@
+-- Who created and last changed each rule, empty for rules saved before this was recorded
+ALTER TABLE anomaly_rules ADD COLUMN IF NOT EXISTS created_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE anomaly_rules ADD COLUMN IF NOT EXISTS updated_by TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+-- History of rule changes. Entries outlive their rule, so rule_id is not a foreign key.
+CREATE TABLE IF NOT EXISTS rule_audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	rule_id BIGINT NOT NULL,
+	action TEXT NOT NULL,
+	old_value JSONB,
+	new_value JSONB,
+	actor TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rule_audit_log_rule_id ON rule_audit_log(rule_id, created_at);
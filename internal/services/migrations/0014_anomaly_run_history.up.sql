@@ -0,0 +1,13 @@
+-- One row per run of detection over all jobs, kept as an audit trail
+CREATE TABLE IF NOT EXISTS anomaly_run_history (
+	id BIGSERIAL PRIMARY KEY,
+	status TEXT NOT NULL,
+	dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+	started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	completed_at TIMESTAMP WITH TIME ZONE,
+	jobs_processed INTEGER NOT NULL DEFAULT 0,
+	anomalies_found INTEGER NOT NULL DEFAULT 0,
+	error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomaly_run_history_started_at ON anomaly_run_history(started_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_anomalies_assignee;
+ALTER TABLE anomalies
+	DROP COLUMN IF EXISTS acknowledged_at,
+	DROP COLUMN IF EXISTS assignee;
@@ -0,0 +1,6 @@
+-- Anomalies can be acknowledged and assigned to someone while they are triaged
+ALTER TABLE anomalies
+	ADD COLUMN IF NOT EXISTS assignee TEXT,
+	ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_anomalies_assignee ON anomalies(assignee);
@@ -0,0 +1,7 @@
+-- Files loaded at startup, keyed by a hash of their content, so the same dump is not ingested twice
+CREATE TABLE IF NOT EXISTS ingested_files (
+	content_hash TEXT PRIMARY KEY,
+	path TEXT NOT NULL,
+	jobs INTEGER NOT NULL DEFAULT 0,
+	ingested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
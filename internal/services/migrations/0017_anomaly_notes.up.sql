@@ -0,0 +1,10 @@
+-- Free-text notes left on an anomaly while it is triaged
+CREATE TABLE IF NOT EXISTS anomaly_notes (
+	id BIGSERIAL PRIMARY KEY,
+	anomaly_id BIGINT NOT NULL REFERENCES anomalies(id) ON DELETE CASCADE,
+	author TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomaly_notes_anomaly_id ON anomaly_notes(anomaly_id, created_at);
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("embedded migrations are paired and ordered", func(t *testing.T) {
+		migrations, err := loadMigrations(migrationFiles)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, migrations)
+		for i, migration := range migrations {
+			assert.NotEmpty(t, migration.Up)
+			assert.NotEmpty(t, migration.Down)
+			if i > 0 {
+				assert.Greater(t, migration.Version, migrations[i-1].Version)
+			}
+		}
+	})
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{
+			name: "missing down file",
+			files: fstest.MapFS{
+				"migrations/0001_create_things.up.sql": {Data: []byte("CREATE TABLE things ();")},
+			},
+		},
+		{
+			name: "invalid file name",
+			files: fstest.MapFS{
+				"migrations/create_things.sql": {Data: []byte("CREATE TABLE things ();")},
+			},
+		},
+		{
+			name: "duplicate version",
+			files: fstest.MapFS{
+				"migrations/0001_create_things.up.sql":   {Data: []byte("CREATE TABLE things ();")},
+				"migrations/0001_create_things.down.sql": {Data: []byte("DROP TABLE things;")},
+				"migrations/0001_create_others.up.sql":   {Data: []byte("CREATE TABLE others ();")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.files)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplyMigrationsIsIdempotent(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	migrations := []Migration{
+		{Version: 1, Name: "create_things", Up: "CREATE TABLE things ()", Down: "DROP TABLE things"},
+		{Version: 2, Name: "create_others", Up: "CREATE TABLE others ()", Down: "DROP TABLE others"},
+	}
+
+	// First run against an empty schema applies every migration
+	sqlMock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	for _, migration := range migrations {
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(regexp.QuoteMeta(migration.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		sqlMock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(migration.Version, migration.Name).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+	}
+
+	// Second run finds both versions recorded and applies nothing
+	sqlMock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery("SELECT version FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1).AddRow(2))
+
+	service := &SQLDB{db: db}
+	assert.NoError(t, applyMigrations(context.Background(), service, migrations, nil))
+	assert.NoError(t, applyMigrations(context.Background(), service, migrations, nil))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestApplyMigrationsStopsOnFailure(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	migrations := []Migration{
+		{Version: 1, Name: "broken", Up: "CREATE TABLE broken", Down: "DROP TABLE broken"},
+		{Version: 2, Name: "never_run", Up: "CREATE TABLE never_run ()", Down: "DROP TABLE never_run"},
+	}
+
+	sqlMock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("CREATE TABLE broken").WillReturnError(assert.AnError)
+	sqlMock.ExpectRollback()
+
+	err = applyMigrations(context.Background(), &SQLDB{db: db}, migrations, nil)
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Contains(t, err.Error(), "1_broken")
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRevertMigrations(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	migrations := []Migration{
+		{Version: 1, Name: "create_things", Up: "CREATE TABLE things ()", Down: "DROP TABLE things"},
+		{Version: 2, Name: "create_others", Up: "CREATE TABLE others ()", Down: "DROP TABLE others"},
+	}
+
+	// Only the newest migration is reverted when steps is 1
+	sqlMock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery("SELECT version FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1).AddRow(2))
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("DROP TABLE others").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("DELETE FROM schema_migrations").WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	assert.NoError(t, revertMigrations(context.Background(), &SQLDB{db: db}, migrations, 1, nil))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestMigrateUpAgainstDatabase(t *testing.T) {
+	db := newTestDatabase(t)
+
+	// newTestDatabase has already migrated an empty schema; a second run must be a no-op
+	assert.NoError(t, MigrateUp(context.Background(), db, nil))
+
+	var count int
+	assert.NoError(t, db.QueryRow(context.Background(), `SELECT COUNT(*) FROM schema_migrations`).Scan(&count))
+	migrations, err := loadMigrations(migrationFiles)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), count)
+}
+
+func TestCreateTablesAppliesAnomalyTimeIndexes(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	migrations, err := loadMigrations(migrationFiles)
+	assert.NoError(t, err)
+
+	// Every migration but the index one is already recorded, as on an existing deployment
+	var pending *Migration
+	rows := sqlmock.NewRows([]string{"version"})
+	for i, migration := range migrations {
+		if migration.Name == "anomalies_created_at_index" {
+			pending = &migrations[i]
+			continue
+		}
+		rows.AddRow(migration.Version)
+	}
+	if !assert.NotNil(t, pending) {
+		return
+	}
+
+	sqlMock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(rows)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("CREATE INDEX IF NOT EXISTS idx_anomalies_created_at ON anomalies\\(created_at\\);\\s+" +
+		"CREATE INDEX IF NOT EXISTS idx_anomalies_job_id_created_at ON anomalies\\(job_id, created_at\\)").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(pending.Version, pending.Name).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	assert.NoError(t, createTables(context.Background(), &SQLDB{db: db}, false, nil))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAnomalyTimeIndexesExist(t *testing.T) {
+	db := newTestDatabase(t)
+
+	for _, index := range []string{"idx_anomalies_created_at", "idx_anomalies_job_id_created_at"} {
+		var exists bool
+		err := db.QueryRow(context.Background(),
+			`SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = 'anomalies' AND indexname = $1)`, index).Scan(&exists)
+		assert.NoError(t, err)
+		assert.True(t, exists, "missing index %s", index)
+	}
+}
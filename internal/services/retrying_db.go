@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RetryingDB wraps a DatabaseServiceInterface and retries calls that fail with
+// transient connection errors, such as those seen during a Postgres failover.
+// Each retry waits twice as long as the previous one, starting at backoff.
+// Errors from the query itself, like constraint violations or syntax errors,
+// are returned immediately. Exec, QueryRow and RunInTx may run writes that are
+// not idempotent, so they are only retried when nothing reached the server.
+type RetryingDB struct {
+	db       DatabaseServiceInterface
+	attempts int
+	backoff  time.Duration
+	logger   *slog.Logger
+}
+
+// NewRetryingDB creates a RetryingDB that makes at most attempts tries per call.
+// An attempts value below 1 is treated as 1, which disables retries.
+// A nil logger uses slog.Default().
+func NewRetryingDB(db DatabaseServiceInterface, attempts int, backoff time.Duration, logger *slog.Logger) *RetryingDB {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &RetryingDB{
+		db:       db,
+		attempts: attempts,
+		backoff:  backoff,
+		logger:   loggerOrDefault(logger),
+	}
+}
+
+// Exec executes a query without returning rows. Only failures to reach the
+// server are retried: when the connection drops after the statement was sent,
+// it may already have been applied, and running it again could apply it twice.
+func (r *RetryingDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := r.retry(ctx, isUnsentDBError, func() error {
+		var err error
+		result, err = r.db.Exec(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// Query executes a query that returns rows, retrying transient failures.
+func (r *RetryingDB) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := r.retry(ctx, isTransientDBError, func() error {
+		var err error
+		rows, err = r.db.Query(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow executes a query that is expected to return at most one row. It
+// also runs INSERT ... RETURNING statements, so like Exec it is only retried
+// when the statement never reached the server. The last row is returned so the
+// caller sees its error on Scan.
+func (r *RetryingDB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	var row *sql.Row
+	r.retry(ctx, isUnsentDBError, func() error {
+		row = r.db.QueryRow(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
+
+// Begin starts a new transaction, retrying transient failures.
+func (r *RetryingDB) Begin(ctx context.Context) (*sql.Tx, error) {
+	var tx *sql.Tx
+	err := r.retry(ctx, isTransientDBError, func() error {
+		var err error
+		tx, err = r.db.Begin(ctx)
+		return err
+	})
+	return tx, err
+}
+
+// RunInTx runs fn inside a transaction, rerunning it only when the connection
+// failed before anything was sent. A connection lost later, even during the
+// commit, may leave the transaction applied, so rerunning fn could apply it twice.
+func (r *RetryingDB) RunInTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return r.retry(ctx, isUnsentDBError, func() error {
+		return r.db.RunInTx(ctx, fn)
+	})
+}
+
+// Close closes the wrapped database.
+func (r *RetryingDB) Close() error {
+	return r.db.Close()
+}
+
+// retry calls op until it succeeds, fails with an error retryable does not
+// accept, or the attempts run out, sleeping with exponential backoff between tries
+func (r *RetryingDB) retry(ctx context.Context, retryable func(error) bool, op func() error) error {
+	delay := r.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = op()
+		if err == nil || !retryable(err) || attempt >= r.attempts {
+			return err
+		}
+
+		r.logger.WarnContext(ctx, "transient database error, retrying", "attempt", attempt, "max_attempts", r.attempts, "delay", delay, "err", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// isTransientDBError reports whether err is a connection-level failure that is
+// worth retrying. Postgres errors are only transient in class 08 (connection
+// exception) or when the server is shutting down or starting up.
+func isTransientDBError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03": // admin_shutdown, crash_shutdown, cannot_connect_now
+			return true
+		}
+		return pqErr.Code.Class() == "08"
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isUnsentDBError reports whether err means the connection failed before a
+// statement could be sent, so nothing ran on the server and it is safe to retry
+// even a write. A connection lost mid-statement, seen as an unexpected EOF, a
+// reset or a class 08 failure, leaves the statement's outcome unknown.
+func isUnsentDBError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "08001", "08004", "57P03": // unable to establish connection, server rejected connection, cannot_connect_now
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
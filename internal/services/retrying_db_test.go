@@ -0,0 +1,233 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetryingDBExec(t *testing.T) {
+	// A refused connection means the statement was never sent
+	connectionRefused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	t.Run("succeeds after two failures to connect", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockResult := new(MockResult)
+		mockDB.On("Exec", "DELETE FROM jobs", mock.Anything).Return((*MockResult)(nil), connectionRefused).Twice()
+		mockDB.On("Exec", "DELETE FROM jobs", mock.Anything).Return(mockResult, nil).Once()
+
+		db := NewRetryingDB(mockDB, 3, time.Millisecond, nil)
+		result, err := db.Exec(context.Background(), "DELETE FROM jobs")
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockResult, result)
+		mockDB.AssertNumberOfCalls(t, "Exec", 3)
+	})
+
+	t.Run("logs each retry with its attempt number", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockDB.On("Exec", "DELETE FROM jobs", mock.Anything).Return((*MockResult)(nil), connectionRefused).Once()
+		mockDB.On("Exec", "DELETE FROM jobs", mock.Anything).Return(new(MockResult), nil).Once()
+
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, nil))
+		db := NewRetryingDB(mockDB, 3, time.Millisecond, logger)
+		_, err := db.Exec(context.Background(), "DELETE FROM jobs")
+
+		assert.NoError(t, err)
+		assert.Contains(t, logs.String(), `"level":"WARN"`)
+		assert.Contains(t, logs.String(), `"attempt":1`)
+		assert.Contains(t, logs.String(), `"max_attempts":3`)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockDB.On("Exec", "DELETE FROM jobs", mock.Anything).Return((*MockResult)(nil), connectionRefused)
+
+		db := NewRetryingDB(mockDB, 3, time.Millisecond, nil)
+		_, err := db.Exec(context.Background(), "DELETE FROM jobs")
+
+		assert.ErrorIs(t, err, connectionRefused)
+		mockDB.AssertNumberOfCalls(t, "Exec", 3)
+	})
+
+	t.Run("does not retry writes that may have reached the server", func(t *testing.T) {
+		for _, sent := range []error{
+			&pq.Error{Code: "08006", Message: "connection failure"},
+			io.ErrUnexpectedEOF,
+			&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		} {
+			mockDB := new(MockDB)
+			mockDB.On("Exec", "INSERT INTO anomaly_notes", mock.Anything).Return((*MockResult)(nil), sent)
+
+			db := NewRetryingDB(mockDB, 3, time.Millisecond, nil)
+			_, err := db.Exec(context.Background(), "INSERT INTO anomaly_notes")
+
+			assert.ErrorIs(t, err, sent)
+			mockDB.AssertNumberOfCalls(t, "Exec", 1)
+		}
+	})
+
+	t.Run("does not retry constraint violations", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockDB.On("Exec", "INSERT INTO jobs", mock.Anything).Return((*MockResult)(nil), &pq.Error{Code: "23505"})
+
+		db := NewRetryingDB(mockDB, 3, time.Millisecond, nil)
+		_, err := db.Exec(context.Background(), "INSERT INTO jobs")
+
+		assert.Error(t, err)
+		mockDB.AssertNumberOfCalls(t, "Exec", 1)
+	})
+
+	t.Run("stops waiting when the context is cancelled", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockDB.On("Exec", "DELETE FROM jobs", mock.Anything).Return((*MockResult)(nil), connectionRefused)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		db := NewRetryingDB(mockDB, 5, time.Hour, nil)
+		start := time.Now()
+		_, err := db.Exec(ctx, "DELETE FROM jobs")
+
+		assert.ErrorIs(t, err, connectionRefused)
+		assert.Less(t, time.Since(start), time.Second)
+		mockDB.AssertNumberOfCalls(t, "Exec", 1)
+	})
+}
+
+func TestRetryingDBQueryRow(t *testing.T) {
+	t.Run("retries when the connection could not be established", func(t *testing.T) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		sqlMock.ExpectQuery("SELECT COUNT").WillReturnError(&pq.Error{Code: "08001"})
+		sqlMock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+		retrying := NewRetryingDB(&SQLDB{db: db}, 3, time.Millisecond, nil)
+		var count int
+		err = retrying.QueryRow(context.Background(), "SELECT COUNT(*) FROM jobs").Scan(&count)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, count)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("does not retry statements that may have reached the server", func(t *testing.T) {
+		for _, sent := range []error{
+			&pq.Error{Code: "08006", Message: "connection failure"},
+			io.ErrUnexpectedEOF,
+			&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		} {
+			db, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+
+			// A second attempt would be an unexpected query and fail with a different error
+			sqlMock.ExpectQuery("INSERT INTO anomaly_notes").WillReturnError(sent)
+
+			retrying := NewRetryingDB(&SQLDB{db: db}, 3, time.Millisecond, nil)
+			var id int64
+			err = retrying.QueryRow(context.Background(), "INSERT INTO anomaly_notes (anomaly_id) VALUES ($1) RETURNING id", 1).Scan(&id)
+
+			assert.ErrorIs(t, err, sent)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+			db.Close()
+		}
+	})
+}
+
+func TestRetryingDBRunInTx(t *testing.T) {
+	t.Run("retries when the connection could not be established", func(t *testing.T) {
+		mockDB := new(MockDB)
+		mockDB.On("RunInTx", mock.Anything).Return(&pq.Error{Code: "08001"}).Once()
+		mockDB.On("RunInTx", mock.Anything).Return(nil).Once()
+
+		db := NewRetryingDB(mockDB, 3, time.Millisecond, nil)
+		err := db.RunInTx(context.Background(), func(tx *sql.Tx) error { return nil })
+
+		assert.NoError(t, err)
+		mockDB.AssertNumberOfCalls(t, "RunInTx", 2)
+	})
+
+	t.Run("does not rerun transactions that may have been applied", func(t *testing.T) {
+		for _, sent := range []error{
+			&pq.Error{Code: "08006", Message: "connection failure"},
+			io.ErrUnexpectedEOF,
+			&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		} {
+			mockDB := new(MockDB)
+			mockDB.On("RunInTx", mock.Anything).Return(sent)
+
+			db := NewRetryingDB(mockDB, 3, time.Millisecond, nil)
+			err := db.RunInTx(context.Background(), func(tx *sql.Tx) error { return nil })
+
+			assert.ErrorIs(t, err, sent)
+			mockDB.AssertNumberOfCalls(t, "RunInTx", 1)
+		}
+	})
+}
+
+func TestIsTransientDBError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "connection exception", err: &pq.Error{Code: "08006"}, expected: true},
+		{name: "connection does not exist", err: &pq.Error{Code: "08003"}, expected: true},
+		{name: "admin shutdown", err: &pq.Error{Code: "57P01"}, expected: true},
+		{name: "bad connection", err: driver.ErrBadConn, expected: true},
+		{name: "wrapped bad connection", err: fmt.Errorf("error querying jobs: %w", driver.ErrBadConn), expected: true},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, expected: false},
+		{name: "syntax error", err: &pq.Error{Code: "42601"}, expected: false},
+		{name: "context cancelled", err: context.Canceled, expected: false},
+		{name: "other error", err: errors.New("boom"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isTransientDBError(tt.err))
+		})
+	}
+}
+
+func TestIsUnsentDBError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "bad connection", err: driver.ErrBadConn, expected: true},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, expected: true},
+		{name: "dial timeout", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("i/o timeout")}, expected: true},
+		{name: "cannot connect now", err: &pq.Error{Code: "57P03"}, expected: true},
+		{name: "unable to establish connection", err: &pq.Error{Code: "08001"}, expected: true},
+		{name: "connection failure", err: &pq.Error{Code: "08006"}, expected: false},
+		{name: "admin shutdown", err: &pq.Error{Code: "57P01"}, expected: false},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, expected: false},
+		{name: "connection reset", err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, expected: false},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, expected: false},
+		{name: "context cancelled", err: context.Canceled, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isUnsentDBError(tt.err))
+		})
+	}
+}
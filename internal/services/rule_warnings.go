@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// ruleFieldBounds are the smallest and largest values stored for each field a
+// rule condition can compare, matching the bounds enforced by ValidateJobData
+var ruleFieldBounds = map[models.AnomalyType][2]float64{
+	models.AnomalyTypeMaxSalary: {0, math.Inf(1)},
+	models.AnomalyTypeMinSalary: {0, math.Inf(1)},
+	models.AnomalyTypeRating:    {MinCompanyRating, MaxCompanyRating},
+}
+
+// ruleWarnings describes settings that are valid but probably not what the rule's
+// author meant: exact comparisons against salaries, and thresholds that no valid
+// job, or every valid job, would match. Warnings never block saving the rule.
+func ruleWarnings(rule *models.AnomalyRule) []string {
+	var warnings []string
+	for i, condition := range rule.EffectiveConditions() {
+		warning := conditionWarning(condition, rule.ValueMode == models.ValueModePercentile)
+		if warning == "" {
+			continue
+		}
+		if len(rule.Conditions) > 0 {
+			warning = fmt.Sprintf("condition %d: %s", i+1, warning)
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// conditionWarning returns a warning for a single condition, or "" when it looks sane.
+// Thresholds are only checked against the field's bounds for absolute values.
+func conditionWarning(condition models.RuleCondition, percentile bool) string {
+	bounds, ok := ruleFieldBounds[condition.Type]
+	if !ok {
+		return ""
+	}
+	if condition.Operator == models.Equal && condition.Type != models.AnomalyTypeRating {
+		return fmt.Sprintf("%s compares a salary for exact equality, which rarely matches; use BETWEEN for a range", condition)
+	}
+	if percentile {
+		return ""
+	}
+
+	switch matchesBounds(condition, bounds[0], bounds[1]) {
+	case matchesNone:
+		return fmt.Sprintf("%s can never match a valid job", condition)
+	case matchesAll:
+		return fmt.Sprintf("%s matches every job with a %s", condition, condition.Type)
+	}
+	return ""
+}
+
+// boundsMatch is how many of a field's possible values a condition matches
+type boundsMatch int
+
+const (
+	matchesSome boundsMatch = iota
+	matchesNone
+	matchesAll
+)
+
+// matchesBounds reports whether condition matches none, all, or only some of the
+// values between low and high inclusive
+func matchesBounds(condition models.RuleCondition, low, high float64) boundsMatch {
+	value, valueHigh := condition.Value, condition.ValueHigh
+	switch condition.Operator {
+	case models.GreaterThan:
+		return classifyBounds(value >= high, value < low)
+	case models.GreaterThanOrEqual:
+		return classifyBounds(value > high, value <= low)
+	case models.LessThan:
+		return classifyBounds(value <= low, value > high)
+	case models.LessThanOrEqual:
+		return classifyBounds(value < low, value >= high)
+	case models.Equal:
+		return classifyBounds(value < low || value > high, false)
+	case models.Between:
+		return classifyBounds(valueHigh < low || value > high, value <= low && valueHigh >= high)
+	case models.NotBetween:
+		return classifyBounds(value <= low && valueHigh >= high, valueHigh < low || value > high)
+	}
+	return matchesSome
+}
+
+func classifyBounds(none, all bool) boundsMatch {
+	switch {
+	case none:
+		return matchesNone
+	case all:
+		return matchesAll
+	}
+	return matchesSome
+}
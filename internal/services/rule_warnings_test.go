@@ -0,0 +1,108 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     models.AnomalyRule
+		expected []string
+	}{
+		{
+			name: "sane salary threshold",
+			rule: models.AnomalyRule{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 500000},
+		},
+		{
+			name: "sane rating range",
+			rule: models.AnomalyRule{Type: models.AnomalyTypeRating, Operator: models.NotBetween, Value: 1, ValueHigh: 4.5},
+		},
+		{
+			name: "exact rating is fine",
+			rule: models.AnomalyRule{Type: models.AnomalyTypeRating, Operator: models.Equal, Value: 0},
+		},
+		{
+			name: "text rule",
+			rule: models.AnomalyRule{Type: models.AnomalyTypeText, Operator: models.Contains, Field: "job_title", TextValue: "mlm"},
+		},
+		{
+			name:     "exact salary",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeMinSalary, Operator: models.Equal, Value: 50000},
+			expected: []string{"min_salary = 50000 compares a salary for exact equality, which rarely matches; use BETWEEN for a range"},
+		},
+		{
+			name:     "exact salary percentile",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeMaxSalary, Operator: models.Equal, Value: 50, ValueMode: models.ValueModePercentile},
+			expected: []string{"max_salary = 50 compares a salary for exact equality, which rarely matches; use BETWEEN for a range"},
+		},
+		{
+			name:     "rating above the maximum",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeRating, Operator: models.GreaterThan, Value: 5},
+			expected: []string{"company_rating > 5 can never match a valid job"},
+		},
+		{
+			name:     "rating below the minimum",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeRating, Operator: models.LessThan, Value: 0},
+			expected: []string{"company_rating < 0 can never match a valid job"},
+		},
+		{
+			name:     "rating at most the maximum",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeRating, Operator: models.LessThanOrEqual, Value: 5},
+			expected: []string{"company_rating <= 5 matches every job with a company_rating"},
+		},
+		{
+			name:     "rating range covering every rating",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeRating, Operator: models.Between, Value: 0, ValueHigh: 5},
+			expected: []string{"company_rating BETWEEN 0 AND 5 matches every job with a company_rating"},
+		},
+		{
+			name:     "rating outside a range covering every rating",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeRating, Operator: models.NotBetween, Value: 0, ValueHigh: 5},
+			expected: []string{"company_rating NOT BETWEEN 0 AND 5 can never match a valid job"},
+		},
+		{
+			name:     "negative salary",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeMaxSalary, Operator: models.LessThan, Value: 0},
+			expected: []string{"max_salary < 0 can never match a valid job"},
+		},
+		{
+			name:     "salary at least zero",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeMinSalary, Operator: models.GreaterThanOrEqual, Value: 0},
+			expected: []string{"min_salary >= 0 matches every job with a min_salary"},
+		},
+		{
+			name:     "salary range below zero",
+			rule:     models.AnomalyRule{Type: models.AnomalyTypeMaxSalary, Operator: models.Between, Value: -10, ValueHigh: -1},
+			expected: []string{"max_salary BETWEEN -10 AND -1 can never match a valid job"},
+		},
+		{
+			name: "percentile thresholds are not checked against field bounds",
+			rule: models.AnomalyRule{Type: models.AnomalyTypeRating, Operator: models.GreaterThan, Value: 90, ValueMode: models.ValueModePercentile},
+		},
+		{
+			name: "compound rule numbers its conditions",
+			rule: models.AnomalyRule{
+				Type: models.AnomalyTypeCompound,
+				Conditions: models.RuleConditions{
+					{Type: models.AnomalyTypeText, Operator: models.Contains, Field: "job_title", TextValue: "mlm"},
+					{Type: models.AnomalyTypeMaxSalary, Operator: models.Equal, Value: 1},
+					{Type: models.AnomalyTypeRating, Operator: models.GreaterThanOrEqual, Value: 0},
+				},
+			},
+			expected: []string{
+				"condition 2: max_salary = 1 compares a salary for exact equality, which rarely matches; use BETWEEN for a range",
+				"condition 3: company_rating >= 0 matches every job with a company_rating",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ruleWarnings(&tt.rule))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/lib/pq"
+)
+
+// Salaries are quoted per hour, day, year, and so on, as recorded in
+// salary_granularity. Detection converts them to annual salaries on the fly,
+// both in the statistics queries and for the job being checked, so an hourly
+// wage is not compared against yearly salaries as is. Salaries whose
+// granularity is missing or has no multiplier are used as stored.
+
+// salaryMultiplier returns the factor that makes a salary of the given
+// granularity annual, or false when granularity is nil or unknown
+func salaryMultiplier(granularity *string, multipliers map[string]float64) (float64, bool) {
+	if granularity == nil {
+		return 0, false
+	}
+	multiplier, ok := multipliers[strings.ToLower(strings.TrimSpace(*granularity))]
+	return multiplier, ok
+}
+
+// annualizeSalaries returns job with its salaries converted to annual ones. The
+// job itself is left untouched; when no conversion applies it is returned as is.
+func annualizeSalaries(job *models.JobData, multipliers map[string]float64) *models.JobData {
+	multiplier, ok := salaryMultiplier(job.SalaryGranularity, multipliers)
+	if !ok || multiplier == 1 || (job.MinSalary == nil && job.MaxSalary == nil) {
+		return job
+	}
+
+	annual := *job
+	if job.MinSalary != nil {
+		minSalary := *job.MinSalary * multiplier
+		annual.MinSalary = &minSalary
+	}
+	if job.MaxSalary != nil {
+		maxSalary := *job.MaxSalary * multiplier
+		annual.MaxSalary = &maxSalary
+	}
+	return &annual
+}
+
+// annualSalaryExpr returns an SQL expression for the salary column converted to
+// an annual salary by its row's salary_granularity. Granularities are quoted as
+// literals, so configured names cannot inject SQL.
+func annualSalaryExpr(column string, multipliers map[string]float64) string {
+	// Sorted so the same multipliers always produce the same query. A multiplier
+	// of 1 is left to the ELSE branch.
+	granularities := make([]string, 0, len(multipliers))
+	for granularity, multiplier := range multipliers {
+		if multiplier != 1 {
+			granularities = append(granularities, granularity)
+		}
+	}
+	if len(granularities) == 0 {
+		return column
+	}
+	slices.Sort(granularities)
+
+	var expr strings.Builder
+	expr.WriteString("CASE LOWER(TRIM(salary_granularity))")
+	for _, granularity := range granularities {
+		expr.WriteString(" WHEN " + pq.QuoteLiteral(granularity) + " THEN " + column + " * " +
+			strconv.FormatFloat(multipliers[granularity], 'g', -1, 64))
+	}
+	expr.WriteString(" ELSE " + column + " END")
+	return expr.String()
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnualizeSalaries(t *testing.T) {
+	tests := []struct {
+		name        string
+		granularity *string
+		expectedMin *float64
+		expectedMax *float64
+	}{
+		{"hourly wage is made annual", StringPtr("hourly"), Float64Ptr(60 * 2080), Float64Ptr(80 * 2080)},
+		{"granularity is matched case-insensitively", StringPtr(" Monthly "), Float64Ptr(60 * 12), Float64Ptr(80 * 12)},
+		{"yearly salary is unchanged", StringPtr("yearly"), Float64Ptr(60), Float64Ptr(80)},
+		{"unknown granularity is unchanged", StringPtr("per_project"), Float64Ptr(60), Float64Ptr(80)},
+		{"missing granularity is unchanged", nil, Float64Ptr(60), Float64Ptr(80)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.JobData{JobID: "job1", MinSalary: Float64Ptr(60), MaxSalary: Float64Ptr(80), SalaryGranularity: tt.granularity}
+
+			annual := annualizeSalaries(job, config.DefaultSalaryMultipliers)
+
+			assert.Equal(t, tt.expectedMin, annual.MinSalary)
+			assert.Equal(t, tt.expectedMax, annual.MaxSalary)
+			assert.Equal(t, 80.0, *job.MaxSalary, "the job itself must not change")
+		})
+	}
+
+	t.Run("missing salaries stay missing", func(t *testing.T) {
+		job := &models.JobData{JobID: "job1", MaxSalary: Float64Ptr(80), SalaryGranularity: StringPtr("hourly")}
+
+		annual := annualizeSalaries(job, config.DefaultSalaryMultipliers)
+
+		assert.Nil(t, annual.MinSalary)
+		assert.Equal(t, 166400.0, *annual.MaxSalary)
+	})
+}
+
+func TestAnnualSalaryExpr(t *testing.T) {
+	assert.Equal(t,
+		"CASE LOWER(TRIM(salary_granularity))"+
+			" WHEN 'daily' THEN max_salary * 260"+
+			" WHEN 'hourly' THEN max_salary * 2080"+
+			" WHEN 'monthly' THEN max_salary * 12"+
+			" WHEN 'weekly' THEN max_salary * 52"+
+			" ELSE max_salary END",
+		annualSalaryExpr("max_salary", config.DefaultSalaryMultipliers))
+
+	assert.Equal(t, "CASE LOWER(TRIM(salary_granularity)) WHEN 'it''s' THEN min_salary * 1.5 ELSE min_salary END",
+		annualSalaryExpr("min_salary", map[string]float64{"it's": 1.5}), "granularities are quoted")
+	assert.Equal(t, "max_salary", annualSalaryExpr("max_salary", map[string]float64{"yearly": 1}))
+	assert.Equal(t, "max_salary", annualSalaryExpr("max_salary", nil))
+}
+
+func TestDetectAnomaliesNormalizesSalaries(t *testing.T) {
+	cfg := config.DefaultDetectionConfig()
+	cfg.RequiredFields = nil
+	service := NewAnomalyService(nil, nil, cfg, nil, nil)
+	// Statistics over annual salaries
+	dc := &detectionContext{
+		stats:        &Statistics{AvgSalary: 100000, SalaryStdDev: 10000, SalaryQ1: 95000, SalaryQ3: 105000},
+		salaryMedian: 100000,
+		salaryMAD:    5000,
+	}
+
+	tests := []struct {
+		name        string
+		salary      float64
+		granularity *string
+		flagged     bool
+	}{
+		{"typical hourly wage is not flagged", 50, StringPtr("hourly"), false},
+		{"high hourly wage is flagged", 150, StringPtr("hourly"), true},
+		{"typical yearly salary is not flagged", 104000, StringPtr("yearly"), false},
+		{"salary without granularity is compared as stored", 50, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.JobData{JobID: "job1", MaxSalary: Float64Ptr(tt.salary), SalaryGranularity: tt.granularity}
+			save := func(ctx context.Context, anomaly *models.Anomaly) error { return nil }
+
+			flagged := false
+			for _, anomaly := range service.detectAnomaliesWithContext(context.Background(), job, dc, save) {
+				if anomaly.Type == models.AnomalyTypeDeviation {
+					flagged = true
+				}
+			}
+			assert.Equal(t, tt.flagged, flagged)
+		})
+	}
+}
+
+func TestDetectAnomaliesForAllJobsNormalizesSalaries(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Statistics over annual salaries, and an hourly wage that is typical once made annual
+	expectDetectionRunStart(sqlMock, 1, true)
+	sqlMock.ExpectQuery("FROM jobs").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(3, 100000.0, 10000.0, 95000.0, 105000.0, 4.0, 0.5, 3.5, 4.5, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("WITH salary_median AS").
+		WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(100000.0, 5000.0))
+	sqlMock.ExpectQuery("FROM anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery("SELECT\\s+job_id").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", map[string]driver.Value{"company_rating": 4.0, "min_salary": 45.0, "max_salary": 50.0, "salary_granularity": "hourly"})...))
+	expectDetectionRunFinish(sqlMock, 1, models.DetectionRunSucceeded, 1, 0)
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	cfg.RequiredFields = nil
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	anomalies, err := service.DetectAnomaliesForAllJobs(context.Background(), true)
+
+	assert.NoError(t, err)
+	assert.Empty(t, anomalies)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// BatchDetector runs detection over every stored job
+type BatchDetector interface {
+	DetectAnomaliesForAllJobs(ctx context.Context, dryRun bool) ([]models.Anomaly, error)
+}
+
+// DetectionScheduler runs batch detection in the background at a fixed interval.
+// A tick that arrives while the previous run is still going is skipped, so runs
+// never overlap.
+type DetectionScheduler struct {
+	detector BatchDetector
+	interval time.Duration
+	logger   *slog.Logger
+
+	running atomic.Bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewDetectionScheduler creates a scheduler running detector every interval.
+// A nil logger uses slog.Default().
+func NewDetectionScheduler(detector BatchDetector, interval time.Duration, logger *slog.Logger) *DetectionScheduler {
+	return &DetectionScheduler{
+		detector: detector,
+		interval: interval,
+		logger:   loggerOrDefault(logger),
+	}
+}
+
+// Start begins running detection every interval until Stop is called or ctx is
+// cancelled. The first run happens one interval after Start.
+func (s *DetectionScheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	ticker := time.NewTicker(s.interval)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Run in its own goroutine so ticks keep arriving, and are
+				// reported as skipped, while a long run is in progress
+				s.wg.Add(1)
+				go func() {
+					defer s.wg.Done()
+					s.RunOnce(ctx)
+				}()
+			}
+		}
+	}()
+	s.logger.Info("scheduled detection started", "interval", s.interval)
+}
+
+// Stop cancels any run in progress and waits for the scheduler to exit
+func (s *DetectionScheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.logger.Info("scheduled detection stopped")
+}
+
+// RunOnce runs detection over all jobs unless a run is already in progress,
+// and logs a summary of the run. It reports whether detection ran.
+func (s *DetectionScheduler) RunOnce(ctx context.Context) bool {
+	if !s.running.CompareAndSwap(false, true) {
+		s.logger.WarnContext(ctx, "skipping scheduled detection, the previous run is still in progress")
+		return false
+	}
+	defer s.running.Store(false)
+
+	start := time.Now()
+	_, err := s.detector.DetectAnomaliesForAllJobs(ctx, false)
+	duration := time.Since(start)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "scheduled detection failed", "duration", duration, "err", err)
+		return true
+	}
+	s.logger.InfoContext(ctx, "scheduled detection finished", "duration", duration)
+	return true
+}
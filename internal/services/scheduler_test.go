@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingDetector counts its runs and holds each one until release is closed
+type blockingDetector struct {
+	runs    atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (d *blockingDetector) DetectAnomaliesForAllJobs(ctx context.Context, dryRun bool) ([]models.Anomaly, error) {
+	d.runs.Add(1)
+	select {
+	case d.started <- struct{}{}:
+	default:
+	}
+	<-d.release
+	return nil, nil
+}
+
+func TestDetectionSchedulerSkipsOverlappingRuns(t *testing.T) {
+	detector := &blockingDetector{started: make(chan struct{}, 1), release: make(chan struct{})}
+	scheduler := NewDetectionScheduler(detector, time.Hour, nil)
+
+	done := make(chan bool)
+	go func() { done <- scheduler.RunOnce(context.Background()) }()
+	<-detector.started
+
+	// The first run is still going, so this one is skipped
+	assert.False(t, scheduler.RunOnce(context.Background()))
+
+	close(detector.release)
+	assert.True(t, <-done)
+	assert.Equal(t, int32(1), detector.runs.Load())
+
+	// Once the first run has finished the next one goes ahead
+	assert.True(t, scheduler.RunOnce(context.Background()))
+	assert.Equal(t, int32(2), detector.runs.Load())
+}
+
+func TestDetectionSchedulerRunsOnInterval(t *testing.T) {
+	detector := &blockingDetector{started: make(chan struct{}, 1), release: make(chan struct{})}
+	close(detector.release)
+	scheduler := NewDetectionScheduler(detector, 10*time.Millisecond, nil)
+
+	scheduler.Start(context.Background())
+	select {
+	case <-detector.started:
+	case <-time.After(time.Second):
+		t.Fatal("scheduled detection did not run")
+	}
+	scheduler.Stop()
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortOrder is the direction a listing is ordered in
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+// SortOptions selects the column and direction a listing is ordered by.
+// The zero value keeps the listing's default ordering.
+type SortOptions struct {
+	Column string
+	Order  SortOrder
+}
+
+// severityRankExpr orders severities by rank rather than alphabetically
+const severityRankExpr = `CASE severity WHEN 'low' THEN 1 WHEN 'medium' THEN 2 WHEN 'high' THEN 3 WHEN 'critical' THEN 4 ELSE 0 END`
+
+// anomalyRuleSortColumns maps the sort keys a client may use for anomaly rules
+// to the SQL expression ordered by
+var anomalyRuleSortColumns = map[string]string{
+	"name":       "name",
+	"type":       "type",
+	"value":      "value",
+	"severity":   severityRankExpr,
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// anomalySortColumns maps the sort keys a client may use for anomalies to the
+// SQL expression ordered by
+var anomalySortColumns = map[string]string{
+	"job_id":     "job_id",
+	"type":       "type",
+	"value":      "value",
+	"severity":   severityRankExpr,
+	"created_at": "created_at",
+}
+
+// companyAnomalySortColumns maps the sort keys a client may use for per-company
+// anomaly counts to the SQL expression ordered by
+var companyAnomalySortColumns = map[string]string{
+	"count":        "anomaly_count",
+	"company_name": "company_name",
+}
+
+// orderByClause builds the ORDER BY expression for opts. Only allow-listed
+// sort keys are accepted, since the expression is interpolated into the query.
+// An empty column falls back to defaultColumn/defaultOrder, and an empty order
+// on an explicit column sorts ascending. id breaks ties so paging is stable.
+func orderByClause(opts SortOptions, allowed map[string]string, defaultColumn string, defaultOrder SortOrder) (string, error) {
+	return orderByClauseWithTiebreak(opts, allowed, defaultColumn, defaultOrder, "id")
+}
+
+// orderByClauseWithTiebreak is orderByClause for listings without an id column,
+// such as grouped queries, breaking ties on the unique tiebreak column instead
+func orderByClauseWithTiebreak(opts SortOptions, allowed map[string]string, defaultColumn string, defaultOrder SortOrder, tiebreak string) (string, error) {
+	column, order := opts.Column, opts.Order
+	if column == "" {
+		column = defaultColumn
+		if order == "" {
+			order = defaultOrder
+		}
+	}
+	if order == "" {
+		order = SortAscending
+	}
+
+	expr, ok := allowed[column]
+	if !ok {
+		return "", fmt.Errorf("%w: cannot sort by %q", ErrValidation, column)
+	}
+	if order != SortAscending && order != SortDescending {
+		return "", fmt.Errorf("%w: sort order must be %q or %q", ErrValidation, SortAscending, SortDescending)
+	}
+
+	direction := strings.ToUpper(string(order))
+	if expr == tiebreak {
+		return fmt.Sprintf("%s %s", expr, direction), nil
+	}
+	return fmt.Sprintf("%s %s, %s %s", expr, direction, tiebreak, direction), nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderByClause(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          SortOptions
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:     "default ordering",
+			opts:     SortOptions{},
+			expected: "created_at DESC, id DESC",
+		},
+		{
+			name:     "explicit column defaults to ascending",
+			opts:     SortOptions{Column: "name"},
+			expected: "name ASC, id ASC",
+		},
+		{
+			name:     "explicit column and order",
+			opts:     SortOptions{Column: "value", Order: SortDescending},
+			expected: "value DESC, id DESC",
+		},
+		{
+			name:     "order only applies to the default column",
+			opts:     SortOptions{Order: SortAscending},
+			expected: "created_at ASC, id ASC",
+		},
+		{
+			name:     "severity sorts by rank",
+			opts:     SortOptions{Column: "severity", Order: SortDescending},
+			expected: severityRankExpr + " DESC, id DESC",
+		},
+		{
+			name:          "unknown column",
+			opts:          SortOptions{Column: "description"},
+			expectedError: true,
+		},
+		{
+			name:          "injection attempt",
+			opts:          SortOptions{Column: "name; DROP TABLE anomaly_rules"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid order",
+			opts:          SortOptions{Column: "name", Order: "sideways"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, err := orderByClause(tt.opts, anomalyRuleSortColumns, "created_at", SortDescending)
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, ErrValidation)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, clause)
+		})
+	}
+}
+
+func TestOrderByClauseWithTiebreak(t *testing.T) {
+	clause, err := orderByClauseWithTiebreak(SortOptions{}, companyAnomalySortColumns, "count", SortDescending, "company_name")
+	assert.NoError(t, err)
+	assert.Equal(t, "anomaly_count DESC, company_name DESC", clause)
+
+	clause, err = orderByClauseWithTiebreak(SortOptions{Column: "company_name"}, companyAnomalySortColumns, "count", SortDescending, "company_name")
+	assert.NoError(t, err)
+	assert.Equal(t, "company_name ASC", clause)
+}
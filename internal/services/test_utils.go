@@ -4,3 +4,8 @@ package services
 func Float64Ptr(f float64) *float64 {
 	return &f
 }
+
+// StringPtr creates a pointer to a string value
+func StringPtr(s string) *string {
+	return &s
+}
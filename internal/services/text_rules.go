@@ -0,0 +1,46 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ainesh01/anomaly_detection/internal/models"
+)
+
+// rulePatterns caches compiled rule patterns by source, so each pattern is
+// compiled once rather than once per job. It is shared by concurrent detection workers.
+var rulePatterns sync.Map // map[string]*regexp.Regexp
+
+// rulePattern returns the compiled regular expression for a text rule pattern
+func rulePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := rulePatterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	rulePatterns.Store(pattern, compiled)
+	return compiled, nil
+}
+
+// compareText compares a job text field with a text condition. Contains checks
+// ignore case; patterns are matched as written, so use (?i) for case-insensitive
+// patterns. A pattern that does not compile never matches.
+func compareText(text string, condition models.RuleCondition) bool {
+	switch condition.Operator {
+	case models.Contains:
+		return strings.Contains(strings.ToLower(text), strings.ToLower(condition.TextValue))
+	case models.NotContains:
+		return !strings.Contains(strings.ToLower(text), strings.ToLower(condition.TextValue))
+	case models.Matches, models.NotMatches:
+		pattern, err := rulePattern(condition.TextValue)
+		if err != nil {
+			return false // Rejected when the rule is saved, so only rules stored before validation get here
+		}
+		return pattern.MatchString(text) == (condition.Operator == models.Matches)
+	default:
+		return false // Unknown operator
+	}
+}
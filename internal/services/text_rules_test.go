@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareText(t *testing.T) {
+	const urlPattern = `^https?://[^\s/]+\.[a-z]{2,}`
+
+	tests := []struct {
+		name      string
+		text      string
+		condition models.RuleCondition
+		expected  bool
+	}{
+		{"contains matches ignoring case", "Join our MLM team today", models.RuleCondition{Operator: models.Contains, TextValue: "mlm"}, true},
+		{"contains without the substring", "Senior backend engineer", models.RuleCondition{Operator: models.Contains, TextValue: "mlm"}, false},
+		{"not contains without the substring", "Senior backend engineer", models.RuleCondition{Operator: models.NotContains, TextValue: "mlm"}, true},
+		{"not contains with the substring", "Join our mlm team", models.RuleCondition{Operator: models.NotContains, TextValue: "MLM"}, false},
+		{"matches pattern", "https://example.com/careers", models.RuleCondition{Operator: models.Matches, TextValue: urlPattern}, true},
+		{"matches is case sensitive", "HTTPS://EXAMPLE.COM", models.RuleCondition{Operator: models.Matches, TextValue: urlPattern}, false},
+		{"matches with inline case flag", "HTTPS://EXAMPLE.COM", models.RuleCondition{Operator: models.Matches, TextValue: `(?i)` + urlPattern}, true},
+		{"not matches with invalid url", "example dot com", models.RuleCondition{Operator: models.NotMatches, TextValue: urlPattern}, true},
+		{"not matches with valid url", "http://example.org", models.RuleCondition{Operator: models.NotMatches, TextValue: urlPattern}, false},
+		{"invalid pattern never matches", "anything", models.RuleCondition{Operator: models.Matches, TextValue: "("}, false},
+		{"invalid pattern never matches negated", "anything", models.RuleCondition{Operator: models.NotMatches, TextValue: "("}, false},
+		{"numeric operator", "anything", models.RuleCondition{Operator: models.GreaterThan, TextValue: "anything"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, compareText(tt.text, tt.condition))
+		})
+	}
+}
+
+func TestRulePatternCompiledOnce(t *testing.T) {
+	first, err := rulePattern(`^\d{5}$`)
+	assert.NoError(t, err)
+	second, err := rulePattern(`^\d{5}$`)
+	assert.NoError(t, err)
+	assert.Same(t, first, second)
+
+	_, err = rulePattern(`[`)
+	assert.Error(t, err)
+}
+
+func TestEvaluateTextRule(t *testing.T) {
+	rule := models.AnomalyRule{
+		Type:      models.AnomalyTypeText,
+		Operator:  models.Contains,
+		Field:     "job_description",
+		TextValue: "MLM",
+	}
+
+	matched, violations, value, threshold := evaluateRuleConditions(&models.JobData{JobDescription: "Be your own boss in our mlm"}, rule)
+	assert.True(t, matched)
+	assert.Equal(t, []string{`job_description contains "MLM"`}, violations)
+	assert.Zero(t, value)
+	assert.Zero(t, threshold)
+
+	matched, _, _, _ = evaluateRuleConditions(&models.JobData{JobDescription: "Backend engineer"}, rule)
+	assert.False(t, matched)
+
+	// Empty fields are left to the null value check, even for negated operators
+	rule.Operator = models.NotContains
+	matched, _, _, _ = evaluateRuleConditions(&models.JobData{}, rule)
+	assert.False(t, matched)
+}
+
+func TestEvaluateCompoundTextRule(t *testing.T) {
+	rule := models.AnomalyRule{
+		Type:  models.AnomalyTypeCompound,
+		Logic: models.RuleLogicAnd,
+		Conditions: models.RuleConditions{
+			{Type: models.AnomalyTypeText, Operator: models.NotMatches, Field: "company_website", TextValue: `^https?://`},
+			{Type: models.AnomalyTypeMaxSalary, Operator: models.GreaterThan, Value: 300000},
+		},
+	}
+
+	matched, violations, value, _ := evaluateRuleConditions(&models.JobData{CompanyWebsite: "www.example.com", MaxSalary: Float64Ptr(400000)}, rule)
+	assert.True(t, matched)
+	assert.Equal(t, []string{`company_website not_matches "^https?://"`, "max_salary > 300000"}, violations)
+	assert.Zero(t, value) // The first matching condition is the text one
+
+	matched, _, _, _ = evaluateRuleConditions(&models.JobData{CompanyWebsite: "https://example.com", MaxSalary: Float64Ptr(400000)}, rule)
+	assert.False(t, matched)
+}
+
+func TestDetectAnomaliesForAllJobsTextRule(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	expectDetectionRunStart(sqlMock, 1, true)
+	expectDetectionContext(sqlMock, sqlmock.NewRows(anomalyRuleRowColumns).
+		AddRow(1, "MLM", "", "text_match", "contains", 0.0, 0.0, "absolute", "job_description", "mlm", true, "high", "and", nil, []byte(`[]`), now, now, "alice", "alice"))
+	sqlMock.ExpectQuery("SELECT\\s+job_id").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(jobRowWith("job1", map[string]driver.Value{"job_description": "Be your own boss in our MLM"})...).
+			AddRow(jobRowWith("job2", map[string]driver.Value{"job_description": "Build backend services"})...))
+	expectDetectionRunFinish(sqlMock, 1, models.DetectionRunSucceeded, 2, 1)
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	cfg.RequiredFields = nil
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	anomalies, err := service.DetectAnomaliesForAllJobs(context.Background(), true)
+
+	assert.NoError(t, err)
+	if assert.Len(t, anomalies, 1) {
+		assert.Equal(t, "job1", anomalies[0].JobID)
+		assert.Equal(t, models.AnomalyTypeText, anomalies[0].Type)
+		assert.Equal(t, []string{`job_description contains "mlm"`}, anomalies[0].Violations)
+	}
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the instrumentation that started the spans of this package
+const tracerName = "github.com/ainesh01/anomaly_detection/internal/services"
+
+// startSpan starts a span as a child of any span in ctx. The tracer is looked up
+// on each call so spans follow the current global provider, which is a no-op
+// unless tracing was set up.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// recordSpanError marks span as failed with err
+func recordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
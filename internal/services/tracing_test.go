@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/ainesh01/anomaly_detection/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider keeping finished spans in memory for
+// the rest of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestDetectAnomaliesSpans(t *testing.T) {
+	recorder := recordSpans(t)
+
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("FROM jobs").
+		WillReturnRows(sqlmock.NewRows(statisticsRowColumns).AddRow(1, 100000.0, nil, 100000.0, 100000.0, 4.0, nil, 4.0, 4.0, nil, nil, nil, nil, nil))
+	sqlMock.ExpectQuery("WITH salary_median AS").
+		WillReturnRows(sqlmock.NewRows([]string{"salary_median", "salary_mad"}).AddRow(100000.0, 0.0))
+	sqlMock.ExpectQuery("FROM anomaly_rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sqlMock.ExpectQuery("INSERT INTO anomalies").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "inserted"}).AddRow(1, time.Now(), true))
+
+	cfg := config.DefaultDetectionConfig()
+	cfg.TrendWindow = 0
+	cfg.RequiredFields = []string{"company_name"}
+	service := NewAnomalyService(&SQLDB{db: db}, NewAnomalyRuleService(&SQLDB{db: db}, nil), cfg, nil, nil)
+	anomalies, err := service.DetectAnomalies(context.Background(), &models.JobData{JobID: "job1"})
+
+	assert.NoError(t, err)
+	assert.Len(t, anomalies, 1)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	root, ok := spans["AnomalyService.DetectAnomalies"]
+	if !assert.True(t, ok, "missing the DetectAnomalies span") {
+		return
+	}
+	assert.False(t, root.Parent().IsValid())
+	for _, name := range []string{"AnomalyService.getStatistics", "AnomalyService.loadRules", "AnomalyService.saveAnomaly"} {
+		if span, ok := spans[name]; assert.True(t, ok, "missing span %s", name) {
+			assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID(), name)
+			assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext().TraceID(), name)
+		}
+	}
+}
+
+func TestDetectAnomaliesSpanRecordsError(t *testing.T) {
+	recorder := recordSpans(t)
+
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlMock.ExpectQuery("FROM jobs").WillReturnError(assert.AnError)
+
+	service := NewAnomalyService(&SQLDB{db: db}, nil, nil, nil, nil)
+	_, err = service.DetectAnomalies(context.Background(), &models.JobData{JobID: "job1"})
+	assert.Error(t, err)
+
+	ended := recorder.Ended()
+	if assert.Len(t, ended, 2) {
+		for _, span := range ended {
+			assert.Equal(t, codes.Error, span.Status().Code, span.Name())
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Package tracing sets up OpenTelemetry trace export for the anomaly detection service.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Setup installs a global tracer provider exporting spans over OTLP/HTTP when
+// cfg enables tracing. Otherwise the global provider is left as the
+// OpenTelemetry no-op one, so spans cost next to nothing. The returned function
+// flushes pending spans and stops the provider; call it before exiting.
+func Setup(ctx context.Context, cfg *config.TracingConfig) (func(context.Context) error, error) {
+	if !cfg.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// The exporter reads the endpoint, headers and protocol options from the
+	// OTEL_EXPORTER_OTLP_* variables; it does not connect until spans are sent
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error building trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ainesh01/anomaly_detection/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	t.Run("disabled leaves the no-op provider", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), &config.TracingConfig{ServiceName: config.DefaultServiceName})
+
+		assert.NoError(t, err)
+		assert.Same(t, previous, otel.GetTracerProvider())
+		assert.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("enabled installs an exporting provider", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:4318")
+
+		shutdown, err := Setup(context.Background(), &config.TracingConfig{Endpoint: "http://127.0.0.1:4318", ServiceName: config.DefaultServiceName})
+
+		assert.NoError(t, err)
+		assert.IsType(t, &sdktrace.TracerProvider{}, otel.GetTracerProvider())
+		// Nothing was recorded, so shutting down sends nothing
+		assert.NoError(t, shutdown(context.Background()))
+	})
+}